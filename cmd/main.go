@@ -1,223 +1,97 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"strconv"
 
 	"github.com/omesh-barhate/ByteForge/internal"
-	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
-	"github.com/omesh-barhate/ByteForge/internal/platform/types"
-	"github.com/omesh-barhate/ByteForge/internal/table/column"
-	"github.com/omesh-barhate/ByteForge/internal/table/fulltext"
+	"github.com/omesh-barhate/ByteForge/internal/shell"
+	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
+	"github.com/omesh-barhate/ByteForge/internal/table/wal"
 )
 
-func main() {
-	if len(os.Args) == 1 {
-		testCreateTable()
-		os.Exit(0)
-	}
-
-	cmd := os.Args[1]
-	switch cmd {
-	case "c":
-		testCreateTable()
-	case "r":
-		testReadTable()
-	case "t":
-		testFoo()
-	}
-}
+const usage = `Usage: byteforge <command> [flags]
 
-func testFoo() {
-	values := []encoding.EmbeddedValueMarshaler{
-		fulltext.NewIndexItem(128, 1),
-		fulltext.NewIndexItem(128, 2),
-	}
-	m := encoding.NewListMarshaler(values)
-	data, err := m.MarshalBinary()
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("%v\n", data)
-}
+Commands:
+  shell    Start an interactive SQL shell
+  help     Show this message
 
-func testCreateTable() {
-	_ = os.Remove("./data/my_db/users.bin")
-	_ = os.Remove("./data/my_db/users_idx.bin")
-	_ = os.Remove("./data/my_db/users_fulltext_idx.bin")
-	_ = os.Remove("./data/my_db/users_wal.bin")
-	_ = os.Remove("./data/my_db/users_wal_last_commit.bin")
+Run 'byteforge <command> -h' for the flags of a command.
+`
 
-	db, err := internal.NewDatabase("my_db")
-	if err != nil {
-		log.Fatal(err)
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
-	defer db.Close()
-
-	createTable(db)
-
-	insert(db, 1, "software engineer", 31, true)
-	insert(db, 2, "software engineer", 27, false)
-	insert(db, 3, "software engineer", 27, false)
-	insert(db, 4, "designer", 28, true)
-
-	printRawFulLTextIdx(db)
-}
 
-func testReadTable() {
-	db, err := internal.NewDatabase("my_db")
-	if err != nil {
-		log.Fatal(err)
+	switch os.Args[1] {
+	case "shell":
+		if err := runShell(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
 	}
-	defer db.Close()
-
-	queryAll(db)
 }
 
-func createTable(db *internal.Database) {
-	id, err := column.New("id", types.TypeInt64, column.NewColumnOpts(false, false))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	username, err := column.New("username", types.TypeString, column.NewColumnOpts(false, false))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	age, err := column.New("age", types.TypeByte, column.Opts{})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	job, err := column.New("job", types.TypeString, column.NewColumnOpts(false, true))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	isCool, err := column.New("is_active", types.TypeBool, column.Opts{})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	_, err = db.CreateTable(db.Path, "users", []string{"id", "username", "age", "job", "is_active"}, map[string]*column.Column{
-		"id":        id,
-		"username":  username,
-		"age":       age,
-		"job":       job,
-		"is_active": isCool,
-	})
-	if err != nil {
-		log.Fatal(err)
+func runShell(args []string) error {
+	fs := flag.NewFlagSet("shell", flag.ContinueOnError)
+	dbName := fs.String("db", "", "name of the database in "+internal.BaseDir+". It is created if it does not exist")
+	verbose := fs.Bool("verbose", false, "print log messages of the storage engine")
+	durabilityName := fs.String("durability", wal.DurabilityCommit.String(), "when writes are synced to the disk: off, commit, group or always")
+	pageSize := fs.Int("page-size", table.DefaultPageSize, "page size of new tables in bytes: 4096, 8192 or 16384")
+	bufferPoolSize := fs.Int("buffer-pool-size", bufferpool.DefaultSize, "memory in bytes that pages of the tables can occupy")
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
-}
-
-func insert(db *internal.Database, id int64, job string, age byte, isActive bool) {
-	_, err := db.Tables["users"].Insert(map[string]interface{}{
-		"id":        id,
-		"username":  "user" + strconv.Itoa(int(id)),
-		"age":       age,
-		"job":       job,
-		"is_active": isActive,
-	}, true)
-	if err != nil {
-		log.Fatal(err)
+	if *dbName == "" {
+		return errors.New("shell: --db is required")
 	}
-}
-
-func update(db *internal.Database) {
-	_, err := db.Tables["users"].Update(map[string]interface{}{
-		"job": "software engineer",
-	}, map[string]interface{}{
-		"job": "developer",
-	})
-
+	durability, err := wal.ParseDurability(*durabilityName)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("shell: %w", err)
 	}
-}
-
-func printRaw(db *internal.Database) {
-	b, _ := db.Tables["users"].ReadRaw()
-	fmt.Println(b)
-}
-
-func printRawIdx(db *internal.Database) {
-	b, _ := db.Tables["users"].ReadRawIdx()
-	fmt.Println(b)
-}
-
-func printRawFulLTextIdx(db *internal.Database) {
-	b, _ := db.Tables["users"].ReadRawFullTextIdx()
-	fmt.Println(b)
-}
-
-func del(db *internal.Database) {
-	_, err := db.Tables["users"].Delete(map[string]interface{}{
-		"job": "designer",
-	})
-
-	if err != nil {
-		fmt.Println("here")
-		log.Fatal(err)
+	if !*verbose {
+		log.SetOutput(io.Discard)
 	}
-}
-
-func delById(db *internal.Database, id int64) {
-	_, err := db.Tables["users"].Delete(map[string]interface{}{
-		"id": id,
-	})
 
+	db, err := openDatabase(*dbName)
 	if err != nil {
-		fmt.Println("here")
-		log.Fatal(err)
+		return fmt.Errorf("shell: %w", err)
 	}
-}
-
-func query(db *internal.Database, id int64) {
-	rows, err := db.Tables["users"].Select(map[string]interface{}{
-		"id": id,
-	})
-	if err != nil {
-		log.Fatal(err)
+	defer db.Close()
+	db.SetDurability(durability)
+	if err = db.SetPageSize(*pageSize); err != nil {
+		return fmt.Errorf("shell: %w", err)
 	}
-	fmt.Println(rows)
-}
-
-func queryByJob(db *internal.Database, job string) {
-	rows, err := db.Tables["users"].Select(map[string]interface{}{
-		"job": job,
-	})
-	if err != nil {
-		log.Fatal(err)
+	if err = db.SetBufferPoolSize(*bufferPoolSize); err != nil {
+		return fmt.Errorf("shell: %w", err)
 	}
-	fmt.Printf("%#v\n", rows)
-	fmt.Println(rows)
-}
 
-func queryAll(db *internal.Database) {
-	rows, err := db.Tables["users"].Select(map[string]interface{}{})
-	if err != nil {
-		log.Fatal(err)
+	fmt.Fprintf(os.Stderr, "Connected to %s. Enter .help for usage.\n", *dbName)
+	sh := shell.New(db, shell.NewLineReader(os.Stdin, os.Stdout), os.Stdout)
+	if err = sh.Run(); err != nil {
+		return fmt.Errorf("shell: %w", err)
 	}
-	fmt.Println(rows)
+	return nil
 }
 
-func seed(db *internal.Database, n int) {
-	fmt.Println("seeding...")
-	for i := 1; i < n; i++ {
-		_, err := db.Tables["users"].Insert(map[string]interface{}{
-			"id":       int64(i),
-			"username": "user" + strconv.Itoa(i),
-			"job":      "software engineer",
-			"age":      byte(30),
-			"is_cool":  true,
-		}, true)
-		if err != nil {
-			log.Fatal(err)
-		}
+// openDatabase opens the database or creates it if it does not exist yet
+func openDatabase(name string) (*internal.Database, error) {
+	db, err := internal.NewDatabase(name)
+	var errNotExist *internal.DatabaseDoesNotExistError
+	if errors.As(err, &errNotExist) {
+		return internal.CreateDatabase(name)
 	}
-	fmt.Println("seeding done")
+	return db, err
 }
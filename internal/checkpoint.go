@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	walencoding "github.com/omesh-barhate/ByteForge/internal/table/wal/encoding"
+)
+
+// Checkpoint syncs the files of every table and records in the WAL that the changes logged so far are durable
+// It waits until the running transaction is finished
+func (db *Database) Checkpoint() error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	if err := db.checkpoint(); err != nil {
+		return fmt.Errorf("Database.Checkpoint: %w", err)
+	}
+	return nil
+}
+
+// checkpoint must not be called while a transaction is in the middle of its changes
+// Versions that no snapshot can see are reclaimed first, so their deletion is covered by the checkpoint too
+func (db *Database) checkpoint() error {
+	if _, err := db.reclaimVersions(); err != nil {
+		return fmt.Errorf("Database.checkpoint: %w", err)
+	}
+	lsn := db.wal.LSN()
+	if lsn == db.wal.CheckpointLSN() {
+		return nil
+	}
+	// A table is only synced after the change it's in the middle of was applied, so every change up to lsn is synced
+	for _, t := range db.Tables {
+		if err := t.Sync(); err != nil {
+			return fmt.Errorf("Database.checkpoint: %w", err)
+		}
+	}
+	if err := db.wal.Checkpoint(lsn); err != nil {
+		return fmt.Errorf("Database.checkpoint: %w", err)
+	}
+	return nil
+}
+
+// recover replays the WAL entries written after the last checkpoint and takes a new checkpoint
+// Every entry is replayed in the order of the LSNs, including the ones of transactions that were not committed, so
+// the pages are in the same state as before the crash. Then the transactions that were neither committed nor rolled
+// back are undone. A table the rollback of a transaction already reverted has an OpUndo entry of it, so it's skipped
+func (db *Database) recover() error {
+	entries, err := db.wal.Entries()
+	if err != nil {
+		return fmt.Errorf("Database.recover: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	redo := make(map[string][]*walencoding.WALUnmarshaler)
+	// running contains the IDs of the transactions that were neither committed nor rolled back
+	running := make(map[int64]bool)
+	for _, e := range entries {
+		switch e.Op {
+		case walencoding.OpBegin:
+			running[e.LSN] = true
+		case walencoding.OpCommit, walencoding.OpRollback:
+			delete(running, e.TxID)
+		default:
+			redo[e.Table] = append(redo[e.Table], e)
+		}
+	}
+
+	for name, tableEntries := range redo {
+		t, ok := db.Tables[name]
+		// DropTable takes a checkpoint, so it's only possible if the process stopped while the table was dropped
+		if !ok {
+			continue
+		}
+		if err = t.Redo(tableEntries); err != nil {
+			return fmt.Errorf("Database.recover: %w", err)
+		}
+	}
+
+	byTx := txEntries(entries)
+	for _, txID := range slices.Sorted(maps.Keys(running)) {
+		for name, tableEntries := range byTx[txID] {
+			t, ok := db.Tables[name]
+			undone := slices.ContainsFunc(tableEntries, func(e *walencoding.WALUnmarshaler) bool {
+				return e.Op == walencoding.OpUndo
+			})
+			if !ok || undone {
+				continue
+			}
+			if err = t.Undo(txID, tableEntries); err != nil {
+				return fmt.Errorf("Database.recover: %w", err)
+			}
+		}
+		if _, err = db.wal.Append(txID, walencoding.OpRollback, "", nil); err != nil {
+			return fmt.Errorf("Database.recover: %w", err)
+		}
+	}
+	if err = db.checkpoint(); err != nil {
+		return fmt.Errorf("Database.recover: %w", err)
+	}
+	return nil
+}
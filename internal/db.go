@@ -4,12 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/omesh-barhate/ByteForge/internal/platform/parser"
 	"github.com/omesh-barhate/ByteForge/internal/platform/parser/io"
 	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
+	"github.com/omesh-barhate/ByteForge/internal/table/column"
 	columnio "github.com/omesh-barhate/ByteForge/internal/table/column/io"
+	"github.com/omesh-barhate/ByteForge/internal/table/fsm"
+	"github.com/omesh-barhate/ByteForge/internal/table/predicate"
+	"github.com/omesh-barhate/ByteForge/internal/table/sequence"
 	"github.com/omesh-barhate/ByteForge/internal/table/wal"
 )
 
@@ -19,10 +26,35 @@ const (
 
 type Tables map[string]*table.Table
 
+// Database is safe to use from multiple goroutines. Transactions and schema changes run one at a time, reads outside of
+// a transaction run in parallel with them and with each other. Such a read sees a snapshot of the records taken when
+// it started
 type Database struct {
 	Name   string
 	Path   string
 	Tables Tables
+	// Sequences contains the sequences created with CreateSequence and the ones that belong to auto-increment columns
+	Sequences map[string]*sequence.Sequence
+
+	// txMu is held by the running transaction from Begin until Commit or Rollback and by schema changes
+	txMu sync.Mutex
+	tx   *Tx
+	// mu guards Tables and Sequences. They are only modified while holding both txMu and mu, so code that holds txMu
+	// can read them without mu
+	mu sync.RWMutex
+	// wal is the write-ahead log shared by every table
+	wal *wal.WAL
+	// pageSize is the size of the pages of the tables created by CreateTable
+	pageSize int
+	// pool caches the pages of every table
+	pool *bufferpool.Pool
+
+	// snapMu guards activeTxID and snapshots
+	snapMu sync.Mutex
+	// activeTxID is the ID of the running transaction after it logged its begin entry or 0
+	activeTxID int64
+	// snapshots counts the snapshots that are being read by their horizon
+	snapshots map[int64]int
 }
 
 func NewDatabase(name string) (*Database, error) {
@@ -31,34 +63,81 @@ func NewDatabase(name string) (*Database, error) {
 	}
 
 	db := &Database{
-		Name: name,
-		Path: path(name),
+		Name:      name,
+		Path:      path(name),
+		pageSize:  table.DefaultPageSize,
+		pool:      bufferpool.New(bufferpool.DefaultSize),
+		snapshots: make(map[int64]int),
 	}
 
+	writeAheadLog, err := wal.Open(db.Path)
+	if err != nil {
+		return nil, fmt.Errorf("NewDatabase: %w", err)
+	}
+	db.wal = writeAheadLog
+	db.pool.SetLog(db.wal)
+
+	sequences, err := db.readSequences()
+	if err != nil {
+		return nil, fmt.Errorf("NewDatabase: %w", err)
+	}
+	db.Sequences = sequences
+
 	tables, err := db.readTables()
 	if err != nil {
 		return nil, fmt.Errorf("NewDatabase: %w", err)
 	}
 
 	db.Tables = tables
-	for _, t := range db.Tables {
-		if err := t.RestoreWAL(); err != nil {
-			return nil, fmt.Errorf("NewDatabase: %w", err)
-		}
+	if err = db.recover(); err != nil {
+		return nil, fmt.Errorf("NewDatabase: %w", err)
 	}
 	return db, nil
 }
 
+// Close rolls back the running transaction, takes a checkpoint and closes every table, sequence and the WAL
 func (db *Database) Close() error {
 	var e error
+	if tx := db.tx; tx != nil {
+		if err := tx.Rollback(); err != nil {
+			e = err
+		}
+	}
+	if err := db.Checkpoint(); err != nil {
+		e = err
+	}
 	for _, t := range db.Tables {
 		if err := t.Close(); err != nil {
 			e = err
 		}
 	}
+	if err := db.wal.Close(); err != nil {
+		e = err
+	}
+	for _, seq := range db.Sequences {
+		if err := seq.Close(); err != nil {
+			e = err
+		}
+	}
 	return e
 }
 
+// SetDurability sets when the WAL, table and index files are synced. The default is wal.DurabilityCommit
+// Checkpoints and sequences are always synced because recovery depends on them
+func (db *Database) SetDurability(d wal.Durability) {
+	db.wal.SetDurability(d)
+}
+
+// SetGroupCommitDelay sets how long wal.DurabilityGroup waits before it syncs the WAL, so the transactions that
+// commit in the meantime share the fsync
+func (db *Database) SetGroupCommitDelay(d time.Duration) {
+	db.wal.SetGroupCommitDelay(d)
+}
+
+func (db *Database) Durability() wal.Durability {
+	return db.wal.Durability()
+}
+
 func CreateDatabase(name string) (*Database, error) {
 	if exists(name) {
 		return nil, fmt.Errorf("CreateDatabase: %w", NewDatabaseAlreadyExistsError(name))
@@ -67,12 +146,95 @@ func CreateDatabase(name string) (*Database, error) {
 	if err := os.MkdirAll(path(name), 0777); err != nil {
 		return nil, fmt.Errorf("CreateDatabase: %w", err)
 	}
+	writeAheadLog, err := wal.Open(path(name))
+	if err != nil {
+		return nil, fmt.Errorf("CreateDatabase: %w", err)
+	}
 
-	return &Database{
-		Name:   name,
-		Path:   path(name),
-		Tables: make(map[string]*table.Table),
-	}, nil
+	db := &Database{
+		Name:      name,
+		Path:      path(name),
+		Tables:    make(map[string]*table.Table),
+		Sequences: make(map[string]*sequence.Sequence),
+		wal:       writeAheadLog,
+		pageSize:  table.DefaultPageSize,
+		pool:      bufferpool.New(bufferpool.DefaultSize),
+		snapshots: make(map[int64]int),
+	}
+	db.pool.SetLog(db.wal)
+	return db, nil
+}
+
+// SetPageSize sets the size of the pages of the tables created afterwards. It has to be one of table.PageSizes
+// Existing tables keep the page size they were created with
+func (db *Database) SetPageSize(size int) error {
+	if !slices.Contains(table.PageSizes, size) {
+		return fmt.Errorf("Database.SetPageSize: %w", table.NewInvalidPageSizeError(size))
+	}
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	db.pageSize = size
+	return nil
+}
+
+// SetBufferPoolSize sets the number of bytes the pages of the tables can occupy in memory. It has to be at least
+// bufferpool.MinSize. Pages are evicted until the pool fits into the new size
+func (db *Database) SetBufferPoolSize(size int) error {
+	if err := db.pool.SetSize(size); err != nil {
+		return fmt.Errorf("Database.SetBufferPoolSize: %w", err)
+	}
+	return nil
+}
+
+// WALStats returns the number of entries appended to the WAL and the number of its fsyncs since the database was
+// opened
+func (db *Database) WALStats() wal.Stats {
+	return db.wal.Stats()
+}
+
+// BufferPoolStats returns the content and the hit ratio of the buffer pool shared by the tables
+func (db *Database) BufferPoolStats() bufferpool.Stats {
+	return db.pool.Stats()
+}
+
+// Table returns the table called name. It reports false if there's no such table
+func (db *Database) Table(name string) (*table.Table, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	t, ok := db.Tables[name]
+	return t, ok
+}
+
+// TableNames returns the name of every table in alphabetical order
+func (db *Database) TableNames() []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	names := make([]string, 0, len(db.Tables))
+	for name := range db.Tables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SelectWhere returns the records of a table that satisfy pred without starting a transaction
+// It reads a snapshot: the changes of transactions committed before it started are visible, the ones of the running
+// transaction are not. Writers don't wait until it's finished
+func (db *Database) SelectWhere(tableName string, pred predicate.Predicate) (*table.SelectResult, error) {
+	// The read lock keeps the table from being dropped while it's read
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	t, ok := db.Tables[tableName]
+	if !ok {
+		return nil, fmt.Errorf("Database.SelectWhere: %w", NewTableDoesNotExistError(tableName))
+	}
+	snap := db.snapshot()
+	defer db.releaseSnapshot(snap)
+	result, err := t.SelectSnapshot(pred, snap)
+	if err != nil {
+		return nil, fmt.Errorf("Database.SelectWhere: %w", err)
+	}
+	return result, nil
 }
 
 func (db *Database) readTables() (Tables, error) {
@@ -83,6 +245,10 @@ func (db *Database) readTables() (Tables, error) {
 
 	tables := make([]*table.Table, 0)
 	for _, v := range entries {
+		if filepath.Ext(v.Name()) != table.FileExtension {
+			continue
+		}
+		// Tables used to have their own WAL files before the database-wide WAL
 		if strings.Contains(v.Name(), "_wal") {
 			continue
 		}
@@ -92,68 +258,127 @@ func (db *Database) readTables() (Tables, error) {
 		if _, err := v.Info(); err != nil {
 			return nil, fmt.Errorf("Database.readTables: %w", err)
 		}
-		f, err := os.OpenFile(filepath.Join(db.Path, v.Name()), os.O_APPEND|os.O_RDWR, 0666)
-		if err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
-		}
-		parts := strings.Split(v.Name(), ".")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("Database.readTables: %w", table.NewInvalidFilename(v.Name()))
-		}
-		idxFile, err := os.OpenFile(filepath.Join(db.Path, parts[0]+"_idx."+parts[1]), os.O_APPEND|os.O_RDWR, 0666)
+		t, err := db.openTable(v.Name())
 		if err != nil {
 			return nil, fmt.Errorf("Database.readTables: %w", err)
 		}
+		tables = append(tables, t)
+	}
 
-		fullTextIdxFile, err := os.OpenFile(filepath.Join(db.Path, parts[0]+"_fulltext_idx."+parts[1]), os.O_APPEND|os.O_RDWR, 0666)
-		if err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
-		}
+	tablesMap := make(Tables)
+	for _, v := range tables {
+		tablesMap[v.Name] = v
+	}
+	return tablesMap, nil
+}
 
-		r, err := io.NewReader(f)
-		columnDefReader := columnio.NewColumnDefinitionReader(f, r)
-		tableName, err := table.GetTableName(f)
-		if err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
-		}
-		writeAheadLog, err := wal.NewWAL(db.Path, tableName)
-		if err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
-		}
+// openTable opens the table stored in filename and loads its indexes
+func (db *Database) openTable(filename string) (*table.Table, error) {
+	// Pages are updated in place so the table file cannot be opened with O_APPEND
+	f, err := os.OpenFile(filepath.Join(db.Path, filename), os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	parts := strings.Split(filename, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Database.openTable: %w", table.NewInvalidFilename(filename))
+	}
+	// The nodes of the B-tree are updated in place too
+	idxFile, err := os.OpenFile(filepath.Join(db.Path, parts[0]+"_idx."+parts[1]), os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
 
-		t, err := table.NewTable(f, idxFile, fullTextIdxFile, r, columnDefReader, writeAheadLog)
-		if err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
-		}
+	fullTextIdxFile, err := os.OpenFile(filepath.Join(db.Path, parts[0]+"_fulltext_idx."+parts[1]), os.O_APPEND|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	// Tables created before the free-space map existed get an empty one that is built from their pages
+	fsmFile, err := os.OpenFile(filepath.Join(db.Path, parts[0]+fsm.FileExtension), os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
 
-		if err = t.ReadColumnDefinitions(); err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
-		}
-		if err = t.SetRecordParser(parser.NewRecordParser(f, t.ColumnNames())); err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
+	r, err := io.NewReader(f)
+	columnDefReader := columnio.NewColumnDefinitionReader(f, r)
+	t, err := table.NewTable(f, idxFile, fullTextIdxFile, fsmFile, r, columnDefReader, db.wal)
+	if err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	if err = t.SetBufferPool(db.pool); err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+
+	if err = t.ReadColumnDefinitions(); err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	if err = t.LoadIdx(); err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	if err = t.LoadFullTextIdx(); err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	if err = db.loadIndexes(t); err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	if t.Legacy() {
+		if err = db.upgradeTable(t); err != nil {
+			return nil, fmt.Errorf("Database.openTable: %w", err)
 		}
-		if err = t.LoadIdx(); err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
+	} else if err = t.LoadFreeSpaceMap(); err != nil {
+		return nil, fmt.Errorf("Database.openTable: %w", err)
+	}
+	if col := t.AutoIncrementColumn(); col != "" {
+		seq, ok := db.Sequences[sequenceName(t.Name, col)]
+		if !ok {
+			return nil, fmt.Errorf("Database.openTable: %w", NewSequenceDoesNotExistError(sequenceName(t.Name, col)))
 		}
-		if err = t.LoadFullTextIdx(); err != nil {
-			return nil, fmt.Errorf("Database.readTables: %w", err)
+		if err = t.SetSequence(seq); err != nil {
+			return nil, fmt.Errorf("Database.openTable: %w", err)
 		}
-		tables = append(tables, t)
 	}
+	return t, nil
+}
 
-	tablesMap := make(Tables)
-	for _, v := range tables {
-		tablesMap[v.Name] = v
+// upgradeTable converts a table created before pages had a fixed size
+// The WAL entries written after the last checkpoint refer to the old pages, so the table can only be converted if
+// none of them belongs to it
+func (db *Database) upgradeTable(t *table.Table) error {
+	entries, err := db.wal.Entries()
+	if err != nil {
+		return fmt.Errorf("Database.upgradeTable: %w", err)
 	}
-	return tablesMap, nil
+	for _, e := range entries {
+		if e.Table == t.Name {
+			return fmt.Errorf("Database.upgradeTable: %w", NewLegacyTableNotCheckpointedError(t.Name))
+		}
+	}
+	if err = t.Upgrade(); err != nil {
+		return fmt.Errorf("Database.upgradeTable: %w", err)
+	}
+	return nil
 }
 
-func (db *Database) CreateTable(dbPath, name string, columnNames []string, columns table.Columns) (*table.Table, error) {
+// CreateTable creates a table whose records are identified by the values of the primaryKey columns
+// It waits until the running transaction is finished
+func (db *Database) CreateTable(dbPath, name string, columnNames []string, columns table.Columns, primaryKey []string) (*table.Table, error) {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	path := filepath.Join(dbPath, name+table.FileExtension)
 	idxPath := filepath.Join(dbPath, name+"_idx"+table.FileExtension)
 	if _, err := os.Open(path); err == nil {
 		return nil, fmt.Errorf("Database.CreateTable: %w", NewTableAlreadyExistsError(name))
 	}
+	// The sequence is created after the table, so its name has to be checked before anything is written
+	for _, col := range columnNames {
+		if columns[col].Opts.AutoIncrement {
+			if _, ok := db.Sequences[sequenceName(name, col)]; ok {
+				return nil, fmt.Errorf("Database.CreateTable: %w", NewSequenceAlreadyExistsError(sequenceName(name, col)))
+			}
+		}
+	}
 	fullTextIdxPath := filepath.Join(dbPath, name+"_fulltext_idx"+table.FileExtension)
 	if _, err := os.Open(path); err == nil {
 		return nil, fmt.Errorf("Database.CreateTable: %w", NewTableAlreadyExistsError(name))
@@ -171,34 +396,259 @@ func (db *Database) CreateTable(dbPath, name string, columnNames []string, colum
 	if err != nil {
 		return nil, fmt.Errorf("Database.CreateTable: %w", err)
 	}
+	fsmFile, err := os.Create(filepath.Join(dbPath, name+fsm.FileExtension))
+	if err != nil {
+		return nil, fmt.Errorf("Database.CreateTable: %w", err)
+	}
 
 	r, err := io.NewReader(f)
 	if err != nil {
 		return nil, fmt.Errorf("Database.CreateTable: %w", err)
 	}
 	columnDefReader := columnio.NewColumnDefinitionReader(f, r)
-	recParser := parser.NewRecordParser(f, columnNames)
-	writeAheadLog, err := wal.NewWAL(db.Path, name)
+	t, err := table.NewTableWithColumns(f, idxFile, fullTextIdxFile, fsmFile, r, columnDefReader, db.wal, columns, columnNames, primaryKey)
 	if err != nil {
-		return nil, NewCannotCreateTableError(err, name)
+		return nil, fmt.Errorf("Database.CreateTable: %w", err)
 	}
-
-	t, err := table.NewTableWithColumns(f, idxFile, fullTextIdxFile, r, columnDefReader, writeAheadLog, columns, columnNames)
-	if err != nil {
+	if err = t.SetPageSize(db.pageSize); err != nil {
 		return nil, fmt.Errorf("Database.CreateTable: %w", err)
 	}
-	if err = t.SetRecordParser(recParser); err != nil {
+	if err = t.SetBufferPool(db.pool); err != nil {
 		return nil, fmt.Errorf("Database.CreateTable: %w", err)
 	}
 
 	if err = t.WriteColumnDefinitions(); err != nil {
 		return nil, fmt.Errorf("Database.CreateTable: %w", err)
 	}
+	if col := t.AutoIncrementColumn(); col != "" {
+		seq, err := db.createSequence(sequenceName(name, col), 1)
+		if err != nil {
+			return nil, fmt.Errorf("Database.CreateTable: %w", err)
+		}
+		if err = t.SetSequence(seq); err != nil {
+			return nil, fmt.Errorf("Database.CreateTable: %w", err)
+		}
+	}
 
 	db.Tables[name] = t
 	return t, nil
 }
 
+// DropTable closes the table and removes every file that belongs to it
+// It waits until the running transaction is finished
+func (db *Database) DropTable(name string) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, ok := db.Tables[name]
+	if !ok {
+		return fmt.Errorf("Database.DropTable: %w", NewTableDoesNotExistError(name))
+	}
+	// The entries of the table must not be replayed into a new table with the same name
+	if err := db.checkpoint(); err != nil {
+		return fmt.Errorf("Database.DropTable: %w", err)
+	}
+	if err := t.Close(); err != nil {
+		return fmt.Errorf("Database.DropTable: %w", err)
+	}
+	delete(db.Tables, name)
+
+	for _, f := range tableFilenames(t) {
+		if err := os.Remove(filepath.Join(db.Path, f)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Database.DropTable: %w", err)
+		}
+	}
+	if col := t.AutoIncrementColumn(); col != "" {
+		if err := db.removeSequence(sequenceName(name, col)); err != nil {
+			return fmt.Errorf("Database.DropTable: %w", err)
+		}
+	}
+	return nil
+}
+
+// tableFilenames returns the names of the files that store the records and the indexes of t
+func tableFilenames(t *table.Table) []string {
+	files := []string{
+		t.Name + table.FileExtension,
+		t.Name + "_idx" + table.FileExtension,
+		t.Name + "_fulltext_idx" + table.FileExtension,
+		t.Name + fsm.FileExtension,
+	}
+	for _, idx := range t.Indexes() {
+		if idx.Type == table.AccessTypeBtreeIdx && !idx.Primary {
+			files = append(files, indexFilename(t.Name, idx.Column))
+		}
+	}
+	return files
+}
+
+// CreateSequence creates a sequence whose first value is start. It's stored in <name>.seq
+func (db *Database) CreateSequence(name string, start int64) (*sequence.Sequence, error) {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	seq, err := db.createSequence(name, start)
+	if err != nil {
+		return nil, fmt.Errorf("Database.CreateSequence: %w", err)
+	}
+	return seq, nil
+}
+
+func (db *Database) createSequence(name string, start int64) (*sequence.Sequence, error) {
+	if _, ok := db.Sequences[name]; ok {
+		return nil, fmt.Errorf("Database.createSequence: %w", NewSequenceAlreadyExistsError(name))
+	}
+	path := filepath.Join(db.Path, name+sequence.FileExtension)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Database.createSequence: %w", err)
+	}
+	seq := sequence.NewSequence(f, name)
+	if err = seq.Reset(start); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("Database.createSequence: %w", err)
+	}
+	db.Sequences[name] = seq
+	return seq, nil
+}
+
+// Sequence returns the sequence called name
+func (db *Database) Sequence(name string) (*sequence.Sequence, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	seq, ok := db.Sequences[name]
+	if !ok {
+		return nil, fmt.Errorf("Database.Sequence: %w", NewSequenceDoesNotExistError(name))
+	}
+	return seq, nil
+}
+
+// DropSequence removes a sequence. The sequence of an auto-increment column is removed with its table
+func (db *Database) DropSequence(name string) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.Sequences[name]; !ok {
+		return fmt.Errorf("Database.DropSequence: %w", NewSequenceDoesNotExistError(name))
+	}
+	for _, t := range db.Tables {
+		if col := t.AutoIncrementColumn(); col != "" && sequenceName(t.Name, col) == name {
+			return fmt.Errorf("Database.DropSequence: %w", NewSequenceInUseError(name, t.Name))
+		}
+	}
+	if err := db.removeSequence(name); err != nil {
+		return fmt.Errorf("Database.DropSequence: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) removeSequence(name string) error {
+	seq, ok := db.Sequences[name]
+	if !ok {
+		return nil
+	}
+	if err := seq.Close(); err != nil {
+		return fmt.Errorf("Database.removeSequence: %w", err)
+	}
+	delete(db.Sequences, name)
+	if err := os.Remove(filepath.Join(db.Path, name+sequence.FileExtension)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Database.removeSequence: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) readSequences() (map[string]*sequence.Sequence, error) {
+	entries, err := os.ReadDir(db.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Database.readSequences: %w", err)
+	}
+	sequences := make(map[string]*sequence.Sequence)
+	for _, v := range entries {
+		if filepath.Ext(v.Name()) != sequence.FileExtension {
+			continue
+		}
+		// WriteAt doesn't work on files opened with O_APPEND
+		f, err := os.OpenFile(filepath.Join(db.Path, v.Name()), os.O_RDWR, 0666)
+		if err != nil {
+			return nil, fmt.Errorf("Database.readSequences: %w", err)
+		}
+		name := strings.TrimSuffix(v.Name(), sequence.FileExtension)
+		seq := sequence.NewSequence(f, name)
+		if err = seq.Load(); err != nil {
+			return nil, fmt.Errorf("Database.readSequences: %w", err)
+		}
+		sequences[name] = seq
+	}
+	return sequences, nil
+}
+
+// sequenceName returns the name of the sequence that generates the values of an auto-increment column
+func sequenceName(tableName, col string) string {
+	return tableName + "_" + col + "_seq"
+}
+
+// CreateIndex creates a B-tree index on a column of a table. The index is stored in <table>_<column>_idx.bin
+// It waits until the running transaction is finished
+func (db *Database) CreateIndex(tableName, col string) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	t, ok := db.Tables[tableName]
+	if !ok {
+		return fmt.Errorf("Database.CreateIndex: %w", NewTableDoesNotExistError(tableName))
+	}
+	if _, ok = t.Columns()[col]; !ok {
+		return fmt.Errorf("Database.CreateIndex: %w", column.NewUnknownColumnError(tableName, col))
+	}
+	for _, idx := range t.Indexes() {
+		if idx.Column == col && idx.Type == table.AccessTypeBtreeIdx {
+			return fmt.Errorf("Database.CreateIndex: %w", table.NewIndexAlreadyExistsError(tableName, col))
+		}
+	}
+
+	// O_EXCL makes sure the index doesn't overwrite a file of another table or the full-text index
+	path := filepath.Join(db.Path, indexFilename(tableName, col))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if err != nil {
+		return fmt.Errorf("Database.CreateIndex: %w", err)
+	}
+	if err = t.CreateIndex(col, f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("Database.CreateIndex: %w", err)
+	}
+	return nil
+}
+
+// loadIndexes loads the B-tree indexes created by CreateIndex
+func (db *Database) loadIndexes(t *table.Table) error {
+	for _, col := range t.ColumnNames() {
+		// <table>_fulltext_idx.bin is the full-text index so a column called fulltext cannot have a B-tree index
+		if col == t.PrimaryKey()[0] || col == "fulltext" {
+			continue
+		}
+		// WriteAt doesn't work on files opened with O_APPEND
+		f, err := os.OpenFile(filepath.Join(db.Path, indexFilename(t.Name, col)), os.O_RDWR, 0666)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("Database.loadIndexes: %w", err)
+		}
+		if err = t.LoadIndex(col, f); err != nil {
+			return fmt.Errorf("Database.loadIndexes: %w", err)
+		}
+	}
+	return nil
+}
+
+func indexFilename(tableName, col string) string {
+	return tableName + "_" + col + "_idx" + table.FileExtension
+}
+
 func exists(name string) bool {
 	_, err := os.ReadDir(path(name))
 	return err == nil
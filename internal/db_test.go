@@ -1,12 +1,24 @@
 package internal
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
 	"github.com/omesh-barhate/ByteForge/internal/table/column"
+	"github.com/omesh-barhate/ByteForge/internal/table/index"
+	"github.com/omesh-barhate/ByteForge/internal/table/predicate"
+	"github.com/omesh-barhate/ByteForge/internal/table/wal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,19 +72,28 @@ func TestInsert(t *testing.T) {
 		t.Errorf("len(res) == %d, len(expected) == 3", len(res.Rows))
 	}
 
-	expected := []byte{90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 105, 100, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 1, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 117, 115, 101, 114, 110, 97, 109, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 97, 103, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 3, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 106, 111, 98, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 105, 115, 95, 97, 99, 116, 105, 118, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 4, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 255, 124, 0, 0, 0, 100, 57, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 49, 3, 1, 0, 0, 0, 31, 2, 17, 0, 0, 0, 115, 111, 102, 116, 119, 97, 114, 101, 32, 101, 110, 103, 105, 110, 101, 101, 114, 4, 1, 0, 0, 0, 1, 100, 57, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 50, 3, 1, 0, 0, 0, 27, 2, 17, 0, 0, 0, 115, 111, 102, 116, 119, 97, 114, 101, 32, 101, 110, 103, 105, 110, 101, 101, 114, 4, 1, 0, 0, 0, 0, 255, 53, 0, 0, 0, 100, 48, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 51, 3, 1, 0, 0, 0, 28, 2, 8, 0, 0, 0, 100, 101, 115, 105, 103, 110, 101, 114, 4, 1, 0, 0, 0, 1}
 	b, err := db.Tables["users"].ReadRaw()
 	if err != nil {
 		t.Errorf("err should be nil: %v", err)
 	}
-	assertBytes(t, "table content", b, expected)
+	assertBytes(t, "column definitions", b[:len(usersColumnDefinitions)], usersColumnDefinitions)
+	// The column definitions are padded to the first page and every record fits into it
+	assert.Len(t, b, 2*table.DefaultPageSize)
 
-	expectedIdx := []byte{240, 63, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 204, 1, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 204, 1, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 77, 2, 0, 0, 0, 0, 0, 0}
-	idx, err := db.Tables["users"].ReadRawIdx()
+	expected := []byte{100, 57, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 49, 3, 1, 0, 0, 0, 31, 2, 17, 0, 0, 0, 115, 111, 102, 116, 119, 97, 114, 101, 32, 101, 110, 103, 105, 110, 101, 101, 114, 4, 1, 0, 0, 0, 1, 100, 57, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 50, 3, 1, 0, 0, 0, 27, 2, 17, 0, 0, 0, 115, 111, 102, 116, 119, 97, 114, 101, 32, 101, 110, 103, 105, 110, 101, 101, 114, 4, 1, 0, 0, 0, 0, 100, 48, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 51, 3, 1, 0, 0, 0, 28, 2, 8, 0, 0, 0, 100, 101, 115, 105, 103, 110, 101, 114, 4, 1, 0, 0, 0, 1}
+	records, err := db.Tables["users"].ReadRawRecords()
 	if err != nil {
 		t.Errorf("err should be nil: %v", err)
 	}
-	assertBytes(t, "index", idx, expectedIdx)
+	assertBytes(t, "records", bytes.Join(records, nil), expected)
+
+	idx, err := db.Tables["users"].GetIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, []index.Item{
+		*index.NewItem(index.NewKey(int64(1)), table.DefaultPageSize),
+		*index.NewItem(index.NewKey(int64(2)), table.DefaultPageSize),
+		*index.NewItem(index.NewKey(int64(3)), table.DefaultPageSize),
+	}, idx)
 }
 
 func TestUpdate(t *testing.T) {
@@ -134,19 +155,88 @@ func TestUpdate(t *testing.T) {
 		t.Errorf("len(res) == %d, len(expected) == 3", len(res.Rows))
 	}
 
-	expected := []byte{90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 105, 100, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 1, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 117, 115, 101, 114, 110, 97, 109, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 97, 103, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 3, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 106, 111, 98, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 105, 115, 95, 97, 99, 116, 105, 118, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 4, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 255, 124, 0, 0, 0, 101, 57, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 101, 57, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 107, 0, 0, 0, 100, 48, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 51, 3, 1, 0, 0, 0, 28, 2, 8, 0, 0, 0, 100, 101, 115, 105, 103, 110, 101, 114, 4, 1, 0, 0, 0, 1, 100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 49, 3, 1, 0, 0, 0, 31, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 1, 255, 54, 0, 0, 0, 100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 50, 3, 1, 0, 0, 0, 27, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 0}
 	b, err := db.Tables["users"].ReadRaw()
 	if err != nil {
 		t.Errorf("err should be nil: %v", err)
 	}
-	assertBytes(t, "table content", b, expected)
+	assertBytes(t, "column definitions", b[:len(usersColumnDefinitions)], usersColumnDefinitions)
+	// The column definitions are padded to the first page and every record fits into it
+	assert.Len(t, b, 2*table.DefaultPageSize)
 
-	expectedIdx := []byte{240, 63, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 77, 2, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 189, 2, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 77, 2, 0, 0, 0, 0, 0, 0}
-	idx, err := db.Tables["users"].ReadRawIdx()
+	expected := []byte{100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 49, 3, 1, 0, 0, 0, 31, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 1, 100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 50, 3, 1, 0, 0, 0, 27, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 0, 100, 48, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 51, 3, 1, 0, 0, 0, 28, 2, 8, 0, 0, 0, 100, 101, 115, 105, 103, 110, 101, 114, 4, 1, 0, 0, 0, 1}
+	records, err := db.Tables["users"].ReadRawRecords()
 	if err != nil {
 		t.Errorf("err should be nil: %v", err)
 	}
-	assertBytes(t, "index", idx, expectedIdx)
+	assertBytes(t, "records", bytes.Join(records, nil), expected)
+
+	idx, err := db.Tables["users"].GetIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, []index.Item{
+		*index.NewItem(index.NewKey(int64(1)), table.DefaultPageSize),
+		*index.NewItem(index.NewKey(int64(2)), table.DefaultPageSize),
+		*index.NewItem(index.NewKey(int64(3)), table.DefaultPageSize),
+	}, idx)
+}
+
+func TestUpdateInPlace(t *testing.T) {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+	defer removeDB()
+	createTable(db)
+
+	// Five records fill the first page, so a record that grows doesn't fit into it anymore
+	for i := 1; i <= 5; i++ {
+		_, err = db.Tables["users"].Insert(map[string]interface{}{
+			"id":        int64(i),
+			"username":  fmt.Sprintf("user%d", i),
+			"age":       byte(30),
+			"job":       strings.Repeat("a", 750),
+			"is_active": true,
+		}, true)
+		if err != nil {
+			t.Errorf("err should be nil: %v", err)
+		}
+	}
+	recordIDs := func() []int64 {
+		records, err := db.Tables["users"].ReadRawRecords()
+		assert.Nil(t, err)
+		ids := make([]int64, 0, len(records))
+		for _, r := range records {
+			ids = append(ids, int64(binary.LittleEndian.Uint64(r[2*types.LenMeta:])))
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, recordIDs())
+
+	// The new version has the same length, so it's written into the slot of the old one
+	res, err := db.Tables["users"].Update(map[string]interface{}{"id": int64(2)}, map[string]interface{}{"job": strings.Repeat("b", 750)})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res.Updated)
+	assert.Equal(t, []int64{table.DefaultPageSize}, res.Pages)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, recordIDs())
+	b, err := db.Tables["users"].ReadRaw()
+	assert.Nil(t, err)
+	assert.Equal(t, 2*table.DefaultPageSize, len(b))
+
+	// The new version doesn't fit into the page, so it's moved to a new one
+	res, err = db.Tables["users"].Update(map[string]interface{}{"id": int64(3)}, map[string]interface{}{"job": strings.Repeat("c", 900)})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res.Updated)
+	assert.Equal(t, []int64{table.DefaultPageSize, 2 * table.DefaultPageSize}, res.Pages)
+	assert.Equal(t, []int64{1, 2, 4, 5, 3}, recordIDs())
+	b, err = db.Tables["users"].ReadRaw()
+	assert.Nil(t, err)
+	assert.Equal(t, 3*table.DefaultPageSize, len(b))
+
+	for id, job := range map[int64]string{2: strings.Repeat("b", 750), 3: strings.Repeat("c", 900)} {
+		res, err := db.Tables["users"].Select(map[string]interface{}{"id": id})
+		assert.Nil(t, err)
+		assert.Len(t, res.Rows, 1)
+		assert.Equal(t, job, res.Rows[0]["job"])
+	}
 }
 
 func TestDelete(t *testing.T) {
@@ -215,19 +305,27 @@ func TestDelete(t *testing.T) {
 		t.Errorf("len(res) == %d, len(expected) == 3", len(res.Rows))
 	}
 
-	expected := []byte{90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 105, 100, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 1, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 117, 115, 101, 114, 110, 97, 109, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 97, 103, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 3, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 106, 111, 98, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 87, 0, 0, 0, 2, 64, 0, 0, 0, 105, 115, 95, 97, 99, 116, 105, 118, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 4, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 255, 124, 0, 0, 0, 101, 57, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 101, 57, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 107, 0, 0, 0, 101, 48, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 49, 3, 1, 0, 0, 0, 31, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 1, 255, 54, 0, 0, 0, 100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 50, 3, 1, 0, 0, 0, 27, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 0}
 	b, err := db.Tables["users"].ReadRaw()
 	if err != nil {
 		t.Errorf("err should be nil: %v", err)
 	}
-	assertBytes(t, "table content", b, expected)
+	assertBytes(t, "column definitions", b[:len(usersColumnDefinitions)], usersColumnDefinitions)
+	// The column definitions are padded to the first page and every record fits into it
+	assert.Len(t, b, 2*table.DefaultPageSize)
 
-	expectedIdx := []byte{240, 42, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 77, 2, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 189, 2, 0, 0, 0, 0, 0, 0}
-	idx, err := db.Tables["users"].ReadRawIdx()
+	expected := []byte{100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 49, 3, 1, 0, 0, 0, 31, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 1, 100, 49, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 50, 3, 1, 0, 0, 0, 27, 2, 9, 0, 0, 0, 100, 101, 118, 101, 108, 111, 112, 101, 114, 4, 1, 0, 0, 0, 0}
+	records, err := db.Tables["users"].ReadRawRecords()
 	if err != nil {
 		t.Errorf("err should be nil: %v", err)
 	}
-	assertBytes(t, "index", idx, expectedIdx)
+	assertBytes(t, "records", bytes.Join(records, nil), expected)
+
+	idx, err := db.Tables["users"].GetIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, []index.Item{
+		*index.NewItem(index.NewKey(int64(1)), table.DefaultPageSize),
+		*index.NewItem(index.NewKey(int64(2)), table.DefaultPageSize),
+	}, idx)
 }
 
 func TestDeleteMany(t *testing.T) {
@@ -335,6 +433,13 @@ func TestSelectWithPageCache(t *testing.T) {
 		t.Errorf("err should be nil: %v", err)
 	}
 
+	// The pages written by the inserts are in the buffer pool, so it's reopened to start with an empty one
+	assert.Nil(t, db.Close())
+	db, err = NewDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+
 	res, err := db.Tables["users"].Select(map[string]interface{}{
 		"id": int64(1),
 	})
@@ -365,6 +470,7 @@ func TestSelectWithPageCache(t *testing.T) {
 		t.Errorf("err should be nil: %v", err)
 	}
 
+	// The update replaced the page in the pool instead of invalidating it
 	res, err = db.Tables["users"].Select(map[string]interface{}{
 		"id": int64(1),
 	})
@@ -372,18 +478,560 @@ func TestSelectWithPageCache(t *testing.T) {
 		t.Errorf("err should be nil: %v", err)
 	}
 	assert.Equal(t, "index (btree)", res.Type)
-	assert.Equal(t, "Not using page cache", res.Extra)
+	assert.Equal(t, "Using page cache", res.Extra)
+	assert.Equal(t, "user_updated", res.Rows[0]["username"])
 
-	res, err = db.Tables["users"].Select(map[string]interface{}{
-		"id": int64(1),
-	})
+	res, err = db.Tables["users"].Select(map[string]interface{}{})
 	if err != nil {
 		t.Errorf("err should be nil: %v", err)
 	}
-	assert.Equal(t, "index (btree)", res.Type)
 	assert.Equal(t, "Using page cache", res.Extra)
+	assert.Len(t, res.Rows, 3)
+
+	stats := db.BufferPoolStats()
+	assert.Equal(t, bufferpool.DefaultSize, stats.Size)
+	assert.Equal(t, 1, stats.Dirty)
+	assert.Greater(t, stats.Hits, int64(0))
+	assert.Greater(t, stats.Misses, int64(0))
+}
+
+func TestInsertDuplicateKey(t *testing.T) {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+	defer removeDB()
+	createTable(db)
+	db.Tables["users"].Columns()["username"].Opts.Unique = true
+
+	record := map[string]interface{}{
+		"id":        int64(1),
+		"username":  "user1",
+		"age":       byte(31),
+		"job":       "software engineer",
+		"is_active": true,
+	}
+	if _, err = db.Tables["users"].Insert(record, true); err != nil {
+		t.Errorf("err should be nil: %v", err)
+	}
+	expected, err := db.Tables["users"].ReadRaw()
+	if err != nil {
+		t.Errorf("err should be nil: %v", err)
+	}
+	expectedWAL, err := db.wal.ReadRaw()
+	if err != nil {
+		t.Errorf("err should be nil: %v", err)
+	}
+
+	// Same id
+	record["username"] = "user2"
+	_, err = db.Tables["users"].Insert(record, true)
+	var errDuplicate *table.DuplicateKeyError
+	assert.ErrorAs(t, err, &errDuplicate)
+
+	// Same username
+	record["id"] = int64(2)
+	record["username"] = "user1"
+	_, err = db.Tables["users"].Insert(record, true)
+	assert.ErrorAs(t, err, &errDuplicate)
+
+	// Nothing is written when the constraint is violated
+	b, err := db.Tables["users"].ReadRaw()
+	if err != nil {
+		t.Errorf("err should be nil: %v", err)
+	}
+	assertBytes(t, "table content", b, expected)
+	wal, err := db.wal.ReadRaw()
+	if err != nil {
+		t.Errorf("err should be nil: %v", err)
+	}
+	assertBytes(t, "wal", wal, expectedWAL)
+}
+
+// Values that don't fit into a page are stored in overflow chunks and read back transparently
+func TestOverflowRecords(t *testing.T) {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+	defer removeDB()
+	createTable(db)
+
+	long := strings.Repeat("a job description that doesn't fit into a page. ", 300)
+	for i, job := range []string{long, "designer"} {
+		_, err = db.Tables["users"].Insert(map[string]interface{}{
+			"id":        int64(i + 1),
+			"username":  fmt.Sprintf("user%d", i+1),
+			"age":       byte(30),
+			"job":       job,
+			"is_active": true,
+		}, true)
+		if err != nil {
+			t.Errorf("err should be nil: %v", err)
+		}
+	}
+	res, err := db.Tables["users"].Select(map[string]interface{}{"id": int64(1)})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, long, res.Rows[0]["job"])
+
+	updated := strings.Repeat("b", 3*table.DefaultPageSize)
+	_, err = db.Tables["users"].Update(map[string]interface{}{"id": int64(1)}, map[string]interface{}{"job": updated})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	db, err = NewDatabase("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	res, err = db.Tables["users"].Select(map[string]interface{}{"job": updated})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, int64(1), res.Rows[0]["id"])
+
+	// The chunks of the old value were deleted by the update and the chunks of the new one by the delete
+	_, err = db.Tables["users"].Delete(map[string]interface{}{"id": int64(1)})
+	assert.Nil(t, err)
+	records, err := db.Tables["users"].ReadRawRecords()
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	res, err = db.Tables["users"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, "designer", res.Rows[0]["job"])
+}
+
+func TestBlobs(t *testing.T) {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+	defer removeDB()
+
+	id, err := column.New("id", types.TypeInt64, column.Opts{})
+	assert.Nil(t, err)
+	name, err := column.New("name", types.TypeString, column.Opts{})
+	assert.Nil(t, err)
+	data, err := column.New("data", types.TypeBlob, column.Opts{AllowNull: true})
+	assert.Nil(t, err)
+	_, err = db.CreateTable(db.Path, "files", []string{"id", "name", "data"}, table.Columns{"id": id, "name": name, "data": data}, []string{"id"})
+	assert.Nil(t, err)
+	_, err = column.New("data", types.TypeBlob, column.Opts{Unique: true})
+	var errBlobIndex *column.BlobIndexError
+	assert.ErrorAs(t, err, &errBlobIndex)
+	assert.ErrorAs(t, db.CreateIndex("files", "data"), &errBlobIndex)
+
+	// A file of several megabytes is read from an io.Reader chunk by chunk
+	large := make([]byte, 4<<20)
+	for i := range large {
+		large[i] = byte(i * 7 % 251)
+	}
+	for i, v := range []interface{}{[]byte{0, 1, 2, 255}, io.MultiReader(bytes.NewReader(large)), bytes.NewReader([]byte("small")), nil} {
+		_, err = db.Tables["files"].Insert(map[string]interface{}{
+			"id":   int64(i + 1),
+			"name": fmt.Sprintf("file%d", i+1),
+			"data": v,
+		}, true)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, db.Close())
+
+	db, err = NewDatabase("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	files := db.Tables["files"]
+	for id, want := range map[int64][]byte{1: {0, 1, 2, 255}, 2: large, 3: []byte("small")} {
+		buf := bytes.Buffer{}
+		n, err := files.ReadBlob(index.NewKey(id), "data", &buf)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(want)), n)
+		assert.True(t, bytes.Equal(want, buf.Bytes()), id)
+	}
+	n, err := files.ReadBlob(index.NewKey(int64(4)), "data", io.Discard)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+
+	_, err = files.ReadBlob(index.NewKey(int64(1)), "name", io.Discard)
+	var errNotABlob *table.NotABlobError
+	assert.ErrorAs(t, err, &errNotABlob)
+	_, err = files.ReadBlob(index.NewKey(int64(5)), "data", io.Discard)
+	var errNotFound *index.ItemNotFoundError
+	assert.ErrorAs(t, err, &errNotFound)
+
+	res, err := files.Select(map[string]interface{}{"data": []byte{0, 1, 2, 255}})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, "file1", res.Rows[0]["name"])
+
+	// An update reads the reader into memory, the chunks of the old value are deleted and so are the new ones by the
+	// delete
+	_, err = files.Update(map[string]interface{}{"id": int64(2)}, map[string]interface{}{"data": bytes.NewReader(large[:1<<20])})
+	assert.Nil(t, err)
+	buf := bytes.Buffer{}
+	_, err = files.ReadBlob(index.NewKey(int64(2)), "data", &buf)
+	assert.Nil(t, err)
+	assert.True(t, bytes.Equal(large[:1<<20], buf.Bytes()))
+	_, err = files.Delete(map[string]interface{}{"id": int64(2)})
+	assert.Nil(t, err)
+	records, err := files.ReadRawRecords()
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestJSON(t *testing.T) {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+	defer removeDB()
+
+	id, err := column.New("id", types.TypeInt64, column.Opts{})
+	assert.Nil(t, err)
+	attrs, err := column.New("attrs", types.TypeJSON, column.Opts{AllowNull: true})
+	assert.Nil(t, err)
+	_, err = db.CreateTable(db.Path, "people", []string{"id", "attrs"}, table.Columns{"id": id, "attrs": attrs}, []string{"id"})
+	assert.Nil(t, err)
+	_, err = column.New("attrs", types.TypeJSON, column.Opts{Unique: true})
+	var errJSONIndex *column.JSONIndexError
+	assert.ErrorAs(t, err, &errJSONIndex)
+
+	// The long document is longer than a quarter of a page, so it's moved into overflow chunks
+	tags := make([]interface{}, 2000)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag%d", i)
+	}
+	docs := []interface{}{
+		`{"address": {"city": "Berlin"}, "tags": ["a", {"b": [true, null, 1.5]}], "": ""}`,
+		map[string]interface{}{"address": map[string]interface{}{"city": "Paris"}, "tags": tags},
+		[]byte(`[]`),
+		`"just a string"`,
+	}
+	for i, doc := range docs {
+		_, err = db.Tables["people"].Insert(map[string]interface{}{"id": int64(i + 1), "attrs": doc}, true)
+		assert.Nil(t, err)
+	}
+	_, err = db.Tables["people"].Insert(map[string]interface{}{"id": int64(5), "attrs": `{"a": 1`}, true)
+	var errInvalidJSON *table.InvalidJSONError
+	assert.ErrorAs(t, err, &errInvalidJSON)
+	assert.Nil(t, db.Close())
+
+	db, err = NewDatabase("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	res, err := db.Tables["people"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, len(docs))
+	for _, row := range res.Rows {
+		doc, ok := row["attrs"].(types.JSON)
+		assert.True(t, ok)
+		var want types.JSON
+		switch v := docs[row["id"].(int64)-1].(type) {
+		case string:
+			want, err = types.ParseJSON(v)
+		case []byte:
+			want, err = types.ParseJSON(string(v))
+		default:
+			want, err = types.NewJSON(v)
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, want, doc)
+	}
+
+	city := predicate.NewJSONPath("attrs", "attrs->'address'->>'city'", []interface{}{"address", "city"}, true, predicate.Eq("attrs->'address'->>'city'", "Paris"))
+	res, err = db.Tables["people"].SelectWhere(city)
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, int64(2), res.Rows[0]["id"])
+}
+
+func TestVacuum(t *testing.T) {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+	defer removeDB()
+	crashedPath := filepath.Join(BaseDir, "test_crashed")
+	defer os.RemoveAll(crashedPath)
+	createTable(db)
+
+	long := strings.Repeat("x", 2*table.DefaultPageSize)
+	for i := range 100 {
+		job := "designer"
+		if i%10 == 0 {
+			job = long
+		}
+		_, err = db.Tables["users"].Insert(map[string]interface{}{
+			"id":        int64(i),
+			"username":  fmt.Sprintf("user%d", i),
+			"age":       byte(30),
+			"job":       job,
+			"is_active": true,
+		}, true)
+		if err != nil {
+			t.Errorf("err should be nil: %v", err)
+		}
+	}
+	_, err = db.Tables["users"].DeleteWhere(predicate.NewComparison("id", predicate.OpGt, int64(20)))
+	assert.Nil(t, err)
+	before, err := db.Tables["users"].ReadRaw()
+	assert.Nil(t, err)
+
+	_, err = db.Vacuum()
+	assert.Nil(t, err)
+	after, err := db.Tables["users"].ReadRaw()
+	assert.Nil(t, err)
+	assert.Less(t, len(after), len(before))
+	res, err := db.Tables["users"].Select(map[string]interface{}{"id": int64(20)})
+	assert.Nil(t, err)
+	assert.Equal(t, "index (btree)", res.Type)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, long, res.Rows[0]["job"])
+	res, err = db.Tables["users"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 21)
+
+	// A crash after the file was replaced but before the indexes were written rebuilds them from the WAL
+	_, err = db.Tables["users"].Delete(map[string]interface{}{"id": int64(0)})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Checkpoint())
+	oldIdx, err := os.ReadFile(filepath.Join(db.Path, "users_idx.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, db.Tables["users"].Vacuum())
+	assert.Nil(t, os.CopyFS(crashedPath, os.DirFS(db.Path)))
+	if err = os.WriteFile(filepath.Join(crashedPath, "users_idx.bin"), oldIdx, 0666); err != nil {
+		t.Fatal(err)
+	}
+	crashed, err := NewDatabase("test_crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crashed.Close()
+	for i := range 21 {
+		res, err = crashed.Tables["users"].Select(map[string]interface{}{"id": int64(i)})
+		assert.Nil(t, err)
+		assert.Len(t, res.Rows, min(i, 1))
+	}
+}
+
+// The WAL entries after the last checkpoint are replayed when the database is opened after a crash
+// The table file may or may not contain their changes
+func TestRecoverFromWAL(t *testing.T) {
+	for _, name := range []string{"applied", "not applied"} {
+		t.Run(name, func(t *testing.T) {
+			db, err := CreateDatabase("test")
+			if err != nil {
+				panic(err)
+			}
+			defer removeDB()
+			crashedPath := filepath.Join(BaseDir, "test_crashed")
+			defer os.RemoveAll(crashedPath)
+			createTable(db)
+			for i, username := range []string{"user1", "user2", "user3"} {
+				_, err = db.Tables["users"].Insert(map[string]interface{}{
+					"id":        int64(i + 1),
+					"username":  username,
+					"age":       byte(30),
+					"job":       "designer",
+					"is_active": true,
+				}, true)
+				if err != nil {
+					t.Errorf("err should be nil: %v", err)
+				}
+			}
+			snapshot := make(map[string][]byte)
+			for _, f := range []string{"users.bin", "users_idx.bin"} {
+				if snapshot[f], err = os.ReadFile(filepath.Join(db.Path, f)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = db.Tables["users"].Delete(map[string]interface{}{"id": int64(1)})
+			assert.Nil(t, err)
+			_, err = db.Tables["users"].Update(map[string]interface{}{"id": int64(2)}, map[string]interface{}{"username": "updated"})
+			assert.Nil(t, err)
+			// Copying the files before Close is the same as a crash before the next checkpoint
+			assert.Nil(t, os.CopyFS(crashedPath, os.DirFS(db.Path)))
+			assert.Nil(t, db.Close())
+			if name == "not applied" {
+				for f, b := range snapshot {
+					if err = os.WriteFile(filepath.Join(crashedPath, f), b, 0666); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			crashed, err := NewDatabase("test_crashed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer crashed.Close()
+			assert.Equal(t, crashed.wal.LSN(), crashed.wal.CheckpointLSN())
+
+			res, err := crashed.Tables["users"].Select(map[string]interface{}{})
+			assert.Nil(t, err)
+			assert.Len(t, res.Rows, 2)
+			res, err = crashed.Tables["users"].Select(map[string]interface{}{"id": int64(2)})
+			assert.Nil(t, err)
+			assert.Equal(t, "index (btree)", res.Type)
+			assert.Len(t, res.Rows, 1)
+			assert.Equal(t, "updated", res.Rows[0]["username"])
+			res, err = crashed.Tables["users"].Select(map[string]interface{}{"id": int64(1)})
+			assert.Nil(t, err)
+			assert.Empty(t, res.Rows)
+		})
+	}
+}
+
+func TestDurability(t *testing.T) {
+	for _, d := range []wal.Durability{wal.DurabilityOff, wal.DurabilityCommit, wal.DurabilityGroup, wal.DurabilityAlways} {
+		t.Run(d.String(), func(t *testing.T) {
+			db, err := CreateDatabase("test")
+			if err != nil {
+				panic(err)
+			}
+			defer removeDB()
+			assert.Equal(t, wal.DurabilityCommit, db.Durability())
+			db.SetDurability(d)
+			createTable(db)
+			for i, username := range []string{"user1", "user2"} {
+				_, err = db.Tables["users"].Insert(map[string]interface{}{
+					"id":        int64(i + 1),
+					"username":  username,
+					"age":       byte(30),
+					"job":       "designer",
+					"is_active": true,
+				}, true)
+				assert.Nil(t, err)
+			}
+			_, err = db.Tables["users"].Update(map[string]interface{}{"id": int64(2)}, map[string]interface{}{"username": "updated"})
+			assert.Nil(t, err)
+			_, err = db.Tables["users"].Delete(map[string]interface{}{"id": int64(1)})
+			assert.Nil(t, err)
+			assert.Nil(t, db.Close())
+
+			db, err = NewDatabase("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			res, err := db.Tables["users"].Select(map[string]interface{}{})
+			assert.Nil(t, err)
+			assert.Len(t, res.Rows, 1)
+			assert.Equal(t, "updated", res.Rows[0]["username"])
+		})
+	}
+}
+
+// Readers don't share the file offset with each other or with the writer, so they can run in parallel with transactions
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	defer db.Close()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, pred := range []predicate.Predicate{predicate.NewAnd(), predicate.Eq("id", int64(1))} {
+					res, err := db.SelectWhere("users", pred)
+					assert.Nil(t, err)
+					for _, row := range res.Rows {
+						assert.Len(t, row, 5)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range 30 {
+		tx, err := db.Begin()
+		assert.Nil(t, err)
+		insertUser(t, tx, int64(i+1), fmt.Sprintf("user%d", i+1))
+		if i%3 == 2 {
+			assert.Nil(t, tx.Rollback())
+		} else {
+			assert.Nil(t, tx.Commit())
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	res, err := db.SelectWhere("users", predicate.NewAnd())
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 20)
 }
 
+// Tables created before the primary key was stored in the file have no header and an index with int64 keys
+func TestOpenLegacyTable(t *testing.T) {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		panic(err)
+	}
+	defer removeDB()
+	createTable(db)
+	// The records are inserted so the WAL matches the legacy files written below
+	for i, username := range []string{"user1", "user2", "user3"} {
+		_, err = db.Tables["users"].Insert(map[string]interface{}{
+			"id":        int64(i + 1),
+			"username":  username,
+			"age":       byte(30),
+			"job":       "designer",
+			"is_active": true,
+		}, true)
+		if err != nil {
+			t.Errorf("err should be nil: %v", err)
+		}
+	}
+	assert.Nil(t, db.Close())
+
+	legacyTable := []byte{90, 99, 0, 0, 0, 2, 64, 0, 0, 0, 105, 100, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 1, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 99, 0, 0, 0, 2, 64, 0, 0, 0, 117, 115, 101, 114, 110, 97, 109, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 99, 0, 0, 0, 2, 64, 0, 0, 0, 97, 103, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 3, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 99, 0, 0, 0, 2, 64, 0, 0, 0, 106, 111, 98, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 90, 99, 0, 0, 0, 2, 64, 0, 0, 0, 105, 115, 95, 97, 99, 116, 105, 118, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 4, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 255, 124, 0, 0, 0, 100, 57, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 49, 3, 1, 0, 0, 0, 31, 2, 17, 0, 0, 0, 115, 111, 102, 116, 119, 97, 114, 101, 32, 101, 110, 103, 105, 110, 101, 101, 114, 4, 1, 0, 0, 0, 1, 100, 57, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 50, 3, 1, 0, 0, 0, 27, 2, 17, 0, 0, 0, 115, 111, 102, 116, 119, 97, 114, 101, 32, 101, 110, 103, 105, 110, 101, 101, 114, 4, 1, 0, 0, 0, 0, 255, 53, 0, 0, 0, 100, 48, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 2, 5, 0, 0, 0, 117, 115, 101, 114, 51, 3, 1, 0, 0, 0, 28, 2, 8, 0, 0, 0, 100, 101, 115, 105, 103, 110, 101, 114, 4, 1, 0, 0, 0, 1}
+	legacyIdx := []byte{240, 63, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 8, 2, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 8, 2, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 137, 2, 0, 0, 0, 0, 0, 0}
+	assert.Nil(t, os.WriteFile(db.Path+"/users.bin", legacyTable, 0644))
+	assert.Nil(t, os.WriteFile(db.Path+"/users_idx.bin", legacyIdx, 0644))
+
+	db, err = NewDatabase("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	users := db.Tables["users"]
+	assert.Equal(t, []string{"id"}, users.PrimaryKey())
+
+	res, err := users.Select(map[string]interface{}{"id": int64(2)})
+	assert.Nil(t, err)
+	assert.Equal(t, "index (btree)", res.Type)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, "user2", res.Rows[0]["username"])
+
+	_, err = users.Insert(map[string]interface{}{
+		"id":        int64(2),
+		"username":  "user4",
+		"age":       byte(40),
+		"job":       "designer",
+		"is_active": true,
+	}, true)
+	var errDuplicate *table.DuplicateKeyError
+	assert.ErrorAs(t, err, &errDuplicate)
+}
+
+// usersColumnDefinitions is the beginning of the file of the table created by createTable
+var usersColumnDefinitions = []byte{80, 16, 0, 0, 0, 2, 2, 0, 0, 0, 105, 100, 5, 4, 0, 0, 0, 0, 16, 0, 0, 90, 117, 0, 0, 0, 2, 64, 0, 0, 0, 105, 100, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 1, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 1, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 90, 117, 0, 0, 0, 2, 64, 0, 0, 0, 117, 115, 101, 114, 110, 97, 109, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 90, 117, 0, 0, 0, 2, 64, 0, 0, 0, 97, 103, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 3, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 90, 117, 0, 0, 0, 2, 64, 0, 0, 0, 106, 111, 98, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 2, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 90, 117, 0, 0, 0, 2, 64, 0, 0, 0, 105, 115, 95, 97, 99, 116, 105, 118, 101, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0, 0, 0, 4, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 4, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0, 3, 1, 0, 0, 0, 0}
+
 func assertBytes(t *testing.T, funcName string, actual, expected []byte) {
 	if len(actual) != len(expected) {
 		t.Errorf("%s: len(actual) == %d, len(expected) == %d", funcName, len(actual), len(expected))
@@ -427,7 +1075,7 @@ func createTable(db *Database) {
 		"age":       age,
 		"job":       job,
 		"is_active": isActive,
-	})
+	}, []string{"id"})
 	if err != nil {
 		log.Fatal(err)
 	}
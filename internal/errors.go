@@ -38,6 +38,18 @@ func (e *TableAlreadyExistsError) Error() string {
 	return fmt.Sprintf("table already exists: %s", e.name)
 }
 
+type TableDoesNotExistError struct {
+	name string
+}
+
+func NewTableDoesNotExistError(name string) *TableDoesNotExistError {
+	return &TableDoesNotExistError{name: name}
+}
+
+func (e *TableDoesNotExistError) Error() string {
+	return fmt.Sprintf("table does not exist: %s", e.name)
+}
+
 type CannotCreateTableError struct {
 	reason error
 	name   string
@@ -50,3 +62,64 @@ func NewCannotCreateTableError(reason error, name string) *CannotCreateTableErro
 func (e *CannotCreateTableError) Error() string {
 	return fmt.Errorf("cannot create table %s: %w", e.name, e.reason).Error()
 }
+
+type SequenceAlreadyExistsError struct {
+	name string
+}
+
+func NewSequenceAlreadyExistsError(name string) *SequenceAlreadyExistsError {
+	return &SequenceAlreadyExistsError{name: name}
+}
+
+func (e *SequenceAlreadyExistsError) Error() string {
+	return fmt.Sprintf("sequence already exists: %s", e.name)
+}
+
+type SequenceDoesNotExistError struct {
+	name string
+}
+
+func NewSequenceDoesNotExistError(name string) *SequenceDoesNotExistError {
+	return &SequenceDoesNotExistError{name: name}
+}
+
+func (e *SequenceDoesNotExistError) Error() string {
+	return fmt.Sprintf("sequence does not exist: %s", e.name)
+}
+
+type SequenceInUseError struct {
+	name  string
+	table string
+}
+
+func NewSequenceInUseError(name, table string) *SequenceInUseError {
+	return &SequenceInUseError{name: name, table: table}
+}
+
+func (e *SequenceInUseError) Error() string {
+	return fmt.Sprintf("sequence %s is used by table %s", e.name, e.table)
+}
+
+type TxDoneError struct{}
+
+func NewTxDoneError() *TxDoneError {
+	return &TxDoneError{}
+}
+
+func (e *TxDoneError) Error() string {
+	return "transaction has already been committed or rolled back"
+}
+
+// LegacyTableNotCheckpointedError means a table created before pages had a fixed size cannot be converted because the
+// WAL contains changes of its old pages
+type LegacyTableNotCheckpointedError struct {
+	table string
+}
+
+func NewLegacyTableNotCheckpointedError(table string) *LegacyTableNotCheckpointedError {
+	return &LegacyTableNotCheckpointedError{table: table}
+}
+
+func (e *LegacyTableNotCheckpointedError) Error() string {
+	return fmt.Sprintf("table %s has to be converted to the new page format but the WAL contains changes that were not checkpointed. Open the database with the previous version to checkpoint them first", e.table)
+}
@@ -2,11 +2,14 @@ package platform
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 )
 
+// LRU is a least recently used cache. It's safe to use from multiple goroutines
 type LRU[K types.Scalar, V any] struct {
+	mu   sync.Mutex
 	list *LinkedList[K]
 	hMap map[K]V
 	cap  int
@@ -22,6 +25,8 @@ func NewLRU[K types.Scalar, V any](cap int, equals EqFunc[K]) *LRU[K, V] {
 }
 
 func (lru *LRU[K, V]) Get(key K) (V, error) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
 	var zero V
 	val, ok := lru.hMap[key]
 	if !ok {
@@ -36,6 +41,8 @@ func (lru *LRU[K, V]) Get(key K) (V, error) {
 }
 
 func (lru *LRU[K, V]) Put(key K, val V) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
 	if lru.len >= lru.cap {
 		if err := lru.removeLeastRecentlyUsed(); err != nil {
 			return fmt.Errorf("lru.Put: %w", err)
@@ -48,6 +55,8 @@ func (lru *LRU[K, V]) Put(key K, val V) error {
 }
 
 func (lru *LRU[K, V]) Remove(key K) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
 	_, ok := lru.hMap[key]
 	if !ok {
 		return NewItemNotFoundError(lru.hMap, key)
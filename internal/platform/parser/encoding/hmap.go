@@ -5,16 +5,22 @@ import (
 	"encoding"
 	"encoding/binary"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 )
 
-type HMapMarshaler[T []EmbeddedValueMarshaler | types.Scalar] struct {
+// HMapMarshaler encodes a map. Its values are scalars, lists ([]EmbeddedValueMarshaler) or values that marshal
+// themselves such as an EmbeddedValueMarshaler
+// The keys are written in sorted order, so the same map is always encoded into the same bytes
+type HMapMarshaler[T any] struct {
 	hMap map[string]T
 }
 
-func NewHMapMarshaler[T []EmbeddedValueMarshaler | types.Scalar](hMap map[string]T) *HMapMarshaler[T] {
+func NewHMapMarshaler[T any](hMap map[string]T) *HMapMarshaler[T] {
 	return &HMapMarshaler[T]{
 		hMap: hMap,
 	}
@@ -29,7 +35,8 @@ func (m *HMapMarshaler[T]) MarshalBinary() ([]byte, error) {
 	}
 
 	tmpBuf := bytes.Buffer{}
-	for key, value := range m.hMap {
+	for _, key := range slices.Sorted(maps.Keys(m.hMap)) {
+		value := m.hMap[key]
 		// type
 		if err := binary.Write(&tmpBuf, binary.LittleEndian, types.TypeHMapKey); err != nil {
 			return nil, fmt.Errorf("hmapMarshaler.MarshalBinary: hmap key type: %w", err)
@@ -91,6 +98,10 @@ func NewHMapUnmarshaler(createItmFn func() EmbeddedValueUnmarshaler) *HMapUnmars
 	}
 }
 
+func (u *HMapUnmarshaler) GetValue() interface{} {
+	return u.Value
+}
+
 func (u *HMapUnmarshaler) UnmarshalBinary(data []byte) error {
 	byteUnmarshaler := NewValueUnmarshaler[byte]()
 	int32Unmarshaler := NewValueUnmarshaler[uint32]()
@@ -150,7 +161,7 @@ func (u *HMapUnmarshaler) UnmarshalBinary(data []byte) error {
 		}
 		n += types.LenInt32
 
-		unmarshaler, err := u.createValueUnmarshaler(byteUnmarshaler.Value)
+		unmarshaler, err := newValueUnmarshaler(byteUnmarshaler.Value, u.createItemFn)
 		if err != nil {
 			return fmt.Errorf("hmapUnmarshaler.UnmarshalBinary: %w", err)
 		}
@@ -176,23 +187,47 @@ func (m *HMapMarshaler[T]) createValueMarshaler(value interface{}) encoding.Bina
 	switch v := value.(type) {
 	case []EmbeddedValueMarshaler:
 		marshaler = NewListMarshaler(v)
+	case encoding.BinaryMarshaler:
+		marshaler = v
 	default:
 		marshaler = NewTLVMarshaler(v)
 	}
 	return marshaler
 }
 
-func (u *HMapUnmarshaler) createValueUnmarshaler(dataType byte) (interface {
+// newValueUnmarshaler returns the unmarshaler of a value of an hmap. The items of lists and the values of nested hmaps
+// are created by createItemFn
+func newValueUnmarshaler(dataType byte, createItemFn func() EmbeddedValueUnmarshaler) (interface {
 	encoding.BinaryUnmarshaler
 	ValueHolder
 }, error) {
 	switch dataType {
 	case types.TypeList:
-		return NewListUnmarshaler(u.createItemFn), nil
+		return NewListUnmarshaler(createItemFn), nil
+	case types.TypeHMap:
+		return NewHMapUnmarshaler(createItemFn), nil
+	case types.TypeNull:
+		return nullUnmarshaler{}, nil
 	case types.TypeInt64:
 		return NewTLVUnmarshaler[int64](NewValueUnmarshaler[int64]()), nil
 	case types.TypeInt32:
 		return NewTLVUnmarshaler[int32](NewValueUnmarshaler[int32]()), nil
+	case types.TypeFloat64:
+		return NewTLVUnmarshaler[float64](NewValueUnmarshaler[float64]()), nil
+	case types.TypeFloat32:
+		return NewTLVUnmarshaler[float32](NewValueUnmarshaler[float32]()), nil
+	case types.TypeDate:
+		return NewTLVUnmarshaler[types.Date](NewValueUnmarshaler[types.Date]()), nil
+	case types.TypeTimestamp:
+		return NewTLVUnmarshaler[time.Time](NewValueUnmarshaler[time.Time]()), nil
+	case types.TypeInterval:
+		return NewTLVUnmarshaler[time.Duration](NewValueUnmarshaler[time.Duration]()), nil
+	case types.TypeDecimal:
+		return NewTLVUnmarshaler[types.Decimal](NewValueUnmarshaler[types.Decimal]()), nil
+	case types.TypeBlob:
+		return NewTLVUnmarshaler[[]byte](NewValueUnmarshaler[[]byte]()), nil
+	case types.TypeJSON:
+		return NewTLVUnmarshaler[types.JSON](NewValueUnmarshaler[types.JSON]()), nil
 	case types.TypeByte:
 		return NewTLVUnmarshaler[byte](NewValueUnmarshaler[byte]()), nil
 	case types.TypeBool:
@@ -200,6 +235,17 @@ func (u *HMapUnmarshaler) createValueUnmarshaler(dataType byte) (interface {
 	case types.TypeString:
 		return NewTLVUnmarshaler[string](NewValueUnmarshaler[string]()), nil
 	default:
-		return nil, fmt.Errorf("hmapUnmarshal.createValueUnmarshaler: %w", NewUnsupportedDataTypeError(strconv.Itoa(int(dataType))))
+		return nil, fmt.Errorf("newValueUnmarshaler: %w", NewUnsupportedDataTypeError(strconv.Itoa(int(dataType))))
 	}
 }
+
+// nullUnmarshaler unmarshals a TypeNull TLV. It has no value part
+type nullUnmarshaler struct{}
+
+func (nullUnmarshaler) UnmarshalBinary([]byte) error {
+	return nil
+}
+
+func (nullUnmarshaler) GetValue() interface{} {
+	return nil
+}
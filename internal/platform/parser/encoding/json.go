@@ -0,0 +1,151 @@
+package encoding
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// JSONMarshaler encodes a value of a types.JSON. Objects are encoded by HMapMarshaler, arrays by ListMarshaler and
+// every other value as a TLV, so {"tags": ["a"]} becomes:
+//
+//	220 len [221 len [2 4 0 0 0 116 97 103 115] 222 len [230 6 0 0 0 [2 1 0 0 0 97]]]
+type JSONMarshaler struct {
+	value interface{}
+	// buf caches the encoded value because lists and hmaps ask for the length of their items before marshaling them
+	buf []byte
+	err error
+}
+
+func NewJSONMarshaler(value interface{}) *JSONMarshaler {
+	return &JSONMarshaler{value: value}
+}
+
+func (m *JSONMarshaler) MarshalBinary() ([]byte, error) {
+	if m.buf != nil || m.err != nil {
+		return m.buf, m.err
+	}
+	m.buf, m.err = m.marshal()
+	return m.buf, m.err
+}
+
+// BinaryLen returns the length of the value without the type and length bytes. It's 0 if the value cannot be
+// marshaled, MarshalBinary returns the error
+func (m *JSONMarshaler) BinaryLen() uint32 {
+	b, err := m.MarshalBinary()
+	if err != nil {
+		return 0
+	}
+	return uint32(len(b)) - types.LenMeta
+}
+
+func (m *JSONMarshaler) marshal() ([]byte, error) {
+	switch v := m.value.(type) {
+	case map[string]interface{}:
+		fields := make(map[string]EmbeddedValueMarshaler, len(v))
+		for key, val := range v {
+			fields[key] = NewJSONMarshaler(val)
+		}
+		b, err := NewHMapMarshaler(fields).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("JSONMarshaler.MarshalBinary: %w", err)
+		}
+		return b, nil
+	case []interface{}:
+		items := make([]EmbeddedValueMarshaler, len(v))
+		for i, val := range v {
+			items[i] = NewJSONMarshaler(val)
+		}
+		// BinaryLen hides the errors of the items, ListMarshaler gets them when it marshals the items
+		b, err := NewListMarshaler(items).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("JSONMarshaler.MarshalBinary: %w", err)
+		}
+		return b, nil
+	case nil, bool, int64, float64, string:
+		b, err := NewTLVMarshaler(v).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("JSONMarshaler.MarshalBinary: %w", err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("JSONMarshaler.MarshalBinary: %w", NewUnsupportedDataTypeError(fmt.Sprintf("%T", v)))
+	}
+}
+
+// JSONUnmarshaler decodes a value encoded by JSONMarshaler. Value is nil, bool, int64, float64, string, []interface{}
+// or map[string]interface{}
+type JSONUnmarshaler struct {
+	Value  interface{}
+	length uint32
+}
+
+func NewJSONUnmarshaler() *JSONUnmarshaler {
+	return &JSONUnmarshaler{}
+}
+
+func (u *JSONUnmarshaler) GetValue() interface{} {
+	return u.Value
+}
+
+func (u *JSONUnmarshaler) BinaryLen() uint32 {
+	return u.length
+}
+
+func (u *JSONUnmarshaler) UnmarshalBinary(data []byte) error {
+	if len(data) < int(types.LenMeta) {
+		return fmt.Errorf("JSONUnmarshaler.UnmarshalBinary: %w", io.ErrUnexpectedEOF)
+	}
+	u.length = binary.LittleEndian.Uint32(data[types.LenByte:])
+	end := int(types.LenMeta) + int(u.length)
+	if len(data) < end {
+		return fmt.Errorf("JSONUnmarshaler.UnmarshalBinary: %w", io.ErrUnexpectedEOF)
+	}
+	// A list passes every byte after the previous item to the next one, so the value has to be cut out of data
+	data = data[:end]
+
+	var unmarshaler interface {
+		encoding.BinaryUnmarshaler
+		ValueHolder
+	}
+	switch data[0] {
+	case types.TypeHMap, types.TypeList, types.TypeNull, types.TypeBool, types.TypeInt64, types.TypeFloat64, types.TypeString:
+		var err error
+		unmarshaler, err = newValueUnmarshaler(data[0], func() EmbeddedValueUnmarshaler {
+			return NewJSONUnmarshaler()
+		})
+		if err != nil {
+			return fmt.Errorf("JSONUnmarshaler.UnmarshalBinary: %w", err)
+		}
+	default:
+		return fmt.Errorf("JSONUnmarshaler.UnmarshalBinary: %w", NewUnsupportedDataTypeError(fmt.Sprint(data[0])))
+	}
+	if err := unmarshaler.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("JSONUnmarshaler.UnmarshalBinary: %w", err)
+	}
+	u.Value = jsonValue(unmarshaler.GetValue())
+	return nil
+}
+
+// jsonValue replaces the lists of JSONUnmarshalers returned by ListUnmarshaler with the values of the items
+// The items already did the same for their own values
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			val[key] = jsonValue(item)
+		}
+		return val
+	case []EmbeddedValueUnmarshaler:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = item.GetValue()
+		}
+		return list
+	default:
+		return v
+	}
+}
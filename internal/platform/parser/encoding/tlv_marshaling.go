@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 )
@@ -45,6 +47,10 @@ func (m *TLVMarshaler[T]) MarshalBinary() ([]byte, error) {
 		return nil, fmt.Errorf("TLVMarshaler.MarshalBinary: len: %w", err)
 	}
 
+	if typeFlag == types.TypeNull {
+		return buf.Bytes(), nil
+	}
+
 	valueBuf, err := m.valueMarshaler.MarshalBinary()
 	if err != nil {
 		return nil, fmt.Errorf("TLVMarshaler.MarshalBinary: value: %w", err)
@@ -61,10 +67,28 @@ func (m *TLVMarshaler[T]) typeFlag() (byte, error) {
 		return types.TypeInt32, nil
 	case int64:
 		return types.TypeInt64, nil
+	case float32:
+		return types.TypeFloat32, nil
+	case float64:
+		return types.TypeFloat64, nil
+	case types.Date:
+		return types.TypeDate, nil
+	case time.Time:
+		return types.TypeTimestamp, nil
+	case time.Duration:
+		return types.TypeInterval, nil
+	case types.Decimal:
+		return types.TypeDecimal, nil
+	case types.JSON:
+		return types.TypeJSON, nil
 	case bool:
 		return types.TypeBool, nil
 	case string:
 		return types.TypeString, nil
+	case []byte:
+		return types.TypeBlob, nil
+	case nil:
+		return types.TypeNull, nil
 	default:
 		return 0, NewUnsupportedDataTypeError(fmt.Sprintf("%T", v))
 	}
@@ -74,14 +98,30 @@ func (m *TLVMarshaler[T]) dataLength() (uint32, error) {
 	switch v := any(m.value).(type) {
 	case byte:
 		return 1, nil
-	case int32, uint32:
+	case int32, uint32, float32, types.Date:
 		return 4, nil
-	case int64:
+	case int64, float64, time.Time, time.Duration:
 		return 8, nil
 	case bool:
 		return 1, nil
 	case string:
 		return uint32(len(v)), nil
+	case []byte:
+		return uint32(len(v)), nil
+	case types.Decimal:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return 0, fmt.Errorf("TLVMarshaler.dataLength: %w", err)
+		}
+		return uint32(len(b)), nil
+	case types.JSON:
+		b, err := NewJSONMarshaler(v.Value()).MarshalBinary()
+		if err != nil {
+			return 0, fmt.Errorf("TLVMarshaler.dataLength: %w", err)
+		}
+		return uint32(len(b)), nil
+	case nil:
+		return 0, nil
 	default:
 		return 0, NewUnsupportedDataTypeError(fmt.Sprintf("%T", v))
 	}
@@ -91,14 +131,24 @@ func (m *TLVMarshaler[T]) TLVLength() (uint32, error) {
 	switch v := any(m.value).(type) {
 	case byte:
 		return 1 + 4 + 1, nil
-	case int32, uint32:
+	case int32, uint32, float32, types.Date:
 		return 1 + 4 + 4, nil
-	case int64:
+	case int64, float64, time.Time, time.Duration:
 		return 1 + 4 + 8, nil
 	case bool:
 		return 1 + 4 + 1, nil
 	case string:
 		return 1 + 4 + uint32(len(v)), nil
+	case []byte:
+		return 1 + 4 + uint32(len(v)), nil
+	case types.Decimal, types.JSON:
+		length, err := m.dataLength()
+		if err != nil {
+			return 0, fmt.Errorf("TLVMarshaler.TLVLength: %w", err)
+		}
+		return 1 + 4 + length, nil
+	case nil:
+		return 1 + 4, nil
 	default:
 		return 0, NewUnsupportedDataTypeError(fmt.Sprintf("%T", v))
 	}
@@ -135,6 +185,9 @@ func (t *TLVUnmarshaler[T]) GetValue() interface{} {
 
 func (t *TLVUnmarshaler[T]) UnmarshalBinary(data []byte) error {
 	t.BytesRead = 0
+	if len(data) < int(types.LenMeta) {
+		return fmt.Errorf("TLVUnmarshaler.UnmarshalBinary: %w", io.ErrUnexpectedEOF)
+	}
 
 	byteUnmarshaler := NewValueUnmarshaler[byte]()
 	intUnmarshaler := NewValueUnmarshaler[uint32]()
@@ -154,7 +207,12 @@ func (t *TLVUnmarshaler[T]) UnmarshalBinary(data []byte) error {
 	t.BytesRead += types.LenInt32
 
 	// value
-	if err := t.unmarshaler.UnmarshalBinary(data[5:]); err != nil {
+	// The value has to be cut out of data because a string consumes every byte it receives
+	value := data[5:]
+	if uint32(len(value)) > t.length {
+		value = value[:t.length]
+	}
+	if err := t.unmarshaler.UnmarshalBinary(value); err != nil {
 		return fmt.Errorf("TLVUnmarshaler.UnmarshalBinary: value: %w", err)
 	}
 	t.Value = t.unmarshaler.Value
@@ -5,6 +5,9 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"time"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 )
 
 // ValueMarshaler marshals the V part of TLV so a single value such as an int, byte, string, etc
@@ -12,6 +15,8 @@ import (
 // 97 becomes 97 0 0 0 if T is int32
 // 97 becomes 97 0 0 0 0 0 0 0 if T is int64
 // "a" becomes 97 if T is string
+// A time.Time is stored as the int64 number of microseconds since the Unix epoch, a types.Decimal as its
+// MarshalBinary and a types.JSON by JSONMarshaler
 type ValueMarshaler[T any] struct {
 	value T
 }
@@ -29,6 +34,22 @@ func (m *ValueMarshaler[T]) MarshalBinary() ([]byte, error) {
 		if err := binary.Write(&buf, binary.LittleEndian, []byte(v)); err != nil {
 			return nil, fmt.Errorf("ValueMarshaler.MarshalBinary: string: %w", err)
 		}
+	case time.Time:
+		if err := binary.Write(&buf, binary.LittleEndian, v.UnixMicro()); err != nil {
+			return nil, fmt.Errorf("ValueMarshaler.MarshalBinary: time: %w", err)
+		}
+	case types.Decimal:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("ValueMarshaler.MarshalBinary: decimal: %w", err)
+		}
+		buf.Write(b)
+	case types.JSON:
+		b, err := NewJSONMarshaler(v.Value()).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("ValueMarshaler.MarshalBinary: json: %w", err)
+		}
+		buf.Write(b)
 	default:
 		if err := binary.Write(&buf, binary.LittleEndian, m.value); err != nil {
 			return nil, fmt.Errorf("ValueMarshaler.MarshalBinary: default: %w", err)
@@ -60,6 +81,32 @@ func (d *ValueUnmarshaler[T]) UnmarshalBinary(data []byte) error {
 	switch v := any(&value).(type) {
 	case *string:
 		*v = string(data)
+	case *[]byte:
+		// data is usually a part of a page, so the value must not share its memory
+		*v = bytes.Clone(data)
+	case *time.Time:
+		var micros int64
+		if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &micros); err != nil {
+			if err == io.EOF {
+				return err
+			}
+			return fmt.Errorf("ValueUnmarshaler.UnmarshalBinary: %w", err)
+		}
+		*v = time.UnixMicro(micros).UTC()
+	case *types.Decimal:
+		if err := v.UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("ValueUnmarshaler.UnmarshalBinary: %w", err)
+		}
+	case *types.JSON:
+		u := NewJSONUnmarshaler()
+		if err := u.UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("ValueUnmarshaler.UnmarshalBinary: %w", err)
+		}
+		j, err := types.NewJSON(u.Value)
+		if err != nil {
+			return fmt.Errorf("ValueUnmarshaler.UnmarshalBinary: %w", err)
+		}
+		*v = j
 	default:
 		if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &value); err != nil {
 			if err == io.EOF {
@@ -69,6 +69,11 @@ func (r *Reader) ReadTLV() ([]byte, error) {
 		return nil, fmt.Errorf("Reader.ReadTLV: len: %w", err)
 	}
 
+	// NULL values have no value part and reading 0 bytes at the end of the input would return io.EOF
+	if length == 0 {
+		return buf.Bytes(), nil
+	}
+
 	valBuf := make([]byte, length)
 	if _, err := r.Read(valBuf); err != nil {
 		return nil, fmt.Errorf("Reader.ReadTLV: value: %w", err)
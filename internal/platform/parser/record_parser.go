@@ -1,20 +1,38 @@
 package parser
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
 	platformio "github.com/omesh-barhate/ByteForge/internal/platform/parser/io"
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 )
 
 type RecordParser struct {
-	file    io.ReadSeeker
-	columns []string
-	Value   *RawRecord
-	reader  *platformio.Reader
+	file     io.ReadSeeker
+	columns  []string
+	Value    *RawRecord
+	reader   *platformio.Reader
+	overflow OverflowReader
+	// kept contains the types whose overflow pointers are returned instead of the values they point to
+	kept map[byte]bool
 }
 
+// OverflowPointer points to the first chunk of a value that was moved out of its record because the record was too
+// large for a page
+type OverflowPointer struct {
+	// Type is the type of the value
+	Type    byte
+	PagePos int64
+	Slot    int
+	Length  uint32
+}
+
+// OverflowReader returns the value that ptr points to
+type OverflowReader func(ptr *OverflowPointer) ([]byte, error)
+
 func NewRecordParser(f io.ReadSeeker, columns []string) *RecordParser {
 	return &RecordParser{
 		file:    f,
@@ -22,6 +40,21 @@ func NewRecordParser(f io.ReadSeeker, columns []string) *RecordParser {
 	}
 }
 
+// SetOverflowReader sets the function that reads the values moved out of the record. Without it Parse fails if the
+// record contains an overflow pointer
+func (r *RecordParser) SetOverflowReader(overflow OverflowReader) {
+	r.overflow = overflow
+}
+
+// KeepOverflowPointers makes Parse return the *OverflowPointer of values of type typ that were moved out of the
+// record, so the caller can read them chunk by chunk
+func (r *RecordParser) KeepOverflowPointers(typ byte) {
+	if r.kept == nil {
+		r.kept = make(map[byte]bool)
+	}
+	r.kept[typ] = true
+}
+
 func (r *RecordParser) Parse() error {
 	read, err := platformio.NewReader(r.file)
 	if err != nil {
@@ -63,6 +96,10 @@ func (r *RecordParser) Parse() error {
 	}
 
 	lenRecord, err := read.ReadUint32()
+	version, err := r.parseVersion()
+	if err != nil {
+		return fmt.Errorf("RecordParser.Parse: %w", err)
+	}
 	record := make(map[string]interface{}, 0)
 	for i := 0; i < len(r.columns); i++ {
 		_, err = read.ReadByte()
@@ -71,6 +108,7 @@ func (r *RecordParser) Parse() error {
 				lenRecord,
 				record,
 			)
+			r.Value.setVersion(version)
 			return nil
 		}
 		if err != nil {
@@ -87,6 +125,11 @@ func (r *RecordParser) Parse() error {
 		if err != nil {
 			return fmt.Errorf("RecordParser.Parse: %w", err)
 		}
+		if ptr, ok := value.(*OverflowPointer); ok && !r.kept[ptr.Type] {
+			if value, err = r.readOverflow(ptr); err != nil {
+				return fmt.Errorf("RecordParser.Parse: %w", err)
+			}
+		}
 
 		record[r.columns[i]] = value
 	}
@@ -94,9 +137,69 @@ func (r *RecordParser) Parse() error {
 		lenRecord,
 		record,
 	)
+	r.Value.setVersion(version)
 	return nil
 }
 
+// readOverflow returns the value ptr points to
+func (r *RecordParser) readOverflow(ptr *OverflowPointer) (interface{}, error) {
+	if r.overflow == nil {
+		return nil, fmt.Errorf("RecordParser.readOverflow: the record contains an overflow pointer but no overflow reader is set")
+	}
+	data, err := r.overflow(ptr)
+	if err != nil {
+		return nil, fmt.Errorf("RecordParser.readOverflow: %w", err)
+	}
+	if len(data) != int(ptr.Length) {
+		return nil, fmt.Errorf("RecordParser.readOverflow: %w", platformio.NewIncompleteReadError(int(ptr.Length), len(data)))
+	}
+	switch ptr.Type {
+	case types.TypeString:
+		return string(data), nil
+	case types.TypeBlob:
+		return data, nil
+	case types.TypeJSON:
+		u := encoding.NewValueUnmarshaler[types.JSON]()
+		if err := u.UnmarshalBinary(data); err != nil {
+			return nil, fmt.Errorf("RecordParser.readOverflow: %w", err)
+		}
+		return u.Value, nil
+	}
+	return nil, fmt.Errorf("RecordParser.readOverflow: unsupported type: %d", ptr.Type)
+}
+
+// parseVersion reads the version at the beginning of a record. It returns nil if the record doesn't have one
+func (r *RecordParser) parseVersion() ([]int64, error) {
+	t, err := r.reader.ReadByte()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("RecordParser.parseVersion: %w", err)
+	}
+	if t != types.TypeRecordVersion {
+		if _, err = r.file.Seek(-1*types.LenByte, io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("RecordParser.parseVersion: %w", err)
+		}
+		return nil, nil
+	}
+	length, err := r.reader.ReadUint32()
+	if err != nil {
+		return nil, fmt.Errorf("RecordParser.parseVersion: %w", err)
+	}
+	if length != types.LenRecordVersion {
+		return nil, fmt.Errorf("RecordParser.parseVersion: invalid length: %d", length)
+	}
+	buf := make([]byte, length)
+	if _, err = io.ReadFull(r.file, buf); err != nil {
+		return nil, fmt.Errorf("RecordParser.parseVersion: %w", err)
+	}
+	return []int64{
+		int64(binary.LittleEndian.Uint64(buf)),
+		int64(binary.LittleEndian.Uint64(buf[types.LenInt64:])),
+	}, nil
+}
+
 func (r *RecordParser) skipDeletedRecords() error {
 	for {
 		t, err := r.reader.ReadByte()
@@ -130,6 +233,12 @@ type RawRecord struct {
 	FullSize uint32
 	// Record contains the actual fields
 	Record map[string]interface{}
+	// Versioned is true if the record was written by a transaction
+	Versioned bool
+	// Xmin and Xmax are the IDs of the transactions that created and deleted the record. Xmax is 0 until it's deleted
+	// Both are 0 if the record has no version
+	Xmin int64
+	Xmax int64
 }
 
 func NewRawRecord(size uint32, record map[string]interface{}) *RawRecord {
@@ -140,10 +249,10 @@ func NewRawRecord(size uint32, record map[string]interface{}) *RawRecord {
 	}
 }
 
-func (r *RawRecord) Id() (int64, error) {
-	val, ok := r.Record["id"].(int64)
-	if !ok {
-		return -1, NewInvalidIDError(r)
+func (r *RawRecord) setVersion(version []int64) {
+	if version == nil {
+		return
 	}
-	return val, nil
+	r.Versioned = true
+	r.Xmin, r.Xmax = version[0], version[1]
 }
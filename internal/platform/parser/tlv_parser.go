@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"encoding/binary"
 	"fmt"
+	"time"
 
 	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
 	"github.com/omesh-barhate/ByteForge/internal/platform/parser/io"
@@ -29,12 +31,32 @@ func (p *TLVParser) Parse() (interface{}, error) {
 		return unmarshalValue[int64](data)
 	case types.TypeInt32:
 		return unmarshalValue[int32](data)
+	case types.TypeFloat64:
+		return unmarshalValue[float64](data)
+	case types.TypeFloat32:
+		return unmarshalValue[float32](data)
+	case types.TypeDate:
+		return unmarshalValue[types.Date](data)
+	case types.TypeTimestamp:
+		return unmarshalValue[time.Time](data)
+	case types.TypeInterval:
+		return unmarshalValue[time.Duration](data)
+	case types.TypeDecimal:
+		return unmarshalValue[types.Decimal](data)
+	case types.TypeBlob:
+		return unmarshalValue[[]byte](data)
+	case types.TypeJSON:
+		return unmarshalValue[types.JSON](data)
 	case types.TypeByte:
 		return unmarshalValue[byte](data)
 	case types.TypeBool:
 		return unmarshalValue[bool](data)
 	case types.TypeString:
 		return unmarshalValue[string](data)
+	case types.TypeNull:
+		return nil, nil
+	case types.TypeOverflowPointer:
+		return UnmarshalOverflowPointer(data)
 	}
 	return nil, fmt.Errorf("TLVParser.Parse: unknown type: %d", data[0])
 }
@@ -46,3 +68,17 @@ func unmarshalValue[T any](data []byte) (interface{}, error) {
 	}
 	return tlvUnmarshaler.Value, nil
 }
+
+// UnmarshalOverflowPointer decodes a TypeOverflowPointer TLV
+func UnmarshalOverflowPointer(data []byte) (*OverflowPointer, error) {
+	if len(data) != int(types.LenMeta)+types.LenOverflowPointer || data[0] != types.TypeOverflowPointer {
+		return nil, fmt.Errorf("parser.UnmarshalOverflowPointer: invalid overflow pointer: %v", data)
+	}
+	v := data[types.LenMeta:]
+	return &OverflowPointer{
+		Type:    v[0],
+		PagePos: int64(binary.LittleEndian.Uint64(v[types.LenByte:])),
+		Slot:    int(binary.LittleEndian.Uint16(v[types.LenByte+types.LenInt64:])),
+		Length:  binary.LittleEndian.Uint32(v[types.LenByte+types.LenInt64+2:]),
+	}, nil
+}
@@ -0,0 +1,192 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Compare compares two values stored in a record
+// It returns -1 if a < b, 0 if a == b and 1 if a > b
+// Both values need to have the same Go type, except for numbers. Integers are compared as int64 regardless of their
+// width, floats as float64, an integer and a float are compared exactly without converting the integer to a float,
+// and a Decimal can be compared with another Decimal or an integer but not with a float because that would not be
+// exact. JSON values are ordered by JSON.Cmp
+//
+// Floats have a total order so they can be sorted and stored in indexes: NaN equals NaN and is greater than every
+// other number, and -0 equals 0
+func Compare(a, b interface{}) (int, error) {
+	if ad, ok := toDecimal(a); ok {
+		bd, ok := toDecimal(b)
+		_, aDecimal := a.(Decimal)
+		_, bDecimal := b.(Decimal)
+		if ok && (aDecimal || bDecimal) {
+			return ad.Cmp(bd), nil
+		}
+	}
+	if _, ok := a.(Decimal); ok {
+		return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+	}
+	if _, ok := b.(Decimal); ok {
+		return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+	}
+
+	if af, ok := toFloat64(a); ok {
+		if bi, ok := toInt64(b); ok {
+			return -compareIntFloat(bi, af), nil
+		}
+		bf, ok := toFloat64(b)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return compareFloats(af, bf), nil
+	}
+	if bf, ok := toFloat64(b); ok {
+		ai, ok := toInt64(a)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return compareIntFloat(ai, bf), nil
+	}
+
+	if ai, ok := toInt64(a); ok {
+		bi, ok := toInt64(b)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return compareOrdered(ai, bi), nil
+	}
+
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return compareOrdered(av, bv), nil
+	case []byte:
+		bv, ok := b.([]byte)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return bytes.Compare(av, bv), nil
+	case JSON:
+		bv, ok := b.(JSON)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return av.Cmp(bv), nil
+	case Date:
+		bv, ok := b.(Date)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return compareOrdered(int64(av), int64(bv)), nil
+	case time.Time:
+		bv, ok := b.(time.Time)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return av.Compare(bv), nil
+	case time.Duration:
+		bv, ok := b.(time.Duration)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		return compareOrdered(int64(av), int64(bv)), nil
+	case bool:
+		bv, ok := b.(bool)
+		if !ok {
+			return 0, fmt.Errorf("types.Compare: cannot compare %T with %T", a, b)
+		}
+		if av == bv {
+			return 0, nil
+		}
+		// false < true
+		if !av {
+			return -1, nil
+		}
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("types.Compare: unsupported type: %T", a)
+	}
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch val := v.(type) {
+	case int64:
+		return val, true
+	case int32:
+		return int64(val), true
+	case byte:
+		return int64(val), true
+	case int:
+		return int64(val), true
+	default:
+		return 0, false
+	}
+}
+
+// toDecimal converts a Decimal or an integer to a Decimal
+func toDecimal(v interface{}) (Decimal, bool) {
+	if d, ok := v.(Decimal); ok {
+		return d, true
+	}
+	if i, ok := toInt64(v); ok {
+		return DecimalFromInt64(i), true
+	}
+	return Decimal{}, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	default:
+		return 0, false
+	}
+}
+
+// compareIntFloat compares an integer with a float exactly. Converting i to a float64 would round it if it's larger
+// than 2^53, so the integral part of f is compared as int64 if it fits into one
+func compareIntFloat(i int64, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return -1
+	// -2^63 and 2^63 are exact float64s
+	case f >= math.MaxInt64:
+		return -1
+	case f < math.MinInt64:
+		return 1
+	}
+	integral := math.Trunc(f)
+	if cmp := compareOrdered(i, int64(integral)); cmp != 0 {
+		return cmp
+	}
+	return compareFloats(integral, f)
+}
+
+func compareFloats(a, b float64) int {
+	switch aNaN, bNaN := math.IsNaN(a), math.IsNaN(b); {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return 1
+	case bNaN:
+		return -1
+	}
+	return compareOrdered(a, b)
+}
+
+func compareOrdered[T int64 | float64 | string](a, b T) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		a, b interface{}
+		want int
+	}{
+		{int64(1), int32(2), -1},
+		{byte(3), int64(3), 0},
+		{1.5, int64(1), 1},
+		{int64(2), float32(2.5), -1},
+		{float32(0.5), 0.5, 0},
+		{math.Copysign(0, -1), 0.0, 0},
+		{nan, nan, 0},
+		{nan, math.Inf(1), 1},
+		{math.Inf(-1), float32(nan), -1},
+		{int64(1<<53 + 1), float64(1 << 53), 1},
+		{float64(1 << 53), int64(1<<53 + 1), -1},
+		{int64(1 << 62), float64(1 << 62), 0},
+		{int64(math.MaxInt64), float64(math.MaxInt64), -1},
+		{int64(math.MinInt64), float64(math.MinInt64), 0},
+		{int64(-3), -2.5, -1},
+		{int64(-2), float32(-2.5), 1},
+		{int64(5), nan, -1},
+		{"a", "b", -1},
+		{true, false, 1},
+	}
+	for _, tt := range tests {
+		cmp, err := Compare(tt.a, tt.b)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.want, cmp, "%v %v", tt.a, tt.b)
+	}
+
+	_, err := Compare(1.5, "1.5")
+	assert.NotNil(t, err)
+	_, err = Compare("1.5", 1.5)
+	assert.NotNil(t, err)
+}
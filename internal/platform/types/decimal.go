@@ -0,0 +1,263 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxDecimalPrecision is the largest number of digits a DECIMAL column can have
+	MaxDecimalPrecision = 38
+	// maxDecimalExponent is the largest scale and the largest number of digits before the decimal point of a parsed
+	// Decimal. Larger numbers never fit into a column and take long to compute
+	maxDecimalExponent = 255
+)
+
+var (
+	// ErrInexactDecimal is returned when a value would have to be rounded to fit into a Decimal
+	ErrInexactDecimal = errors.New("the value cannot be represented exactly")
+	// ErrDecimalOutOfRange is returned when a value has more digits than the precision allows
+	ErrDecimalOutOfRange = errors.New("the value has too many digits")
+)
+
+var bigTen = big.NewInt(10)
+
+// Decimal is an exact decimal number: unscaled * 10^-scale. For example, 12.30 is 1230 with a scale of 2
+// The zero value is 0. A Decimal is never modified after it's created, so it can be copied freely
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// NewDecimal returns unscaled * 10^-scale. A negative scale multiplies unscaled by a power of 10
+func NewDecimal(unscaled *big.Int, scale int) Decimal {
+	if scale < 0 {
+		return Decimal{unscaled: new(big.Int).Mul(unscaled, pow10(-scale))}
+	}
+	return Decimal{unscaled: new(big.Int).Set(unscaled), scale: scale}
+}
+
+// DecimalFromInt64 returns v with a scale of 0
+func DecimalFromInt64(v int64) Decimal {
+	return Decimal{unscaled: big.NewInt(v)}
+}
+
+// DecimalFromRat returns r with the smallest scale that represents it exactly
+// It returns ErrInexactDecimal if r has no finite decimal representation, such as 1/3, and ErrDecimalOutOfRange if
+// the scale would be larger than maxDecimalExponent
+func DecimalFromRat(r *big.Rat) (Decimal, error) {
+	// r is a finite decimal if its denominator only has the prime factors 2 and 5. The scale is the larger one of
+	// their counts
+	twos := r.Denom().TrailingZeroBits()
+	if twos > maxDecimalExponent {
+		return Decimal{}, fmt.Errorf("types.DecimalFromRat: %w", ErrDecimalOutOfRange)
+	}
+	rest := new(big.Int).Rsh(r.Denom(), twos)
+	fives := 0
+	one, five := big.NewInt(1), big.NewInt(5)
+	q, rem := new(big.Int), new(big.Int)
+	for rest.Cmp(one) != 0 {
+		q.QuoRem(rest, five, rem)
+		if rem.Sign() != 0 {
+			return Decimal{}, fmt.Errorf("types.DecimalFromRat: %s: %w", r.RatString(), ErrInexactDecimal)
+		}
+		fives++
+		if fives > maxDecimalExponent {
+			return Decimal{}, fmt.Errorf("types.DecimalFromRat: %w", ErrDecimalOutOfRange)
+		}
+		rest, q = q, rest
+	}
+	scale := max(int(twos), fives)
+	unscaled := new(big.Int).Mul(r.Num(), pow10(scale))
+	unscaled.Quo(unscaled, r.Denom())
+	return Decimal{unscaled: unscaled, scale: scale}, nil
+}
+
+// ParseDecimal parses a number such as 12.30, -0.5 or 1.5e3. The scale of the result is the smallest one that
+// represents the number
+// It returns ErrDecimalOutOfRange if the number has more than maxDecimalExponent digits before or after the decimal
+// point. The exponent is checked before the number is parsed because parsing 1e-100000 takes seconds
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if mantissa, exp, ok := strings.Cut(strings.ToLower(s), "e"); ok {
+		e, err := strconv.ParseInt(exp, 10, 32)
+		if errors.Is(err, strconv.ErrRange) {
+			return Decimal{}, fmt.Errorf("types.ParseDecimal: %q: %w", s, ErrDecimalOutOfRange)
+		}
+		if err != nil {
+			return Decimal{}, fmt.Errorf("types.ParseDecimal: invalid decimal: %q", s)
+		}
+		intPart, frac, _ := strings.Cut(strings.TrimLeft(mantissa, "+-"), ".")
+		if int64(len(frac))-e > maxDecimalExponent || int64(len(intPart))+e > maxDecimalExponent {
+			return Decimal{}, fmt.Errorf("types.ParseDecimal: %q: %w", s, ErrDecimalOutOfRange)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	// Rat accepts fractions such as 1/3 too
+	if !ok || strings.Contains(s, "/") {
+		return Decimal{}, fmt.Errorf("types.ParseDecimal: invalid decimal: %q", s)
+	}
+	d, err := DecimalFromRat(r)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("types.ParseDecimal: %w", err)
+	}
+	return d, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// Unscaled returns the digits of d as an integer
+func (d Decimal) Unscaled() *big.Int {
+	return new(big.Int).Set(d.int())
+}
+
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Rat returns d as a fraction
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.int(), pow10(d.scale))
+}
+
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// Digits returns the number of digits of the unscaled value. 0 has one digit
+func (d Decimal) Digits() int {
+	s := d.int().String()
+	return len(strings.TrimPrefix(s, "-"))
+}
+
+// Rescale returns d with the given scale. It returns ErrInexactDecimal if digits that are not zero would be cut off
+func (d Decimal) Rescale(scale int) (Decimal, error) {
+	if scale >= d.scale {
+		return Decimal{unscaled: new(big.Int).Mul(d.int(), pow10(scale-d.scale)), scale: scale}, nil
+	}
+	q, r := new(big.Int).QuoRem(d.int(), pow10(d.scale-scale), new(big.Int))
+	if r.Sign() != 0 {
+		return Decimal{}, fmt.Errorf("Decimal.Rescale: %s to scale %d: %w", d, scale, ErrInexactDecimal)
+	}
+	return Decimal{unscaled: q, scale: scale}, nil
+}
+
+// Fit returns d with the scale of a DECIMAL(precision, scale) column
+// It returns ErrInexactDecimal if d has more digits after the decimal point than scale and ErrDecimalOutOfRange if
+// it has more digits before the decimal point than precision - scale
+func (d Decimal) Fit(precision, scale int) (Decimal, error) {
+	r, err := d.Rescale(scale)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("Decimal.Fit: %w", err)
+	}
+	if r.Digits() > precision {
+		return Decimal{}, fmt.Errorf("Decimal.Fit: %s does not fit into DECIMAL(%d, %d): %w", d, precision, scale, ErrDecimalOutOfRange)
+	}
+	return r, nil
+}
+
+// align returns the unscaled values of d and other with the larger of their scales
+func (d Decimal) align(other Decimal) (*big.Int, *big.Int, int) {
+	scale := max(d.scale, other.scale)
+	a := new(big.Int).Mul(d.int(), pow10(scale-d.scale))
+	b := new(big.Int).Mul(other.int(), pow10(scale-other.scale))
+	return a, b, scale
+}
+
+// Cmp returns -1 if d < other, 0 if d == other and 1 if d > other. The scales don't matter, 1.50 equals 1.5
+func (d Decimal) Cmp(other Decimal) int {
+	a, b, _ := d.align(other)
+	return a.Cmp(b)
+}
+
+// Add returns d + other with the larger of their scales
+func (d Decimal) Add(other Decimal) Decimal {
+	a, b, scale := d.align(other)
+	return Decimal{unscaled: a.Add(a, b), scale: scale}
+}
+
+// Sub returns d - other with the larger of their scales
+func (d Decimal) Sub(other Decimal) Decimal {
+	a, b, scale := d.align(other)
+	return Decimal{unscaled: a.Sub(a, b), scale: scale}
+}
+
+// Mul returns d * other with the sum of their scales
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), other.int()), scale: d.scale + other.scale}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Quo returns d / other with the given scale. It returns ErrInexactDecimal if the result has more digits after the
+// decimal point, so the caller decides how to round
+func (d Decimal) Quo(other Decimal, scale int) (Decimal, error) {
+	if other.Sign() == 0 {
+		return Decimal{}, fmt.Errorf("Decimal.Quo: division by zero")
+	}
+	q, err := DecimalFromRat(new(big.Rat).Quo(d.Rat(), other.Rat()))
+	if err != nil {
+		return Decimal{}, fmt.Errorf("Decimal.Quo: %w", err)
+	}
+	r, err := q.Rescale(scale)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("Decimal.Quo: %w", err)
+	}
+	return r, nil
+}
+
+// String returns d with every digit of its scale, such as 12.30
+func (d Decimal) String() string {
+	s := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if len(s) <= d.scale {
+			s = strings.Repeat("0", d.scale-len(s)+1) + s
+		}
+		s = s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]
+	}
+	if d.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalBinary encodes d as [scale byte][sign byte][absolute value of unscaled as big-endian bytes]
+// The sign is 1 for negative numbers and 0 otherwise
+func (d Decimal) MarshalBinary() ([]byte, error) {
+	if d.scale < 0 || d.scale > 255 {
+		return nil, fmt.Errorf("Decimal.MarshalBinary: invalid scale: %d", d.scale)
+	}
+	var sign byte
+	if d.Sign() < 0 {
+		sign = 1
+	}
+	return append([]byte{byte(d.scale), sign}, d.int().Bytes()...), nil
+}
+
+func (d *Decimal) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("Decimal.UnmarshalBinary: expected at least 2 bytes received %d", len(data))
+	}
+	u := new(big.Int).SetBytes(data[2:])
+	if data[1] == 1 {
+		u.Neg(u)
+	}
+	d.unscaled = u
+	d.scale = int(data[0])
+	return nil
+}
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDecimal(t *testing.T, s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		s     string
+		want  string
+		scale int
+	}{
+		{"12.30", "12.3", 1},
+		{"-0.05", "-0.05", 2},
+		{"1.5e3", "1500", 0},
+		{"2.5E-3", "0.0025", 4},
+		{"0", "0", 0},
+		{"99999999999999999999999999999999999999", "99999999999999999999999999999999999999", 0},
+	}
+	for _, tt := range tests {
+		d := mustDecimal(t, tt.s)
+		assert.Equal(t, tt.want, d.String(), tt.s)
+		assert.Equal(t, tt.scale, d.Scale(), tt.s)
+	}
+
+	for _, s := range []string{"abc", "1/3", "", "NaN"} {
+		_, err := ParseDecimal(s)
+		assert.NotNil(t, err, s)
+	}
+	_, err := DecimalFromRat(big.NewRat(1, 3))
+	assert.ErrorIs(t, err, ErrInexactDecimal)
+	d, err := DecimalFromRat(big.NewRat(3, 40))
+	assert.Nil(t, err)
+	assert.Equal(t, "0.075", d.String())
+
+	// Huge exponents are rejected before they are computed
+	for _, s := range []string{"1e-100000", "1e400", "-1.5e99999999999", "0.5e-255"} {
+		_, err = ParseDecimal(s)
+		assert.ErrorIs(t, err, ErrDecimalOutOfRange, s)
+	}
+	_, err = DecimalFromRat(new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), 300)))
+	assert.ErrorIs(t, err, ErrDecimalOutOfRange)
+}
+
+func TestDecimal_Fit(t *testing.T) {
+	d, err := mustDecimal(t, "12.3").Fit(5, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "12.30", d.String())
+
+	_, err = mustDecimal(t, "12.345").Fit(5, 2)
+	assert.ErrorIs(t, err, ErrInexactDecimal)
+	_, err = mustDecimal(t, "1234.5").Fit(5, 2)
+	assert.ErrorIs(t, err, ErrDecimalOutOfRange)
+	// Trailing zeros can be cut off without rounding
+	d, err = mustDecimal(t, "-7.500").Fit(3, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "-7.5", d.String())
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	a, err := mustDecimal(t, "0.1").Rescale(2)
+	assert.Nil(t, err)
+	b := mustDecimal(t, "0.2")
+	assert.Equal(t, "0.30", a.Add(b).String())
+	assert.Equal(t, "-0.10", a.Sub(b).String())
+	assert.Equal(t, "0.020", a.Mul(b).String())
+	assert.Equal(t, "0.10", a.Neg().Neg().String())
+	assert.Equal(t, 0, mustDecimal(t, "0.3").Cmp(a.Add(b)))
+	assert.Equal(t, -1, a.Cmp(b))
+
+	q, err := mustDecimal(t, "10").Quo(mustDecimal(t, "4"), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "2.50", q.String())
+	_, err = mustDecimal(t, "10").Quo(mustDecimal(t, "3"), 2)
+	assert.ErrorIs(t, err, ErrInexactDecimal)
+	_, err = mustDecimal(t, "10").Quo(Decimal{}, 2)
+	assert.NotNil(t, err)
+}
+
+func TestDecimal_MarshalBinary(t *testing.T) {
+	for _, s := range []string{"0", "-123.45", "0.001", "12345678901234567890123456789.012345678"} {
+		d := mustDecimal(t, s)
+		b, err := d.MarshalBinary()
+		assert.Nil(t, err)
+		var got Decimal
+		assert.Nil(t, got.UnmarshalBinary(b))
+		assert.Equal(t, d.String(), got.String(), s)
+		assert.Equal(t, d.Scale(), got.Scale(), s)
+	}
+}
+
+func TestCompare_Decimal(t *testing.T) {
+	cmp, err := Compare(mustDecimal(t, "1.50"), mustDecimal(t, "1.5"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cmp)
+	cmp, err = Compare(int64(2), mustDecimal(t, "1.99"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cmp)
+	// A float is never compared with a decimal because the result would not be exact
+	_, err = Compare(mustDecimal(t, "0.1"), 0.1)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,259 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// JSON is a JSON document. Its value is nil (null), bool, int64, float64, string, []interface{} or
+// map[string]interface{}. Numbers that are integers and fit into an int64 are int64, every other number is a float64
+// A JSON is never modified after it's created, so it can be copied freely
+type JSON struct {
+	value interface{}
+}
+
+// ParseJSON parses and validates a JSON document such as {"address": {"city": "Berlin"}}
+func ParseJSON(s string) (JSON, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return JSON{}, fmt.Errorf("types.ParseJSON: %w", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return JSON{}, fmt.Errorf("types.ParseJSON: unexpected data after the document")
+	}
+	j, err := NewJSON(v)
+	if err != nil {
+		return JSON{}, fmt.Errorf("types.ParseJSON: %w", err)
+	}
+	return j, nil
+}
+
+// NewJSON converts v to a JSON document. Maps, slices, numbers, strings, bools and nil are converted directly, any
+// other value is converted the way encoding/json marshals it
+func NewJSON(v interface{}) (JSON, error) {
+	value, err := jsonValue(v)
+	if err != nil {
+		return JSON{}, fmt.Errorf("types.NewJSON: %w", err)
+	}
+	return JSON{value: value}, nil
+}
+
+func jsonValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, bool, string, int64:
+		return val, nil
+	case JSON:
+		return val.value, nil
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", val)
+		}
+		return f, nil
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return nil, fmt.Errorf("%v is not a JSON number", val)
+		}
+		return val, nil
+	case float32:
+		return jsonValue(float64(val))
+	case int:
+		return int64(val), nil
+	case int32:
+		return int64(val), nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = converted
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = converted
+		}
+		return list, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var decoded interface{}
+	if err = dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return jsonValue(decoded)
+}
+
+// Value returns the document as nil, bool, int64, float64, string, []interface{} or map[string]interface{}
+// The maps and slices must not be modified
+func (j JSON) Value() interface{} {
+	return j.value
+}
+
+// Field returns the value under key if j is an object that has it
+func (j JSON) Field(key string) (JSON, bool) {
+	m, ok := j.value.(map[string]interface{})
+	if !ok {
+		return JSON{}, false
+	}
+	v, ok := m[key]
+	if !ok {
+		return JSON{}, false
+	}
+	return JSON{value: v}, true
+}
+
+// Index returns the ith element if j is an array that has it. A negative index counts from the end, so -1 is the last
+// element
+func (j JSON) Index(i int) (JSON, bool) {
+	list, ok := j.value.([]interface{})
+	if !ok {
+		return JSON{}, false
+	}
+	if i < 0 {
+		i += len(list)
+	}
+	if i < 0 || i >= len(list) {
+		return JSON{}, false
+	}
+	return JSON{value: list[i]}, true
+}
+
+// Extract returns the value at path for the -> and ->> operators. path contains object keys as strings and array
+// indexes as int64s
+// The result is a JSON, or a string if asText is true: strings without quotes and other values as JSON text
+// It is nil if there is no such value or if asText is true and the value is null
+func (j JSON) Extract(path []interface{}, asText bool) interface{} {
+	curr := j
+	for _, step := range path {
+		var ok bool
+		switch s := step.(type) {
+		case string:
+			curr, ok = curr.Field(s)
+		case int64:
+			curr, ok = curr.Index(int(s))
+		}
+		if !ok {
+			return nil
+		}
+	}
+	if !asText {
+		return curr
+	}
+	switch v := curr.value.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	default:
+		return curr.String()
+	}
+}
+
+// String returns j as compact JSON text with the keys of objects in sorted order
+func (j JSON) String() string {
+	b, err := j.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("%v", j.value)
+	}
+	return string(b)
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(j.value); err != nil {
+		return nil, fmt.Errorf("JSON.MarshalJSON: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonKind returns the rank of the kind of v. The kinds are ranked the way PostgreSQL orders jsonb values
+func jsonKind(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case string:
+		return 1
+	case int64, float64:
+		return 2
+	case bool:
+		return 3
+	case []interface{}:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// Cmp returns -1 if j < other, 0 if j == other and 1 if j > other
+// Values of different kinds are ordered as null < string < number < bool < array < object. Numbers are compared by
+// value and arrays element by element. An object with more keys is greater, objects with the same number of keys are
+// compared key by key and value by value in the order of their keys
+func (j JSON) Cmp(other JSON) int {
+	a, b := j.value, other.value
+	if ak, bk := jsonKind(a), jsonKind(b); ak != bk {
+		return compareOrdered(int64(ak), int64(bk))
+	}
+	switch av := a.(type) {
+	case nil:
+		return 0
+	case string:
+		return compareOrdered(av, b.(string))
+	case int64, float64:
+		cmp, _ := Compare(av, b)
+		return cmp
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if !av {
+			return -1
+		}
+		return 1
+	case []interface{}:
+		return slices.CompareFunc(av, b.([]interface{}), func(x, y interface{}) int {
+			return JSON{value: x}.Cmp(JSON{value: y})
+		})
+	default:
+		am, bm := av.(map[string]interface{}), b.(map[string]interface{})
+		if len(am) != len(bm) {
+			return compareOrdered(int64(len(am)), int64(len(bm)))
+		}
+		aKeys, bKeys := slices.Sorted(maps.Keys(am)), slices.Sorted(maps.Keys(bm))
+		for i := range aKeys {
+			if cmp := compareOrdered(aKeys[i], bKeys[i]); cmp != 0 {
+				return cmp
+			}
+			if cmp := (JSON{value: am[aKeys[i]]}).Cmp(JSON{value: bm[bKeys[i]]}); cmp != 0 {
+				return cmp
+			}
+		}
+		return 0
+	}
+}
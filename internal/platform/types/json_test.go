@@ -0,0 +1,74 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustJSON(t *testing.T, s string) JSON {
+	j, err := ParseJSON(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestParseJSON(t *testing.T) {
+	j := mustJSON(t, ` {"b": [1, 2.5, "<x>", true, null], "a": {"n": 12345678901234567890}} `)
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{"n": 1.2345678901234567e19},
+		"b": []interface{}{int64(1), 2.5, "<x>", true, nil},
+	}, j.Value())
+	assert.Equal(t, `{"a":{"n":12345678901234567000},"b":[1,2.5,"<x>",true,null]}`, j.String())
+
+	for _, s := range []string{"", "{", `{"a": 1} x`, "{'a': 1}", "NaN"} {
+		_, err := ParseJSON(s)
+		assert.NotNil(t, err, s)
+	}
+
+	fromGo, err := NewJSON(map[string]interface{}{"n": 1, "f": float32(0.5), "s": struct {
+		Name string `json:"name"`
+	}{"x"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"f":0.5,"n":1,"s":{"name":"x"}}`, fromGo.String())
+}
+
+func TestJSON_Extract(t *testing.T) {
+	j := mustJSON(t, `{"address": {"city": "Berlin", "zip": 10115}, "tags": ["a", "b"], "none": null}`)
+
+	assert.Equal(t, "Berlin", j.Extract([]interface{}{"address", "city"}, true))
+	assert.Equal(t, mustJSON(t, `"Berlin"`), j.Extract([]interface{}{"address", "city"}, false))
+	assert.Equal(t, "10115", j.Extract([]interface{}{"address", "zip"}, true))
+	assert.Equal(t, `{"city":"Berlin","zip":10115}`, j.Extract([]interface{}{"address"}, true))
+	assert.Equal(t, "b", j.Extract([]interface{}{"tags", int64(-1)}, true))
+	assert.Equal(t, "a", j.Extract([]interface{}{"tags", int64(0)}, true))
+	assert.Equal(t, mustJSON(t, "null"), j.Extract([]interface{}{"none"}, false))
+	assert.Nil(t, j.Extract([]interface{}{"none"}, true))
+	assert.Nil(t, j.Extract([]interface{}{"tags", int64(2)}, false))
+	assert.Nil(t, j.Extract([]interface{}{"tags", "a"}, false))
+	assert.Nil(t, j.Extract([]interface{}{"missing"}, false))
+}
+
+func TestJSON_Cmp(t *testing.T) {
+	ordered := []string{`null`, `"a"`, `"b"`, `-1`, `1`, `1.5`, `2`, `false`, `true`, `[]`, `[1]`, `[1, 2]`, `[2]`, `{}`, `{"b": 1}`, `{"a": 1, "b": 1}`, `{"a": 1, "b": 2}`, `{"a": 1, "c": 0}`}
+	for i := range ordered {
+		for k := range ordered {
+			want := 0
+			if i < k {
+				want = -1
+			} else if i > k {
+				want = 1
+			}
+			assert.Equal(t, want, mustJSON(t, ordered[i]).Cmp(mustJSON(t, ordered[k])), ordered[i]+" "+ordered[k])
+		}
+	}
+	assert.Equal(t, 0, mustJSON(t, `1`).Cmp(mustJSON(t, `1.0`)))
+	assert.Equal(t, 0, mustJSON(t, `{"b": 1, "a": 2}`).Cmp(mustJSON(t, `{"a":2,"b":1}`)))
+
+	cmp, err := Compare(mustJSON(t, `2`), mustJSON(t, `10`))
+	assert.Nil(t, err)
+	assert.Equal(t, -1, cmp)
+	_, err = Compare(mustJSON(t, `2`), int64(2))
+	assert.NotNil(t, err)
+}
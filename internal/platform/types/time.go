@@ -0,0 +1,110 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date is a calendar date without a time of day or time zone. It's the number of days since 1970-01-01
+type Date int32
+
+const secondsPerDay = 24 * 60 * 60
+
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the date of t in the location of t
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / secondsPerDay
+	return Date(days)
+}
+
+// Time returns the beginning of the date in UTC
+func (d Date) Time() time.Time {
+	return time.Unix(int64(d)*secondsPerDay, 0).UTC()
+}
+
+func (d Date) String() string {
+	return d.Time().Format(time.DateOnly)
+}
+
+// ParseDate parses a date such as 2024-01-31
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("types.ParseDate: %w", err)
+	}
+	return DateOf(t), nil
+}
+
+// Timestamp returns t as it's stored in a TIMESTAMP column: in UTC with microsecond precision
+func Timestamp(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond).UTC()
+}
+
+// timestampLayouts are tried in order by ParseTimestamp. Fractional seconds are optional in every layout
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02T15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+// ParseTimestamp parses a timestamp such as 2024-01-31 13:45:00, 2024-01-31T13:45:00.123Z or 2024-01-31 13:45:00+02:00
+// A timestamp without an offset is in loc
+func ParseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return Timestamp(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("types.ParseTimestamp: invalid timestamp: %q", s)
+}
+
+// intervalUnits maps the units accepted by ParseInterval to their length
+var intervalUnits = map[string]time.Duration{
+	"microsecond": time.Microsecond,
+	"millisecond": time.Millisecond,
+	"second":      time.Second,
+	"sec":         time.Second,
+	"minute":      time.Minute,
+	"min":         time.Minute,
+	"hour":        time.Hour,
+	"day":         24 * time.Hour,
+	"week":        7 * 24 * time.Hour,
+}
+
+// ParseInterval parses an interval written as a Go duration such as 1h30m or as numbers followed by units such as
+// 1 day 12 hours. Months and years don't have a fixed length so they are not supported
+func ParseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return 0, fmt.Errorf("types.ParseInterval: invalid interval: %q", s)
+	}
+	var d time.Duration
+	for i := 0; i < len(fields); i += 2 {
+		n, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("types.ParseInterval: invalid interval: %q", s)
+		}
+		unit, ok := intervalUnits[strings.TrimSuffix(fields[i+1], "s")]
+		if !ok {
+			return 0, fmt.Errorf("types.ParseInterval: unknown unit %q", fields[i+1])
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
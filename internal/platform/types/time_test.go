@@ -0,0 +1,65 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDate(t *testing.T) {
+	d, err := ParseDate("1969-12-31")
+	assert.Nil(t, err)
+	assert.Equal(t, Date(-1), d)
+	assert.Equal(t, "1969-12-31", d.String())
+	assert.Equal(t, NewDate(2024, time.February, 29), DateOf(time.Date(2024, 2, 29, 23, 59, 0, 0, time.FixedZone("", -5*3600))))
+
+	_, err = ParseDate("2024-02-30")
+	assert.NotNil(t, err)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	loc := time.FixedZone("", 2*3600)
+	want := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	for _, s := range []string{
+		"2024-01-31 10:00:00Z",
+		"2024-01-31T12:00:00+02:00",
+		"2024-01-31 11:00:00+01",
+		"2024-01-31 12:00:00",
+		"2024-01-31 12:00",
+	} {
+		ts, err := ParseTimestamp(s, loc)
+		assert.Nil(t, err, s)
+		assert.Equal(t, want, ts, s)
+	}
+
+	// Timestamps have microsecond precision
+	ts, err := ParseTimestamp("2024-01-31 10:00:00.1234567Z", loc)
+	assert.Nil(t, err)
+	assert.Equal(t, want.Add(123456*time.Microsecond), ts)
+
+	_, err = ParseTimestamp("31/01/2024", loc)
+	assert.NotNil(t, err)
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"1h30m", 90 * time.Minute},
+		{"1 day 12 hours", 36 * time.Hour},
+		{"2 weeks -1 day", 13 * 24 * time.Hour},
+		{"500 Milliseconds", 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		d, err := ParseInterval(tt.s)
+		assert.Nil(t, err, tt.s)
+		assert.Equal(t, tt.want, d, tt.s)
+	}
+
+	for _, s := range []string{"", "1 month", "day 1", "3"} {
+		_, err := ParseInterval(s)
+		assert.NotNil(t, err, s)
+	}
+}
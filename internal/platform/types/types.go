@@ -6,24 +6,69 @@ const (
 	TypeByte   byte = 3
 	TypeBool   byte = 4
 	TypeInt32  byte = 5
+	// TypeNull is stored for NULL values of nullable columns. It has no value part, only type and length (0)
+	TypeNull byte = 6
+	// TypeFloat64 and TypeFloat32 are IEEE 754 floating-point numbers
+	TypeFloat64 byte = 7
+	TypeFloat32 byte = 8
+	// TypeDate is a Date stored as an int32 number of days since 1970-01-01
+	TypeDate byte = 9
+	// TypeTimestamp is a point in time stored as an int64 number of microseconds since 1970-01-01 00:00:00 UTC
+	TypeTimestamp byte = 10
+	// TypeInterval is a time.Duration stored as an int64 number of nanoseconds
+	TypeInterval byte = 11
+	// TypeDecimal is a Decimal stored as [scale byte][sign byte][absolute value of the unscaled value in big-endian]
+	TypeDecimal byte = 12
+	// TypeBlob is a []byte stored as it is
+	TypeBlob byte = 13
+	// TypeJSON is a JSON document. Its value is an hmap for objects, a list for arrays and a TLV for other values
+	TypeJSON byte = 14
 
 	TypeWALEntry         byte = 20
-	TypeWALLastIDItem    byte = 21
+	TypeWALCheckpoint    byte = 21
+	TypeTableHeader      byte = 80
 	TypeColumnDefinition byte = 90
 	TypeRecord           byte = 100
 	TypeDeletedRecord    byte = 101
-	TypeHMap             byte = 220
-	TypeHMapKey          byte = 221
-	TypeHMapVal          byte = 222
-	TypeList             byte = 230
-	TypeIndex            byte = 240
-	TypeIndexItem        byte = 241
-	TypePage             byte = 255
+	// TypeRecordVersion is the first value inside a record written by a transaction. It contains the IDs of the
+	// transactions that created and deleted the record as two int64s
+	TypeRecordVersion byte = 102
+	// TypeOverflowPointer replaces a value that was moved into overflow chunks. It contains the type of the value, the
+	// position of the page and the slot of the first chunk and the length of the value
+	TypeOverflowPointer byte = 103
+	// TypeOverflowChunk is a record that contains a part of a value and the position of the page and the slot of the
+	// next chunk
+	TypeOverflowChunk byte = 104
+	TypeHMap          byte = 220
+	TypeHMapKey       byte = 221
+	TypeHMapVal       byte = 222
+	TypeList          byte = 230
+	TypeIndex         byte = 240
+	TypeIndexItem     byte = 241
+	// TypeBtreeMeta is the first page of an index file. It points to the root node of the B+tree
+	TypeBtreeMeta byte = 242
+	// TypeBtreeNode is a leaf or internal node of a B+tree stored in a fixed-size page
+	TypeBtreeNode byte = 243
+	// TypeFullTextSegment is a segment of a full-text index file. It contains postings that were added or removed
+	TypeFullTextSegment byte = 244
+	// TypeFreeSpaceMap is stored at the beginning of a free-space map file
+	TypeFreeSpaceMap byte = 253
+	// TypeSlottedPage is the first byte of a fixed-size page with a slot directory
+	TypeSlottedPage byte = 254
+	// TypePage is a page of tables created before pages had a fixed size. They are converted when they are opened
+	TypePage byte = 255
 )
 
 const (
-	LenByte  = 1
-	LenInt32 = 4
+	LenByte    = 1
+	LenInt32   = 4
+	LenInt64   = 8
+	LenFloat32 = 4
+	LenFloat64 = 8
+	// LenRecordVersion is the length of the value of a TypeRecordVersion TLV
+	LenRecordVersion = 2 * LenInt64
+	// LenOverflowPointer is the length of the value of a TypeOverflowPointer TLV
+	LenOverflowPointer = LenByte + LenInt64 + 2 + LenInt32
 	// LenMeta represents the "meta" bytes in each TLV record that accounts for type+len, for example: 1 8 0 0 0 || 10 0 0 0 0 0 0 0 0 the bytes before the || are the "meta" bytes and 10 ... is the actual value
 	LenMeta uint32 = 5
 )
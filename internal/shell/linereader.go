@@ -0,0 +1,261 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned by LineReader.ReadLine when the user presses Ctrl-C
+var ErrInterrupted = errors.New("interrupted")
+
+type LineReader interface {
+	// ReadLine returns the next line without the line ending. It returns io.EOF when there is no more input
+	ReadLine(prompt string) (string, error)
+	AddHistory(line string)
+}
+
+// NewLineReader returns a line editor if in is a terminal and a plain line reader otherwise, for example when a script is piped into the shell
+func NewLineReader(in *os.File, out io.Writer) LineReader {
+	if isTerminal(int(in.Fd())) {
+		return newEditor(in, out)
+	}
+	return newPlainReader(in)
+}
+
+// plainReader reads lines without prompts or editing
+type plainReader struct {
+	reader *bufio.Reader
+}
+
+func newPlainReader(in io.Reader) *plainReader {
+	return &plainReader{
+		reader: bufio.NewReader(in),
+	}
+}
+
+func (r *plainReader) ReadLine(_ string) (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			return strings.TrimRight(line, "\r"), nil
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (r *plainReader) AddHistory(_ string) {}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyCtrlK     = 11
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// editor is a minimal readline-like line editor. It supports:
+//   - moving the cursor with the arrow keys, Home/End, Ctrl-A/E and Ctrl-B/F
+//   - deleting with Backspace, Delete, Ctrl-D, Ctrl-K, Ctrl-U and Ctrl-W
+//   - browsing the history with the up and down arrows or Ctrl-P/N
+type editor struct {
+	in      *os.File
+	reader  *bufio.Reader
+	out     io.Writer
+	history []string
+}
+
+func newEditor(in *os.File, out io.Writer) *editor {
+	return &editor{
+		in:     in,
+		reader: bufio.NewReader(in),
+		out:    out,
+	}
+}
+
+func (e *editor) AddHistory(line string) {
+	if line == "" {
+		return
+	}
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+func (e *editor) ReadLine(prompt string) (line string, err error) {
+	state, err := makeRaw(int(e.in.Fd()))
+	if err != nil {
+		return "", fmt.Errorf("editor.ReadLine: %w", err)
+	}
+	defer func() {
+		if restoreErr := restoreTerminal(int(e.in.Fd()), state); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}()
+
+	buf := make([]rune, 0)
+	cursor := 0
+	historyIdx := len(e.history)
+	e.refresh(prompt, buf, cursor)
+
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case keyEnter, '\n':
+			e.write("\r\n")
+			return string(buf), nil
+		case keyCtrlC:
+			e.write("^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(buf) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			buf, cursor = deleteAt(buf, cursor)
+		case keyBackspace, keyCtrlH:
+			if cursor > 0 {
+				buf, cursor = deleteAt(buf, cursor-1)
+			}
+		case keyCtrlA:
+			cursor = 0
+		case keyCtrlE:
+			cursor = len(buf)
+		case keyCtrlB:
+			cursor = max(cursor-1, 0)
+		case keyCtrlF:
+			cursor = min(cursor+1, len(buf))
+		case keyCtrlK:
+			buf = buf[:cursor]
+		case keyCtrlU:
+			buf = buf[cursor:]
+			cursor = 0
+		case keyCtrlW:
+			start := cursor
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[cursor:]...)
+			cursor = start
+		case keyCtrlP:
+			buf, cursor, historyIdx = e.historyPrev(buf, historyIdx)
+		case keyCtrlN:
+			buf, cursor, historyIdx = e.historyNext(buf, historyIdx)
+		case keyEscape:
+			buf, cursor, historyIdx = e.handleEscape(buf, cursor, historyIdx)
+		default:
+			if r < ' ' || r == utf8.RuneError {
+				continue
+			}
+			buf = append(buf[:cursor], append([]rune{r}, buf[cursor:]...)...)
+			cursor++
+		}
+		e.refresh(prompt, buf, cursor)
+	}
+}
+
+// handleEscape handles ANSI escape sequences such as ESC [ A (up arrow)
+func (e *editor) handleEscape(buf []rune, cursor, historyIdx int) ([]rune, int, int) {
+	b, err := e.reader.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return buf, cursor, historyIdx
+	}
+	code, err := e.reader.ReadByte()
+	if err != nil {
+		return buf, cursor, historyIdx
+	}
+	switch code {
+	case 'A':
+		return e.historyPrev(buf, historyIdx)
+	case 'B':
+		return e.historyNext(buf, historyIdx)
+	case 'C':
+		return buf, min(cursor+1, len(buf)), historyIdx
+	case 'D':
+		return buf, max(cursor-1, 0), historyIdx
+	case 'H':
+		return buf, 0, historyIdx
+	case 'F':
+		return buf, len(buf), historyIdx
+	case '1', '3', '4', '7', '8':
+		// ESC [ n ~ sequences: 1 and 7 are Home, 4 and 8 are End, 3 is Delete
+		if tilde, err := e.reader.ReadByte(); err != nil || tilde != '~' {
+			return buf, cursor, historyIdx
+		}
+		switch code {
+		case '1', '7':
+			return buf, 0, historyIdx
+		case '4', '8':
+			return buf, len(buf), historyIdx
+		case '3':
+			buf, cursor = deleteAt(buf, cursor)
+		}
+	}
+	return buf, cursor, historyIdx
+}
+
+func (e *editor) historyPrev(buf []rune, historyIdx int) ([]rune, int, int) {
+	if historyIdx == 0 {
+		return buf, len(buf), historyIdx
+	}
+	historyIdx--
+	line := []rune(e.history[historyIdx])
+	return line, len(line), historyIdx
+}
+
+func (e *editor) historyNext(buf []rune, historyIdx int) ([]rune, int, int) {
+	if historyIdx >= len(e.history)-1 {
+		return []rune{}, 0, len(e.history)
+	}
+	historyIdx++
+	line := []rune(e.history[historyIdx])
+	return line, len(line), historyIdx
+}
+
+// refresh redraws the current line and moves the terminal cursor to the editing position
+func (e *editor) refresh(prompt string, buf []rune, cursor int) {
+	sb := strings.Builder{}
+	sb.WriteString("\r")
+	sb.WriteString(prompt)
+	sb.WriteString(string(buf))
+	// clear everything right of the cursor
+	sb.WriteString("\x1b[K")
+	sb.WriteString("\r")
+	if pos := utf8.RuneCountInString(prompt) + cursor; pos > 0 {
+		sb.WriteString(fmt.Sprintf("\x1b[%dC", pos))
+	}
+	e.write(sb.String())
+}
+
+func (e *editor) write(s string) {
+	_, _ = io.WriteString(e.out, s)
+}
+
+func deleteAt(buf []rune, pos int) ([]rune, int) {
+	if pos < 0 || pos >= len(buf) {
+		return buf, pos
+	}
+	return append(buf[:pos], buf[pos+1:]...), pos
+}
@@ -0,0 +1,92 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// writeTable prints rows as an ASCII table such as:
+//
+//	+----+----------+
+//	| id | username |
+//	+----+----------+
+//	| 1  | user1    |
+//	+----+----------+
+func writeTable(w io.Writer, columns []string, rows [][]string) {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = utf8.RuneCountInString(c)
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(v))
+		}
+	}
+
+	sep := strings.Builder{}
+	sep.WriteString("+")
+	for _, w := range widths {
+		sep.WriteString(strings.Repeat("-", w+2))
+		sep.WriteString("+")
+	}
+	sep.WriteString("\n")
+
+	writeRow := func(values []string) {
+		sb := strings.Builder{}
+		sb.WriteString("|")
+		for i, v := range values {
+			sb.WriteString(" ")
+			sb.WriteString(v)
+			sb.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+			sb.WriteString(" |")
+		}
+		sb.WriteString("\n")
+		_, _ = io.WriteString(w, sb.String())
+	}
+
+	_, _ = io.WriteString(w, sep.String())
+	writeRow(columns)
+	_, _ = io.WriteString(w, sep.String())
+	for _, row := range rows {
+		writeRow(row)
+	}
+	if len(rows) > 0 {
+		_, _ = io.WriteString(w, sep.String())
+	}
+}
+
+// formatRows converts record maps into display strings in the order of columns
+func formatRows(columns []string, records []map[string]interface{}) [][]string {
+	rows := make([][]string, 0, len(records))
+	for _, rec := range records {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = formatValue(rec[c])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05.999999-07:00")
+	case []byte:
+		return fmt.Sprintf("\\x%x", val)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
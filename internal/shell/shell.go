@@ -0,0 +1,281 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/omesh-barhate/ByteForge/internal"
+	"github.com/omesh-barhate/ByteForge/internal/sql"
+	"github.com/omesh-barhate/ByteForge/internal/table"
+)
+
+const (
+	Prompt             = "byteforge> "
+	ContinuationPrompt = "       ...> "
+)
+
+const helpText = `Enter SQL statements terminated by a semicolon. Statements can span multiple lines.
+
+Meta-commands:
+  .help               Show this message
+  .tables             List the tables of the database
+  .schema [table]     Show the CREATE TABLE statement of a table or every table
+  .indexes [table]    List the indexes of a table or every table
+  .explain <query>    Run a SELECT statement and show how the table was accessed
+  .stats              Show the content and the hit ratio of the buffer pool
+  .quit, .exit        Exit the shell
+`
+
+// Shell is an interactive SQL shell for a single database
+type Shell struct {
+	db       *internal.Database
+	executor *sql.Executor
+	in       LineReader
+	out      io.Writer
+}
+
+func New(db *internal.Database, in LineReader, out io.Writer) *Shell {
+	return &Shell{
+		db:       db,
+		executor: sql.NewExecutor(db),
+		in:       in,
+		out:      out,
+	}
+}
+
+// Run reads and executes statements until the input ends or the user quits
+func (s *Shell) Run() error {
+	buf := strings.Builder{}
+	for {
+		prompt := Prompt
+		if buf.Len() > 0 {
+			prompt = ContinuationPrompt
+		}
+		line, err := s.in.ReadLine(prompt)
+		if err != nil {
+			if errors.Is(err, ErrInterrupted) {
+				buf.Reset()
+				continue
+			}
+			if err == io.EOF {
+				// Execute the last statement even if the semicolon is missing
+				if strings.TrimSpace(buf.String()) != "" {
+					s.execute(buf.String())
+				}
+				return nil
+			}
+			return fmt.Errorf("Shell.Run: %w", err)
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if buf.Len() == 0 {
+			if trimmed == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, ".") {
+				s.in.AddHistory(trimmed)
+				if quit := s.meta(trimmed); quit {
+					return nil
+				}
+				continue
+			}
+		}
+
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if !statementComplete(buf.String()) {
+			continue
+		}
+		s.in.AddHistory(strings.Join(strings.Fields(buf.String()), " "))
+		s.execute(buf.String())
+		buf.Reset()
+	}
+}
+
+func (s *Shell) execute(query string) {
+	results, err := s.executor.Exec(query)
+	for _, res := range results {
+		s.printResult(res)
+	}
+	if err != nil {
+		s.printf("Error: %v\n", err)
+	}
+}
+
+func (s *Shell) printResult(res *sql.Result) {
+	if res.Columns == nil {
+		s.printf("Query OK, %s affected\n", pluralize(res.RowsAffected, "row"))
+		return
+	}
+	if len(res.Rows) == 0 {
+		s.printf("Empty set\n")
+		return
+	}
+	writeTable(s.out, res.Columns, formatRows(res.Columns, res.Rows))
+	s.printf("%s in set\n", pluralize(len(res.Rows), "row"))
+}
+
+// meta runs a meta-command such as .tables. It returns true if the shell should exit
+func (s *Shell) meta(line string) bool {
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case ".quit", ".exit":
+		return true
+	case ".help":
+		s.printf("%s", helpText)
+	case ".tables":
+		s.tables()
+	case ".schema":
+		s.schema(arg)
+	case ".indexes":
+		s.indexes(arg)
+	case ".explain":
+		s.explain(arg)
+	case ".stats":
+		s.stats()
+	default:
+		s.printf("Error: unknown command %s. Enter .help for usage\n", cmd)
+	}
+	return false
+}
+
+func (s *Shell) tables() {
+	names := s.db.TableNames()
+	if len(names) == 0 {
+		s.printf("No tables\n")
+		return
+	}
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, []string{name})
+	}
+	writeTable(s.out, []string{"table"}, rows)
+}
+
+func (s *Shell) schema(name string) {
+	tables, err := s.selectTables(name)
+	if err != nil {
+		s.printf("Error: %v\n", err)
+		return
+	}
+	for _, t := range tables {
+		s.printf("%s\n", sql.FormatCreateTable(t))
+	}
+}
+
+func (s *Shell) indexes(name string) {
+	tables, err := s.selectTables(name)
+	if err != nil {
+		s.printf("Error: %v\n", err)
+		return
+	}
+	rows := make([][]string, 0)
+	for _, t := range tables {
+		for _, idx := range t.Indexes() {
+			rows = append(rows, []string{t.Name, idx.Column, idx.Type})
+		}
+	}
+	if len(rows) == 0 {
+		s.printf("No indexes\n")
+		return
+	}
+	writeTable(s.out, []string{"table", "column", "type"}, rows)
+}
+
+func (s *Shell) explain(query string) {
+	stmt, err := sql.ParseOne(query)
+	if err != nil {
+		s.printf("Error: %v\n", err)
+		return
+	}
+	selectStmt, ok := stmt.(*sql.SelectStmt)
+	if !ok {
+		s.printf("Error: .explain only supports SELECT statements\n")
+		return
+	}
+	res, err := s.executor.ExecStatement(selectStmt)
+	if err != nil {
+		s.printf("Error: %v\n", err)
+		return
+	}
+	writeTable(s.out, []string{"table", "type", "rows_inspected", "rows", "extra"}, [][]string{{
+		selectStmt.Table,
+		res.AccessType,
+		strconv.Itoa(res.RowsInspected),
+		strconv.Itoa(len(res.Rows)),
+		res.Extra,
+	}})
+}
+
+func (s *Shell) stats() {
+	st := s.db.BufferPoolStats()
+	writeTable(s.out, []string{"size", "used", "pages", "dirty", "hits", "misses", "hit_ratio", "evictions", "writes"}, [][]string{{
+		strconv.Itoa(st.Size),
+		strconv.Itoa(st.Used),
+		strconv.Itoa(st.Pages),
+		strconv.Itoa(st.Dirty),
+		strconv.FormatInt(st.Hits, 10),
+		strconv.FormatInt(st.Misses, 10),
+		strconv.FormatFloat(st.HitRatio(), 'f', 2, 64),
+		strconv.FormatInt(st.Evictions, 10),
+		strconv.FormatInt(st.Writes, 10),
+	}})
+}
+
+// selectTables returns the table called name or every table sorted by name if name is empty
+func (s *Shell) selectTables(name string) ([]*table.Table, error) {
+	if name != "" {
+		t, ok := s.db.Table(name)
+		if !ok {
+			return nil, internal.NewTableDoesNotExistError(name)
+		}
+		return []*table.Table{t}, nil
+	}
+	names := s.db.TableNames()
+	tables := make([]*table.Table, 0, len(names))
+	for _, n := range names {
+		// The table may have been dropped by another goroutine in the meantime
+		if t, ok := s.db.Table(n); ok {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+func (s *Shell) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(s.out, format, args...)
+}
+
+// statementComplete returns true if the input ends with a semicolon that is not inside a string literal, quoted identifier or comment
+func statementComplete(input string) bool {
+	var quote byte
+	inComment := false
+	complete := false
+	for i := 0; i < len(input); i++ {
+		ch := input[i]
+		switch {
+		case inComment:
+			if ch == '\n' {
+				inComment = false
+			}
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			complete = false
+		case ch == '-' && i+1 < len(input) && input[i+1] == '-':
+			inComment = true
+		case ch == ';':
+			complete = true
+		case ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r':
+			complete = false
+		}
+	}
+	return complete && quote == 0
+}
@@ -0,0 +1,138 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/omesh-barhate/ByteForge/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+type scriptReader struct {
+	lines   []string
+	prompts []string
+}
+
+func (r *scriptReader) ReadLine(prompt string) (string, error) {
+	r.prompts = append(r.prompts, prompt)
+	if len(r.lines) == 0 {
+		return "", io.EOF
+	}
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	return line, nil
+}
+
+func (r *scriptReader) AddHistory(_ string) {}
+
+func TestShell_MultiLineStatements(t *testing.T) {
+	out, reader := runScript(t,
+		"CREATE TABLE users (",
+		"  id INT, username STRING",
+		");",
+		"INSERT INTO users VALUES (1, 'user;1'), (2, 'user2');",
+		"SELECT * FROM users",
+		"WHERE id = 1;",
+		"SELECT * FROM users WHERE id = 3;",
+	)
+
+	expected := `Query OK, 0 rows affected
+Query OK, 2 rows affected
++----+----------+
+| id | username |
++----+----------+
+| 1  | user;1   |
++----+----------+
+1 row in set
+Empty set
+`
+	assert.Equal(t, expected, out)
+	assert.Equal(t, []string{Prompt, ContinuationPrompt, ContinuationPrompt, Prompt, Prompt, ContinuationPrompt, Prompt, Prompt}, reader.prompts)
+}
+
+func TestShell_MetaCommands(t *testing.T) {
+	out, _ := runScript(t,
+		"CREATE TABLE users (id INT, job STRING FULLTEXT);",
+		".tables",
+		".schema users",
+		".indexes",
+		".explain SELECT * FROM users WHERE id = 1",
+		".stats",
+		".nope",
+		".quit",
+		"SELECT * FROM users;",
+	)
+
+	expected := `Query OK, 0 rows affected
++-------+
+| table |
++-------+
+| users |
++-------+
+CREATE TABLE users (
+  id INT64 NOT NULL PRIMARY KEY,
+  job STRING NOT NULL FULLTEXT
+);
++-------+--------+----------+
+| table | column | type     |
++-------+--------+----------+
+| users | id     | btree    |
+| users | job    | fulltext |
++-------+--------+----------+
++-------+------+----------------+------+----------------------+
+| table | type | rows_inspected | rows | extra                |
++-------+------+----------------+------+----------------------+
+| users | ALL  | 0              | 0    | Not using page cache |
++-------+------+----------------+------+----------------------+
++---------+------+-------+-------+------+--------+-----------+-----------+--------+
+| size    | used | pages | dirty | hits | misses | hit_ratio | evictions | writes |
++---------+------+-------+-------+------+--------+-----------+-----------+--------+
+| 8388608 | 0    | 0     | 0     | 0    | 0      | 0.00      | 0         | 0      |
++---------+------+-------+-------+------+--------+-----------+-----------+--------+
+Error: unknown command .nope. Enter .help for usage
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestShell_Errors(t *testing.T) {
+	out, _ := runScript(t,
+		"SELECT * FROM nope;",
+		"SELEC 1;",
+	)
+	assert.Contains(t, out, "Error: Executor.Exec: Executor.selectRows: table does not exist: nope\n")
+	assert.Contains(t, out, "Error: Executor.Exec: syntax error at position 0")
+}
+
+func TestStatementComplete(t *testing.T) {
+	assert.True(t, statementComplete("SELECT 1;"))
+	assert.True(t, statementComplete("SELECT 1; \n"))
+	assert.True(t, statementComplete("SELECT 1; -- comment"))
+	assert.False(t, statementComplete("SELECT 1"))
+	assert.False(t, statementComplete("SELECT ';"))
+	assert.False(t, statementComplete("SELECT 1 -- ;"))
+	assert.False(t, statementComplete("SELECT 1; SELECT 2"))
+}
+
+func runScript(t *testing.T, lines ...string) (string, *scriptReader) {
+	db, err := internal.CreateDatabase("shell_test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer removeDB()
+
+	reader := &scriptReader{lines: lines}
+	out := bytes.Buffer{}
+	if err = New(db, reader, &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), reader
+}
+
+func removeDB() {
+	if err := os.RemoveAll("./data/shell_test"); err != nil {
+		log.Fatal(err)
+	}
+}
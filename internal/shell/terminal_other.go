@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package shell
+
+import "errors"
+
+type terminalState struct{}
+
+// Line editing is only supported on linux and darwin. Other platforms fall back to plain line reading
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errors.New("makeRaw: raw mode is not supported on this platform")
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build linux || darwin
+
+package shell
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+type terminalState struct {
+	termios syscall.Termios
+}
+
+func isTerminal(fd int) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// makeRaw puts the terminal into raw mode so key presses are delivered one by one without echo
+// It returns the previous state that needs to be passed to restoreTerminal
+func makeRaw(fd int) (*terminalState, error) {
+	orig, err := getTermios(fd)
+	if err != nil {
+		return nil, fmt.Errorf("makeRaw: %w", err)
+	}
+	raw := *orig
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.BRKINT | syscall.INPCK | syscall.ISTRIP
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err = setTermios(fd, &raw); err != nil {
+		return nil, fmt.Errorf("makeRaw: %w", err)
+	}
+	return &terminalState{termios: *orig}, nil
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	if state == nil {
+		return nil
+	}
+	if err := setTermios(fd, &state.termios); err != nil {
+		return fmt.Errorf("restoreTerminal: %w", err)
+	}
+	return nil
+}
+
+func getTermios(fd int) (*syscall.Termios, error) {
+	termios := &syscall.Termios{}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return nil, errno
+	}
+	return termios, nil
+}
+
+func setTermios(fd int, termios *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return errno
+	}
+	return nil
+}
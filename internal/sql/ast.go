@@ -0,0 +1,247 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Statement interface {
+	statement()
+}
+
+type Expr interface {
+	expr()
+}
+
+type (
+	CreateTableStmt struct {
+		Table   string
+		Columns []*ColumnDef
+		// PrimaryKey is set by a PRIMARY KEY (col, ...) constraint after the columns
+		PrimaryKey []string
+	}
+
+	ColumnDef struct {
+		Name string
+		// Type is one of the types.Type* constants
+		Type          byte
+		AllowNull     bool
+		FullTextIdx   bool
+		PrimaryKey    bool
+		Unique        bool
+		AutoIncrement bool
+		// Precision and Scale are only set for DECIMAL columns
+		Precision int
+		Scale     int
+	}
+
+	DropTableStmt struct {
+		Table string
+	}
+
+	CreateSequenceStmt struct {
+		Name string
+		// Start is the first value of the sequence. It's 1 if there is no START clause
+		Start int64
+	}
+
+	DropSequenceStmt struct {
+		Name string
+	}
+
+	// CreateIndexStmt creates a B-tree index on a single column. Indexes are identified by their column so the optional name of the index is not stored
+	CreateIndexStmt struct {
+		Table  string
+		Column string
+	}
+
+	InsertStmt struct {
+		Table string
+		// Columns is empty if the statement doesn't list the columns explicitly. In that case values are in table order
+		Columns []string
+		Rows    [][]Expr
+	}
+
+	SelectStmt struct {
+		Table string
+		// Columns is empty for SELECT *. A JSON path is in Columns as it's formatted by PathExpr.String
+		Columns []string
+		// Paths contains the JSON paths of the select list by their name in Columns
+		Paths   map[string]*PathExpr
+		Where   Expr
+		OrderBy []*OrderByItem
+		// Limit is -1 if there is no LIMIT clause
+		Limit  int64
+		Offset int64
+	}
+
+	OrderByItem struct {
+		Column string
+		Desc   bool
+	}
+
+	UpdateStmt struct {
+		Table string
+		Set   []*Assignment
+		Where Expr
+	}
+
+	Assignment struct {
+		Column string
+		Value  Expr
+	}
+
+	DeleteStmt struct {
+		Table string
+		Where Expr
+	}
+
+	// BeginStmt starts a transaction. The statements until COMMIT or ROLLBACK belong to it
+	BeginStmt struct{}
+
+	CommitStmt struct{}
+
+	RollbackStmt struct{}
+
+	// VacuumStmt rewrites the file of a table so the space of deleted records is given back
+	VacuumStmt struct {
+		// Table is empty if every table is vacuumed
+		Table string
+	}
+
+	// SetTimeZoneStmt sets the time zone of timestamps without an offset and of the timestamps returned by SELECT
+	SetTimeZoneStmt struct {
+		Name string
+	}
+)
+
+func (*CreateTableStmt) statement()    {}
+func (*DropTableStmt) statement()      {}
+func (*CreateSequenceStmt) statement() {}
+func (*DropSequenceStmt) statement()   {}
+func (*CreateIndexStmt) statement()    {}
+func (*InsertStmt) statement()         {}
+func (*SelectStmt) statement()         {}
+func (*UpdateStmt) statement()         {}
+func (*DeleteStmt) statement()         {}
+func (*BeginStmt) statement()          {}
+func (*CommitStmt) statement()         {}
+func (*RollbackStmt) statement()       {}
+func (*VacuumStmt) statement()         {}
+func (*SetTimeZoneStmt) statement()    {}
+
+type (
+	// Ident is a reference to a column
+	Ident struct {
+		Name string
+	}
+
+	// Literal is a constant value. Value is int64, float64, string, []byte, bool or nil
+	// Text is the number as it was written for float literals, so DECIMAL columns get it without rounding
+	Literal struct {
+		Value interface{}
+		Text  string
+	}
+
+	// TypedLiteral is a string preceded by a type name such as DATE '2024-01-31'. Type is a types.Type* constant
+	TypedLiteral struct {
+		Type  byte
+		Value string
+	}
+
+	BinaryExpr struct {
+		Op    string
+		Left  Expr
+		Right Expr
+	}
+
+	NotExpr struct {
+		Expr Expr
+	}
+
+	// InExpr is expr [NOT] IN (value [, value...])
+	InExpr struct {
+		Expr   Expr
+		Values []Expr
+		Not    bool
+	}
+
+	// BetweenExpr is expr [NOT] BETWEEN from AND to. Both bounds are inclusive
+	BetweenExpr struct {
+		Expr Expr
+		From Expr
+		To   Expr
+		Not  bool
+	}
+
+	// IsNullExpr is expr IS [NOT] NULL
+	IsNullExpr struct {
+		Expr Expr
+		Not  bool
+	}
+
+	// LikeExpr is expr [NOT] LIKE pattern
+	LikeExpr struct {
+		Expr    Expr
+		Pattern Expr
+		Not     bool
+	}
+
+	// PathExpr reads a value from a JSON column such as attrs->'address'->>'city'
+	PathExpr struct {
+		Column string
+		// Path contains object keys as strings and array indexes as int64s
+		Path []interface{}
+		// AsText is set if the last operator is ->>, which returns the value as a string instead of JSON
+		AsText bool
+	}
+
+	// CallExpr is a function call such as NEXTVAL('seq'). Name is upper-cased
+	CallExpr struct {
+		Name string
+		Args []Expr
+	}
+)
+
+func (*Ident) expr()        {}
+func (*Literal) expr()      {}
+func (*BinaryExpr) expr()   {}
+func (*NotExpr) expr()      {}
+func (*InExpr) expr()       {}
+func (*BetweenExpr) expr()  {}
+func (*IsNullExpr) expr()   {}
+func (*LikeExpr) expr()     {}
+func (*CallExpr) expr()     {}
+func (*TypedLiteral) expr() {}
+func (*PathExpr) expr()     {}
+
+// String returns the path as it's written in SQL, for example attrs->'tags'->>0
+func (p *PathExpr) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.Column)
+	for i, step := range p.Path {
+		if p.AsText && i == len(p.Path)-1 {
+			sb.WriteString("->>")
+		} else {
+			sb.WriteString("->")
+		}
+		switch s := step.(type) {
+		case string:
+			sb.WriteString("'" + strings.ReplaceAll(s, "'", "''") + "'")
+		default:
+			fmt.Fprint(&sb, s)
+		}
+	}
+	return sb.String()
+}
+
+const (
+	OpAnd   = "AND"
+	OpOr    = "OR"
+	OpEq    = "="
+	OpNotEq = "!="
+	OpLt    = "<"
+	OpLte   = "<="
+	OpGt    = ">"
+	OpGte   = ">="
+)
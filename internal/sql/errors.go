@@ -0,0 +1,95 @@
+package sql
+
+import "fmt"
+
+type SyntaxError struct {
+	pos int
+	msg string
+}
+
+func NewSyntaxError(pos int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{pos: pos, msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.pos, e.msg)
+}
+
+type UnsupportedExpressionError struct {
+	reason string
+}
+
+func NewUnsupportedExpressionError(reason string) *UnsupportedExpressionError {
+	return &UnsupportedExpressionError{reason: reason}
+}
+
+func (e *UnsupportedExpressionError) Error() string {
+	return fmt.Sprintf("unsupported expression: %s", e.reason)
+}
+
+type TypeMismatchError struct {
+	column string
+	value  interface{}
+}
+
+func NewTypeMismatchError(column string, value interface{}) *TypeMismatchError {
+	return &TypeMismatchError{column: column, value: value}
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("value %v (%T) cannot be stored in column %s", e.value, e.value, e.column)
+}
+
+type MissingValueError struct {
+	column string
+}
+
+func NewMissingValueError(column string) *MissingValueError {
+	return &MissingValueError{column: column}
+}
+
+func (e *MissingValueError) Error() string {
+	return fmt.Sprintf("column %s is missing from the insert but cannot be null", e.column)
+}
+
+type NotJSONError struct {
+	column string
+}
+
+func NewNotJSONError(column string) *NotJSONError {
+	return &NotJSONError{column: column}
+}
+
+func (e *NotJSONError) Error() string {
+	return fmt.Sprintf("-> and ->> cannot be used on column %s because it's not JSON", e.column)
+}
+
+type TransactionInProgressError struct{}
+
+func NewTransactionInProgressError() *TransactionInProgressError {
+	return &TransactionInProgressError{}
+}
+
+func (e *TransactionInProgressError) Error() string {
+	return "a transaction is already in progress"
+}
+
+type NoTransactionError struct{}
+
+func NewNoTransactionError() *NoTransactionError {
+	return &NoTransactionError{}
+}
+
+func (e *NoTransactionError) Error() string {
+	return "no transaction is in progress"
+}
+
+type SchemaChangeInTransactionError struct{}
+
+func NewSchemaChangeInTransactionError() *SchemaChangeInTransactionError {
+	return &SchemaChangeInTransactionError{}
+}
+
+func (e *SchemaChangeInTransactionError) Error() string {
+	return "CREATE, DROP and VACUUM statements cannot be used inside a transaction"
+}
@@ -0,0 +1,901 @@
+package sql
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omesh-barhate/ByteForge/internal"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/column"
+	"github.com/omesh-barhate/ByteForge/internal/table/index"
+	"github.com/omesh-barhate/ByteForge/internal/table/predicate"
+)
+
+// Executor runs parsed statements against a database by translating them into calls on table.Table
+type Executor struct {
+	db *internal.Database
+	// tx is the transaction started by BEGIN. Statements outside of it run in a transaction of their own
+	tx *internal.Tx
+	// loc is the time zone of timestamps without an offset and of the timestamps returned by SELECT
+	loc *time.Location
+}
+
+func NewExecutor(db *internal.Database) *Executor {
+	return &Executor{
+		db:  db,
+		loc: time.UTC,
+	}
+}
+
+// SetTimeZone sets the time zone of timestamps without an offset and of the timestamps returned by SELECT
+func (e *Executor) SetTimeZone(loc *time.Location) {
+	e.loc = loc
+}
+
+// Result is the outcome of a single statement
+type Result struct {
+	// Columns contains the column names of Rows in display order. It is only set for SELECT
+	Columns []string
+	Rows    []map[string]interface{}
+	// RowsAffected is the number of rows inserted, updated or deleted
+	RowsAffected int
+	// LastInsertKey is the primary key of the last inserted row including the generated auto-increment value. It is only set for INSERT
+	LastInsertKey index.Key
+	// AccessType, Extra and RowsInspected describe how the table was read. They come from table.SelectResult
+	AccessType    string
+	Extra         string
+	RowsInspected int
+}
+
+// Exec parses and executes every statement in query
+// Execution stops at the first failing statement and the results of the previous statements are returned alongside the error
+func (e *Executor) Exec(query string) ([]*Result, error) {
+	stmts, err := Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.Exec: %w", err)
+	}
+	results := make([]*Result, 0, len(stmts))
+	for _, stmt := range stmts {
+		res, err := e.ExecStatement(stmt)
+		if err != nil {
+			return results, fmt.Errorf("Executor.Exec: %w", err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (e *Executor) ExecStatement(stmt Statement) (*Result, error) {
+	switch stmt.(type) {
+	case *CreateTableStmt, *DropTableStmt, *CreateSequenceStmt, *DropSequenceStmt, *CreateIndexStmt, *VacuumStmt:
+		// Schema changes and rewritten table files cannot be undone by a rollback
+		if e.tx != nil {
+			return nil, fmt.Errorf("Executor.ExecStatement: %w", NewSchemaChangeInTransactionError())
+		}
+	}
+	switch s := stmt.(type) {
+	case *CreateTableStmt:
+		return e.createTable(s)
+	case *DropTableStmt:
+		return e.dropTable(s)
+	case *CreateSequenceStmt:
+		return e.createSequence(s)
+	case *DropSequenceStmt:
+		return e.dropSequence(s)
+	case *CreateIndexStmt:
+		return e.createIndex(s)
+	case *InsertStmt:
+		return e.insert(s)
+	case *SelectStmt:
+		return e.selectRows(s)
+	case *UpdateStmt:
+		return e.update(s)
+	case *DeleteStmt:
+		return e.delete(s)
+	case *BeginStmt:
+		return e.begin()
+	case *CommitStmt:
+		return e.commit()
+	case *RollbackStmt:
+		return e.rollback()
+	case *VacuumStmt:
+		return e.vacuum(s)
+	case *SetTimeZoneStmt:
+		return e.setTimeZone(s)
+	default:
+		return nil, fmt.Errorf("Executor.ExecStatement: unknown statement: %T", stmt)
+	}
+}
+
+func (e *Executor) createTable(stmt *CreateTableStmt) (*Result, error) {
+	names := make([]string, 0, len(stmt.Columns))
+	cols := make(table.Columns)
+	primaryKey := stmt.PrimaryKey
+	for _, def := range stmt.Columns {
+		if _, ok := cols[def.Name]; ok {
+			return nil, fmt.Errorf("Executor.createTable: duplicate column: %s", def.Name)
+		}
+		if def.PrimaryKey {
+			if primaryKey != nil {
+				return nil, fmt.Errorf("Executor.createTable: multiple primary keys: %s, %s", strings.Join(primaryKey, ", "), def.Name)
+			}
+			primaryKey = []string{def.Name}
+		}
+		col, err := column.New(def.Name, def.Type, column.Opts{
+			AllowNull:     def.AllowNull,
+			FullTextIdx:   def.FullTextIdx,
+			PrimaryKey:    def.PrimaryKey,
+			Unique:        def.Unique,
+			AutoIncrement: def.AutoIncrement,
+			Precision:     def.Precision,
+			Scale:         def.Scale,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Executor.createTable: %w", err)
+		}
+		names = append(names, def.Name)
+		cols[def.Name] = col
+	}
+	// Tables without a declared primary key use their id column
+	if primaryKey == nil {
+		if _, ok := cols["id"]; !ok {
+			return nil, fmt.Errorf("Executor.createTable: %w", table.NewInvalidPrimaryKeyError(stmt.Table, "no PRIMARY KEY and no id column"))
+		}
+		primaryKey = []string{"id"}
+	}
+	if _, err := e.db.CreateTable(e.db.Path, stmt.Table, names, cols, primaryKey); err != nil {
+		return nil, fmt.Errorf("Executor.createTable: %w", err)
+	}
+	return &Result{}, nil
+}
+
+func (e *Executor) createSequence(stmt *CreateSequenceStmt) (*Result, error) {
+	if _, err := e.db.CreateSequence(stmt.Name, stmt.Start); err != nil {
+		return nil, fmt.Errorf("Executor.createSequence: %w", err)
+	}
+	return &Result{}, nil
+}
+
+func (e *Executor) dropSequence(stmt *DropSequenceStmt) (*Result, error) {
+	if err := e.db.DropSequence(stmt.Name); err != nil {
+		return nil, fmt.Errorf("Executor.dropSequence: %w", err)
+	}
+	return &Result{}, nil
+}
+
+func (e *Executor) dropTable(stmt *DropTableStmt) (*Result, error) {
+	if err := e.db.DropTable(stmt.Table); err != nil {
+		return nil, fmt.Errorf("Executor.dropTable: %w", err)
+	}
+	return &Result{}, nil
+}
+
+func (e *Executor) createIndex(stmt *CreateIndexStmt) (*Result, error) {
+	if err := e.db.CreateIndex(stmt.Table, stmt.Column); err != nil {
+		return nil, fmt.Errorf("Executor.createIndex: %w", err)
+	}
+	return &Result{}, nil
+}
+
+func (e *Executor) vacuum(stmt *VacuumStmt) (*Result, error) {
+	var tables []string
+	if stmt.Table != "" {
+		tables = append(tables, stmt.Table)
+	}
+	if _, err := e.db.Vacuum(tables...); err != nil {
+		return nil, fmt.Errorf("Executor.vacuum: %w", err)
+	}
+	return &Result{}, nil
+}
+
+func (e *Executor) insert(stmt *InsertStmt) (*Result, error) {
+	res := &Result{}
+	err := e.inTx(func(tx *internal.Tx) error {
+		t, err := e.table(stmt.Table)
+		if err != nil {
+			return err
+		}
+		colNames := stmt.Columns
+		if len(colNames) == 0 {
+			colNames = t.ColumnNames()
+		}
+		omitted, err := e.omittedColumns(t, colNames)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range stmt.Rows {
+			if len(row) != len(colNames) {
+				return column.NewMismatchingColumnsError(len(colNames), len(row))
+			}
+			record := make(map[string]interface{}, len(row)+len(omitted))
+			for _, name := range omitted {
+				record[name] = nil
+			}
+			for i, name := range colNames {
+				val, err := e.columnValue(t, name, row[i])
+				if err != nil {
+					return err
+				}
+				record[name] = val
+			}
+			key, err := tx.Insert(t.Name, record)
+			if err != nil {
+				return err
+			}
+			res.RowsAffected++
+			res.LastInsertKey = key
+		}
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("Executor.insert: %w", err)
+	}
+	return res, nil
+}
+
+// omittedColumns returns the columns that are missing from the column list of an INSERT and are stored as NULL
+// The auto-increment column is left out because the table generates its value
+func (e *Executor) omittedColumns(t *table.Table, colNames []string) ([]string, error) {
+	var omitted []string
+	for _, name := range t.ColumnNames() {
+		col := t.Columns()[name]
+		if slices.Contains(colNames, name) || col.Opts.AutoIncrement {
+			continue
+		}
+		if !col.Opts.AllowNull {
+			return nil, fmt.Errorf("Executor.omittedColumns: %w", NewMissingValueError(name))
+		}
+		omitted = append(omitted, name)
+	}
+	return omitted, nil
+}
+
+func (e *Executor) selectRows(stmt *SelectStmt) (*Result, error) {
+	t, err := e.table(stmt.Table)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.selectRows: %w", err)
+	}
+	cols := stmt.Columns
+	if len(cols) == 0 {
+		cols = t.ColumnNames()
+	}
+	for _, c := range cols {
+		if path, ok := stmt.Paths[c]; ok {
+			if _, _, err = e.operandColumn(t, path); err != nil {
+				return nil, fmt.Errorf("Executor.selectRows: %w", err)
+			}
+			continue
+		}
+		if err = e.ensureColumn(t, c); err != nil {
+			return nil, fmt.Errorf("Executor.selectRows: %w", err)
+		}
+	}
+	for _, o := range stmt.OrderBy {
+		if err = e.ensureColumn(t, o.Column); err != nil {
+			return nil, fmt.Errorf("Executor.selectRows: %w", err)
+		}
+	}
+
+	where, err := e.wherePredicate(t, stmt.Where)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.selectRows: %w", err)
+	}
+	// Without BEGIN the table is read without a transaction so it doesn't wait for other connections
+	var selectResult *table.SelectResult
+	if e.tx != nil {
+		selectResult, err = e.tx.SelectWhere(t.Name, where)
+	} else {
+		selectResult, err = e.db.SelectWhere(t.Name, where)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Executor.selectRows: %w", err)
+	}
+
+	rows := selectResult.Rows
+	if err = sortRows(rows, stmt.OrderBy); err != nil {
+		return nil, fmt.Errorf("Executor.selectRows: %w", err)
+	}
+	rows = limitRows(rows, stmt.Limit, stmt.Offset)
+
+	projected := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		p := make(map[string]interface{}, len(cols))
+		for _, c := range cols {
+			if path, ok := stmt.Paths[c]; ok {
+				if doc, ok := row[path.Column].(types.JSON); ok {
+					p[c] = doc.Extract(path.Path, path.AsText)
+				} else {
+					p[c] = nil
+				}
+				continue
+			}
+			p[c] = row[c]
+			// Timestamps are stored in UTC
+			if tm, ok := row[c].(time.Time); ok {
+				p[c] = tm.In(e.loc)
+			}
+		}
+		projected = append(projected, p)
+	}
+	return &Result{
+		Columns:       cols,
+		Rows:          projected,
+		AccessType:    selectResult.Type,
+		Extra:         selectResult.Extra,
+		RowsInspected: selectResult.RowsInspected,
+	}, nil
+}
+
+func (e *Executor) update(stmt *UpdateStmt) (*Result, error) {
+	t, err := e.table(stmt.Table)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.update: %w", err)
+	}
+	values := make(map[string]interface{}, len(stmt.Set))
+	for _, a := range stmt.Set {
+		val, err := e.columnValue(t, a.Column, a.Value)
+		if err != nil {
+			return nil, fmt.Errorf("Executor.update: %w", err)
+		}
+		values[a.Column] = val
+	}
+	where, err := e.wherePredicate(t, stmt.Where)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.update: %w", err)
+	}
+	var res *table.UpdateResult
+	err = e.inTx(func(tx *internal.Tx) error {
+		res, err = tx.UpdateWhere(t.Name, where, values)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Executor.update: %w", err)
+	}
+	return &Result{RowsAffected: res.Updated}, nil
+}
+
+func (e *Executor) delete(stmt *DeleteStmt) (*Result, error) {
+	t, err := e.table(stmt.Table)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.delete: %w", err)
+	}
+	where, err := e.wherePredicate(t, stmt.Where)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.delete: %w", err)
+	}
+	var n int
+	err = e.inTx(func(tx *internal.Tx) error {
+		n, err = tx.DeleteWhere(t.Name, where)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Executor.delete: %w", err)
+	}
+	return &Result{RowsAffected: n}, nil
+}
+
+func (e *Executor) begin() (*Result, error) {
+	if e.tx != nil {
+		return nil, fmt.Errorf("Executor.begin: %w", NewTransactionInProgressError())
+	}
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("Executor.begin: %w", err)
+	}
+	e.tx = tx
+	return &Result{}, nil
+}
+
+func (e *Executor) commit() (*Result, error) {
+	if e.tx == nil {
+		return nil, fmt.Errorf("Executor.commit: %w", NewNoTransactionError())
+	}
+	tx := e.tx
+	e.tx = nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("Executor.commit: %w", err)
+	}
+	return &Result{}, nil
+}
+
+func (e *Executor) rollback() (*Result, error) {
+	if e.tx == nil {
+		return nil, fmt.Errorf("Executor.rollback: %w", NewNoTransactionError())
+	}
+	tx := e.tx
+	e.tx = nil
+	if err := tx.Rollback(); err != nil {
+		return nil, fmt.Errorf("Executor.rollback: %w", err)
+	}
+	return &Result{}, nil
+}
+
+// inTx runs fn in the transaction started by BEGIN
+// Without BEGIN fn runs in a new transaction that is committed if fn succeeds and rolled back if it fails,
+// so a statement that fails halfway leaves no changes behind
+func (e *Executor) inTx(fn func(tx *internal.Tx) error) error {
+	if e.tx != nil {
+		return fn(e.tx)
+	}
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Executor.inTx: %w", err)
+	}
+	if err = fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w, rollback: %w", err, rbErr)
+		}
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("Executor.inTx: %w", err)
+	}
+	return nil
+}
+
+func (e *Executor) table(name string) (*table.Table, error) {
+	t, ok := e.db.Table(name)
+	if !ok {
+		return nil, internal.NewTableDoesNotExistError(name)
+	}
+	return t, nil
+}
+
+func (e *Executor) ensureColumn(t *table.Table, name string) error {
+	if _, ok := t.Columns()[name]; !ok {
+		return column.NewUnknownColumnError(t.Name, name)
+	}
+	return nil
+}
+
+// wherePredicate converts a WHERE expression into a predicate accepted by table.Table
+// A missing WHERE clause matches every record
+func (e *Executor) wherePredicate(t *table.Table, expr Expr) (predicate.Predicate, error) {
+	if expr == nil {
+		return predicate.NewAnd(), nil
+	}
+	pred, err := e.predicate(t, expr)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.wherePredicate: %w", err)
+	}
+	return pred, nil
+}
+
+func (e *Executor) predicate(t *table.Table, expr Expr) (predicate.Predicate, error) {
+	switch ex := expr.(type) {
+	case *BinaryExpr:
+		if ex.Op == OpAnd || ex.Op == OpOr {
+			left, err := e.predicate(t, ex.Left)
+			if err != nil {
+				return nil, err
+			}
+			right, err := e.predicate(t, ex.Right)
+			if err != nil {
+				return nil, err
+			}
+			if ex.Op == OpAnd {
+				return predicate.NewAnd(left, right), nil
+			}
+			return predicate.NewOr(left, right), nil
+		}
+		return e.comparison(t, ex)
+	case *NotExpr:
+		p, err := e.predicate(t, ex.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return predicate.NewNot(p), nil
+	case *InExpr:
+		col, path, err := e.operandColumn(t, ex.Expr)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, len(ex.Values))
+		for _, v := range ex.Values {
+			val, err := e.operandValue(t, col, path, v)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, val)
+		}
+		return negate(withPath(path, predicate.NewIn(col, values)), ex.Not), nil
+	case *BetweenExpr:
+		col, path, err := e.operandColumn(t, ex.Expr)
+		if err != nil {
+			return nil, err
+		}
+		from, err := e.operandValue(t, col, path, ex.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := e.operandValue(t, col, path, ex.To)
+		if err != nil {
+			return nil, err
+		}
+		return negate(withPath(path, predicate.NewBetween(col, from, to)), ex.Not), nil
+	case *IsNullExpr:
+		col, path, err := e.operandColumn(t, ex.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return negate(withPath(path, predicate.NewIsNull(col)), ex.Not), nil
+	case *LikeExpr:
+		col, path, err := e.operandColumn(t, ex.Expr)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := e.operandValue(t, col, path, ex.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := pattern.(string)
+		if !ok {
+			return nil, NewUnsupportedExpressionError(fmt.Sprintf("LIKE pattern for column %s must be a string", col))
+		}
+		return negate(withPath(path, predicate.NewLike(col, s)), ex.Not), nil
+	default:
+		return nil, NewUnsupportedExpressionError(fmt.Sprintf("%T in WHERE clause", expr))
+	}
+}
+
+// comparison converts column op value or value op column into a predicate
+func (e *Executor) comparison(t *table.Table, bin *BinaryExpr) (predicate.Predicate, error) {
+	op := predicate.Op(bin.Op)
+	switch op {
+	case predicate.OpEq, predicate.OpNotEq, predicate.OpLt, predicate.OpLte, predicate.OpGt, predicate.OpGte:
+	default:
+		return nil, NewUnsupportedExpressionError(fmt.Sprintf("operator %s in WHERE clause", bin.Op))
+	}
+
+	ident, lit := bin.Left, bin.Right
+	if !isColumnRef(ident) {
+		ident, lit = lit, ident
+		op = op.Flip()
+	}
+	if !isColumnRef(ident) {
+		return nil, NewUnsupportedExpressionError("comparison needs a column on one side")
+	}
+	col, path, err := e.operandColumn(t, ident)
+	if err != nil {
+		return nil, err
+	}
+	val, err := e.operandValue(t, col, path, lit)
+	if err != nil {
+		return nil, err
+	}
+	return withPath(path, predicate.NewComparison(col, op, val)), nil
+}
+
+func isColumnRef(expr Expr) bool {
+	switch expr.(type) {
+	case *Ident, *PathExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// operandColumn returns the column name if expr is a reference to an existing column
+// If expr is a JSON path it returns the path as it's written and the path itself
+func (e *Executor) operandColumn(t *table.Table, expr Expr) (string, *PathExpr, error) {
+	switch ex := expr.(type) {
+	case *Ident:
+		if err := e.ensureColumn(t, ex.Name); err != nil {
+			return "", nil, err
+		}
+		return ex.Name, nil, nil
+	case *PathExpr:
+		if err := e.ensureColumn(t, ex.Column); err != nil {
+			return "", nil, err
+		}
+		if t.Columns()[ex.Column].DataType() != types.TypeJSON {
+			return "", nil, NewNotJSONError(ex.Column)
+		}
+		return ex.String(), ex, nil
+	default:
+		return "", nil, NewUnsupportedExpressionError(fmt.Sprintf("%T used as a column", expr))
+	}
+}
+
+// operandValue evaluates expr and converts the result to the type of the column or the JSON path returned by
+// operandColumn. A value compared with -> is JSON and a value compared with ->> is a string
+func (e *Executor) operandValue(t *table.Table, col string, path *PathExpr, expr Expr) (interface{}, error) {
+	if path == nil {
+		val, err := e.columnValue(t, col, expr)
+		if err == nil {
+			return val, nil
+		}
+		// A number that cannot be stored in a numeric column, such as 10.5 or 300 for a BYTE, is still compared by its value
+		if lit, ok := expr.(*Literal); ok && isNumericColumn(t, col) {
+			switch lit.Value.(type) {
+			case int64, float64:
+				return lit.Value, nil
+			}
+		}
+		return nil, err
+	}
+	lit, ok := expr.(*Literal)
+	if !ok {
+		return nil, NewUnsupportedExpressionError(fmt.Sprintf("%T compared with %s", expr, col))
+	}
+	if path.AsText {
+		return coerce(col, types.TypeString, lit.Value, e.loc)
+	}
+	return coerce(col, types.TypeJSON, lit.Value, e.loc)
+}
+
+func isNumericColumn(t *table.Table, col string) bool {
+	switch t.Columns()[col].DataType() {
+	case types.TypeInt64, types.TypeInt32, types.TypeByte, types.TypeFloat64, types.TypeFloat32:
+		return true
+	default:
+		return false
+	}
+}
+
+// withPath returns p evaluated against the value at path. p is returned as it is if path is nil
+func withPath(path *PathExpr, p predicate.Predicate) predicate.Predicate {
+	if path == nil {
+		return p
+	}
+	return predicate.NewJSONPath(path.Column, path.String(), path.Path, path.AsText, p)
+}
+
+func negate(p predicate.Predicate, not bool) predicate.Predicate {
+	if not {
+		return predicate.NewNot(p)
+	}
+	return p
+}
+
+// columnValue evaluates expr and converts the result to the Go type of the column
+func (e *Executor) columnValue(t *table.Table, colName string, expr Expr) (interface{}, error) {
+	col, ok := t.Columns()[colName]
+	if !ok {
+		return nil, column.NewUnknownColumnError(t.Name, colName)
+	}
+	if call, ok := expr.(*CallExpr); ok {
+		v, err := e.call(call)
+		if err != nil {
+			return nil, err
+		}
+		return coerce(colName, col.DataType(), v, e.loc)
+	}
+	if typed, ok := expr.(*TypedLiteral); ok {
+		// DATE '...' can only be stored in a DATE column and so on
+		if typed.Type != col.DataType() {
+			return nil, NewTypeMismatchError(colName, typed.Value)
+		}
+		return coerce(colName, col.DataType(), typed.Value, e.loc)
+	}
+	lit, ok := expr.(*Literal)
+	if !ok {
+		return nil, NewUnsupportedExpressionError(fmt.Sprintf("%T used as a value for column %s", expr, colName))
+	}
+	val := lit.Value
+	// 0.1 is not exact as a float64, so a DECIMAL column gets the number as it was written
+	if col.DataType() == types.TypeDecimal && lit.Text != "" {
+		val = lit.Text
+	}
+	return coerce(colName, col.DataType(), val, e.loc)
+}
+
+// call evaluates a function call. NEXTVAL('seq') and NOW() are supported
+func (e *Executor) call(call *CallExpr) (interface{}, error) {
+	switch call.Name {
+	case "NEXTVAL":
+		return e.nextval(call)
+	case "NOW":
+		if len(call.Args) != 0 {
+			return nil, NewUnsupportedExpressionError("NOW expects no arguments")
+		}
+		return time.Now(), nil
+	default:
+		return nil, NewUnsupportedExpressionError(fmt.Sprintf("function %s", call.Name))
+	}
+}
+
+func (e *Executor) nextval(call *CallExpr) (interface{}, error) {
+	if len(call.Args) != 1 {
+		return nil, NewUnsupportedExpressionError("NEXTVAL expects one argument")
+	}
+	var name string
+	if lit, ok := call.Args[0].(*Literal); ok {
+		name, _ = lit.Value.(string)
+	}
+	if name == "" {
+		return nil, NewUnsupportedExpressionError("NEXTVAL expects the name of a sequence")
+	}
+	seq, err := e.db.Sequence(name)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.call: %w", err)
+	}
+	v, err := seq.Next()
+	if err != nil {
+		return nil, fmt.Errorf("Executor.call: %w", err)
+	}
+	return v, nil
+}
+
+func (e *Executor) setTimeZone(stmt *SetTimeZoneStmt) (*Result, error) {
+	loc, err := time.LoadLocation(stmt.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Executor.setTimeZone: %w", err)
+	}
+	e.loc = loc
+	return &Result{}, nil
+}
+
+// coerce converts a literal into the Go type used by the storage layer for dataType
+// Integer literals are always int64 after parsing so they need to be narrowed for int32 and byte columns
+// Float columns also accept integers and the strings 'NaN', 'Infinity' and '-Infinity'
+// Date, timestamp and interval columns accept strings. A timestamp without an offset and the date of a time.Time are in loc
+// Decimal columns accept integers and numbers written as strings. The table checks if they fit into the column
+// Blob columns accept X'...' literals and the bytes of strings
+// JSON columns accept documents written as strings. A number or a boolean becomes a document that only contains it
+func coerce(colName string, dataType byte, val interface{}, loc *time.Location) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch dataType {
+	case types.TypeInt64:
+		if v, ok := val.(int64); ok {
+			return v, nil
+		}
+	case types.TypeInt32:
+		if v, ok := val.(int64); ok && v >= math.MinInt32 && v <= math.MaxInt32 {
+			return int32(v), nil
+		}
+	case types.TypeByte:
+		if v, ok := val.(int64); ok && v >= 0 && v <= math.MaxUint8 {
+			return byte(v), nil
+		}
+	case types.TypeFloat64:
+		if v, ok := floatValue(val); ok {
+			return v, nil
+		}
+	case types.TypeFloat32:
+		// Values that are too large for a float32 are rejected instead of becoming infinite
+		if v, ok := floatValue(val); ok && (math.Abs(v) <= math.MaxFloat32 || math.IsInf(v, 0) || math.IsNaN(v)) {
+			return float32(v), nil
+		}
+	case types.TypeDate:
+		switch v := val.(type) {
+		case time.Time:
+			return types.DateOf(v.In(loc)), nil
+		case string:
+			if d, err := types.ParseDate(v); err == nil {
+				return d, nil
+			}
+		}
+	case types.TypeTimestamp:
+		switch v := val.(type) {
+		case time.Time:
+			return types.Timestamp(v), nil
+		case string:
+			if tm, err := types.ParseTimestamp(v, loc); err == nil {
+				return tm, nil
+			}
+		}
+	case types.TypeInterval:
+		if v, ok := val.(string); ok {
+			if d, err := types.ParseInterval(v); err == nil {
+				return d, nil
+			}
+		}
+	case types.TypeDecimal:
+		switch v := val.(type) {
+		case int64:
+			return types.DecimalFromInt64(v), nil
+		case string:
+			if d, err := types.ParseDecimal(v); err == nil {
+				return d, nil
+			}
+		}
+	case types.TypeBlob:
+		switch v := val.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	case types.TypeJSON:
+		switch v := val.(type) {
+		case string:
+			j, err := types.ParseJSON(v)
+			if err != nil {
+				return nil, table.NewInvalidJSONError(colName, err)
+			}
+			return j, nil
+		case int64, float64, bool:
+			if j, err := types.NewJSON(v); err == nil {
+				return j, nil
+			}
+		}
+	case types.TypeBool:
+		if v, ok := val.(bool); ok {
+			return v, nil
+		}
+	case types.TypeString:
+		if v, ok := val.(string); ok {
+			return v, nil
+		}
+	}
+	return nil, NewTypeMismatchError(colName, val)
+}
+
+// floatValue converts a literal that can be stored in a float column to float64
+func floatValue(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case string:
+		switch {
+		case strings.EqualFold(v, "NaN"):
+			return math.NaN(), true
+		case strings.EqualFold(v, "Infinity"):
+			return math.Inf(1), true
+		case strings.EqualFold(v, "-Infinity"):
+			return math.Inf(-1), true
+		}
+	}
+	return 0, false
+}
+
+func sortRows(rows []map[string]interface{}, orderBy []*OrderByItem) error {
+	if len(orderBy) == 0 {
+		return nil
+	}
+	var sortErr error
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, o := range orderBy {
+			cmp, err := compareNullable(rows[i][o.Column], rows[j][o.Column])
+			if err != nil {
+				sortErr = err
+				return false
+			}
+			if cmp == 0 {
+				continue
+			}
+			if o.Desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+	return sortErr
+}
+
+// compareNullable works like types.Compare but NULL values are smaller than anything else
+func compareNullable(a, b interface{}) (int, error) {
+	if a == nil && b == nil {
+		return 0, nil
+	}
+	if a == nil {
+		return -1, nil
+	}
+	if b == nil {
+		return 1, nil
+	}
+	return types.Compare(a, b)
+}
+
+func limitRows(rows []map[string]interface{}, limit, offset int64) []map[string]interface{} {
+	if offset >= int64(len(rows)) {
+		return rows[:0]
+	}
+	rows = rows[offset:]
+	if limit >= 0 && limit < int64(len(rows)) {
+		rows = rows[:limit]
+	}
+	return rows
+}
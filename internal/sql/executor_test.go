@@ -0,0 +1,969 @@
+package sql
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omesh-barhate/ByteForge/internal"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/column"
+	"github.com/omesh-barhate/ByteForge/internal/table/index"
+	"github.com/omesh-barhate/ByteForge/internal/table/wal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutor_CRUD(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT, username STRING, age BYTE, job STRING, is_active BOOL)")
+
+	res := mustExec(t, exec, `INSERT INTO users VALUES
+		(1, 'user1', 31, 'software engineer', TRUE),
+		(2, 'user2', 27, 'software engineer', FALSE),
+		(3, 'user3', 28, 'designer', TRUE)`)
+	assert.Equal(t, 3, res[0].RowsAffected)
+
+	res = mustExec(t, exec, "SELECT username, age FROM users WHERE job = 'software engineer' ORDER BY age")
+	assert.Equal(t, []string{"username", "age"}, res[0].Columns)
+	assert.Equal(t, []map[string]interface{}{
+		{"username": "user2", "age": byte(27)},
+		{"username": "user1", "age": byte(31)},
+	}, res[0].Rows)
+
+	res = mustExec(t, exec, "SELECT * FROM users WHERE id = 3")
+	assert.Equal(t, "index (btree)", res[0].AccessType)
+	assert.Len(t, res[0].Rows, 1)
+	assert.Equal(t, "designer", res[0].Rows[0]["job"])
+
+	res = mustExec(t, exec, "SELECT id FROM users WHERE id = 100")
+	assert.Len(t, res[0].Rows, 0)
+
+	res = mustExec(t, exec, "UPDATE users SET job = 'developer' WHERE job = 'software engineer'")
+	assert.Equal(t, 2, res[0].RowsAffected)
+
+	res = mustExec(t, exec, "DELETE FROM users WHERE id = 3")
+	assert.Equal(t, 1, res[0].RowsAffected)
+
+	res = mustExec(t, exec, "SELECT id, job FROM users ORDER BY id DESC LIMIT 1")
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(2), "job": "developer"},
+	}, res[0].Rows)
+
+	mustExec(t, exec, "DROP TABLE users")
+	_, err := exec.Exec("SELECT * FROM users")
+	var errNotExist *internal.TableDoesNotExistError
+	assert.ErrorAs(t, err, &errNotExist)
+}
+
+func TestExecutor_EmptyTable(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT, username STRING)")
+	res := mustExec(t, exec, "SELECT * FROM users; DELETE FROM users WHERE id = 1")
+	assert.Len(t, res[0].Rows, 0)
+	assert.Equal(t, 0, res[1].RowsAffected)
+}
+
+func TestExecutor_Errors(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT, age BYTE)")
+
+	_, err := exec.Exec("INSERT INTO users VALUES (1, 300)")
+	var errTypeMismatch *TypeMismatchError
+	assert.ErrorAs(t, err, &errTypeMismatch)
+
+	_, err = exec.Exec("INSERT INTO users VALUES (1)")
+	assert.NotNil(t, err)
+
+	_, err = exec.Exec("SELECT nope FROM users")
+	assert.NotNil(t, err)
+
+	_, err = exec.Exec("SELECT * FROM users WHERE id = age")
+	var errUnsupported *UnsupportedExpressionError
+	assert.ErrorAs(t, err, &errUnsupported)
+
+	_, err = exec.Exec("SELECT * FROM users WHERE age LIKE 'a%'")
+	assert.ErrorAs(t, err, &errTypeMismatch)
+}
+
+func TestExecutor_Predicates(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT, username STRING, age BYTE NULL)")
+	mustExec(t, exec, `INSERT INTO users VALUES
+		(1, 'alice', 31), (2, 'bob', 27), (3, 'carol', NULL), (4, 'dave', 45), (5, 'alfred', 19)`)
+
+	ids := func(res *Result) []int64 {
+		out := make([]int64, 0, len(res.Rows))
+		for _, r := range res.Rows {
+			out = append(out, r["id"].(int64))
+		}
+		return out
+	}
+
+	tests := []struct {
+		where      string
+		want       []int64
+		accessType string
+	}{
+		{"id > 2", []int64{3, 4, 5}, "range (btree)"},
+		{"id BETWEEN 2 AND 4", []int64{2, 3, 4}, "range (btree)"},
+		{"3 >= id AND id != 2", []int64{1, 3}, "range (btree)"},
+		{"id > 4 AND id < 3", []int64{}, "range (btree)"},
+		{"id IN (1, 4, 100)", []int64{1, 4}, "index (btree)"},
+		{"age < 30", []int64{2, 5}, "ALL"},
+		{"age IS NULL", []int64{3}, "ALL"},
+		{"age IS NOT NULL AND age NOT BETWEEN 20 AND 40", []int64{4, 5}, "ALL"},
+		{"username LIKE 'al%'", []int64{1, 5}, "ALL"},
+		{"username NOT LIKE '_o%'", []int64{1, 3, 4, 5}, "ALL"},
+		{"age > 40 OR username = 'bob'", []int64{2, 4}, "ALL"},
+		{"NOT (id IN (1, 2) OR age IS NULL)", []int64{4, 5}, "ALL"},
+		{"NOT (age > 30)", []int64{2, 5}, "ALL"},
+		{"age NOT IN (27, 31)", []int64{4, 5}, "ALL"},
+		{"age NOT BETWEEN 20 AND 40", []int64{4, 5}, "ALL"},
+		{"NOT (age > 30 AND id = 3)", []int64{1, 2, 4, 5}, "ALL"},
+		{"NOT (age > 30 OR id = 3)", []int64{2, 5}, "ALL"},
+	}
+	for _, tt := range tests {
+		res := mustExec(t, exec, "SELECT id FROM users WHERE "+tt.where+" ORDER BY id")
+		assert.Equal(t, tt.want, ids(res[0]), tt.where)
+		assert.Equal(t, tt.accessType, res[0].AccessType, tt.where)
+	}
+
+	res := mustExec(t, exec, "UPDATE users SET age = 50 WHERE age IS NULL OR age < 20")
+	assert.Equal(t, 2, res[0].RowsAffected)
+
+	res = mustExec(t, exec, "DELETE FROM users WHERE id >= 4")
+	assert.Equal(t, 2, res[0].RowsAffected)
+
+	res = mustExec(t, exec, "SELECT id FROM users WHERE age = 50")
+	assert.Equal(t, []int64{3}, ids(res[0]))
+
+	mustExec(t, exec, "INSERT INTO users VALUES (6, 'erin', NULL)")
+	res = mustExec(t, exec, "SELECT * FROM users WHERE id = 6")
+	assert.Equal(t, []map[string]interface{}{{"id": int64(6), "username": "erin", "age": nil}}, res[0].Rows)
+}
+
+func TestExecutor_CreateIndex(t *testing.T) {
+	db, err := internal.CreateDatabase("sql_test")
+	assert.Nil(t, err)
+	defer removeDB()
+	exec := NewExecutor(db)
+
+	mustExec(t, exec, "CREATE TABLE users (id INT, username STRING, age BYTE NULL)")
+	mustExec(t, exec, `INSERT INTO users VALUES
+		(1, 'alice', 31), (2, 'bob', 27), (3, 'carol', NULL), (4, 'dave', 45)`)
+	mustExec(t, exec, "CREATE INDEX users_age ON users (age); CREATE INDEX ON users (username)")
+
+	_, err = exec.Exec("CREATE INDEX ON users (age)")
+	var errExists *table.IndexAlreadyExistsError
+	assert.ErrorAs(t, err, &errExists)
+	_, err = exec.Exec("CREATE INDEX ON users (nope)")
+	assert.NotNil(t, err)
+
+	mustExec(t, exec, "INSERT INTO users VALUES (5, 'erin', 27)")
+	mustExec(t, exec, "UPDATE users SET age = 28 WHERE username = 'bob'")
+	mustExec(t, exec, "DELETE FROM users WHERE id = 4")
+
+	// A key that doesn't fit into an index is rejected before the record is written
+	long := strings.Repeat("x", index.MaxKeyLen)
+	var errTooLong *index.KeyTooLongError
+	for _, query := range []string{
+		fmt.Sprintf("INSERT INTO users VALUES (6, '%s', 30)", long),
+		fmt.Sprintf("UPDATE users SET username = '%s' WHERE id = 1", long),
+	} {
+		_, err = exec.Exec(query)
+		assert.ErrorAs(t, err, &errTooLong, query)
+	}
+	res := mustExec(t, exec, "SELECT id, username FROM users WHERE id >= 1 ORDER BY id")
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "username": "alice"},
+		{"id": int64(2), "username": "bob"},
+		{"id": int64(3), "username": "carol"},
+		{"id": int64(5), "username": "erin"},
+	}, res[0].Rows)
+
+	// The indexes need to be maintained after every write and survive reopening the database
+	assert.Nil(t, db.Close())
+	db, err = internal.NewDatabase("sql_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	exec = NewExecutor(db)
+
+	tests := []struct {
+		query      string
+		want       []map[string]interface{}
+		accessType string
+	}{
+		{"SELECT id FROM users WHERE age = 27", []map[string]interface{}{{"id": int64(5)}}, "index (btree)"},
+		{"SELECT id FROM users WHERE age >= 28 ORDER BY id", []map[string]interface{}{{"id": int64(1)}, {"id": int64(2)}}, "range (btree)"},
+		{"SELECT id FROM users WHERE age > 40", []map[string]interface{}{}, "range (btree)"},
+		{"SELECT id FROM users WHERE username IN ('carol', 'zed')", []map[string]interface{}{{"id": int64(3)}}, "index (btree)"},
+		{"SELECT id FROM users WHERE username >= 'd' AND age IS NULL", []map[string]interface{}{}, "range (btree)"},
+	}
+	for _, tt := range tests {
+		res := mustExec(t, exec, tt.query)
+		assert.Equal(t, tt.want, res[0].Rows, tt.query)
+		assert.Equal(t, tt.accessType, res[0].AccessType, tt.query)
+	}
+	assert.Equal(t, []table.IndexInfo{
+		{Type: table.AccessTypeBtreeIdx, Column: "id", Primary: true},
+		{Type: table.AccessTypeBtreeIdx, Column: "username"},
+		{Type: table.AccessTypeBtreeIdx, Column: "age"},
+	}, db.Tables["users"].Indexes())
+}
+
+func TestExecutor_NumericLiterals(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT, age INT32, score BYTE, rating FLOAT32)")
+	mustExec(t, exec, `INSERT INTO users VALUES
+		(1, 9, 100, 1.5), (2, 10, 200, 2.5), (3, 11, 250, 3.5), (4, 12, 255, 4.5)`)
+	mustExec(t, exec, "CREATE INDEX ON users (age)")
+
+	ids := func(res *Result) []int64 {
+		out := make([]int64, 0, len(res.Rows))
+		for _, r := range res.Rows {
+			out = append(out, r["id"].(int64))
+		}
+		return out
+	}
+
+	// Float bounds of integer columns are rounded towards the inside of the range, and floats that cannot
+	// equal an integer are checked on every record
+	tests := []struct {
+		where      string
+		want       []int64
+		accessType string
+	}{
+		{"age > 10.5", []int64{3, 4}, "range (btree)"},
+		{"age >= 10.5", []int64{3, 4}, "range (btree)"},
+		{"age < 10.5", []int64{1, 2}, "range (btree)"},
+		{"age <= 10.0", []int64{1, 2}, "range (btree)"},
+		{"age = 10.0", []int64{2}, "index (btree)"},
+		{"age = 10.5", []int64{}, "ALL"},
+		{"age IN (9.0, 11)", []int64{1, 3}, "index (btree)"},
+		{"age BETWEEN 9.5 AND 11.5", []int64{2, 3}, "range (btree)"},
+		{"age NOT BETWEEN 9.5 AND 11.5", []int64{1, 4}, "ALL"},
+		{"id > 2.5", []int64{3, 4}, "range (btree)"},
+		{"id = 1e30", []int64{}, "ALL"},
+		{"score > 254.5", []int64{4}, "ALL"},
+		{"score < 300", []int64{1, 2, 3, 4}, "ALL"},
+		{"score > -1", []int64{1, 2, 3, 4}, "ALL"},
+		{"rating > 2", []int64{2, 3, 4}, "ALL"},
+		{"rating < 1e39", []int64{1, 2, 3, 4}, "ALL"},
+	}
+	for _, tt := range tests {
+		res := mustExec(t, exec, "SELECT id FROM users WHERE "+tt.where+" ORDER BY id")
+		assert.Equal(t, tt.want, ids(res[0]), tt.where)
+		assert.Equal(t, tt.accessType, res[0].AccessType, tt.where)
+	}
+
+	// Values are still converted to the type of the column when they are stored
+	_, err := exec.Exec("UPDATE users SET age = 10.5 WHERE id = 1")
+	var errTypeMismatch *TypeMismatchError
+	assert.ErrorAs(t, err, &errTypeMismatch)
+	res := mustExec(t, exec, "UPDATE users SET score = 0 WHERE age > 10.5")
+	assert.Equal(t, 2, res[0].RowsAffected)
+}
+
+func TestExecutor_Floats(t *testing.T) {
+	db, err := internal.CreateDatabase("sql_test")
+	assert.Nil(t, err)
+	defer removeDB()
+	exec := NewExecutor(db)
+
+	mustExec(t, exec, "CREATE TABLE products (id INT, price FLOAT, weight REAL NULL)")
+	mustExec(t, exec, `INSERT INTO products VALUES
+		(1, 9.99, 1.5), (2, 10, 0.25), (3, 'NaN', NULL), (4, -2.5e1, 'Infinity'), (5, 0.1, 0.1)`)
+	mustExec(t, exec, "CREATE INDEX ON products (price)")
+
+	var errTypeMismatch *TypeMismatchError
+	for _, query := range []string{
+		"INSERT INTO products VALUES (6, 'abc', NULL)",
+		"INSERT INTO products VALUES (6, 1.5, 1e39)",
+	} {
+		_, err = exec.Exec(query)
+		assert.ErrorAs(t, err, &errTypeMismatch, query)
+	}
+	rows := mustExec(t, exec, "SELECT id FROM products WHERE id = 1.5")
+	assert.Len(t, rows[0].Rows, 0)
+
+	// The values and the index need to survive reopening the database
+	assert.Nil(t, db.Close())
+	db, err = internal.NewDatabase("sql_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	exec = NewExecutor(db)
+
+	res := mustExec(t, exec, "SELECT * FROM products WHERE id = 1")
+	assert.Equal(t, []map[string]interface{}{{"id": int64(1), "price": 9.99, "weight": float32(1.5)}}, res[0].Rows)
+
+	tests := []struct {
+		query      string
+		want       []int64
+		accessType string
+	}{
+		// NaN is greater than every other number
+		{"SELECT id FROM products ORDER BY price", []int64{4, 5, 1, 2, 3}, ""},
+		{"SELECT id FROM products WHERE price > 9.99 ORDER BY id", []int64{2, 3}, "range (btree)"},
+		{"SELECT id FROM products WHERE price = 'NaN'", []int64{3}, "index (btree)"},
+		{"SELECT id FROM products WHERE price BETWEEN -100 AND 0.1 ORDER BY id", []int64{4, 5}, "range (btree)"},
+		{"SELECT id FROM products WHERE price IN (10, 0.1) ORDER BY id", []int64{2, 5}, "index (btree)"},
+		// 0.1 is rounded to a float32 like the stored value
+		{"SELECT id FROM products WHERE weight = 0.1", []int64{5}, ""},
+		{"SELECT id FROM products WHERE weight > 1e30", []int64{4}, ""},
+	}
+	for _, tt := range tests {
+		res = mustExec(t, exec, tt.query)
+		ids := make([]int64, 0, len(res[0].Rows))
+		for _, row := range res[0].Rows {
+			ids = append(ids, row["id"].(int64))
+		}
+		assert.Equal(t, tt.want, ids, tt.query)
+		if tt.accessType != "" {
+			assert.Equal(t, tt.accessType, res[0].AccessType, tt.query)
+		}
+	}
+
+	// -0 equals 0 and NaN equals NaN, so they are duplicate keys
+	mustExec(t, exec, "CREATE TABLE points (x FLOAT64 PRIMARY KEY, label STRING)")
+	mustExec(t, exec, "INSERT INTO points VALUES (0.0, 'zero'), ('NaN', 'nan'), (-1.5, 'a')")
+	var errDuplicate *table.DuplicateKeyError
+	for _, query := range []string{
+		"INSERT INTO points VALUES (-0.0, 'negative zero')",
+		"INSERT INTO points VALUES ('nan', 'nan')",
+	} {
+		_, err = exec.Exec(query)
+		assert.ErrorAs(t, err, &errDuplicate, query)
+	}
+	res = mustExec(t, exec, "SELECT label FROM points WHERE x < 0")
+	assert.Equal(t, []map[string]interface{}{{"label": "a"}}, res[0].Rows)
+	assert.Equal(t, "CREATE TABLE points (\n  x FLOAT64 NOT NULL PRIMARY KEY,\n  label STRING NOT NULL\n);",
+		FormatCreateTable(db.Tables["points"]))
+}
+
+func TestExecutor_Times(t *testing.T) {
+	db, err := internal.CreateDatabase("sql_test")
+	assert.Nil(t, err)
+	defer removeDB()
+	exec := NewExecutor(db)
+
+	mustExec(t, exec, "CREATE TABLE events (id INT, day DATE, at TIMESTAMP, ttl INTERVAL NULL)")
+	mustExec(t, exec, `INSERT INTO events VALUES
+		(1, DATE '2024-01-31', TIMESTAMP '2024-01-31 10:00:00', INTERVAL '1 day'),
+		(2, '2024-02-01', '2024-01-31T23:30:00-02:00', '90m'),
+		(3, '2023-12-31', '2024-01-31 12:00:00.123456789+01:00', NULL)`)
+	mustExec(t, exec, "CREATE INDEX ON events (at)")
+
+	// A time.Time is stored as the date in its location or as a timestamp with microseconds
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.Nil(t, err)
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Insert("events", map[string]interface{}{
+		"id":  int64(4),
+		"day": time.Date(2024, 3, 1, 0, 30, 0, 0, berlin),
+		"at":  time.Date(2024, 3, 1, 0, 30, 0, 1500, berlin),
+		"ttl": 2 * time.Hour,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	var errTypeMismatch *TypeMismatchError
+	for _, query := range []string{
+		"INSERT INTO events VALUES (5, '2024-02-30', '2024-01-01', NULL)",
+		"INSERT INTO events VALUES (5, '2024-02-01', 'yesterday', NULL)",
+		"INSERT INTO events VALUES (5, '2024-02-01', '2024-01-01', '1 month')",
+		"SELECT id FROM events WHERE day = TIMESTAMP '2024-01-31 00:00:00'",
+	} {
+		_, err = exec.Exec(query)
+		assert.ErrorAs(t, err, &errTypeMismatch, query)
+	}
+
+	assert.Nil(t, db.Close())
+	db, err = internal.NewDatabase("sql_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	exec = NewExecutor(db)
+
+	res := mustExec(t, exec, "SELECT * FROM events WHERE id = 4")
+	assert.Equal(t, []map[string]interface{}{{
+		"id":  int64(4),
+		"day": types.NewDate(2024, time.March, 1),
+		"at":  time.Date(2024, 2, 29, 23, 30, 0, 1000, time.UTC),
+		"ttl": 2 * time.Hour,
+	}}, res[0].Rows)
+
+	tests := []struct {
+		query      string
+		want       []int64
+		accessType string
+	}{
+		{"SELECT id FROM events ORDER BY day", []int64{3, 1, 2, 4}, ""},
+		{"SELECT id FROM events ORDER BY at", []int64{1, 3, 2, 4}, ""},
+		{"SELECT id FROM events WHERE day >= '2024-01-31' AND day < DATE '2024-03-01' ORDER BY id", []int64{1, 2}, ""},
+		{"SELECT id FROM events WHERE at = '2024-02-01 01:30:00Z'", []int64{2}, "index (btree)"},
+		{"SELECT id FROM events WHERE at BETWEEN '2024-01-31 11:00:00Z' AND '2024-02-01 02:00:00' ORDER BY id", []int64{2, 3}, "range (btree)"},
+		{"SELECT id FROM events WHERE at < NOW() ORDER BY id", []int64{1, 2, 3, 4}, "range (btree)"},
+		{"SELECT id FROM events WHERE ttl > '1h' ORDER BY id", []int64{1, 2, 4}, ""},
+	}
+	for _, tt := range tests {
+		res = mustExec(t, exec, tt.query)
+		ids := make([]int64, 0, len(res[0].Rows))
+		for _, row := range res[0].Rows {
+			ids = append(ids, row["id"].(int64))
+		}
+		assert.Equal(t, tt.want, ids, tt.query)
+		if tt.accessType != "" {
+			assert.Equal(t, tt.accessType, res[0].AccessType, tt.query)
+		}
+	}
+
+	// The time zone applies to timestamps without an offset and to the timestamps returned by SELECT
+	mustExec(t, exec, "SET TIME ZONE 'Europe/Berlin'")
+	res = mustExec(t, exec, "SELECT id, at FROM events WHERE at = '2024-01-31 11:00:00'")
+	assert.Equal(t, int64(1), res[0].Rows[0]["id"])
+	assert.Equal(t, "2024-01-31 11:00:00 +0100 CET", res[0].Rows[0]["at"].(time.Time).String())
+	_, err = exec.Exec("SET TIME ZONE 'Nowhere/Special'")
+	assert.NotNil(t, err)
+
+	mustExec(t, exec, "CREATE TABLE days (day DATE PRIMARY KEY, note STRING)")
+	mustExec(t, exec, "INSERT INTO days VALUES ('2024-01-01', 'a'), ('2024-01-02', 'b')")
+	_, err = exec.Exec("INSERT INTO days VALUES (DATE '2024-01-02', 'c')")
+	var errDuplicate *table.DuplicateKeyError
+	assert.ErrorAs(t, err, &errDuplicate)
+	res = mustExec(t, exec, "SELECT note FROM days WHERE day > '2024-01-01'")
+	assert.Equal(t, "range (btree)", res[0].AccessType)
+	assert.Equal(t, []map[string]interface{}{{"note": "b"}}, res[0].Rows)
+	assert.Equal(t, "CREATE TABLE days (\n  day DATE NOT NULL PRIMARY KEY,\n  note STRING NOT NULL\n);",
+		FormatCreateTable(db.Tables["days"]))
+}
+
+func TestExecutor_Decimals(t *testing.T) {
+	db, err := internal.CreateDatabase("sql_test")
+	assert.Nil(t, err)
+	defer removeDB()
+	exec := NewExecutor(db)
+
+	mustExec(t, exec, "CREATE TABLE accounts (id INT, balance DECIMAL(10, 2), rate NUMERIC(5, 4) NULL, units DECIMAL NULL)")
+	mustExec(t, exec, `INSERT INTO accounts VALUES
+		(1, 0.1, 0.0125, 12345678901234567890), (2, '19.99', NULL, NULL), (3, -5, 1, 0), (4, 0.30, 0.5, NULL)`)
+	mustExec(t, exec, "CREATE INDEX ON accounts (balance)")
+
+	// Go values are converted at the table boundary without rounding
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Insert("accounts", map[string]interface{}{
+		"id":      int64(5),
+		"balance": big.NewRat(1, 8),
+		"rate":    nil,
+		"units":   nil,
+	})
+	assert.NotNil(t, err)
+	_, err = tx.Insert("accounts", map[string]interface{}{
+		"id":      int64(5),
+		"balance": big.NewRat(1, 4),
+		"rate":    "0.0001",
+		"units":   big.NewInt(7),
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	var errTypeMismatch *TypeMismatchError
+	var errInvalidDecimal *table.InvalidDecimalError
+	for _, query := range []string{
+		"INSERT INTO accounts VALUES (6, 'abc', NULL, NULL)",
+		"INSERT INTO accounts VALUES (6, 'NaN', NULL, NULL)",
+	} {
+		_, err = exec.Exec(query)
+		assert.ErrorAs(t, err, &errTypeMismatch, query)
+	}
+	for _, tt := range []struct {
+		query string
+		err   error
+	}{
+		// Values are never rounded to fit into a column
+		{"INSERT INTO accounts VALUES (6, 1.005, NULL, NULL)", types.ErrInexactDecimal},
+		{"INSERT INTO accounts VALUES (6, 1, NULL, 0.5)", types.ErrInexactDecimal},
+		{"INSERT INTO accounts VALUES (6, 123456789, NULL, NULL)", types.ErrDecimalOutOfRange},
+		{"UPDATE accounts SET rate = 10 WHERE id = 1", types.ErrDecimalOutOfRange},
+	} {
+		_, err = exec.Exec(tt.query)
+		assert.ErrorAs(t, err, &errInvalidDecimal, tt.query)
+		assert.ErrorIs(t, err, tt.err, tt.query)
+	}
+	_, err = exec.Exec("CREATE TABLE wrong (id INT, amount DECIMAL(39, 2))")
+	var errPrecision *column.DecimalPrecisionError
+	assert.ErrorAs(t, err, &errPrecision)
+	_, err = exec.Exec("CREATE TABLE wrong (id INT, amount DECIMAL(2, 3))")
+	assert.ErrorAs(t, err, &errPrecision)
+
+	assert.Nil(t, db.Close())
+	db, err = internal.NewDatabase("sql_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	exec = NewExecutor(db)
+
+	res := mustExec(t, exec, "SELECT * FROM accounts WHERE id = 1")
+	row := res[0].Rows[0]
+	assert.Equal(t, "0.10", row["balance"].(types.Decimal).String())
+	assert.Equal(t, "0.0125", row["rate"].(types.Decimal).String())
+	assert.Equal(t, "12345678901234567890", row["units"].(types.Decimal).String())
+
+	tests := []struct {
+		query      string
+		want       []int64
+		accessType string
+	}{
+		{"SELECT id FROM accounts ORDER BY balance", []int64{3, 1, 5, 4, 2}, ""},
+		// 0.1 + 0.2 is exactly 0.3
+		{"SELECT id FROM accounts WHERE balance = 0.3", []int64{4}, "index (btree)"},
+		{"SELECT id FROM accounts WHERE balance = '0.300'", []int64{4}, "index (btree)"},
+		{"SELECT id FROM accounts WHERE balance > 0.1 ORDER BY id", []int64{2, 4, 5}, "range (btree)"},
+		{"SELECT id FROM accounts WHERE balance BETWEEN -5 AND 0.25 ORDER BY id", []int64{1, 3, 5}, "range (btree)"},
+		{"SELECT id FROM accounts WHERE rate = 1", []int64{3}, ""},
+		{"SELECT id FROM accounts WHERE units > 10000000000000000000", []int64{1}, ""},
+	}
+	for _, tt := range tests {
+		res = mustExec(t, exec, tt.query)
+		ids := make([]int64, 0, len(res[0].Rows))
+		for _, row := range res[0].Rows {
+			ids = append(ids, row["id"].(int64))
+		}
+		assert.Equal(t, tt.want, ids, tt.query)
+		if tt.accessType != "" {
+			assert.Equal(t, tt.accessType, res[0].AccessType, tt.query)
+		}
+	}
+
+	mustExec(t, exec, "UPDATE accounts SET balance = 20 WHERE id = 2")
+	res = mustExec(t, exec, "SELECT balance FROM accounts WHERE id = 2")
+	assert.Equal(t, "20.00", res[0].Rows[0]["balance"].(types.Decimal).String())
+	assert.Equal(t, "CREATE TABLE accounts (\n  id INT64 NOT NULL PRIMARY KEY,\n  balance DECIMAL(10, 2) NOT NULL,\n  rate DECIMAL(5, 4) NULL,\n  units DECIMAL(38, 0) NULL\n);\nCREATE INDEX ON accounts (balance);",
+		FormatCreateTable(db.Tables["accounts"]))
+}
+
+func TestExecutor_Blobs(t *testing.T) {
+	db, err := internal.CreateDatabase("sql_test")
+	assert.Nil(t, err)
+	defer removeDB()
+	exec := NewExecutor(db)
+
+	mustExec(t, exec, "CREATE TABLE files (id INT, data BLOB NULL)")
+	mustExec(t, exec, "INSERT INTO files VALUES (1, X'DEADbeef'), (2, 'text'), (3, NULL), (4, x'')")
+
+	res := mustExec(t, exec, "SELECT id, data FROM files ORDER BY id")
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "data": []byte{0xde, 0xad, 0xbe, 0xef}},
+		{"id": int64(2), "data": []byte("text")},
+		{"id": int64(3), "data": nil},
+		{"id": int64(4), "data": []byte{}},
+	}, res[0].Rows)
+	res = mustExec(t, exec, "SELECT id FROM files WHERE data = X'deadbeef' OR data = 'text' ORDER BY id")
+	assert.Equal(t, []map[string]interface{}{{"id": int64(1)}, {"id": int64(2)}}, res[0].Rows)
+
+	_, err = exec.Exec("INSERT INTO files VALUES (5, X'ABC')")
+	var errSyntax *SyntaxError
+	assert.ErrorAs(t, err, &errSyntax)
+	_, err = exec.Exec("INSERT INTO files VALUES (5, 1)")
+	var errTypeMismatch *TypeMismatchError
+	assert.ErrorAs(t, err, &errTypeMismatch)
+	_, err = exec.Exec("CREATE INDEX ON files (data)")
+	var errBlobIndex *column.BlobIndexError
+	assert.ErrorAs(t, err, &errBlobIndex)
+	assert.Equal(t, "CREATE TABLE files (\n  id INT64 NOT NULL PRIMARY KEY,\n  data BLOB NULL\n);", FormatCreateTable(db.Tables["files"]))
+}
+
+func TestExecutor_JSON(t *testing.T) {
+	db, err := internal.CreateDatabase("sql_test")
+	assert.Nil(t, err)
+	defer removeDB()
+	exec := NewExecutor(db)
+
+	mustExec(t, exec, "CREATE TABLE people (id INT, attrs JSON NULL)")
+	mustExec(t, exec, `INSERT INTO people VALUES
+		(1, '{"address": {"city": "Berlin", "zip": 10115}, "tags": ["a", "b"], "age": 31}'),
+		(2, '{"address": {"city": "Paris"}, "tags": [], "age": 27.5}'),
+		(3, '{"address": null}'),
+		(4, NULL)`)
+
+	res := mustExec(t, exec, "SELECT id, attrs->'address'->>'city', attrs->'tags'->-1 FROM people ORDER BY id")
+	assert.Equal(t, []string{"id", "attrs->'address'->>'city'", "attrs->'tags'->-1"}, res[0].Columns)
+	b, err := types.ParseJSON(`"b"`)
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "attrs->'address'->>'city'": "Berlin", "attrs->'tags'->-1": b},
+		{"id": int64(2), "attrs->'address'->>'city'": "Paris", "attrs->'tags'->-1": nil},
+		{"id": int64(3), "attrs->'address'->>'city'": nil, "attrs->'tags'->-1": nil},
+		{"id": int64(4), "attrs->'address'->>'city'": nil, "attrs->'tags'->-1": nil},
+	}, res[0].Rows)
+
+	tests := []struct {
+		where string
+		ids   []int64
+	}{
+		{"attrs->'address'->>'city' = 'Berlin'", []int64{1}},
+		{"attrs->'address'->>'city' LIKE 'P%'", []int64{2}},
+		{"attrs->'address'->>'city' IN ('Paris', 'Rome')", []int64{2}},
+		{"attrs->'address'->>'zip' = '10115'", []int64{1}},
+		{"attrs->'age' > 30", []int64{1}},
+		{"attrs->'age' BETWEEN 20 AND 28", []int64{2}},
+		{"attrs->'tags' = '[\"a\", \"b\"]'", []int64{1}},
+		{"attrs->'address' = 'null'", []int64{3}},
+		{"attrs->>'address' IS NULL", []int64{3, 4}},
+		{"attrs IS NULL", []int64{4}},
+		{"attrs = '{\"address\": null}'", []int64{3}},
+	}
+	for _, tt := range tests {
+		res = mustExec(t, exec, "SELECT id FROM people WHERE "+tt.where+" ORDER BY id")
+		ids := make([]int64, 0)
+		for _, row := range res[0].Rows {
+			ids = append(ids, row["id"].(int64))
+		}
+		assert.Equal(t, tt.ids, ids, tt.where)
+	}
+
+	mustExec(t, exec, `UPDATE people SET attrs = '{"address": {"city": "Rome"}}' WHERE attrs->'address'->>'city' = 'Paris'`)
+	res = mustExec(t, exec, "SELECT attrs FROM people WHERE id = 2")
+	assert.Equal(t, `{"address":{"city":"Rome"}}`, res[0].Rows[0]["attrs"].(types.JSON).String())
+
+	_, err = exec.Exec("INSERT INTO people VALUES (5, '{\"a\": }')")
+	var errInvalidJSON *table.InvalidJSONError
+	assert.ErrorAs(t, err, &errInvalidJSON)
+	_, err = exec.Exec("SELECT id FROM people WHERE id->'a' = 1")
+	var errNotJSON *NotJSONError
+	assert.ErrorAs(t, err, &errNotJSON)
+	_, err = exec.Exec("CREATE INDEX ON people (attrs)")
+	var errJSONIndex *column.JSONIndexError
+	assert.ErrorAs(t, err, &errJSONIndex)
+	assert.Equal(t, "CREATE TABLE people (\n  id INT64 NOT NULL PRIMARY KEY,\n  attrs JSON NULL\n);", FormatCreateTable(db.Tables["people"]))
+}
+
+func TestExecutor_UniqueConstraints(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT UNIQUE, email STRING PRIMARY KEY, nickname STRING NULL UNIQUE)")
+	mustExec(t, exec, "INSERT INTO users VALUES (1, 'a@x.io', 'al'), (2, 'b@x.io', NULL), (3, 'c@x.io', NULL)")
+
+	var errDuplicate *table.DuplicateKeyError
+	for _, query := range []string{
+		"INSERT INTO users VALUES (1, 'd@x.io', NULL)",
+		"INSERT INTO users VALUES (4, 'a@x.io', NULL)",
+		"INSERT INTO users VALUES (4, 'd@x.io', 'al')",
+		"UPDATE users SET nickname = 'al' WHERE id = 2",
+		"UPDATE users SET nickname = 'bo' WHERE id >= 2",
+		"UPDATE users SET id = 1 WHERE email = 'c@x.io'",
+	} {
+		_, err := exec.Exec(query)
+		assert.ErrorAs(t, err, &errDuplicate, query)
+	}
+	_, err := exec.Exec("CREATE TABLE t (id INT, a INT PRIMARY KEY, b INT PRIMARY KEY)")
+	assert.NotNil(t, err)
+	_, err = exec.Exec("CREATE TABLE t (id INT, a INT NULL PRIMARY KEY)")
+	var errNullable *column.NullablePrimaryKeyError
+	assert.ErrorAs(t, err, &errNullable)
+
+	// A failed update doesn't delete the records it matched
+	res := mustExec(t, exec, "SELECT id, nickname FROM users ORDER BY id")
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "nickname": "al"},
+		{"id": int64(2), "nickname": nil},
+		{"id": int64(3), "nickname": nil},
+	}, res[0].Rows)
+
+	// Keeping the same value is not a duplicate
+	mustExec(t, exec, "UPDATE users SET nickname = 'al', email = 'a@x.io' WHERE id = 1")
+	mustExec(t, exec, "UPDATE users SET nickname = 'bo' WHERE id = 2")
+	assert.Equal(t, "CREATE TABLE users (\n  id INT64 NOT NULL UNIQUE,\n  email STRING NOT NULL PRIMARY KEY,\n  nickname STRING NULL UNIQUE\n);",
+		FormatCreateTable(exec.db.Tables["users"]))
+}
+
+func TestExecutor_PrimaryKey(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	// A string primary key and a table without an id column
+	mustExec(t, exec, "CREATE TABLE countries (code STRING PRIMARY KEY, name STRING)")
+	mustExec(t, exec, "INSERT INTO countries VALUES ('hu', 'Hungary'), ('at', 'Austria'), ('de', 'Germany')")
+	res := mustExec(t, exec, "SELECT name FROM countries WHERE code = 'at'")
+	assert.Equal(t, "index (btree)", res[0].AccessType)
+	assert.Equal(t, []map[string]interface{}{{"name": "Austria"}}, res[0].Rows)
+	res = mustExec(t, exec, "SELECT code FROM countries WHERE code > 'b' ORDER BY code")
+	assert.Equal(t, "range (btree)", res[0].AccessType)
+	assert.Equal(t, []map[string]interface{}{{"code": "de"}, {"code": "hu"}}, res[0].Rows)
+
+	var errDuplicate *table.DuplicateKeyError
+	_, err := exec.Exec("INSERT INTO countries VALUES ('hu', 'Hungary')")
+	assert.ErrorAs(t, err, &errDuplicate)
+	mustExec(t, exec, "DELETE FROM countries WHERE code = 'hu'")
+	mustExec(t, exec, "INSERT INTO countries VALUES ('hu', 'Hungary')")
+
+	// A composite primary key is only violated if every column is the same
+	mustExec(t, exec, "CREATE TABLE visits (country STRING, day INT, visitors INT, PRIMARY KEY (country, day))")
+	mustExec(t, exec, "INSERT INTO visits VALUES ('hu', 1, 10), ('hu', 2, 20), ('at', 1, 30)")
+	_, err = exec.Exec("INSERT INTO visits VALUES ('hu', 2, 50)")
+	assert.ErrorAs(t, err, &errDuplicate)
+	_, err = exec.Exec("UPDATE visits SET day = 1 WHERE country = 'hu'")
+	assert.ErrorAs(t, err, &errDuplicate)
+
+	res = mustExec(t, exec, "SELECT day, visitors FROM visits WHERE country = 'hu' ORDER BY day")
+	assert.Equal(t, "index (btree)", res[0].AccessType)
+	assert.Equal(t, []map[string]interface{}{
+		{"day": int64(1), "visitors": int64(10)},
+		{"day": int64(2), "visitors": int64(20)},
+	}, res[0].Rows)
+	mustExec(t, exec, "UPDATE visits SET visitors = 25 WHERE country = 'hu' AND day = 2")
+	res = mustExec(t, exec, "SELECT visitors FROM visits WHERE country = 'hu' AND day = 2")
+	assert.Equal(t, []map[string]interface{}{{"visitors": int64(25)}}, res[0].Rows)
+	assert.Equal(t, "CREATE TABLE visits (\n  country STRING NOT NULL,\n  day INT64 NOT NULL,\n  visitors INT64 NOT NULL,\n  PRIMARY KEY (country, day)\n);",
+		FormatCreateTable(exec.db.Tables["visits"]))
+
+	for _, query := range []string{
+		"CREATE TABLE t (a INT, b INT)",
+		"CREATE TABLE t (a INT PRIMARY KEY, b INT, PRIMARY KEY (b))",
+		"CREATE TABLE t (a INT, PRIMARY KEY (b))",
+		"CREATE TABLE t (a BOOL, PRIMARY KEY (a))",
+	} {
+		_, err = exec.Exec(query)
+		assert.NotNil(t, err, query)
+	}
+}
+
+func TestExecutor_AutoIncrement(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT PRIMARY KEY AUTOINCREMENT, username STRING)")
+	res := mustExec(t, exec, "INSERT INTO users (username) VALUES ('alice'), ('bob')")
+	assert.Equal(t, 2, res[0].RowsAffected)
+	assert.Equal(t, index.NewKey(int64(2)), res[0].LastInsertKey)
+
+	// An explicit value moves the sequence forward
+	mustExec(t, exec, "INSERT INTO users VALUES (10, 'carol')")
+	res = mustExec(t, exec, "INSERT INTO users VALUES (NULL, 'dave')")
+	assert.Equal(t, index.NewKey(int64(11)), res[0].LastInsertKey)
+
+	mustExec(t, exec, "CREATE SEQUENCE invoice_no START WITH 1000")
+	mustExec(t, exec, "CREATE TABLE invoices (no INT PRIMARY KEY, user_id INT)")
+	mustExec(t, exec, "INSERT INTO invoices VALUES (NEXTVAL('invoice_no'), 1), (nextval('invoice_no'), 2)")
+
+	_, err := exec.Exec("DROP SEQUENCE users_id_seq")
+	var errInUse *internal.SequenceInUseError
+	assert.ErrorAs(t, err, &errInUse)
+	_, err = exec.Exec("INSERT INTO invoices VALUES (NEXTVAL('nope'), 3)")
+	var errNotExists *internal.SequenceDoesNotExistError
+	assert.ErrorAs(t, err, &errNotExists)
+	_, err = exec.Exec("CREATE TABLE t (id STRING PRIMARY KEY AUTOINCREMENT)")
+	var errType *column.AutoIncrementTypeError
+	assert.ErrorAs(t, err, &errType)
+
+	// The sequence is not truncated when it passes the largest value of a narrow type
+	mustExec(t, exec, "CREATE TABLE tags (name STRING PRIMARY KEY, n BYTE AUTOINCREMENT)")
+	mustExec(t, exec, "INSERT INTO tags VALUES ('a', 254), ('b', NULL)")
+	_, err = exec.Exec("INSERT INTO tags (name) VALUES ('c')")
+	var errRange *table.AutoIncrementOutOfRangeError
+	assert.ErrorAs(t, err, &errRange)
+	res = mustExec(t, exec, "SELECT n FROM tags ORDER BY n")
+	assert.Equal(t, []map[string]interface{}{{"n": byte(254)}, {"n": byte(255)}}, res[0].Rows)
+	mustExec(t, exec, "DROP TABLE tags")
+
+	// Sequences continue where they stopped after reopening the database
+	assert.Nil(t, exec.db.Close())
+	db, err := internal.NewDatabase("sql_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	exec = NewExecutor(db)
+	mustExec(t, exec, "INSERT INTO users (username) VALUES ('erin')")
+	mustExec(t, exec, "INSERT INTO invoices (no, user_id) VALUES (NEXTVAL('invoice_no'), 5)")
+
+	res = mustExec(t, exec, "SELECT id, username FROM users ORDER BY id")
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "username": "alice"},
+		{"id": int64(2), "username": "bob"},
+		{"id": int64(10), "username": "carol"},
+		{"id": int64(11), "username": "dave"},
+		{"id": int64(12), "username": "erin"},
+	}, res[0].Rows)
+	res = mustExec(t, exec, "SELECT no FROM invoices ORDER BY no")
+	assert.Equal(t, []map[string]interface{}{{"no": int64(1000)}, {"no": int64(1001)}, {"no": int64(1002)}}, res[0].Rows)
+	assert.Equal(t, "CREATE TABLE users (\n  id INT64 NOT NULL PRIMARY KEY AUTOINCREMENT,\n  username STRING NOT NULL\n);",
+		FormatCreateTable(db.Tables["users"]))
+
+	mustExec(t, exec, "DROP TABLE users; DROP SEQUENCE invoice_no")
+	assert.Empty(t, db.Sequences)
+}
+
+func TestExecutor_InsertOmittedColumns(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT PRIMARY KEY AUTOINCREMENT, username STRING, job STRING NULL, age BYTE NULL)")
+	res := mustExec(t, exec, "INSERT INTO users (username, age) VALUES ('alice', 30), ('bob', 25)")
+	assert.Equal(t, 2, res[0].RowsAffected)
+	mustExec(t, exec, "INSERT INTO users (job, username) VALUES ('designer', 'carol')")
+
+	res = mustExec(t, exec, "SELECT * FROM users ORDER BY id")
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "username": "alice", "job": nil, "age": byte(30)},
+		{"id": int64(2), "username": "bob", "job": nil, "age": byte(25)},
+		{"id": int64(3), "username": "carol", "job": "designer", "age": nil},
+	}, res[0].Rows)
+
+	_, err := exec.Exec("INSERT INTO users (job) VALUES ('developer')")
+	var errMissing *MissingValueError
+	assert.ErrorAs(t, err, &errMissing)
+	assert.Contains(t, err.Error(), "column username is missing")
+	res = mustExec(t, exec, "SELECT id FROM users")
+	assert.Len(t, res[0].Rows, 3)
+}
+
+func TestExecutor_Transactions(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE accounts (id INT, balance INT); CREATE TABLE transfers (id INT, amount INT)")
+	mustExec(t, exec, "INSERT INTO accounts VALUES (1, 100), (2, 0)")
+
+	mustExec(t, exec, `BEGIN;
+		UPDATE accounts SET balance = 50 WHERE id = 1;
+		UPDATE accounts SET balance = 50 WHERE id = 2;
+		INSERT INTO transfers VALUES (1, 50)`)
+	res := mustExec(t, exec, "SELECT balance FROM accounts ORDER BY id")
+	assert.Equal(t, []map[string]interface{}{{"balance": int64(50)}, {"balance": int64(50)}}, res[0].Rows)
+	mustExec(t, exec, "ROLLBACK")
+
+	res = mustExec(t, exec, "SELECT balance FROM accounts ORDER BY id; SELECT * FROM transfers")
+	assert.Equal(t, []map[string]interface{}{{"balance": int64(100)}, {"balance": int64(0)}}, res[0].Rows)
+	assert.Empty(t, res[1].Rows)
+
+	mustExec(t, exec, "BEGIN TRANSACTION; DELETE FROM accounts WHERE id = 2; INSERT INTO transfers VALUES (2, 10); COMMIT")
+	res = mustExec(t, exec, "SELECT id FROM accounts; SELECT id FROM transfers")
+	assert.Equal(t, []map[string]interface{}{{"id": int64(1)}}, res[0].Rows)
+	assert.Equal(t, []map[string]interface{}{{"id": int64(2)}}, res[1].Rows)
+
+	// A statement outside of a transaction is atomic on its own
+	_, err := exec.Exec("INSERT INTO transfers VALUES (3, 1), (2, 1)")
+	var errDuplicate *table.DuplicateKeyError
+	assert.ErrorAs(t, err, &errDuplicate)
+	res = mustExec(t, exec, "SELECT id FROM transfers")
+	assert.Len(t, res[0].Rows, 1)
+
+	_, err = exec.Exec("COMMIT")
+	var errNoTx *NoTransactionError
+	assert.ErrorAs(t, err, &errNoTx)
+	mustExec(t, exec, "BEGIN")
+	_, err = exec.Exec("BEGIN")
+	var errInProgress *TransactionInProgressError
+	assert.ErrorAs(t, err, &errInProgress)
+	_, err = exec.Exec("CREATE TABLE t (id INT)")
+	var errSchema *SchemaChangeInTransactionError
+	assert.ErrorAs(t, err, &errSchema)
+	_, err = exec.Exec("VACUUM")
+	assert.ErrorAs(t, err, &errSchema)
+	mustExec(t, exec, "ROLLBACK")
+}
+
+// The transactions of sessions that commit at the same time share the fsyncs of the WAL
+func TestExecutor_GroupCommit(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+	exec.db.SetDurability(wal.DurabilityGroup)
+	// An fsync can be faster than a transaction, so the WAL waits until the other sessions committed too
+	exec.db.SetGroupCommitDelay(2 * time.Millisecond)
+	mustExec(t, exec, "CREATE TABLE events (id INT)")
+
+	const sessions, inserts = 8, 25
+	before := exec.db.WALStats()
+	var wg sync.WaitGroup
+	for s := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session := NewExecutor(exec.db)
+			for i := range inserts {
+				_, err := session.Exec(fmt.Sprintf("INSERT INTO events VALUES (%d)", s*inserts+i))
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every INSERT is a transaction of its own, so without group commit each of them would need an fsync
+	after := exec.db.WALStats()
+	assert.Less(t, after.Syncs-before.Syncs, int64(sessions*inserts))
+	res := mustExec(t, exec, "SELECT id FROM events")
+	assert.Len(t, res[0].Rows, sessions*inserts)
+}
+
+func TestExecutor_Vacuum(t *testing.T) {
+	exec := newTestExecutor()
+	defer removeDB()
+
+	mustExec(t, exec, "CREATE TABLE users (id INT, username STRING)")
+	for i := range 200 {
+		mustExec(t, exec, fmt.Sprintf("INSERT INTO users VALUES (%d, 'user%d')", i, i))
+	}
+	mustExec(t, exec, "DELETE FROM users WHERE id > 9")
+	before, err := exec.db.Tables["users"].ReadRaw()
+	assert.Nil(t, err)
+
+	mustExec(t, exec, "VACUUM users")
+	after, err := exec.db.Tables["users"].ReadRaw()
+	assert.Nil(t, err)
+	assert.Less(t, len(after), len(before))
+	res := mustExec(t, exec, "SELECT username FROM users WHERE id = 7; SELECT id FROM users")
+	assert.Equal(t, "index (btree)", res[0].AccessType)
+	assert.Equal(t, []map[string]interface{}{{"username": "user7"}}, res[0].Rows)
+	assert.Len(t, res[1].Rows, 10)
+
+	_, err = exec.Exec("VACUUM posts")
+	var errNotExist *internal.TableDoesNotExistError
+	assert.ErrorAs(t, err, &errNotExist)
+}
+
+func mustExec(t *testing.T, exec *Executor, query string) []*Result {
+	res, err := exec.Exec(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return res
+}
+
+func newTestExecutor() *Executor {
+	db, err := internal.CreateDatabase("sql_test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return NewExecutor(db)
+}
+
+func removeDB() {
+	if err := os.RemoveAll("./data/sql_test"); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,83 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table"
+)
+
+// TypeName returns the canonical SQL name of a types.Type* constant
+func TypeName(dataType byte) string {
+	switch dataType {
+	case types.TypeInt64:
+		return "INT64"
+	case types.TypeInt32:
+		return "INT32"
+	case types.TypeFloat64:
+		return "FLOAT64"
+	case types.TypeFloat32:
+		return "FLOAT32"
+	case types.TypeByte:
+		return "BYTE"
+	case types.TypeBool:
+		return "BOOL"
+	case types.TypeString:
+		return "STRING"
+	case types.TypeDate:
+		return "DATE"
+	case types.TypeTimestamp:
+		return "TIMESTAMP"
+	case types.TypeInterval:
+		return "INTERVAL"
+	case types.TypeDecimal:
+		return "DECIMAL"
+	case types.TypeBlob:
+		return "BLOB"
+	case types.TypeJSON:
+		return "JSON"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", dataType)
+	}
+}
+
+// FormatCreateTable returns the CREATE TABLE statement that recreates the schema of t followed by its CREATE INDEX statements
+func FormatCreateTable(t *table.Table) string {
+	defs := make([]string, 0, len(t.ColumnNames()))
+	for _, name := range t.ColumnNames() {
+		col := t.Columns()[name]
+		def := name + " " + TypeName(col.DataType())
+		if col.DataType() == types.TypeDecimal {
+			def += fmt.Sprintf("(%d, %d)", col.Opts.Precision, col.Opts.Scale)
+		}
+		if col.Opts.AllowNull {
+			def += " NULL"
+		} else {
+			def += " NOT NULL"
+		}
+		if col.Opts.FullTextIdx {
+			def += " FULLTEXT"
+		}
+		if col.Opts.PrimaryKey && len(t.PrimaryKey()) == 1 {
+			def += " PRIMARY KEY"
+		}
+		if col.Opts.Unique {
+			def += " UNIQUE"
+		}
+		if col.Opts.AutoIncrement {
+			def += " AUTOINCREMENT"
+		}
+		defs = append(defs, def)
+	}
+	if len(t.PrimaryKey()) > 1 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(t.PrimaryKey(), ", ")))
+	}
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n  %s\n);", t.Name, strings.Join(defs, ",\n  "))
+	for _, idx := range t.Indexes() {
+		if idx.Type == table.AccessTypeBtreeIdx && !idx.Primary {
+			stmt += fmt.Sprintf("\nCREATE INDEX ON %s (%s);", t.Name, idx.Column)
+		}
+	}
+	return stmt
+}
@@ -0,0 +1,218 @@
+package sql
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Lexer splits a query string into tokens
+// For example, SELECT * FROM users WHERE id = 1 becomes:
+// KEYWORD(SELECT) STAR KEYWORD(FROM) IDENT(users) KEYWORD(WHERE) IDENT(id) EQ INT(1) EOF
+type Lexer struct {
+	input string
+	pos   int
+}
+
+func NewLexer(input string) *Lexer {
+	return &Lexer{
+		input: input,
+	}
+}
+
+// Tokenize returns every token in the input including the closing TokenEOF
+func (l *Lexer) Tokenize() ([]Token, error) {
+	tokens := make([]Token, 0)
+	for {
+		tok, err := l.Next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Type == TokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *Lexer) Next() (Token, error) {
+	l.skipWhitespaceAndComments()
+	if l.pos >= len(l.input) {
+		return Token{Type: TokenEOF, Pos: l.pos}, nil
+	}
+
+	start := l.pos
+	ch := l.input[l.pos]
+	switch {
+	case isIdentStart(ch):
+		return l.readIdent(), nil
+	case isDigit(ch):
+		return l.readNumber(), nil
+	case ch == '\'':
+		return l.readString()
+	case ch == '"':
+		return l.readQuotedIdent()
+	}
+
+	l.pos++
+	switch ch {
+	case ',':
+		return Token{Type: TokenComma, Literal: ",", Pos: start}, nil
+	case ';':
+		return Token{Type: TokenSemicolon, Literal: ";", Pos: start}, nil
+	case '(':
+		return Token{Type: TokenLParen, Literal: "(", Pos: start}, nil
+	case ')':
+		return Token{Type: TokenRParen, Literal: ")", Pos: start}, nil
+	case '*':
+		return Token{Type: TokenStar, Literal: "*", Pos: start}, nil
+	case '-':
+		if l.peekByte() == '>' {
+			l.pos++
+			if l.peekByte() == '>' {
+				l.pos++
+				return Token{Type: TokenArrowText, Literal: "->>", Pos: start}, nil
+			}
+			return Token{Type: TokenArrow, Literal: "->", Pos: start}, nil
+		}
+		return Token{Type: TokenMinus, Literal: "-", Pos: start}, nil
+	case '=':
+		return Token{Type: TokenEq, Literal: "=", Pos: start}, nil
+	case '!':
+		if l.peekByte() == '=' {
+			l.pos++
+			return Token{Type: TokenNotEq, Literal: "!=", Pos: start}, nil
+		}
+	case '<':
+		switch l.peekByte() {
+		case '=':
+			l.pos++
+			return Token{Type: TokenLte, Literal: "<=", Pos: start}, nil
+		case '>':
+			l.pos++
+			return Token{Type: TokenNotEq, Literal: "<>", Pos: start}, nil
+		}
+		return Token{Type: TokenLt, Literal: "<", Pos: start}, nil
+	case '>':
+		if l.peekByte() == '=' {
+			l.pos++
+			return Token{Type: TokenGte, Literal: ">=", Pos: start}, nil
+		}
+		return Token{Type: TokenGt, Literal: ">", Pos: start}, nil
+	}
+	return Token{}, NewSyntaxError(start, "unexpected character %q", ch)
+}
+
+func (l *Lexer) peekByte() byte {
+	if l.pos >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos]
+}
+
+func (l *Lexer) skipWhitespaceAndComments() {
+	for l.pos < len(l.input) {
+		ch := l.input[l.pos]
+		if unicode.IsSpace(rune(ch)) {
+			l.pos++
+			continue
+		}
+		// -- comment until the end of the line
+		if ch == '-' && l.pos+1 < len(l.input) && l.input[l.pos+1] == '-' {
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+func (l *Lexer) readIdent() Token {
+	start := l.pos
+	for l.pos < len(l.input) && isIdentPart(l.input[l.pos]) {
+		l.pos++
+	}
+	lit := l.input[start:l.pos]
+	if isKeyword(lit) {
+		return Token{Type: TokenKeyword, Literal: strings.ToUpper(lit), Pos: start}
+	}
+	return Token{Type: TokenIdent, Literal: lit, Pos: start}
+}
+
+// readQuotedIdent reads an identifier such as "order" that would otherwise be a keyword
+func (l *Lexer) readQuotedIdent() (Token, error) {
+	start := l.pos
+	l.pos++
+	end := strings.IndexByte(l.input[l.pos:], '"')
+	if end == -1 {
+		return Token{}, NewSyntaxError(start, "unterminated quoted identifier")
+	}
+	lit := l.input[l.pos : l.pos+end]
+	l.pos += end + 1
+	return Token{Type: TokenIdent, Literal: lit, Pos: start}, nil
+}
+
+// readNumber reads an integer or a float such as 1.5, 2e10 or 1.5E-3
+func (l *Lexer) readNumber() Token {
+	start := l.pos
+	l.readDigits()
+	typ := TokenInt
+	if l.peekByte() == '.' && l.pos+1 < len(l.input) && isDigit(l.input[l.pos+1]) {
+		l.pos++
+		l.readDigits()
+		typ = TokenFloat
+	}
+	if ch := l.peekByte(); ch == 'e' || ch == 'E' {
+		exp := l.pos + 1
+		if exp < len(l.input) && (l.input[exp] == '+' || l.input[exp] == '-') {
+			exp++
+		}
+		if exp < len(l.input) && isDigit(l.input[exp]) {
+			l.pos = exp
+			l.readDigits()
+			typ = TokenFloat
+		}
+	}
+	return Token{Type: typ, Literal: l.input[start:l.pos], Pos: start}
+}
+
+func (l *Lexer) readDigits() {
+	for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+		l.pos++
+	}
+}
+
+// readString reads a single-quoted string literal. A quote inside the string is escaped by doubling it
+func (l *Lexer) readString() (Token, error) {
+	start := l.pos
+	l.pos++
+	sb := strings.Builder{}
+	for {
+		if l.pos >= len(l.input) {
+			return Token{}, NewSyntaxError(start, "unterminated string literal")
+		}
+		ch := l.input[l.pos]
+		l.pos++
+		if ch == '\'' {
+			if l.peekByte() == '\'' {
+				sb.WriteByte('\'')
+				l.pos++
+				continue
+			}
+			return Token{Type: TokenString, Literal: sb.String(), Pos: start}, nil
+		}
+		sb.WriteByte(ch)
+	}
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || isDigit(ch)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
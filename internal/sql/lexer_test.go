@@ -0,0 +1,69 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexer_Tokenize(t *testing.T) {
+	tokens, err := NewLexer("select id, name FROM users WHERE age >= 18 AND name != 'it''s' -- comment\n;").Tokenize()
+	assert.Nil(t, err)
+
+	expected := []Token{
+		{Type: TokenKeyword, Literal: "SELECT", Pos: 0},
+		{Type: TokenIdent, Literal: "id", Pos: 7},
+		{Type: TokenComma, Literal: ",", Pos: 9},
+		{Type: TokenIdent, Literal: "name", Pos: 11},
+		{Type: TokenKeyword, Literal: "FROM", Pos: 16},
+		{Type: TokenIdent, Literal: "users", Pos: 21},
+		{Type: TokenKeyword, Literal: "WHERE", Pos: 27},
+		{Type: TokenIdent, Literal: "age", Pos: 33},
+		{Type: TokenGte, Literal: ">=", Pos: 37},
+		{Type: TokenInt, Literal: "18", Pos: 40},
+		{Type: TokenKeyword, Literal: "AND", Pos: 43},
+		{Type: TokenIdent, Literal: "name", Pos: 47},
+		{Type: TokenNotEq, Literal: "!=", Pos: 52},
+		{Type: TokenString, Literal: "it's", Pos: 55},
+		{Type: TokenSemicolon, Literal: ";", Pos: 74},
+		{Type: TokenEOF, Pos: 75},
+	}
+	assert.Equal(t, expected, tokens)
+}
+
+func TestLexer_QuotedIdent(t *testing.T) {
+	tokens, err := NewLexer(`"order" <> 1`).Tokenize()
+	assert.Nil(t, err)
+	assert.Equal(t, TokenIdent, tokens[0].Type)
+	assert.Equal(t, "order", tokens[0].Literal)
+	assert.Equal(t, TokenNotEq, tokens[1].Type)
+}
+
+func TestLexer_UnterminatedString(t *testing.T) {
+	_, err := NewLexer("SELECT 'abc").Tokenize()
+	var syntaxErr *SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+}
+
+func TestLexer_IllegalCharacter(t *testing.T) {
+	_, err := NewLexer("SELECT # FROM users").Tokenize()
+	var syntaxErr *SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+}
+
+func TestLexer_Numbers(t *testing.T) {
+	tokens, err := NewLexer("1 1.5 2e10 1.5E-3 3e").Tokenize()
+	assert.Nil(t, err)
+
+	expected := []Token{
+		{Type: TokenInt, Literal: "1", Pos: 0},
+		{Type: TokenFloat, Literal: "1.5", Pos: 2},
+		{Type: TokenFloat, Literal: "2e10", Pos: 6},
+		{Type: TokenFloat, Literal: "1.5E-3", Pos: 11},
+		// An exponent without digits is not part of the number
+		{Type: TokenInt, Literal: "3", Pos: 18},
+		{Type: TokenIdent, Literal: "e", Pos: 19},
+		{Type: TokenEOF, Pos: 20},
+	}
+	assert.Equal(t, expected, tokens)
+}
@@ -0,0 +1,997 @@
+package sql
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// columnTypes maps the type names accepted in CREATE TABLE to types.Type* constants
+var columnTypes = map[string]byte{
+	"INT":         types.TypeInt64,
+	"INTEGER":     types.TypeInt64,
+	"INT64":       types.TypeInt64,
+	"BIGINT":      types.TypeInt64,
+	"INT32":       types.TypeInt32,
+	"FLOAT":       types.TypeFloat64,
+	"FLOAT64":     types.TypeFloat64,
+	"DOUBLE":      types.TypeFloat64,
+	"FLOAT32":     types.TypeFloat32,
+	"REAL":        types.TypeFloat32,
+	"BYTE":        types.TypeByte,
+	"TINYINT":     types.TypeByte,
+	"BOOL":        types.TypeBool,
+	"BOOLEAN":     types.TypeBool,
+	"STRING":      types.TypeString,
+	"TEXT":        types.TypeString,
+	"VARCHAR":     types.TypeString,
+	"DATE":        types.TypeDate,
+	"TIMESTAMP":   types.TypeTimestamp,
+	"TIMESTAMPTZ": types.TypeTimestamp,
+	"INTERVAL":    types.TypeInterval,
+	"DECIMAL":     types.TypeDecimal,
+	"NUMERIC":     types.TypeDecimal,
+	"BLOB":        types.TypeBlob,
+	"BYTEA":       types.TypeBlob,
+	"BYTES":       types.TypeBlob,
+	"JSON":        types.TypeJSON,
+	"JSONB":       types.TypeJSON,
+}
+
+// defaultDecimalPrecision is the precision of a DECIMAL column declared without one. Its scale is 0
+const defaultDecimalPrecision = types.MaxDecimalPrecision
+
+// literalTypes are the types that can precede a string to make it a TypedLiteral
+var literalTypes = map[string]byte{
+	"DATE":      types.TypeDate,
+	"TIMESTAMP": types.TypeTimestamp,
+	"INTERVAL":  types.TypeInterval,
+}
+
+// Parser is a recursive descent parser that builds the AST from the tokens of a Lexer
+type Parser struct {
+	tokens []Token
+	pos    int
+}
+
+// Parse parses one or more statements separated by semicolons
+func Parse(query string) ([]Statement, error) {
+	tokens, err := NewLexer(query).Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	p := &Parser{tokens: tokens}
+
+	stmts := make([]Statement, 0)
+	for {
+		for p.curr().Type == TokenSemicolon {
+			p.advance()
+		}
+		if p.curr().Type == TokenEOF {
+			return stmts, nil
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+
+		if p.curr().Type != TokenSemicolon && p.curr().Type != TokenEOF {
+			return nil, p.unexpected("; or end of input")
+		}
+	}
+}
+
+// ParseOne parses exactly one statement
+func ParseOne(query string) (Statement, error) {
+	stmts, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) != 1 {
+		return nil, NewSyntaxError(0, "expected exactly one statement, found %d", len(stmts))
+	}
+	return stmts[0], nil
+}
+
+func (p *Parser) parseStatement() (Statement, error) {
+	tok := p.curr()
+	if tok.Type != TokenKeyword {
+		return nil, p.unexpected("statement")
+	}
+	switch tok.Literal {
+	case "SELECT":
+		return p.parseSelect()
+	case "INSERT":
+		return p.parseInsert()
+	case "UPDATE":
+		return p.parseUpdate()
+	case "DELETE":
+		return p.parseDelete()
+	case "CREATE":
+		return p.parseCreate()
+	case "DROP":
+		return p.parseDrop()
+	case "BEGIN":
+		return p.parseTransaction(&BeginStmt{})
+	case "COMMIT":
+		return p.parseTransaction(&CommitStmt{})
+	case "ROLLBACK":
+		return p.parseTransaction(&RollbackStmt{})
+	case "VACUUM":
+		return p.parseVacuum()
+	case "SET":
+		return p.parseSetTimeZone()
+	}
+	return nil, p.unexpected("statement")
+}
+
+// BEGIN [TRANSACTION] | COMMIT [TRANSACTION] | ROLLBACK [TRANSACTION]
+func (p *Parser) parseTransaction(stmt Statement) (Statement, error) {
+	p.advance()
+	if p.isWord("TRANSACTION") {
+		p.advance()
+	}
+	return stmt, nil
+}
+
+// VACUUM [table]
+func (p *Parser) parseVacuum() (Statement, error) {
+	p.advance()
+	stmt := &VacuumStmt{}
+	if p.curr().Type == TokenIdent {
+		stmt.Table = p.curr().Literal
+		p.advance()
+	}
+	return stmt, nil
+}
+
+// SET TIME ZONE 'name'
+func (p *Parser) parseSetTimeZone() (Statement, error) {
+	p.advance()
+	for _, w := range []string{"TIME", "ZONE"} {
+		if !p.isWord(w) {
+			return nil, p.unexpected(w)
+		}
+		p.advance()
+	}
+	tok := p.curr()
+	if tok.Type != TokenString {
+		return nil, p.unexpected("time zone")
+	}
+	p.advance()
+	return &SetTimeZoneStmt{Name: tok.Literal}, nil
+}
+
+// SELECT * | col [, col...] FROM table [WHERE expr] [ORDER BY col [ASC|DESC] [, ...]] [LIMIT n [OFFSET m]]
+// A col can be a JSON path such as attrs->>'city'
+func (p *Parser) parseSelect() (Statement, error) {
+	p.advance()
+	stmt := &SelectStmt{Limit: -1}
+
+	if p.curr().Type == TokenStar {
+		p.advance()
+	} else {
+		for {
+			col, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			if p.curr().Type == TokenArrow || p.curr().Type == TokenArrowText {
+				path, err := p.parsePath(col)
+				if err != nil {
+					return nil, err
+				}
+				col = path.String()
+				if stmt.Paths == nil {
+					stmt.Paths = make(map[string]*PathExpr)
+				}
+				stmt.Paths[col] = path
+			}
+			stmt.Columns = append(stmt.Columns, col)
+			if p.curr().Type != TokenComma {
+				break
+			}
+			p.advance()
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Table = table
+
+	if stmt.Where, err = p.parseOptionalWhere(); err != nil {
+		return nil, err
+	}
+
+	if p.isKeyword("ORDER") {
+		p.advance()
+		if err = p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			col, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			item := &OrderByItem{Column: col}
+			if p.isKeyword("DESC") {
+				item.Desc = true
+				p.advance()
+			} else if p.isKeyword("ASC") {
+				p.advance()
+			}
+			stmt.OrderBy = append(stmt.OrderBy, item)
+			if p.curr().Type != TokenComma {
+				break
+			}
+			p.advance()
+		}
+	}
+
+	if p.isKeyword("LIMIT") {
+		p.advance()
+		if stmt.Limit, err = p.expectUint(); err != nil {
+			return nil, err
+		}
+		if p.isKeyword("OFFSET") {
+			p.advance()
+			if stmt.Offset, err = p.expectUint(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stmt, nil
+}
+
+// INSERT INTO table [(col [, col...])] VALUES (expr [, expr...]) [, (...)]
+func (p *Parser) parseInsert() (Statement, error) {
+	p.advance()
+	if err := p.expectKeyword("INTO"); err != nil {
+		return nil, err
+	}
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &InsertStmt{Table: table}
+
+	if p.curr().Type == TokenLParen {
+		p.advance()
+		if stmt.Columns, err = p.parseIdentList(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.expectKeyword("VALUES"); err != nil {
+		return nil, err
+	}
+	for {
+		if err = p.expect(TokenLParen); err != nil {
+			return nil, err
+		}
+		row := make([]Expr, 0)
+		for {
+			val, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, val)
+			if p.curr().Type != TokenComma {
+				break
+			}
+			p.advance()
+		}
+		if err = p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+		stmt.Rows = append(stmt.Rows, row)
+
+		if p.curr().Type != TokenComma {
+			return stmt, nil
+		}
+		p.advance()
+	}
+}
+
+// UPDATE table SET col = expr [, col = expr...] [WHERE expr]
+func (p *Parser) parseUpdate() (Statement, error) {
+	p.advance()
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &UpdateStmt{Table: table}
+	if err = p.expectKeyword("SET"); err != nil {
+		return nil, err
+	}
+	for {
+		col, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(TokenEq); err != nil {
+			return nil, err
+		}
+		val, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Set = append(stmt.Set, &Assignment{Column: col, Value: val})
+		if p.curr().Type != TokenComma {
+			break
+		}
+		p.advance()
+	}
+	if stmt.Where, err = p.parseOptionalWhere(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// DELETE FROM table [WHERE expr]
+func (p *Parser) parseDelete() (Statement, error) {
+	p.advance()
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &DeleteStmt{Table: table}
+	if stmt.Where, err = p.parseOptionalWhere(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// CREATE TABLE table (col type [NULL | NOT NULL] [FULLTEXT] [PRIMARY KEY] [UNIQUE] [, ...] [, PRIMARY KEY (col [, ...])])
+func (p *Parser) parseCreate() (Statement, error) {
+	p.advance()
+	if p.isKeyword("INDEX") {
+		return p.parseCreateIndex()
+	}
+	if p.isKeyword("SEQUENCE") {
+		return p.parseCreateSequence()
+	}
+	if err := p.expectKeyword("TABLE"); err != nil {
+		return nil, err
+	}
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &CreateTableStmt{Table: table}
+	if err = p.expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	for {
+		if p.isKeyword("PRIMARY") {
+			if stmt.PrimaryKey != nil {
+				return nil, NewSyntaxError(p.curr().Pos, "multiple PRIMARY KEY constraints")
+			}
+			if stmt.PrimaryKey, err = p.parsePrimaryKeyConstraint(); err != nil {
+				return nil, err
+			}
+		} else {
+			col, err := p.parseColumnDef()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Columns = append(stmt.Columns, col)
+		}
+		if p.curr().Type != TokenComma {
+			break
+		}
+		p.advance()
+	}
+	if err = p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// PRIMARY KEY (col [, ...])
+func (p *Parser) parsePrimaryKeyConstraint() ([]string, error) {
+	p.advance()
+	if err := p.expectKeyword("KEY"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	cols := make([]string, 0, 1)
+	for {
+		col, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+		if p.curr().Type != TokenComma {
+			break
+		}
+		p.advance()
+	}
+	if err := p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// CREATE SEQUENCE name [START [WITH] n]
+func (p *Parser) parseCreateSequence() (Statement, error) {
+	p.advance()
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &CreateSequenceStmt{Name: name, Start: 1}
+	if p.isWord("START") {
+		p.advance()
+		if p.isWord("WITH") {
+			p.advance()
+		}
+		if stmt.Start, err = p.expectUint(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// CREATE INDEX [name] ON table (col)
+func (p *Parser) parseCreateIndex() (Statement, error) {
+	p.advance()
+	if p.curr().Type == TokenIdent {
+		p.advance()
+	}
+	if err := p.expectKeyword("ON"); err != nil {
+		return nil, err
+	}
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	col, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return &CreateIndexStmt{Table: table, Column: col}, nil
+}
+
+func (p *Parser) parseColumnDef() (*ColumnDef, error) {
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	typeTok := p.curr()
+	if typeTok.Type != TokenIdent {
+		return nil, p.unexpected("column type")
+	}
+	dataType, ok := columnTypes[strings.ToUpper(typeTok.Literal)]
+	if !ok {
+		return nil, NewSyntaxError(typeTok.Pos, "unknown column type %s", typeTok.Literal)
+	}
+	p.advance()
+
+	col := &ColumnDef{Name: name, Type: dataType}
+	if dataType == types.TypeDecimal {
+		if col.Precision, col.Scale, err = p.parseDecimalParams(); err != nil {
+			return nil, err
+		}
+	} else if p.curr().Type == TokenLParen {
+		// VARCHAR(255) is accepted, but the length is not enforced
+		p.advance()
+		if _, err = p.expectUint(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		switch {
+		case p.isKeyword("NULL"):
+			col.AllowNull = true
+			p.advance()
+		case p.isKeyword("NOT"):
+			p.advance()
+			if err = p.expectKeyword("NULL"); err != nil {
+				return nil, err
+			}
+			col.AllowNull = false
+		case p.isKeyword("FULLTEXT"):
+			col.FullTextIdx = true
+			p.advance()
+		case p.isKeyword("PRIMARY"):
+			p.advance()
+			if err = p.expectKeyword("KEY"); err != nil {
+				return nil, err
+			}
+			col.PrimaryKey = true
+		case p.isKeyword("UNIQUE"):
+			col.Unique = true
+			p.advance()
+		case p.isKeyword("AUTOINCREMENT"), p.isKeyword("AUTO_INCREMENT"):
+			col.AutoIncrement = true
+			p.advance()
+		default:
+			return col, nil
+		}
+	}
+}
+
+// [(precision [, scale])] after DECIMAL. The limits are checked by column.New
+func (p *Parser) parseDecimalParams() (int, int, error) {
+	if p.curr().Type != TokenLParen {
+		return defaultDecimalPrecision, 0, nil
+	}
+	p.advance()
+	precision, err := p.expectUint()
+	if err != nil {
+		return 0, 0, err
+	}
+	var scale int64
+	if p.curr().Type == TokenComma {
+		p.advance()
+		if scale, err = p.expectUint(); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err = p.expect(TokenRParen); err != nil {
+		return 0, 0, err
+	}
+	return int(precision), int(scale), nil
+}
+
+// DROP TABLE table | DROP SEQUENCE name
+func (p *Parser) parseDrop() (Statement, error) {
+	p.advance()
+	if p.isKeyword("SEQUENCE") {
+		p.advance()
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		return &DropSequenceStmt{Name: name}, nil
+	}
+	if err := p.expectKeyword("TABLE"); err != nil {
+		return nil, err
+	}
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	return &DropTableStmt{Table: table}, nil
+}
+
+func (p *Parser) parseOptionalWhere() (Expr, error) {
+	if !p.isKeyword("WHERE") {
+		return nil, nil
+	}
+	p.advance()
+	return p.parseExpr()
+}
+
+// Expressions are parsed with the following precedence (lowest first):
+//
+//	OR
+//	AND
+//	NOT
+//	comparison (=, !=, <>, <, <=, >, >=, [NOT] IN, [NOT] BETWEEN, [NOT] LIKE, IS [NOT] NULL)
+//	operand (identifier, JSON path, literal, parenthesized expression)
+func (p *Parser) parseExpr() (Expr, error) {
+	return p.parseOr()
+}
+
+func (p *Parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: OpOr, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		p.advance()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: OpAnd, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseNot() (Expr, error) {
+	if p.isKeyword("NOT") {
+		p.advance()
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: e}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *Parser) parseComparison() (Expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.curr().Type == TokenKeyword {
+		return p.parseKeywordComparison(left)
+	}
+	var op string
+	switch p.curr().Type {
+	case TokenEq:
+		op = OpEq
+	case TokenNotEq:
+		op = OpNotEq
+	case TokenLt:
+		op = OpLt
+	case TokenLte:
+		op = OpLte
+	case TokenGt:
+		op = OpGt
+	case TokenGte:
+		op = OpGte
+	default:
+		return left, nil
+	}
+	p.advance()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryExpr{Op: op, Left: left, Right: right}, nil
+}
+
+// parseKeywordComparison parses the comparisons that start with a keyword after the left operand such as IN or IS NULL
+// If the keyword doesn't start a comparison left is returned as it is
+func (p *Parser) parseKeywordComparison(left Expr) (Expr, error) {
+	if p.isKeyword("IS") {
+		p.advance()
+		not := false
+		if p.isKeyword("NOT") {
+			p.advance()
+			not = true
+		}
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return &IsNullExpr{Expr: left, Not: not}, nil
+	}
+
+	not := false
+	if p.isKeyword("NOT") {
+		p.advance()
+		not = true
+		if !p.isKeyword("IN") && !p.isKeyword("BETWEEN") && !p.isKeyword("LIKE") {
+			return nil, p.unexpected("IN, BETWEEN or LIKE")
+		}
+	}
+
+	switch {
+	case p.isKeyword("IN"):
+		p.advance()
+		if err := p.expect(TokenLParen); err != nil {
+			return nil, err
+		}
+		values := make([]Expr, 0)
+		for {
+			v, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			if p.curr().Type != TokenComma {
+				break
+			}
+			p.advance()
+		}
+		if err := p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+		return &InExpr{Expr: left, Values: values, Not: not}, nil
+	case p.isKeyword("BETWEEN"):
+		p.advance()
+		from, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		to, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &BetweenExpr{Expr: left, From: from, To: to, Not: not}, nil
+	case p.isKeyword("LIKE"):
+		p.advance()
+		pattern, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &LikeExpr{Expr: left, Pattern: pattern, Not: not}, nil
+	}
+	return left, nil
+}
+
+func (p *Parser) parseOperand() (Expr, error) {
+	tok := p.curr()
+	switch tok.Type {
+	case TokenIdent:
+		p.advance()
+		if p.curr().Type == TokenLParen {
+			return p.parseCall(tok)
+		}
+		if typ, ok := literalTypes[strings.ToUpper(tok.Literal)]; ok && p.curr().Type == TokenString {
+			lit := p.curr()
+			p.advance()
+			return &TypedLiteral{Type: typ, Value: lit.Literal}, nil
+		}
+		// X'DEADBEEF' is a BLOB written in hex
+		if strings.EqualFold(tok.Literal, "X") && p.curr().Type == TokenString {
+			lit := p.curr()
+			p.advance()
+			b, err := hex.DecodeString(lit.Literal)
+			if err != nil {
+				return nil, NewSyntaxError(lit.Pos, "invalid hex string %s", lit.Literal)
+			}
+			return &Literal{Value: b}, nil
+		}
+		if p.curr().Type == TokenArrow || p.curr().Type == TokenArrowText {
+			return p.parsePath(tok.Literal)
+		}
+		return &Ident{Name: tok.Literal}, nil
+	case TokenInt:
+		p.advance()
+		return p.intLiteral(tok, false)
+	case TokenFloat:
+		p.advance()
+		return p.floatLiteral(tok, false)
+	case TokenMinus:
+		p.advance()
+		numTok := p.curr()
+		switch numTok.Type {
+		case TokenInt:
+			p.advance()
+			return p.intLiteral(numTok, true)
+		case TokenFloat:
+			p.advance()
+			return p.floatLiteral(numTok, true)
+		}
+		return nil, p.unexpected("number")
+	case TokenString:
+		p.advance()
+		return &Literal{Value: tok.Literal}, nil
+	case TokenLParen:
+		p.advance()
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case TokenKeyword:
+		switch tok.Literal {
+		case "TRUE":
+			p.advance()
+			return &Literal{Value: true}, nil
+		case "FALSE":
+			p.advance()
+			return &Literal{Value: false}, nil
+		case "NULL":
+			p.advance()
+			return &Literal{Value: nil}, nil
+		}
+	}
+	return nil, p.unexpected("expression")
+}
+
+// col->'key'->0->>'key'. The column has already been consumed. A key is a string and an array index is an integer
+// ->> returns text, so it can only be the last operator
+func (p *Parser) parsePath(col string) (*PathExpr, error) {
+	path := &PathExpr{Column: col}
+	for p.curr().Type == TokenArrow || p.curr().Type == TokenArrowText {
+		if path.AsText {
+			return nil, NewSyntaxError(p.curr().Pos, "%s cannot follow ->>", p.curr().Literal)
+		}
+		path.AsText = p.curr().Type == TokenArrowText
+		p.advance()
+
+		tok := p.curr()
+		negative := false
+		if tok.Type == TokenMinus {
+			p.advance()
+			tok = p.curr()
+			negative = true
+		}
+		switch {
+		case tok.Type == TokenString && !negative:
+			path.Path = append(path.Path, tok.Literal)
+		case tok.Type == TokenInt:
+			lit := tok.Literal
+			if negative {
+				lit = "-" + lit
+			}
+			i, err := strconv.ParseInt(lit, 10, 64)
+			if err != nil {
+				return nil, NewSyntaxError(tok.Pos, "invalid array index %s", lit)
+			}
+			path.Path = append(path.Path, i)
+		default:
+			return nil, p.unexpected("object key or array index")
+		}
+		p.advance()
+	}
+	return path, nil
+}
+
+// name(expr [, expr...]). The name has already been consumed
+func (p *Parser) parseCall(name Token) (Expr, error) {
+	p.advance()
+	call := &CallExpr{Name: strings.ToUpper(name.Literal)}
+	for p.curr().Type != TokenRParen {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		if p.curr().Type != TokenComma {
+			break
+		}
+		p.advance()
+	}
+	if err := p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+func (p *Parser) intLiteral(tok Token, negative bool) (Expr, error) {
+	lit := tok.Literal
+	if negative {
+		lit = "-" + lit
+	}
+	v, err := strconv.ParseInt(lit, 10, 64)
+	if err != nil {
+		// Integers that don't fit into an int64 are floats, but a DECIMAL column gets them exactly
+		if errors.Is(err, strconv.ErrRange) {
+			return p.floatLiteral(tok, negative)
+		}
+		return nil, NewSyntaxError(tok.Pos, "invalid integer %s", lit)
+	}
+	return &Literal{Value: v}, nil
+}
+
+func (p *Parser) floatLiteral(tok Token, negative bool) (Expr, error) {
+	lit := tok.Literal
+	if negative {
+		lit = "-" + lit
+	}
+	v, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return nil, NewSyntaxError(tok.Pos, "invalid float %s", lit)
+	}
+	return &Literal{Value: v, Text: lit}, nil
+}
+
+func (p *Parser) parseIdentList() ([]string, error) {
+	idents := make([]string, 0)
+	for {
+		ident, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		idents = append(idents, ident)
+		if p.curr().Type != TokenComma {
+			return idents, nil
+		}
+		p.advance()
+	}
+}
+
+func (p *Parser) curr() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *Parser) advance() {
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+}
+
+func (p *Parser) isKeyword(kw string) bool {
+	tok := p.curr()
+	return tok.Type == TokenKeyword && tok.Literal == kw
+}
+
+// isWord reports whether the current token is the non-reserved word w. Such words are only special in a few places
+// so they're lexed as identifiers and can still be used as column names
+func (p *Parser) isWord(w string) bool {
+	tok := p.curr()
+	return tok.Type == TokenIdent && strings.EqualFold(tok.Literal, w)
+}
+
+func (p *Parser) expect(t TokenType) error {
+	if p.curr().Type != t {
+		return p.unexpected(t.String())
+	}
+	p.advance()
+	return nil
+}
+
+func (p *Parser) expectKeyword(kw string) error {
+	if !p.isKeyword(kw) {
+		return p.unexpected(kw)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *Parser) expectIdent() (string, error) {
+	tok := p.curr()
+	if tok.Type != TokenIdent {
+		return "", p.unexpected("identifier")
+	}
+	p.advance()
+	return tok.Literal, nil
+}
+
+func (p *Parser) expectUint() (int64, error) {
+	tok := p.curr()
+	if tok.Type != TokenInt {
+		return 0, p.unexpected("number")
+	}
+	p.advance()
+	v, err := strconv.ParseInt(tok.Literal, 10, 64)
+	if err != nil {
+		return 0, NewSyntaxError(tok.Pos, "invalid number %s", tok.Literal)
+	}
+	return v, nil
+}
+
+func (p *Parser) unexpected(expected string) error {
+	tok := p.curr()
+	found := tok.Literal
+	if tok.Type == TokenEOF {
+		found = tok.Type.String()
+	}
+	return NewSyntaxError(tok.Pos, "expected %s, found %s", expected, found)
+}
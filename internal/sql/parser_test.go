@@ -0,0 +1,249 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Select(t *testing.T) {
+	stmt, err := ParseOne("SELECT id, username FROM users WHERE id = 1 AND NOT (age < 18 OR job = 'x') ORDER BY age DESC, id LIMIT 10 OFFSET 5")
+	assert.Nil(t, err)
+
+	expected := &SelectStmt{
+		Table:   "users",
+		Columns: []string{"id", "username"},
+		Where: &BinaryExpr{
+			Op:   OpAnd,
+			Left: &BinaryExpr{Op: OpEq, Left: &Ident{Name: "id"}, Right: &Literal{Value: int64(1)}},
+			Right: &NotExpr{Expr: &BinaryExpr{
+				Op:    OpOr,
+				Left:  &BinaryExpr{Op: OpLt, Left: &Ident{Name: "age"}, Right: &Literal{Value: int64(18)}},
+				Right: &BinaryExpr{Op: OpEq, Left: &Ident{Name: "job"}, Right: &Literal{Value: "x"}},
+			}},
+		},
+		OrderBy: []*OrderByItem{{Column: "age", Desc: true}, {Column: "id"}},
+		Limit:   10,
+		Offset:  5,
+	}
+	assert.Equal(t, expected, stmt)
+}
+
+func TestParse_KeywordComparisons(t *testing.T) {
+	stmt, err := ParseOne("SELECT * FROM users WHERE id IN (1, 2) AND age NOT BETWEEN 18 AND 30 OR job IS NOT NULL AND username NOT LIKE 'a%'")
+	assert.Nil(t, err)
+
+	expected := &BinaryExpr{
+		Op: OpOr,
+		Left: &BinaryExpr{
+			Op:    OpAnd,
+			Left:  &InExpr{Expr: &Ident{Name: "id"}, Values: []Expr{&Literal{Value: int64(1)}, &Literal{Value: int64(2)}}},
+			Right: &BetweenExpr{Expr: &Ident{Name: "age"}, From: &Literal{Value: int64(18)}, To: &Literal{Value: int64(30)}, Not: true},
+		},
+		Right: &BinaryExpr{
+			Op:    OpAnd,
+			Left:  &IsNullExpr{Expr: &Ident{Name: "job"}, Not: true},
+			Right: &LikeExpr{Expr: &Ident{Name: "username"}, Pattern: &Literal{Value: "a%"}, Not: true},
+		},
+	}
+	assert.Equal(t, expected, stmt.(*SelectStmt).Where)
+
+	_, err = ParseOne("SELECT * FROM users WHERE id NOT = 1")
+	var errSyntax *SyntaxError
+	assert.ErrorAs(t, err, &errSyntax)
+}
+
+func TestParse_CreateIndex(t *testing.T) {
+	stmts, err := Parse("CREATE INDEX users_age ON users (age); create index on users (job)")
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		&CreateIndexStmt{Table: "users", Column: "age"},
+		&CreateIndexStmt{Table: "users", Column: "job"},
+	}, stmts)
+}
+
+func TestParse_SelectStar(t *testing.T) {
+	stmt, err := ParseOne("select * from users")
+	assert.Nil(t, err)
+	assert.Equal(t, &SelectStmt{Table: "users", Limit: -1}, stmt)
+}
+
+func TestParse_Insert(t *testing.T) {
+	stmt, err := ParseOne("INSERT INTO users (id, username, is_active) VALUES (1, 'user1', TRUE), (-2, 'user2', NULL)")
+	assert.Nil(t, err)
+
+	expected := &InsertStmt{
+		Table:   "users",
+		Columns: []string{"id", "username", "is_active"},
+		Rows: [][]Expr{
+			{&Literal{Value: int64(1)}, &Literal{Value: "user1"}, &Literal{Value: true}},
+			{&Literal{Value: int64(-2)}, &Literal{Value: "user2"}, &Literal{Value: nil}},
+		},
+	}
+	assert.Equal(t, expected, stmt)
+}
+
+func TestParse_UpdateAndDelete(t *testing.T) {
+	stmts, err := Parse("UPDATE users SET job = 'developer', age = 30 WHERE id = 1; DELETE FROM users;")
+	assert.Nil(t, err)
+	assert.Len(t, stmts, 2)
+
+	assert.Equal(t, &UpdateStmt{
+		Table: "users",
+		Set: []*Assignment{
+			{Column: "job", Value: &Literal{Value: "developer"}},
+			{Column: "age", Value: &Literal{Value: int64(30)}},
+		},
+		Where: &BinaryExpr{Op: OpEq, Left: &Ident{Name: "id"}, Right: &Literal{Value: int64(1)}},
+	}, stmts[0])
+	assert.Equal(t, &DeleteStmt{Table: "users"}, stmts[1])
+}
+
+func TestParse_CreateAndDropTable(t *testing.T) {
+	stmts, err := Parse("CREATE TABLE users (id INT NOT NULL PRIMARY KEY, username VARCHAR(64) UNIQUE, job TEXT FULLTEXT, nickname STRING NULL, age BYTE); DROP TABLE users")
+	assert.Nil(t, err)
+
+	assert.Equal(t, &CreateTableStmt{
+		Table: "users",
+		Columns: []*ColumnDef{
+			{Name: "id", Type: types.TypeInt64, PrimaryKey: true},
+			{Name: "username", Type: types.TypeString, Unique: true},
+			{Name: "job", Type: types.TypeString, FullTextIdx: true},
+			{Name: "nickname", Type: types.TypeString, AllowNull: true},
+			{Name: "age", Type: types.TypeByte},
+		},
+	}, stmts[0])
+	assert.Equal(t, &DropTableStmt{Table: "users"}, stmts[1])
+
+	stmts, err = Parse("CREATE TABLE visits (country STRING, day INT, PRIMARY KEY (country, day))")
+	assert.Nil(t, err)
+	assert.Equal(t, &CreateTableStmt{
+		Table: "visits",
+		Columns: []*ColumnDef{
+			{Name: "country", Type: types.TypeString},
+			{Name: "day", Type: types.TypeInt64},
+		},
+		PrimaryKey: []string{"country", "day"},
+	}, stmts[0])
+
+	stmts, err = Parse("CREATE TABLE prices (id INT, amount DECIMAL(12, 2), rate NUMERIC(5), total DECIMAL)")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ColumnDef{
+		{Name: "id", Type: types.TypeInt64},
+		{Name: "amount", Type: types.TypeDecimal, Precision: 12, Scale: 2},
+		{Name: "rate", Type: types.TypeDecimal, Precision: 5},
+		{Name: "total", Type: types.TypeDecimal, Precision: types.MaxDecimalPrecision},
+	}, stmts[0].(*CreateTableStmt).Columns)
+}
+
+func TestParse_Sequences(t *testing.T) {
+	stmts, err := Parse("CREATE TABLE users (id INT PRIMARY KEY AUTOINCREMENT, start INT); CREATE SEQUENCE s START WITH 100; CREATE SEQUENCE t; DROP SEQUENCE s; INSERT INTO users VALUES (nextval('s'), 1)")
+	assert.Nil(t, err)
+
+	assert.Equal(t, &CreateTableStmt{
+		Table: "users",
+		Columns: []*ColumnDef{
+			{Name: "id", Type: types.TypeInt64, PrimaryKey: true, AutoIncrement: true},
+			{Name: "start", Type: types.TypeInt64},
+		},
+	}, stmts[0])
+	assert.Equal(t, &CreateSequenceStmt{Name: "s", Start: 100}, stmts[1])
+	assert.Equal(t, &CreateSequenceStmt{Name: "t", Start: 1}, stmts[2])
+	assert.Equal(t, &DropSequenceStmt{Name: "s"}, stmts[3])
+	assert.Equal(t, &InsertStmt{
+		Table: "users",
+		Rows:  [][]Expr{{&CallExpr{Name: "NEXTVAL", Args: []Expr{&Literal{Value: "s"}}}, &Literal{Value: int64(1)}}},
+	}, stmts[4])
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	queries := []string{
+		"SELECT FROM users",
+		"SELECT * users",
+		"INSERT INTO users VALUES 1, 2",
+		"UPDATE users job = 1",
+		"CREATE TABLE users (id UNKNOWN)",
+		"SELECT * FROM users WHERE",
+		"SELECT * FROM users LIMIT x",
+		"SELECT * FROM users users",
+	}
+	for _, q := range queries {
+		_, err := Parse(q)
+		var syntaxErr *SyntaxError
+		assert.ErrorAs(t, err, &syntaxErr, q)
+	}
+}
+
+func TestParse_Transactions(t *testing.T) {
+	stmts, err := Parse("BEGIN; begin transaction; COMMIT; COMMIT TRANSACTION; ROLLBACK")
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{&BeginStmt{}, &BeginStmt{}, &CommitStmt{}, &CommitStmt{}, &RollbackStmt{}}, stmts)
+
+	_, err = Parse("BEGIN WORK")
+	var errSyntax *SyntaxError
+	assert.ErrorAs(t, err, &errSyntax)
+}
+
+func TestParse_Vacuum(t *testing.T) {
+	stmts, err := Parse("VACUUM; vacuum users")
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{&VacuumStmt{}, &VacuumStmt{Table: "users"}}, stmts)
+
+	_, err = Parse("VACUUM TABLE users")
+	var errSyntax *SyntaxError
+	assert.ErrorAs(t, err, &errSyntax)
+}
+
+func TestParse_TypedLiterals(t *testing.T) {
+	stmt, err := ParseOne("SELECT * FROM events WHERE day = DATE '2024-01-31' AND ttl < INTERVAL '1 day' AND at > NOW()")
+	assert.Nil(t, err)
+
+	expected := &BinaryExpr{
+		Op: OpAnd,
+		Left: &BinaryExpr{
+			Op:    OpAnd,
+			Left:  &BinaryExpr{Op: OpEq, Left: &Ident{Name: "day"}, Right: &TypedLiteral{Type: types.TypeDate, Value: "2024-01-31"}},
+			Right: &BinaryExpr{Op: OpLt, Left: &Ident{Name: "ttl"}, Right: &TypedLiteral{Type: types.TypeInterval, Value: "1 day"}},
+		},
+		Right: &BinaryExpr{Op: OpGt, Left: &Ident{Name: "at"}, Right: &CallExpr{Name: "NOW"}},
+	}
+	assert.Equal(t, expected, stmt.(*SelectStmt).Where)
+
+	stmt, err = ParseOne("SET TIME ZONE 'Europe/Berlin'")
+	assert.Nil(t, err)
+	assert.Equal(t, &SetTimeZoneStmt{Name: "Europe/Berlin"}, stmt)
+	_, err = ParseOne("SET ZONE 'UTC'")
+	var syntaxErr *SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+}
+
+func TestParse_JSONPath(t *testing.T) {
+	stmt, err := ParseOne("SELECT id, attrs->'address'->>'city' FROM people WHERE attrs->'tags'->-1 = '\"b\"' AND attrs->>'it''s' IS NULL")
+	assert.Nil(t, err)
+
+	city := &PathExpr{Column: "attrs", Path: []interface{}{"address", "city"}, AsText: true}
+	expected := &SelectStmt{
+		Table:   "people",
+		Columns: []string{"id", "attrs->'address'->>'city'"},
+		Paths:   map[string]*PathExpr{"attrs->'address'->>'city'": city},
+		Where: &BinaryExpr{
+			Op:    OpAnd,
+			Left:  &BinaryExpr{Op: OpEq, Left: &PathExpr{Column: "attrs", Path: []interface{}{"tags", int64(-1)}}, Right: &Literal{Value: `"b"`}},
+			Right: &IsNullExpr{Expr: &PathExpr{Column: "attrs", Path: []interface{}{"it's"}, AsText: true}},
+		},
+		Limit: -1,
+	}
+	assert.Equal(t, expected, stmt)
+	assert.Equal(t, "attrs->>'it''s'", expected.Where.(*BinaryExpr).Right.(*IsNullExpr).Expr.(*PathExpr).String())
+
+	for _, query := range []string{
+		"SELECT * FROM people WHERE attrs->>'a'->'b' = '1'",
+		"SELECT * FROM people WHERE attrs-> = '1'",
+		"SELECT * FROM people WHERE attrs->-'a' = '1'",
+	} {
+		_, err = ParseOne(query)
+		var errSyntax *SyntaxError
+		assert.ErrorAs(t, err, &errSyntax, query)
+	}
+}
@@ -0,0 +1,106 @@
+package sql
+
+import "strings"
+
+type TokenType int
+
+const (
+	TokenIllegal TokenType = iota
+	TokenEOF
+	TokenIdent
+	TokenKeyword
+	TokenInt
+	TokenFloat
+	TokenString
+
+	TokenComma
+	TokenSemicolon
+	TokenLParen
+	TokenRParen
+	TokenStar
+	TokenMinus
+	// TokenArrow (->) and TokenArrowText (->>) read a value from a JSON column
+	TokenArrow
+	TokenArrowText
+
+	TokenEq
+	TokenNotEq
+	TokenLt
+	TokenLte
+	TokenGt
+	TokenGte
+)
+
+// keywords contains every reserved word. Identifiers matching a keyword (case-insensitive) are lexed as TokenKeyword
+var keywords = map[string]struct{}{
+	"SELECT": {}, "FROM": {}, "WHERE": {}, "ORDER": {}, "BY": {}, "ASC": {}, "DESC": {}, "LIMIT": {}, "OFFSET": {},
+	"INSERT": {}, "INTO": {}, "VALUES": {},
+	"UPDATE": {}, "SET": {},
+	"DELETE": {},
+	"CREATE": {}, "DROP": {}, "TABLE": {}, "INDEX": {}, "ON": {},
+	"AND": {}, "OR": {}, "NOT": {}, "IN": {}, "BETWEEN": {}, "LIKE": {}, "IS": {},
+	"NULL": {}, "TRUE": {}, "FALSE": {},
+	"FULLTEXT": {}, "PRIMARY": {}, "KEY": {}, "UNIQUE": {}, "AUTOINCREMENT": {}, "AUTO_INCREMENT": {},
+	"SEQUENCE": {}, "BEGIN": {}, "COMMIT": {}, "ROLLBACK": {},
+	"VACUUM": {},
+}
+
+type Token struct {
+	Type TokenType
+	// Literal is the raw text of the token. Keywords are upper-cased, string literals are unquoted
+	Literal string
+	// Pos is the byte offset of the token in the query
+	Pos int
+}
+
+func isKeyword(s string) bool {
+	_, ok := keywords[strings.ToUpper(s)]
+	return ok
+}
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenEOF:
+		return "end of input"
+	case TokenIdent:
+		return "identifier"
+	case TokenKeyword:
+		return "keyword"
+	case TokenInt:
+		return "integer"
+	case TokenFloat:
+		return "float"
+	case TokenString:
+		return "string"
+	case TokenComma:
+		return ","
+	case TokenSemicolon:
+		return ";"
+	case TokenLParen:
+		return "("
+	case TokenRParen:
+		return ")"
+	case TokenStar:
+		return "*"
+	case TokenMinus:
+		return "-"
+	case TokenArrow:
+		return "->"
+	case TokenArrowText:
+		return "->>"
+	case TokenEq:
+		return "="
+	case TokenNotEq:
+		return "!="
+	case TokenLt:
+		return "<"
+	case TokenLte:
+		return "<="
+	case TokenGt:
+		return ">"
+	case TokenGte:
+		return ">="
+	default:
+		return "illegal"
+	}
+}
@@ -0,0 +1,147 @@
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table/column"
+	"github.com/omesh-barhate/ByteForge/internal/table/index"
+	"github.com/omesh-barhate/ByteForge/internal/table/mvcc"
+)
+
+// encodedValue is a column value that is already encoded as a TLV, such as the overflow pointer of a BLOB that was
+// read from an io.Reader. marshalRecord writes it as it is
+type encodedValue []byte
+
+// streamBlobs returns a copy of record where the io.Readers of BLOB columns are replaced by the encodedValues that
+// streamOverflow returns for them. The chunks are placed by pl, so they are inserted with the record
+func (t *Table) streamBlobs(pl *placement, record map[string]interface{}) (map[string]interface{}, error) {
+	var streamed map[string]interface{}
+	for _, col := range t.columnNames {
+		r, ok := record[col].(io.Reader)
+		if !ok || t.columns[col].DataType() != types.TypeBlob {
+			continue
+		}
+		tlv, err := streamOverflow(pl, types.TypeBlob, r, t.pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("Table.streamBlobs: %s: %w", col, err)
+		}
+		if streamed == nil {
+			streamed = maps.Clone(record)
+		}
+		streamed[col] = encodedValue(tlv)
+	}
+	if streamed == nil {
+		return record, nil
+	}
+	return streamed, nil
+}
+
+// readBlobs returns a copy of values where the io.Readers are replaced by the bytes they return
+// An update can write the same value into several records, so the value cannot be streamed into one of them
+func readBlobs(values map[string]interface{}) (map[string]interface{}, error) {
+	var read map[string]interface{}
+	for col, v := range values {
+		r, ok := v.(io.Reader)
+		if !ok {
+			continue
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("readBlobs: %s: %w", col, err)
+		}
+		if read == nil {
+			read = maps.Clone(values)
+		}
+		read[col] = b
+	}
+	if read == nil {
+		return values, nil
+	}
+	return read, nil
+}
+
+// ReadBlob writes the value of the BLOB column col of the record with the primary key key into w and returns the
+// number of bytes written. Nothing is written if the value is NULL
+// A value that was moved into overflow chunks is written one chunk at a time, so it's never read into memory as a
+// whole. Writers wait until the value was written
+func (t *Table) ReadBlob(key index.Key, col string, w io.Writer) (int64, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	c, ok := t.columns[col]
+	if !ok {
+		return 0, fmt.Errorf("Table.ReadBlob: %w", column.NewUnknownColumnError(t.Name, col))
+	}
+	if c.DataType() != types.TypeBlob {
+		return 0, fmt.Errorf("Table.ReadBlob: %w", NewNotABlobError(t.Name, col))
+	}
+	item, err := t.index.Get(key)
+	if err != nil {
+		return 0, fmt.Errorf("Table.ReadBlob: %w", err)
+	}
+	p, err := t.readPage(item.PagePos)
+	if err != nil {
+		return 0, fmt.Errorf("Table.ReadBlob: %w", err)
+	}
+
+	// The page can contain older versions of the record too
+	for _, slot := range p.slots() {
+		if p.record(slot)[0] == types.TypeOverflowChunk {
+			continue
+		}
+		recordParser := parser.NewRecordParser(bytes.NewReader(p.record(slot)), t.columnNames)
+		recordParser.SetOverflowReader(t.readOverflow)
+		recordParser.KeepOverflowPointers(types.TypeBlob)
+		if err = recordParser.Parse(); err != nil {
+			return 0, fmt.Errorf("Table.ReadBlob: slot %d: %w", slot, err)
+		}
+		raw := recordParser.Value
+		if !mvcc.Latest.Visible(raw.Xmin, raw.Xmax) {
+			continue
+		}
+		recordKey, err := t.primaryKeyOf(raw.Record)
+		if err != nil {
+			return 0, fmt.Errorf("Table.ReadBlob: %w", err)
+		}
+		if recordKey.Compare(key) != 0 {
+			continue
+		}
+		n, err := t.writeBlob(raw.Record[col], w)
+		if err != nil {
+			return n, fmt.Errorf("Table.ReadBlob: %w", err)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("Table.ReadBlob: %w", index.NewItemNotFoundError(key))
+}
+
+// writeBlob writes a value parsed by a RecordParser that keeps the overflow pointers of blobs into w
+func (t *Table) writeBlob(v interface{}, w io.Writer) (int64, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		n, err := w.Write(val)
+		if err != nil {
+			return int64(n), fmt.Errorf("Table.writeBlob: %w", err)
+		}
+		return int64(n), nil
+	case *parser.OverflowPointer:
+		var written int64
+		err := t.walkOverflow(val, func(_ int64, _ int, chunk []byte) error {
+			n, err := w.Write(chunk[overflowChunkHeaderLen:])
+			written += int64(n)
+			return err
+		})
+		if err != nil {
+			return written, fmt.Errorf("Table.writeBlob: %w", err)
+		}
+		return written, nil
+	default:
+		return 0, fmt.Errorf("Table.writeBlob: unexpected value: %T", v)
+	}
+}
@@ -0,0 +1,330 @@
+package bufferpool
+
+import (
+	"container/list"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+const (
+	// DefaultSize is the memory budget of a pool in bytes
+	DefaultSize = 8 << 20
+	// MinSize is the smallest budget. It holds four pages of the largest page size
+	MinSize = 64 << 10
+)
+
+// PageID identifies a page by the file it belongs to and its position in the file
+type PageID struct {
+	File string
+	Pos  int64
+}
+
+// LoadFunc reads the content of a page that is not in the pool
+type LoadFunc func() ([]byte, error)
+
+// WriteFunc writes the content of a page back into its file
+type WriteFunc func(pos int64, data []byte) error
+
+// Log is the write-ahead log that describes the changes of the pages
+type Log interface {
+	// LSN returns the LSN of the last appended entry
+	LSN() int64
+	// SyncUpTo makes every entry up to lsn durable
+	SyncUpTo(lsn int64) error
+}
+
+// Pool keeps the pages of every table of a database in memory up to a budget in bytes. The least recently used page
+// is evicted when a new one doesn't fit
+//
+// A page is pinned by Fetch until Unpin is called and a pinned page is never evicted. Update replaces the content of
+// a pinned page and marks it dirty. Dirty pages are written back with the WriteFunc attached to their file when they
+// are evicted or flushed. The content returned by Fetch is never modified, so it can be read after Unpin
+//
+// If a log is set, a dirty page is only written back after the log is durable up to the last entry that was appended
+// when the page was updated. The entries that changed the page are among them, so the file never contains a change
+// that can be lost from the log
+//
+// It's safe for concurrent use. A page is loaded without holding the lock of the pool, so other pages can be fetched
+// in the meantime. Callers fetching the same page wait until it's loaded
+type Pool struct {
+	mu   sync.Mutex
+	size int
+	used int
+	// lru contains the frames from the least to the most recently used one
+	lru    *list.List
+	frames map[PageID]*list.Element
+	files  map[string]WriteFunc
+	log    Log
+	stats  Stats
+}
+
+type frame struct {
+	id    PageID
+	data  []byte
+	pins  int
+	dirty bool
+	// lsn is the LSN of the log when the page was last updated
+	lsn int64
+	// loaded is closed when the page is loaded. It's nil afterwards. err is the error of the load
+	loaded chan struct{}
+	err    error
+}
+
+// Stats describes the content of the pool and how it was used since it was created
+type Stats struct {
+	// Size is the budget and Used is the number of bytes of the pages in the pool
+	Size   int
+	Used   int
+	Pages  int
+	Dirty  int
+	Pinned int
+	Hits   int64
+	Misses int64
+	// Evictions counts the pages removed to make space for others and Writes counts the dirty pages written back
+	Evictions int64
+	Writes    int64
+}
+
+// HitRatio returns the share of fetches that found the page in the pool
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func New(size int) *Pool {
+	return &Pool{
+		size:   max(size, MinSize),
+		lru:    list.New(),
+		frames: make(map[PageID]*list.Element),
+		files:  make(map[string]WriteFunc),
+	}
+}
+
+// SetSize changes the budget and evicts pages until the pool fits into it
+func (p *Pool) SetSize(size int) error {
+	if size < MinSize {
+		return fmt.Errorf("Pool.SetSize: %w", NewInvalidSizeError(size))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = size
+	if err := p.evict(0); err != nil {
+		return fmt.Errorf("Pool.SetSize: %w", err)
+	}
+	return nil
+}
+
+// Attach sets the function that writes the dirty pages of file back. It replaces the one attached before
+func (p *Pool) Attach(file string, write WriteFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files[file] = write
+}
+
+// SetLog sets the log that has to be synced before a dirty page is written back
+func (p *Pool) SetLog(log Log) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.log = log
+}
+
+// Detach removes every page of file without writing them back and forgets its WriteFunc
+func (p *Pool) Detach(file string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.discard(file)
+	delete(p.files, file)
+}
+
+// Fetch pins the page and returns its content. load is called if the page is not in the pool
+// It reports true if the page was in the pool
+func (p *Pool) Fetch(id PageID, load LoadFunc) ([]byte, bool, error) {
+	p.mu.Lock()
+	if e, ok := p.frames[id]; ok {
+		f := e.Value.(*frame)
+		f.pins++
+		p.lru.MoveToBack(e)
+		p.stats.Hits++
+		loaded := f.loaded
+		p.mu.Unlock()
+		if loaded != nil {
+			<-loaded
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if f.err != nil {
+			return nil, false, fmt.Errorf("Pool.Fetch: %w", f.err)
+		}
+		return f.data, true, nil
+	}
+
+	// The frame is pinned while it's loaded, so it's not evicted
+	p.stats.Misses++
+	f := &frame{id: id, pins: 1, loaded: make(chan struct{})}
+	e := p.lru.PushBack(f)
+	p.frames[id] = e
+	p.mu.Unlock()
+
+	data, err := load()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer close(f.loaded)
+	if err == nil {
+		err = p.evict(len(data))
+	}
+	// The frame is gone if its file was discarded in the meantime
+	if curr, ok := p.frames[id]; !ok || curr != e {
+		if err != nil {
+			f.err = err
+			return nil, false, fmt.Errorf("Pool.Fetch: %w", err)
+		}
+		f.data, f.loaded = data, nil
+		return data, false, nil
+	}
+	if err != nil {
+		f.err = err
+		p.remove(e)
+		return nil, false, fmt.Errorf("Pool.Fetch: %w", err)
+	}
+	f.data, f.loaded = data, nil
+	p.used += len(data)
+	return data, false, nil
+}
+
+// Unpin releases a page pinned by Fetch
+func (p *Pool) Unpin(id PageID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.frames[id]; ok {
+		f := e.Value.(*frame)
+		f.pins = max(0, f.pins-1)
+	}
+}
+
+// Update replaces the content of a pinned page. dirty has to be true unless the caller already wrote data into the
+// file. data must not be modified afterwards
+func (p *Pool) Update(id PageID, data []byte, dirty bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.frames[id]
+	if !ok || e.Value.(*frame).pins == 0 {
+		return fmt.Errorf("Pool.Update: %w", NewPageNotPinnedError(id))
+	}
+	f := e.Value.(*frame)
+	p.used += len(data) - len(f.data)
+	f.data = data
+	f.dirty = f.dirty || dirty
+	if p.log != nil {
+		f.lsn = p.log.LSN()
+	}
+	return nil
+}
+
+// Flush writes the dirty pages of file back in the order of their positions
+func (p *Pool) Flush(file string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	dirty := make([]*frame, 0)
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		if f := e.Value.(*frame); f.id.File == file && f.dirty {
+			dirty = append(dirty, f)
+		}
+	}
+	slices.SortFunc(dirty, func(a, b *frame) int {
+		return int(a.id.Pos - b.id.Pos)
+	})
+	for _, f := range dirty {
+		if err := p.write(f); err != nil {
+			return fmt.Errorf("Pool.Flush: %w", err)
+		}
+	}
+	return nil
+}
+
+// Discard removes every page of file without writing them back
+func (p *Pool) Discard(file string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.discard(file)
+}
+
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.Size = p.size
+	s.Used = p.used
+	s.Pages = p.lru.Len()
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		f := e.Value.(*frame)
+		if f.dirty {
+			s.Dirty++
+		}
+		if f.pins > 0 {
+			s.Pinned++
+		}
+	}
+	return s
+}
+
+// evict removes the least recently used pages that are not pinned until n more bytes fit into the budget
+func (p *Pool) evict(n int) error {
+	e := p.lru.Front()
+	for p.used+n > p.size {
+		if e == nil {
+			return NewPoolFullError(p.size)
+		}
+		next := e.Next()
+		f := e.Value.(*frame)
+		if f.pins == 0 {
+			if err := p.write(f); err != nil {
+				return fmt.Errorf("Pool.evict: %w", err)
+			}
+			p.remove(e)
+			p.stats.Evictions++
+		}
+		e = next
+	}
+	return nil
+}
+
+// write writes a dirty page back into its file after syncing the log up to the LSN of the page
+func (p *Pool) write(f *frame) error {
+	if !f.dirty {
+		return nil
+	}
+	write, ok := p.files[f.id.File]
+	if !ok {
+		return fmt.Errorf("Pool.write: %w", NewFileNotAttachedError(f.id.File))
+	}
+	if p.log != nil {
+		if err := p.log.SyncUpTo(f.lsn); err != nil {
+			return fmt.Errorf("Pool.write: %w", err)
+		}
+	}
+	if err := write(f.id.Pos, f.data); err != nil {
+		return fmt.Errorf("Pool.write: %w", err)
+	}
+	f.dirty = false
+	p.stats.Writes++
+	return nil
+}
+
+func (p *Pool) discard(file string) {
+	for e := p.lru.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(*frame).id.File == file {
+			p.remove(e)
+		}
+		e = next
+	}
+}
+
+func (p *Pool) remove(e *list.Element) {
+	f := p.lru.Remove(e).(*frame)
+	delete(p.frames, f.id)
+	p.used -= len(f.data)
+}
@@ -0,0 +1,227 @@
+package bufferpool
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pageSize = MinSize / 4
+
+// fakeFile stores the pages written back by a pool and counts how many times a page was loaded
+type fakeFile struct {
+	pages map[int64][]byte
+	loads int
+}
+
+func newFakeFile() *fakeFile {
+	return &fakeFile{pages: make(map[int64][]byte)}
+}
+
+func (f *fakeFile) load(pos int64) LoadFunc {
+	return func() ([]byte, error) {
+		f.loads++
+		if b, ok := f.pages[pos]; ok {
+			return b, nil
+		}
+		return make([]byte, pageSize), nil
+	}
+}
+
+func (f *fakeFile) write(pos int64, data []byte) error {
+	f.pages[pos] = data
+	return nil
+}
+
+func fetch(t *testing.T, p *Pool, f *fakeFile, pos int64) ([]byte, bool) {
+	data, hit, err := p.Fetch(PageID{File: "users", Pos: pos}, f.load(pos))
+	assert.Nil(t, err)
+	p.Unpin(PageID{File: "users", Pos: pos})
+	return data, hit
+}
+
+func TestPool_HitsAndEvictions(t *testing.T) {
+	p := New(MinSize)
+	f := newFakeFile()
+	p.Attach("users", f.write)
+
+	for pos := range int64(4) {
+		_, hit := fetch(t, p, f, pos)
+		assert.False(t, hit)
+	}
+	_, hit := fetch(t, p, f, 0)
+	assert.True(t, hit)
+
+	// Page 1 is the least recently used one, so it's evicted for page 4
+	_, hit = fetch(t, p, f, 4)
+	assert.False(t, hit)
+	_, hit = fetch(t, p, f, 0)
+	assert.True(t, hit)
+	_, hit = fetch(t, p, f, 1)
+	assert.False(t, hit)
+
+	s := p.Stats()
+	assert.Equal(t, 4, s.Pages)
+	assert.Equal(t, MinSize, s.Used)
+	assert.Equal(t, int64(2), s.Hits)
+	assert.Equal(t, int64(6), s.Misses)
+	assert.Equal(t, 6, f.loads)
+	assert.Equal(t, int64(2), s.Evictions)
+	assert.Equal(t, 0.25, s.HitRatio())
+}
+
+func TestPool_DirtyPages(t *testing.T) {
+	p := New(MinSize)
+	f := newFakeFile()
+	p.Attach("users", f.write)
+
+	id := PageID{File: "users", Pos: 0}
+	assert.NotNil(t, p.Update(id, nil, true), "the page is not pinned")
+	_, _, err := p.Fetch(id, f.load(0))
+	assert.Nil(t, err)
+	updated := bytes.Repeat([]byte{1}, pageSize)
+	assert.Nil(t, p.Update(id, updated, true))
+	p.Unpin(id)
+	assert.Equal(t, 1, p.Stats().Dirty)
+
+	// The dirty page is written back when it's evicted
+	for pos := range int64(4) {
+		fetch(t, p, f, pos+1)
+	}
+	assert.Equal(t, updated, f.pages[0])
+	assert.Equal(t, int64(1), p.Stats().Writes)
+	data, hit := fetch(t, p, f, 0)
+	assert.False(t, hit)
+	assert.Equal(t, updated, data)
+
+	// Flush writes the dirty pages without removing them
+	_, _, err = p.Fetch(id, f.load(0))
+	assert.Nil(t, err)
+	assert.Nil(t, p.Update(id, bytes.Repeat([]byte{2}, pageSize), true))
+	p.Unpin(id)
+	assert.Nil(t, p.Flush("users"))
+	assert.Equal(t, bytes.Repeat([]byte{2}, pageSize), f.pages[0])
+	assert.Equal(t, 0, p.Stats().Dirty)
+	_, hit = fetch(t, p, f, 0)
+	assert.True(t, hit)
+
+	p.Discard("users")
+	assert.Equal(t, 0, p.Stats().Pages)
+}
+
+func TestPool_PinnedPages(t *testing.T) {
+	p := New(MinSize)
+	f := newFakeFile()
+	p.Attach("users", f.write)
+
+	for pos := range int64(4) {
+		_, _, err := p.Fetch(PageID{File: "users", Pos: pos}, f.load(pos))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 4, p.Stats().Pinned)
+	_, _, err := p.Fetch(PageID{File: "users", Pos: 4}, f.load(4))
+	var errFull *PoolFullError
+	assert.ErrorAs(t, err, &errFull)
+
+	// Only the unpinned page can be evicted
+	p.Unpin(PageID{File: "users", Pos: 2})
+	_, hit := fetch(t, p, f, 4)
+	assert.False(t, hit)
+	for _, pos := range []int64{0, 1, 3} {
+		_, hit = fetch(t, p, f, pos)
+		assert.True(t, hit)
+	}
+
+	assert.NotNil(t, p.SetSize(MinSize-1))
+	assert.Nil(t, p.SetSize(2*MinSize))
+	assert.Equal(t, 2*MinSize, p.Stats().Size)
+}
+
+// fakeLog is a log whose entries up to synced are durable
+type fakeLog struct {
+	lsn    int64
+	synced int64
+}
+
+func (l *fakeLog) LSN() int64 {
+	return l.lsn
+}
+
+func (l *fakeLog) SyncUpTo(lsn int64) error {
+	l.synced = max(l.synced, lsn)
+	return nil
+}
+
+func TestPool_SyncsLogBeforeWrite(t *testing.T) {
+	p := New(MinSize)
+	log := &fakeLog{lsn: 7}
+	p.SetLog(log)
+	f := newFakeFile()
+	synced := make(map[int64]int64)
+	p.Attach("users", func(pos int64, data []byte) error {
+		synced[pos] = log.synced
+		return f.write(pos, data)
+	})
+
+	id := PageID{File: "users", Pos: 0}
+	_, _, err := p.Fetch(id, f.load(0))
+	assert.Nil(t, err)
+	assert.Nil(t, p.Update(id, bytes.Repeat([]byte{1}, pageSize), true))
+	p.Unpin(id)
+	log.lsn = 9
+
+	// The log is synced up to the LSN of the update before the page is evicted
+	for pos := range int64(4) {
+		fetch(t, p, f, pos+1)
+	}
+	assert.Equal(t, int64(7), synced[0])
+	assert.Equal(t, int64(7), log.synced)
+}
+
+func TestPool_LoadsOutsideTheLock(t *testing.T) {
+	p := New(MinSize)
+	f := newFakeFile()
+	p.Attach("users", f.write)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	loads := 0
+	slowLoad := func() ([]byte, error) {
+		loads++
+		close(started)
+		<-release
+		return bytes.Repeat([]byte{1}, pageSize), nil
+	}
+
+	id := PageID{File: "users", Pos: 0}
+	wg := sync.WaitGroup{}
+	results := make([][]byte, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i == 1 {
+				<-started
+			}
+			data, _, err := p.Fetch(id, slowLoad)
+			assert.Nil(t, err)
+			p.Unpin(id)
+			results[i] = data
+		}()
+	}
+	<-started
+
+	// Another page can be fetched while the first one is loaded
+	_, hit := fetch(t, p, f, 1)
+	assert.False(t, hit)
+	close(release)
+	wg.Wait()
+
+	// The page was loaded once and both callers got its content
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, bytes.Repeat([]byte{1}, pageSize), results[0])
+	assert.Equal(t, results[0], results[1])
+	assert.Equal(t, 2, p.Stats().Pages)
+	assert.Equal(t, 2*pageSize, p.Stats().Used)
+}
@@ -0,0 +1,51 @@
+package bufferpool
+
+import "fmt"
+
+type PoolFullError struct {
+	size int
+}
+
+func NewPoolFullError(size int) *PoolFullError {
+	return &PoolFullError{size: size}
+}
+
+func (e *PoolFullError) Error() string {
+	return fmt.Sprintf("every page of the buffer pool of %d bytes is pinned", e.size)
+}
+
+type PageNotPinnedError struct {
+	id PageID
+}
+
+func NewPageNotPinnedError(id PageID) *PageNotPinnedError {
+	return &PageNotPinnedError{id: id}
+}
+
+func (e *PageNotPinnedError) Error() string {
+	return fmt.Sprintf("page %d of %s is not pinned", e.id.Pos, e.id.File)
+}
+
+type FileNotAttachedError struct {
+	file string
+}
+
+func NewFileNotAttachedError(file string) *FileNotAttachedError {
+	return &FileNotAttachedError{file: file}
+}
+
+func (e *FileNotAttachedError) Error() string {
+	return fmt.Sprintf("no function is attached to write the pages of %s", e.file)
+}
+
+type InvalidSizeError struct {
+	size int
+}
+
+func NewInvalidSizeError(size int) *InvalidSizeError {
+	return &InvalidSizeError{size: size}
+}
+
+func (e *InvalidSizeError) Error() string {
+	return fmt.Sprintf("invalid buffer pool size %d: it has to be at least %d bytes", e.size, MinSize)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	platformbytes "github.com/omesh-barhate/ByteForge/internal/platform/bytes"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 	columnencoding "github.com/omesh-barhate/ByteForge/internal/table/column/encoding"
 )
 
@@ -21,6 +22,24 @@ func New(name string, dataType byte, opts Opts) (*Column, error) {
 	if len(name) > int(NameLength) {
 		return nil, fmt.Errorf("New: %w", NewNameTooLongError(int(NameLength), len(name)))
 	}
+	if opts.PrimaryKey && opts.AllowNull {
+		return nil, fmt.Errorf("New: %w", NewNullablePrimaryKeyError(name))
+	}
+	if opts.AutoIncrement && dataType != types.TypeInt64 && dataType != types.TypeInt32 && dataType != types.TypeByte {
+		return nil, fmt.Errorf("New: %w", NewAutoIncrementTypeError(name))
+	}
+	if dataType == types.TypeDecimal && (opts.Precision < 1 || opts.Precision > types.MaxDecimalPrecision || opts.Scale < 0 || opts.Scale > opts.Precision) {
+		return nil, fmt.Errorf("New: %w", NewDecimalPrecisionError(name, opts.Precision, opts.Scale))
+	}
+	if dataType == types.TypeBlob && (opts.Unique || opts.FullTextIdx) {
+		return nil, fmt.Errorf("New: %w", NewBlobIndexError(name))
+	}
+	if dataType == types.TypeJSON && (opts.PrimaryKey || opts.Unique || opts.FullTextIdx) {
+		return nil, fmt.Errorf("New: %w", NewJSONIndexError(name))
+	}
+	if dataType != types.TypeDecimal {
+		opts.Precision, opts.Scale = 0, 0
+	}
 	col := &Column{
 		dataType: dataType,
 		Opts:     opts,
@@ -32,6 +51,16 @@ func New(name string, dataType byte, opts Opts) (*Column, error) {
 type Opts struct {
 	AllowNull   bool
 	FullTextIdx bool
+	// PrimaryKey columns are part of the primary key of the table and cannot be NULL
+	PrimaryKey bool
+	// Unique columns cannot contain the same value twice. Any number of records can be NULL
+	Unique bool
+	// AutoIncrement columns get the next value of the sequence of the column if they are inserted without a value
+	AutoIncrement bool
+	// Precision is the maximum number of digits of a DECIMAL column and Scale is the number of digits after the
+	// decimal point. Both are ignored for other types
+	Precision int
+	Scale     int
 }
 
 func NewColumnOpts(allowNull bool, fullTextIdx bool) Opts {
@@ -42,12 +71,11 @@ func NewColumnOpts(allowNull bool, fullTextIdx bool) Opts {
 }
 
 func (c *Column) MarshalBinary() ([]byte, error) {
-	marshaler := columnencoding.NewColumnDefinitionMarshaler(c.name, c.dataType, c.Opts.AllowNull, c.Opts.FullTextIdx)
-	return marshaler.MarshalBinary()
+	return c.marshaler().MarshalBinary()
 }
 
 func (c *Column) UnmarshalBinary(data []byte) error {
-	marshaler := columnencoding.NewColumnDefinitionMarshaler(c.name, c.dataType, c.Opts.AllowNull, c.Opts.FullTextIdx)
+	marshaler := c.marshaler()
 	if err := marshaler.UnmarshalBinary(data); err != nil {
 		return fmt.Errorf("Column.UnmarshalBinary: %w", err)
 	}
@@ -55,9 +83,28 @@ func (c *Column) UnmarshalBinary(data []byte) error {
 	c.dataType = marshaler.DataType
 	c.Opts.AllowNull = marshaler.AllowNull
 	c.Opts.FullTextIdx = marshaler.FullTextIdx
+	c.Opts.PrimaryKey = marshaler.PrimaryKey
+	c.Opts.Unique = marshaler.Unique
+	c.Opts.AutoIncrement = marshaler.AutoIncrement
+	c.Opts.Precision = int(marshaler.Precision)
+	c.Opts.Scale = int(marshaler.Scale)
 	return nil
 }
 
+func (c *Column) marshaler() *columnencoding.ColumnDefinitionMarshaler {
+	return columnencoding.NewColumnDefinitionMarshaler(c.name, c.dataType, c.Opts.AllowNull, c.Opts.FullTextIdx, c.Opts.PrimaryKey, c.Opts.Unique, c.Opts.AutoIncrement, byte(c.Opts.Precision), byte(c.Opts.Scale))
+}
+
+// IsUnique reports whether two records can't have the same value in the column
+// A primary key column alone is not unique if the primary key has more than one column
+func (c *Column) IsUnique() bool {
+	return c.Opts.Unique
+}
+
+func (c *Column) DataType() byte {
+	return c.dataType
+}
+
 func (c *Column) NameToStr() string {
 	trimmed := platformbytes.TrimZeroBytes(c.name[:])
 	str := ""
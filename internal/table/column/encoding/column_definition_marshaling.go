@@ -10,18 +10,29 @@ import (
 )
 
 type ColumnDefinitionMarshaler struct {
-	Name        [64]byte
-	DataType    byte
-	AllowNull   bool
-	FullTextIdx bool
+	Name          [64]byte
+	DataType      byte
+	AllowNull     bool
+	FullTextIdx   bool
+	PrimaryKey    bool
+	Unique        bool
+	AutoIncrement bool
+	// Precision and Scale are the number of digits of DECIMAL columns and 0 for other types
+	Precision byte
+	Scale     byte
 }
 
-func NewColumnDefinitionMarshaler(name [64]byte, dataType byte, allowNull bool, fullTextIdx bool) *ColumnDefinitionMarshaler {
+func NewColumnDefinitionMarshaler(name [64]byte, dataType byte, allowNull, fullTextIdx, primaryKey, unique, autoIncrement bool, precision, scale byte) *ColumnDefinitionMarshaler {
 	return &ColumnDefinitionMarshaler{
-		Name:        name,
-		DataType:    dataType,
-		AllowNull:   allowNull,
-		FullTextIdx: fullTextIdx,
+		Name:          name,
+		DataType:      dataType,
+		AllowNull:     allowNull,
+		FullTextIdx:   fullTextIdx,
+		PrimaryKey:    primaryKey,
+		Unique:        unique,
+		AutoIncrement: autoIncrement,
+		Precision:     precision,
+		Scale:         scale,
 	}
 }
 
@@ -37,7 +48,22 @@ func (c *ColumnDefinitionMarshaler) Size() uint32 {
 		uint32(binary.Size(c.AllowNull)) + // value
 		types.LenByte + // type
 		types.LenInt32 + // len
-		uint32(binary.Size(c.AllowNull)) // value
+		uint32(binary.Size(c.AllowNull)) + // value
+		types.LenByte + // type
+		types.LenInt32 + // len
+		uint32(binary.Size(c.PrimaryKey)) + // value
+		types.LenByte + // type
+		types.LenInt32 + // len
+		uint32(binary.Size(c.Unique)) + // value
+		types.LenByte + // type
+		types.LenInt32 + // len
+		uint32(binary.Size(c.AutoIncrement)) + // value
+		types.LenByte + // type
+		types.LenInt32 + // len
+		uint32(binary.Size(c.Precision)) + // value
+		types.LenByte + // type
+		types.LenInt32 + // len
+		uint32(binary.Size(c.Scale)) // value
 }
 
 func (c *ColumnDefinitionMarshaler) MarshalBinary() ([]byte, error) {
@@ -81,6 +107,41 @@ func (c *ColumnDefinitionMarshaler) MarshalBinary() ([]byte, error) {
 	}
 	buf.Write(b)
 
+	primaryKey := encoding.NewTLVMarshaler(c.PrimaryKey)
+	b, err = primaryKey.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("ColumnDefinitionMarshaler.MarshalBinary: primary key: %w", err)
+	}
+	buf.Write(b)
+
+	unique := encoding.NewTLVMarshaler(c.Unique)
+	b, err = unique.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("ColumnDefinitionMarshaler.MarshalBinary: unique: %w", err)
+	}
+	buf.Write(b)
+
+	autoIncrement := encoding.NewTLVMarshaler(c.AutoIncrement)
+	b, err = autoIncrement.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("ColumnDefinitionMarshaler.MarshalBinary: auto increment: %w", err)
+	}
+	buf.Write(b)
+
+	precision := encoding.NewTLVMarshaler(c.Precision)
+	b, err = precision.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("ColumnDefinitionMarshaler.MarshalBinary: precision: %w", err)
+	}
+	buf.Write(b)
+
+	scale := encoding.NewTLVMarshaler(c.Scale)
+	b, err = scale.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("ColumnDefinitionMarshaler.MarshalBinary: scale: %w", err)
+	}
+	buf.Write(b)
+
 	return buf.Bytes(), nil
 }
 
@@ -107,6 +168,7 @@ func (c *ColumnDefinitionMarshaler) UnmarshalBinary(data []byte) error {
 		return fmt.Errorf("ColumnDefinitionMarshaler.UnmarshalBinary: len: %w", err)
 	}
 	n += 4
+	end := n + intUnmarshaler.Value
 
 	// unmarshal name
 	nameTLV := encoding.NewTLVUnmarshaler[string](strUnmarshaler)
@@ -140,9 +202,59 @@ func (c *ColumnDefinitionMarshaler) UnmarshalBinary(data []byte) error {
 	fullText := fullTextTLV.Value
 	n += fullTextTLV.BytesRead
 
+	// Tables created before primary key and unique columns existed don't have these fields
+	var primaryKey, unique byte
+	if n < end {
+		primaryKeyTLV := encoding.NewTLVUnmarshaler[byte](byteUnmarshaler)
+		if err := primaryKeyTLV.UnmarshalBinary(data[n:]); err != nil {
+			return fmt.Errorf("ColumnDefinitionMarshaler.UnmarshalBinary: primary key: %w", err)
+		}
+		primaryKey = primaryKeyTLV.Value
+		n += primaryKeyTLV.BytesRead
+
+		uniqueTLV := encoding.NewTLVUnmarshaler[byte](byteUnmarshaler)
+		if err := uniqueTLV.UnmarshalBinary(data[n:]); err != nil {
+			return fmt.Errorf("ColumnDefinitionMarshaler.UnmarshalBinary: unique: %w", err)
+		}
+		unique = uniqueTLV.Value
+		n += uniqueTLV.BytesRead
+	}
+	// Tables created before auto-increment columns existed don't have this field
+	var autoIncrement byte
+	if n < end {
+		autoIncrementTLV := encoding.NewTLVUnmarshaler[byte](byteUnmarshaler)
+		if err := autoIncrementTLV.UnmarshalBinary(data[n:]); err != nil {
+			return fmt.Errorf("ColumnDefinitionMarshaler.UnmarshalBinary: auto increment: %w", err)
+		}
+		autoIncrement = autoIncrementTLV.Value
+		n += autoIncrementTLV.BytesRead
+	}
+	// Tables created before DECIMAL columns existed don't have these fields
+	var precision, scale byte
+	if n < end {
+		precisionTLV := encoding.NewTLVUnmarshaler[byte](byteUnmarshaler)
+		if err := precisionTLV.UnmarshalBinary(data[n:]); err != nil {
+			return fmt.Errorf("ColumnDefinitionMarshaler.UnmarshalBinary: precision: %w", err)
+		}
+		precision = precisionTLV.Value
+		n += precisionTLV.BytesRead
+
+		scaleTLV := encoding.NewTLVUnmarshaler[byte](byteUnmarshaler)
+		if err := scaleTLV.UnmarshalBinary(data[n:]); err != nil {
+			return fmt.Errorf("ColumnDefinitionMarshaler.UnmarshalBinary: scale: %w", err)
+		}
+		scale = scaleTLV.Value
+		n += scaleTLV.BytesRead
+	}
+
 	copy(c.Name[:], name)
 	c.DataType = dataTypeVal
 	c.AllowNull = allowNull != 0
 	c.FullTextIdx = fullText != 0
+	c.PrimaryKey = primaryKey != 0
+	c.Unique = unique != 0
+	c.AutoIncrement = autoIncrement != 0
+	c.Precision = precision
+	c.Scale = scale
 	return nil
 }
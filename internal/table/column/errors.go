@@ -1,6 +1,10 @@
 package column
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
 
 type NameTooLongError struct {
 	maxLength    int
@@ -52,3 +56,65 @@ func NewCannotBeNullError(column string) *CannotBeNullError {
 func (e *CannotBeNullError) Error() string {
 	return fmt.Sprintf("column %s cannot be null", e.column)
 }
+
+type NullablePrimaryKeyError struct {
+	column string
+}
+
+func NewNullablePrimaryKeyError(column string) *NullablePrimaryKeyError {
+	return &NullablePrimaryKeyError{column: column}
+}
+
+func (e *NullablePrimaryKeyError) Error() string {
+	return fmt.Sprintf("primary key column %s cannot allow null", e.column)
+}
+
+type AutoIncrementTypeError struct {
+	column string
+}
+
+func NewAutoIncrementTypeError(column string) *AutoIncrementTypeError {
+	return &AutoIncrementTypeError{column: column}
+}
+
+func (e *AutoIncrementTypeError) Error() string {
+	return fmt.Sprintf("auto-increment column %s must be an integer", e.column)
+}
+
+type DecimalPrecisionError struct {
+	column    string
+	precision int
+	scale     int
+}
+
+func NewDecimalPrecisionError(column string, precision, scale int) *DecimalPrecisionError {
+	return &DecimalPrecisionError{column: column, precision: precision, scale: scale}
+}
+
+func (e *DecimalPrecisionError) Error() string {
+	return fmt.Sprintf("DECIMAL(%d, %d) column %s: precision must be between 1 and %d and scale between 0 and the precision", e.precision, e.scale, e.column, types.MaxDecimalPrecision)
+}
+
+type BlobIndexError struct {
+	column string
+}
+
+func NewBlobIndexError(column string) *BlobIndexError {
+	return &BlobIndexError{column: column}
+}
+
+func (e *BlobIndexError) Error() string {
+	return fmt.Sprintf("BLOB column %s cannot be indexed", e.column)
+}
+
+type JSONIndexError struct {
+	column string
+}
+
+func NewJSONIndexError(column string) *JSONIndexError {
+	return &JSONIndexError{column: column}
+}
+
+func (e *JSONIndexError) Error() string {
+	return fmt.Sprintf("JSON column %s cannot be indexed or be part of the primary key", e.column)
+}
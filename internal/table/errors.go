@@ -14,28 +14,227 @@ func (e *InvalidFilename) Error() string {
 	return fmt.Sprintf("invalid filename: %s", e.filename)
 }
 
-type PageNotFoundError struct {
-	pages []int64
-	pos   int64
+// ChangeConflictError means the slot of a WAL change contains something else than the change expects
+type ChangeConflictError struct {
+	op      string
+	pagePos int64
+	slot    int64
 }
 
-func NewPageNotFoundError(pages []int64, pos int64) *PageNotFoundError {
-	return &PageNotFoundError{pages: pages, pos: pos}
+func NewChangeConflictError(op string, pagePos, slot int64) *ChangeConflictError {
+	return &ChangeConflictError{op: op, pagePos: pagePos, slot: slot}
 }
 
-func (e *PageNotFoundError) Error() string {
-	return fmt.Sprintf("page not found: pages: %v, pos: %d", e.pages, e.pos)
+func (e *ChangeConflictError) Error() string {
+	return fmt.Sprintf("unable to apply %s change: unexpected data in slot %d of the page at %d", e.op, e.slot, e.pagePos)
 }
 
-type PageNotEmptyError struct {
-	pos    int64
-	length uint32
+type IndexAlreadyExistsError struct {
+	table  string
+	column string
 }
 
-func NewPageNotEmptyError(pos int64, length uint32) *PageNotEmptyError {
-	return &PageNotEmptyError{pos: pos, length: length}
+func NewIndexAlreadyExistsError(table, column string) *IndexAlreadyExistsError {
+	return &IndexAlreadyExistsError{table: table, column: column}
 }
 
-func (e *PageNotEmptyError) Error() string {
-	return fmt.Sprintf("page is not empty: pos: %d, length: %d", e.pos, e.length)
+func (e *IndexAlreadyExistsError) Error() string {
+	return fmt.Sprintf("table %s already has an index on column %s", e.table, e.column)
+}
+
+type IndexDoesNotExistError struct {
+	table  string
+	column string
+}
+
+func NewIndexDoesNotExistError(table, column string) *IndexDoesNotExistError {
+	return &IndexDoesNotExistError{table: table, column: column}
+}
+
+func (e *IndexDoesNotExistError) Error() string {
+	return fmt.Sprintf("table %s has no index on column %s", e.table, e.column)
+}
+
+type DuplicateKeyError struct {
+	table  string
+	column string
+	value  interface{}
+}
+
+func NewDuplicateKeyError(table, column string, value interface{}) *DuplicateKeyError {
+	return &DuplicateKeyError{table: table, column: column, value: value}
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key: table %s already has a record with %s = %v", e.table, e.column, e.value)
+}
+
+type InvalidPrimaryKeyError struct {
+	table  string
+	reason string
+}
+
+func NewInvalidPrimaryKeyError(table, reason string) *InvalidPrimaryKeyError {
+	return &InvalidPrimaryKeyError{table: table, reason: reason}
+}
+
+func (e *InvalidPrimaryKeyError) Error() string {
+	return fmt.Sprintf("invalid primary key in table %s: %s", e.table, e.reason)
+}
+
+type MultipleAutoIncrementColumnsError struct {
+	table string
+}
+
+func NewMultipleAutoIncrementColumnsError(table string) *MultipleAutoIncrementColumnsError {
+	return &MultipleAutoIncrementColumnsError{table: table}
+}
+
+func (e *MultipleAutoIncrementColumnsError) Error() string {
+	return fmt.Sprintf("table %s can only have one auto-increment column", e.table)
+}
+
+type NoAutoIncrementColumnError struct {
+	table string
+}
+
+func NewNoAutoIncrementColumnError(table string) *NoAutoIncrementColumnError {
+	return &NoAutoIncrementColumnError{table: table}
+}
+
+func (e *NoAutoIncrementColumnError) Error() string {
+	return fmt.Sprintf("table %s has no auto-increment column", e.table)
+}
+
+type SequenceNotSetError struct {
+	table  string
+	column string
+}
+
+func NewSequenceNotSetError(table, column string) *SequenceNotSetError {
+	return &SequenceNotSetError{table: table, column: column}
+}
+
+func (e *SequenceNotSetError) Error() string {
+	return fmt.Sprintf("auto-increment column %s in table %s has no sequence", e.column, e.table)
+}
+
+// AutoIncrementOutOfRangeError means the next value of a sequence doesn't fit into the type of its auto-increment
+// column
+type AutoIncrementOutOfRangeError struct {
+	table  string
+	column string
+	value  int64
+}
+
+func NewAutoIncrementOutOfRangeError(table, column string, value int64) *AutoIncrementOutOfRangeError {
+	return &AutoIncrementOutOfRangeError{table: table, column: column, value: value}
+}
+
+func (e *AutoIncrementOutOfRangeError) Error() string {
+	return fmt.Sprintf("auto-increment value out of range: %d for column %s in table %s", e.value, e.column, e.table)
+}
+
+// ChecksumMismatchError means a page was not written completely or it was modified outside of the database
+type ChecksumMismatchError struct {
+	pos int64
+}
+
+func NewChecksumMismatchError(pos int64) *ChecksumMismatchError {
+	return &ChecksumMismatchError{pos: pos}
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch: the page at %d is corrupt", e.pos)
+}
+
+type InvalidPageSizeError struct {
+	size int
+}
+
+func NewInvalidPageSizeError(size int) *InvalidPageSizeError {
+	return &InvalidPageSizeError{size: size}
+}
+
+func (e *InvalidPageSizeError) Error() string {
+	return fmt.Sprintf("invalid page size: %d. It has to be one of %v", e.size, PageSizes)
+}
+
+type RecordTooLargeError struct {
+	table string
+	size  int
+	max   int
+}
+
+func NewRecordTooLargeError(table string, size, max int) *RecordTooLargeError {
+	return &RecordTooLargeError{table: table, size: size, max: max}
+}
+
+func (e *RecordTooLargeError) Error() string {
+	return fmt.Sprintf("record of %d bytes doesn't fit into a page of table %s. The limit is %d bytes", e.size, e.table, e.max)
+}
+
+type OverflowChunkNotFoundError struct {
+	pagePos int64
+	slot    int
+}
+
+func NewOverflowChunkNotFoundError(pagePos int64, slot int) *OverflowChunkNotFoundError {
+	return &OverflowChunkNotFoundError{pagePos: pagePos, slot: slot}
+}
+
+func (e *OverflowChunkNotFoundError) Error() string {
+	return fmt.Sprintf("overflow chunk expected in slot %d of the page at %d", e.slot, e.pagePos)
+}
+
+// InvalidDecimalError means a value cannot be stored in a DECIMAL column without rounding it
+type InvalidDecimalError struct {
+	column    string
+	value     interface{}
+	precision int
+	scale     int
+	err       error
+}
+
+func NewInvalidDecimalError(column string, value interface{}, precision, scale int, err error) *InvalidDecimalError {
+	return &InvalidDecimalError{column: column, value: value, precision: precision, scale: scale, err: err}
+}
+
+func (e *InvalidDecimalError) Error() string {
+	return fmt.Sprintf("value %v cannot be stored in DECIMAL(%d, %d) column %s: %s", e.value, e.precision, e.scale, e.column, e.err)
+}
+
+func (e *InvalidDecimalError) Unwrap() error {
+	return e.err
+}
+
+// InvalidJSONError means a value of a JSON column is not a valid JSON document
+type InvalidJSONError struct {
+	column string
+	err    error
+}
+
+func NewInvalidJSONError(column string, err error) *InvalidJSONError {
+	return &InvalidJSONError{column: column, err: err}
+}
+
+func (e *InvalidJSONError) Error() string {
+	return fmt.Sprintf("invalid JSON for column %s: %s", e.column, e.err)
+}
+
+func (e *InvalidJSONError) Unwrap() error {
+	return e.err
+}
+
+type NotABlobError struct {
+	table  string
+	column string
+}
+
+func NewNotABlobError(table, column string) *NotABlobError {
+	return &NotABlobError{table: table, column: column}
+}
+
+func (e *NotABlobError) Error() string {
+	return fmt.Sprintf("column %s of table %s is not a BLOB", e.column, e.table)
 }
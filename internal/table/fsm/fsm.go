@@ -0,0 +1,170 @@
+package fsm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/google/btree"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+const (
+	FileExtension = ".fsm"
+	// Categories is the number of ranges the free space of a page is rounded down to
+	Categories = 256
+)
+
+// FreeSpaceMap stores how much free space the pages of a table have, so an insert can find a page without reading
+// the others. The free space is stored as a category: a page in category c has at least c/Categories of the page size
+// free. Pages are kept in one B-tree per category, so Find checks at most Categories trees
+//
+// The file contains one byte per page:
+//
+//	253 len [category byte]...
+//
+// It's a hint and it's not logged in the WAL. Callers have to check that the page really has enough space
+// The table guards it with its own lock, so it's not safe for concurrent use
+type FreeSpaceMap struct {
+	file       *os.File
+	pageSize   int
+	categories []byte
+	pages      [Categories]*btree.BTreeG[int64]
+}
+
+func New(f *os.File, pageSize int) *FreeSpaceMap {
+	m := &FreeSpaceMap{
+		file:     f,
+		pageSize: pageSize,
+	}
+	for c := range m.pages {
+		m.pages[c] = btree.NewOrderedG[int64](2)
+	}
+	return m
+}
+
+// SetPageSize sets the size of the pages of the table. It has to be called before the map is loaded
+func (m *FreeSpaceMap) SetPageSize(size int) {
+	m.pageSize = size
+}
+
+// Load reads the categories from the file. An empty file means the table has no pages yet
+func (m *FreeSpaceMap) Load() error {
+	stat, err := m.file.Stat()
+	if err != nil {
+		return fmt.Errorf("FreeSpaceMap.Load: %w", err)
+	}
+	if stat.Size() == 0 {
+		return nil
+	}
+	b := make([]byte, stat.Size())
+	if _, err = m.file.ReadAt(b, 0); err != nil {
+		return fmt.Errorf("FreeSpaceMap.Load: %w", err)
+	}
+	if len(b) < int(types.LenMeta) || b[0] != types.TypeFreeSpaceMap {
+		return fmt.Errorf("FreeSpaceMap.Load: expected type flag %d", types.TypeFreeSpaceMap)
+	}
+	length := binary.LittleEndian.Uint32(b[types.LenByte:])
+	if int(types.LenMeta+length) > len(b) {
+		return fmt.Errorf("FreeSpaceMap.Load: unexpected end of file")
+	}
+	m.categories = make([]byte, 0, length)
+	for pageNo, c := range b[types.LenMeta : types.LenMeta+length] {
+		m.categories = append(m.categories, c)
+		m.pages[c].ReplaceOrInsert(int64(pageNo))
+	}
+	return nil
+}
+
+// Len returns the number of pages in the map
+func (m *FreeSpaceMap) Len() int {
+	return len(m.categories)
+}
+
+// Find returns the page with the lowest number in the fullest category that has at least n bytes free
+// It reports false if no page has enough space
+func (m *FreeSpaceMap) Find(n int) (int64, bool) {
+	for c := m.minCategory(n); c < Categories; c++ {
+		if pageNo, ok := m.pages[c].Min(); ok {
+			return pageNo, true
+		}
+	}
+	return 0, false
+}
+
+// Set stores how many bytes are free in a page and writes its category into the file if it changed
+// Pages between the last one and pageNo are added as full pages
+func (m *FreeSpaceMap) Set(pageNo int64, free int) error {
+	c := m.category(free)
+	if pageNo < int64(len(m.categories)) && m.categories[pageNo] == c {
+		return nil
+	}
+	grown := pageNo >= int64(len(m.categories))
+	for int64(len(m.categories)) <= pageNo {
+		m.pages[0].ReplaceOrInsert(int64(len(m.categories)))
+		m.categories = append(m.categories, 0)
+	}
+	m.pages[m.categories[pageNo]].Delete(pageNo)
+	m.pages[c].ReplaceOrInsert(pageNo)
+	m.categories[pageNo] = c
+
+	if grown {
+		if err := m.persist(); err != nil {
+			return fmt.Errorf("FreeSpaceMap.Set: %w", err)
+		}
+		return nil
+	}
+	if _, err := m.file.WriteAt([]byte{c}, int64(types.LenMeta)+pageNo); err != nil {
+		return fmt.Errorf("FreeSpaceMap.Set: %w", err)
+	}
+	return nil
+}
+
+// Reset replaces the map with the free space of every page of the table
+func (m *FreeSpaceMap) Reset(free []int) error {
+	m.categories = make([]byte, 0, len(free))
+	for c := range m.pages {
+		m.pages[c].Clear(false)
+	}
+	for pageNo, n := range free {
+		c := m.category(n)
+		m.categories = append(m.categories, c)
+		m.pages[c].ReplaceOrInsert(int64(pageNo))
+	}
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("FreeSpaceMap.Reset: %w", err)
+	}
+	return nil
+}
+
+// persist writes the whole map into the file
+func (m *FreeSpaceMap) persist() error {
+	b := make([]byte, types.LenMeta, int(types.LenMeta)+len(m.categories))
+	b[0] = types.TypeFreeSpaceMap
+	binary.LittleEndian.PutUint32(b[types.LenByte:], uint32(len(m.categories)))
+	b = append(b, m.categories...)
+	if err := m.file.Truncate(int64(len(b))); err != nil {
+		return fmt.Errorf("FreeSpaceMap.persist: %w", err)
+	}
+	if _, err := m.file.WriteAt(b, 0); err != nil {
+		return fmt.Errorf("FreeSpaceMap.persist: %w", err)
+	}
+	return nil
+}
+
+func (m *FreeSpaceMap) Sync() error {
+	return m.file.Sync()
+}
+
+func (m *FreeSpaceMap) Close() error {
+	return m.file.Close()
+}
+
+func (m *FreeSpaceMap) category(free int) byte {
+	return byte(min(Categories-1, max(0, free)*Categories/m.pageSize))
+}
+
+// minCategory returns the lowest category in which every page has at least n bytes free
+func (m *FreeSpaceMap) minCategory(n int) int {
+	return (max(0, n)*Categories + m.pageSize - 1) / m.pageSize
+}
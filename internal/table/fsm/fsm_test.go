@@ -0,0 +1,59 @@
+package fsm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openMap(t *testing.T, path string) *FreeSpaceMap {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(f, 4096)
+	if err = m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestFreeSpaceMap_FindAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users"+FileExtension)
+	m := openMap(t, path)
+	_, ok := m.Find(100)
+	assert.False(t, ok)
+
+	assert.Nil(t, m.Set(0, 50))
+	assert.Nil(t, m.Set(2, 3000))
+	assert.Nil(t, m.Set(3, 1000))
+	assert.Equal(t, 4, m.Len())
+
+	// Page 1 was added as a full page. Page 3 is the fullest one that has enough space
+	pageNo, ok := m.Find(500)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), pageNo)
+	pageNo, ok = m.Find(2000)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), pageNo)
+	_, ok = m.Find(3500)
+	assert.False(t, ok)
+
+	assert.Nil(t, m.Set(3, 10))
+	assert.Nil(t, m.Close())
+
+	m = openMap(t, path)
+	defer m.Close()
+	assert.Equal(t, 4, m.Len())
+	pageNo, ok = m.Find(500)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), pageNo)
+
+	assert.Nil(t, m.Reset([]int{4000, 0}))
+	assert.Equal(t, 2, m.Len())
+	pageNo, ok = m.Find(500)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), pageNo)
+}
@@ -3,30 +3,67 @@ package fulltext
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
+	"path/filepath"
 	"slices"
 
-	platformencoding "github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 )
 
+const (
+	// minMergeLen is the number of bytes that can be appended before the segments are merged even if the merged
+	// segment is shorter
+	minMergeLen = 64 << 10
+	// segmentHeaderLen is the length of the type, checksum and length of a segment
+	segmentHeaderLen = 9
+
+	opAdd    byte = 1
+	opRemove byte = 2
+)
+
+// Index maps the values of the full-text indexed column to the pages of the records that contain them
+//
+// The file is a sequence of segments. Persist appends a segment with the items added and removed since the previous
+// one, so it costs as much as the changes and not as the whole index:
+//
+//	244 [checksum uint32][len uint32] ([op byte][page int64][id int64][value len uint32][value])...
+//
+// Once the appended segments are longer than the file written by the last merge, the file is replaced by a single
+// segment that adds every item of the index
+//
+// The items of a value are not kept in any particular order, so an item is removed by moving the last one into its place
 type Index struct {
 	hMap map[string][]*IndexItem
-	file *os.File
+	// positions contains the positions of the items of every ID in the lists of hMap by their value, so the items of
+	// an ID are removed without walking the whole list of every value
+	positions map[int64]map[string][]int
+	file      *os.File
+	// pending contains the entries of the next segment
+	pending bytes.Buffer
+	// size is the length of the file and merged is its length after the last merge
+	size   int64
+	merged int64
+	// cleared makes the next Persist replace the file
+	cleared bool
 }
 
 func NewIndex(f *os.File) *Index {
 	return &Index{
-		hMap: make(map[string][]*IndexItem),
-		file: f,
+		hMap:      make(map[string][]*IndexItem),
+		positions: make(map[int64]map[string][]int),
+		file:      f,
 	}
 }
 
 type IndexItem struct {
 	PagePos int64
-	ID      int64
+	// ID is the primary key of the record if it's a single INT64 column, otherwise 0
+	// Items are removed by their value and page so it's only informational
+	ID int64
 }
 
 func NewIndexItem(page, id int64) *IndexItem {
@@ -36,21 +73,18 @@ func NewIndexItem(page, id int64) *IndexItem {
 	}
 }
 
+// Add adds an item without persisting the index
 func (idx *Index) Add(word string, page, id int64) {
 	if word == "" {
 		return
 	}
-	item := NewIndexItem(page, id)
-	_, ok := idx.hMap[word]
-	if !ok {
-		idx.hMap[word] = make([]*IndexItem, 0)
-	}
-	idx.hMap[word] = append(idx.hMap[word], item)
+	idx.add(word, page, id)
+	idx.log(opAdd, word, page, id)
 }
 
 func (idx *Index) AddAndPersist(word string, page, id int64) error {
 	idx.Add(word, page, id)
-	if err := idx.persist(); err != nil {
+	if err := idx.Persist(); err != nil {
 		return fmt.Errorf("fulltext.index.AddAndPersist: %w", err)
 	}
 	return nil
@@ -64,243 +98,327 @@ func (idx *Index) Get(word string) ([]*IndexItem, error) {
 	return val, nil
 }
 
+// RemoveMany removes every item of the IDs without persisting the index
 func (idx *Index) RemoveMany(ids []int64) {
-	if len(ids) == 0 {
-		return
-	}
-	for w, _ := range idx.hMap {
-		idx.hMap[w] = slices.DeleteFunc(idx.hMap[w], func(item *IndexItem) bool {
-			return slices.Contains(ids, item.ID)
-		})
-		if len(idx.hMap[w]) == 0 {
-			delete(idx.hMap, w)
+	for _, id := range ids {
+		for word := range idx.positions[id] {
+			for len(idx.positions[id][word]) > 0 {
+				positions := idx.positions[id][word]
+				i := positions[len(positions)-1]
+				idx.log(opRemove, word, idx.hMap[word][i].PagePos, id)
+				idx.removeAt(word, i)
+			}
 		}
 	}
 }
 
 func (idx *Index) RemoveManyAndPersist(ids []int64) error {
 	idx.RemoveMany(ids)
-	if err := idx.persist(); err != nil {
+	if err := idx.Persist(); err != nil {
 		return fmt.Errorf("fulltext.index.RemoveManyAndPersist: %w", err)
 	}
 	return nil
 }
 
-func (idx *Index) persist() error {
-	if err := idx.file.Truncate(0); err != nil {
-		return fmt.Errorf("fulltext.index.persist: %w", err)
-	}
-	if _, err := idx.file.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("fulltext.index.persist: %w", err)
-	}
+// Clear removes every item. The next Persist replaces the file
+func (idx *Index) Clear() {
+	idx.hMap = make(map[string][]*IndexItem)
+	idx.positions = make(map[int64]map[string][]int)
+	idx.pending.Reset()
+	idx.cleared = true
+}
 
-	b, err := idx.MarshalBinary()
-	if err != nil {
-		return fmt.Errorf("fulltext.index.persist: %w", err)
-	}
-	n, err := idx.file.Write(b)
-	if err != nil {
-		return fmt.Errorf("fulltext.index.persist: %w", err)
-	}
-	if n != len(b) {
-		return fmt.Errorf("fulltext.index.persist: incopmlete write")
+// RemoveOne removes one item of word that points to page without persisting the index
+// Multiple records on the same page can have the same value, so every deleted record removes only one of them
+func (idx *Index) RemoveOne(word string, page int64) {
+	i := slices.IndexFunc(idx.hMap[word], func(item *IndexItem) bool {
+		return item.PagePos == page
+	})
+	if i == -1 {
+		return
 	}
-	return nil
+	id := idx.hMap[word][i].ID
+	idx.remove(word, page, id)
+	idx.log(opRemove, word, page, id)
 }
 
-func (idx *Index) Load() error {
-	if _, err := idx.file.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("fulltext.index.Load: %w", err)
-	}
-	stat, err := idx.file.Stat()
-	if err != nil {
-		return fmt.Errorf("fulltext.index.Load: %w", err)
-	}
-	b := make([]byte, stat.Size())
-	n, err := idx.file.Read(b)
-	if err != nil {
-		return fmt.Errorf("fulltext.index.Load: %w", err)
-	}
-	if n != len(b) {
-		return fmt.Errorf("fulltext.index.Load: incomplete read")
-	}
-	if err = idx.UnmarshalBinary(b); err != nil {
-		return fmt.Errorf("fulltext.index.Load: %w", err)
+// add adds an item to the maps
+func (idx *Index) add(word string, page, id int64) {
+	idx.hMap[word] = append(idx.hMap[word], NewIndexItem(page, id))
+	if idx.positions[id] == nil {
+		idx.positions[id] = make(map[string][]int)
 	}
-	return nil
+	idx.positions[id][word] = append(idx.positions[id][word], len(idx.hMap[word])-1)
 }
 
-func (idx *Index) Close() error {
-	return idx.file.Close()
+// remove removes an item of word with the given page and ID from the maps
+func (idx *Index) remove(word string, page, id int64) {
+	items := idx.hMap[word]
+	j := slices.IndexFunc(idx.positions[id][word], func(i int) bool {
+		return items[i].PagePos == page
+	})
+	if j == -1 {
+		return
+	}
+	idx.removeAt(word, idx.positions[id][word][j])
 }
 
-func (idx *Index) MarshalBinary() ([]byte, error) {
-	buf := bytes.Buffer{}
-	// type
-	if err := binary.Write(&buf, binary.LittleEndian, types.TypeIndex); err != nil {
-		return nil, fmt.Errorf("fulltext.index.MarshalBinary: idx type: %w", err)
-	}
+// removeAt removes the item at position i of word by moving the last item of word into its place
+func (idx *Index) removeAt(word string, i int) {
+	items := idx.hMap[word]
+	last := len(items) - 1
+	removed, moved := items[i], items[last]
 
-	hMapMarshaler := platformencoding.NewHMapMarshaler(convertHMap(idx.hMap))
-	hMapBytes, err := hMapMarshaler.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("fulltext.index.MarshalBinary: %w", err)
+	idx.positions[removed.ID][word] = slices.DeleteFunc(idx.positions[removed.ID][word], func(pos int) bool {
+		return pos == i
+	})
+	if len(idx.positions[removed.ID][word]) == 0 {
+		delete(idx.positions[removed.ID], word)
+		if len(idx.positions[removed.ID]) == 0 {
+			delete(idx.positions, removed.ID)
+		}
+	}
+	if i != last {
+		positions := idx.positions[moved.ID][word]
+		positions[slices.Index(positions, last)] = i
+		items[i] = moved
 	}
 
-	// len
-	if err := binary.Write(&buf, binary.LittleEndian, uint32(len(hMapBytes))); err != nil {
-		return nil, fmt.Errorf("fulltext.index.MarshalBinary: idx len: %w", err)
+	items[last] = nil
+	if last == 0 {
+		delete(idx.hMap, word)
+	} else {
+		idx.hMap[word] = items[:last]
 	}
+}
 
-	buf.Write(hMapBytes)
-	return buf.Bytes(), nil
+// log adds an entry to the next segment
+func (idx *Index) log(op byte, word string, page, id int64) {
+	appendEntry(&idx.pending, op, word, page, id)
 }
 
-func (idx *Index) UnmarshalBinary(data []byte) error {
-	byteUnmarshaler := platformencoding.NewValueUnmarshaler[byte]()
-	int32Unmarshaler := platformencoding.NewValueUnmarshaler[uint32]()
+func appendEntry(buf *bytes.Buffer, op byte, word string, page, id int64) {
+	buf.WriteByte(op)
+	buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(page)))
+	buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(id)))
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(word))))
+	buf.WriteString(word)
+}
 
-	n := 0
-	// type
-	if err := byteUnmarshaler.UnmarshalBinary(data); err != nil {
-		return fmt.Errorf("fulltext.Index.UnmarshalBinary: type: %w", err)
+// segment returns a segment that contains entries
+func segment(entries []byte) []byte {
+	data := make([]byte, segmentHeaderLen, segmentHeaderLen+len(entries))
+	data[0] = types.TypeFullTextSegment
+	binary.LittleEndian.PutUint32(data[5:], uint32(len(entries)))
+	data = append(data, entries...)
+	binary.LittleEndian.PutUint32(data[1:], crc32.ChecksumIEEE(data[5:]))
+	return data
+}
+
+// Persist appends the changes since the previous call to the file as a segment or merges the segments if they have
+// grown too long
+func (idx *Index) Persist() error {
+	if idx.pending.Len() == 0 && !idx.cleared {
+		return nil
 	}
-	n++
-	// len
-	if err := int32Unmarshaler.UnmarshalBinary(data[n:]); err != nil {
-		return fmt.Errorf("fulltext.Index.UnmarshalBinary: len: %w", err)
+	appended := idx.size - idx.merged + int64(segmentHeaderLen+idx.pending.Len())
+	if idx.cleared || appended > max(idx.merged, minMergeLen) {
+		if err := idx.merge(); err != nil {
+			return fmt.Errorf("fulltext.index.Persist: %w", err)
+		}
+		return nil
 	}
-	n += types.LenInt32
-
-	hmapUnmarshaler := platformencoding.NewHMapUnmarshaler(func() platformencoding.EmbeddedValueUnmarshaler {
-		return &IndexItem{}
-	})
 
-	if err := hmapUnmarshaler.UnmarshalBinary(data[n:]); err != nil {
-		return fmt.Errorf("fulltext.index.UnmarshalBinary: hmap: %w", err)
-	}
-
-	for key, items := range hmapUnmarshaler.Value {
-		switch v := items.(type) {
-		case []platformencoding.EmbeddedValueUnmarshaler:
-			for _, item := range v {
-				val := item.GetValue()
-				switch itemVal := val.(type) {
-				case map[string]int64:
-					idx.Add(key, itemVal["page"], itemVal["id"])
-				default:
-					return fmt.Errorf("fulltext.index.UnmarshalBinary: hMap unmarshaler has invalid item type. want: map[string]int64, have: %T", itemVal)
-				}
-			}
-
-		default:
-			return fmt.Errorf("fulltext.index.UnmarshalBinary: hMap unmarshaler has invalid type. want: []interface{...}, have: %T", v)
-		}
+	data := segment(idx.pending.Bytes())
+	if _, err := idx.file.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("fulltext.index.Persist: %w", err)
+	}
+	n, err := idx.file.Write(data)
+	if err != nil {
+		return fmt.Errorf("fulltext.index.Persist: %w", err)
+	}
+	if n != len(data) {
+		return fmt.Errorf("fulltext.index.Persist: incomplete write")
 	}
+	idx.size += int64(n)
+	idx.pending.Reset()
 	return nil
 }
 
-func (item *IndexItem) BinaryLen() uint32 {
-	return uint32(binary.Size(item)) + // two integers = 16
-		(2 * types.LenMeta) // 10 meta bytes for the two ints = 10
-}
-
-func (item *IndexItem) GetValue() interface{} {
-	return map[string]int64{
-		"id":   item.ID,
-		"page": item.PagePos,
+// merge replaces the file with one that contains a single segment with every item
+// The new file is written next to the old one and renamed over it, so a crash leaves one of them intact
+func (idx *Index) merge() error {
+	entries := bytes.Buffer{}
+	words := make([]string, 0, len(idx.hMap))
+	for word := range idx.hMap {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	for _, word := range words {
+		for _, item := range idx.hMap[word] {
+			appendEntry(&entries, opAdd, word, item.PagePos, item.ID)
+		}
+	}
+	var data []byte
+	if entries.Len() > 0 {
+		data = segment(entries.Bytes())
 	}
-}
 
-func (item *IndexItem) MarshalBinary() ([]byte, error) {
-	buf := bytes.Buffer{}
-	// type
-	if err := binary.Write(&buf, binary.LittleEndian, types.TypeIndexItem); err != nil {
-		return nil, fmt.Errorf("fulltext.indexItem.MarshalBinary: type: %w", err)
+	path := idx.file.Name()
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return fmt.Errorf("fulltext.index.merge: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("fulltext.index.merge: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("fulltext.index.merge: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("fulltext.index.merge: %w", err)
 	}
-	// len
-	if err := binary.Write(&buf, binary.LittleEndian, item.BinaryLen()); err != nil {
-		return nil, fmt.Errorf("fulltext.indexItem.MarshalBinary: len: %w", err)
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("fulltext.index.merge: %w", err)
 	}
-	idTLV := platformencoding.NewTLVMarshaler(item.ID)
-	idBuf, err := idTLV.MarshalBinary()
+	if err = syncDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("fulltext.index.merge: %w", err)
+	}
+	if err = idx.file.Close(); err != nil {
+		return fmt.Errorf("fulltext.index.merge: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
-		return nil, fmt.Errorf("fulltext.indexItem.MarshalBinary: ID: %w", err)
+		return fmt.Errorf("fulltext.index.merge: %w", err)
 	}
-	buf.Write(idBuf)
+	idx.file = f
+	idx.size = int64(len(data))
+	idx.merged = idx.size
+	idx.pending.Reset()
+	idx.cleared = false
+	return nil
+}
 
-	pageTLV := platformencoding.NewTLVMarshaler(item.PagePos)
-	pageBuf, err := pageTLV.MarshalBinary()
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
 	if err != nil {
-		return nil, fmt.Errorf("fulltext.indexItem.MarshalBinary: page: %w", err)
+		return fmt.Errorf("fulltext.syncDir: %w", err)
 	}
-	buf.Write(pageBuf)
-	return buf.Bytes(), nil
+	defer d.Close()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("fulltext.syncDir: %w", err)
+	}
+	return nil
 }
 
-func (item *IndexItem) UnmarshalBinary(data []byte) error {
-	byteUnmarshaler := platformencoding.NewValueUnmarshaler[byte]()
-	int32Unmarshaler := platformencoding.NewValueUnmarshaler[uint32]()
-	int64Unmarshaler := platformencoding.NewValueUnmarshaler[int64]()
+// Sync commits the index file to the disk
+func (idx *Index) Sync() error {
+	if err := idx.file.Sync(); err != nil {
+		return fmt.Errorf("fulltext.index.Sync: %w", err)
+	}
+	return nil
+}
 
-	n := 0
-	// type
-	if err := byteUnmarshaler.UnmarshalBinary(data); err != nil {
-		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: type: %w", err)
+// Load applies the segments of the file. An index written in the format used before segments is converted
+func (idx *Index) Load() error {
+	b, err := idx.ReadRaw()
+	if err != nil {
+		return fmt.Errorf("fulltext.index.Load: %w", err)
 	}
-	n++
-	// len
-	if err := int32Unmarshaler.UnmarshalBinary(data[n:]); err != nil {
-		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: len: %w", err)
+	// The file is empty until the first word is added
+	if len(b) == 0 {
+		return nil
 	}
-	n += types.LenInt32
-
-	idTLV := platformencoding.NewTLVUnmarshaler[int64](int64Unmarshaler)
-	if err := idTLV.UnmarshalBinary(data[n:]); err != nil {
-		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: ID: %w", err)
+	if b[0] == types.TypeIndex {
+		if err = idx.unmarshalLegacy(b); err != nil {
+			return fmt.Errorf("fulltext.index.Load: %w", err)
+		}
+		idx.cleared = true
+		if err = idx.Persist(); err != nil {
+			return fmt.Errorf("fulltext.index.Load: %w", err)
+		}
+		return nil
 	}
-	n += int(idTLV.BytesRead)
-	id := idTLV.Value
 
-	pageTLV := platformencoding.NewTLVUnmarshaler[int64](int64Unmarshaler)
-	if err := pageTLV.UnmarshalBinary(data[n:]); err != nil {
-		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: ID: %w", err)
+	n := 0
+	for n < len(b) {
+		entries, ok := readSegment(b[n:])
+		if !ok {
+			// The last segment was not written completely. Its changes are in the WAL, which rebuilds the index
+			if err = idx.file.Truncate(int64(n)); err != nil {
+				return fmt.Errorf("fulltext.index.Load: %w", err)
+			}
+			break
+		}
+		if err = idx.apply(entries); err != nil {
+			return fmt.Errorf("fulltext.index.Load: %w", err)
+		}
+		n += segmentHeaderLen + len(entries)
 	}
-	n += int(pageTLV.BytesRead)
-	page := pageTLV.Value
-
-	item.ID = id
-	item.PagePos = page
+	idx.size = int64(n)
+	idx.merged = idx.size
 	return nil
 }
 
-// It returns a map that is compatible with platformencoding.HMapMarshaler
-// It does not modify values
-func convertHMap(hMap map[string][]*IndexItem) map[string][]platformencoding.EmbeddedValueMarshaler {
-	convertedMap := make(map[string][]platformencoding.EmbeddedValueMarshaler)
-	for k, v := range hMap {
-		convertedSlice := make([]platformencoding.EmbeddedValueMarshaler, len(v))
-		for i, item := range v {
-			convertedSlice[i] = platformencoding.EmbeddedValueMarshaler(item)
+// readSegment returns the entries of the segment at the beginning of data. It reports false if the segment is
+// incomplete or its checksum is wrong
+func readSegment(data []byte) ([]byte, bool) {
+	if len(data) < segmentHeaderLen || data[0] != types.TypeFullTextSegment {
+		return nil, false
+	}
+	end := segmentHeaderLen + int(binary.LittleEndian.Uint32(data[5:]))
+	if end > len(data) || binary.LittleEndian.Uint32(data[1:]) != crc32.ChecksumIEEE(data[5:end]) {
+		return nil, false
+	}
+	return data[segmentHeaderLen:end], true
+}
+
+// apply adds and removes the items of the entries of a segment
+func (idx *Index) apply(entries []byte) error {
+	const entryHeaderLen = 1 + 2*types.LenInt64 + types.LenInt32
+	n := 0
+	for n < len(entries) {
+		if n+entryHeaderLen > len(entries) {
+			return fmt.Errorf("fulltext.index.apply: incomplete entry at %d", n)
+		}
+		op := entries[n]
+		page := int64(binary.LittleEndian.Uint64(entries[n+1:]))
+		id := int64(binary.LittleEndian.Uint64(entries[n+1+types.LenInt64:]))
+		wordLen := int(binary.LittleEndian.Uint32(entries[n+1+2*types.LenInt64:]))
+		n += entryHeaderLen
+		if n+wordLen > len(entries) {
+			return fmt.Errorf("fulltext.index.apply: incomplete entry at %d", n)
+		}
+		word := string(entries[n : n+wordLen])
+		n += wordLen
+
+		switch op {
+		case opAdd:
+			idx.add(word, page, id)
+		case opRemove:
+			idx.remove(word, page, id)
+		default:
+			return fmt.Errorf("fulltext.index.apply: unknown operation %d", op)
 		}
-		convertedMap[k] = convertedSlice
 	}
-	return convertedMap
+	return nil
+}
+
+func (idx *Index) Close() error {
+	return idx.file.Close()
 }
 
 // ReadRaw returns the raw byte array stored in the idx. It's for debugging
 func (idx *Index) ReadRaw() ([]byte, error) {
-	if _, err := idx.file.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("index.ReadRaw: %w", err)
-	}
-
 	stat, err := idx.file.Stat()
 	if err != nil {
 		return nil, fmt.Errorf("index.ReadRaw: %w", err)
 	}
-
 	buf := make([]byte, stat.Size())
-	if _, err = idx.file.Read(buf); err != nil {
+	if _, err = idx.file.ReadAt(buf, 0); err != nil && !errors.Is(err, io.EOF) {
 		return nil, fmt.Errorf("index.ReadRaw: %w", err)
 	}
 	return buf, nil
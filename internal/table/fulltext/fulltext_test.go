@@ -1,9 +1,12 @@
 package fulltext
 
 import (
-	"github.com/stretchr/testify/assert"
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestIndex_Add(t *testing.T) {
@@ -58,6 +61,43 @@ func TestIndex_RemoveMany(t *testing.T) {
 	val, err = idx.Get("engineer")
 	assert.NotNil(t, err)
 	assert.Nil(t, val)
+
+	// The items that are moved into the places of the removed ones can still be removed
+	for i := range int64(6) {
+		idx.Add("engineer", 100*i, i%3)
+	}
+	idx.RemoveMany([]int64{0})
+	val, err = idx.Get("engineer")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []*IndexItem{NewIndexItem(100, 1), NewIndexItem(200, 2), NewIndexItem(400, 1), NewIndexItem(500, 2)}, val)
+	idx.RemoveOne("engineer", 100)
+	idx.RemoveMany([]int64{2, 10})
+	val, err = idx.Get("engineer")
+	assert.Nil(t, err)
+	assert.Equal(t, []*IndexItem{NewIndexItem(400, 1)}, val)
+	assert.Equal(t, map[int64]map[string][]int{1: {"engineer": {0}}}, idx.positions)
+}
+
+func TestIndex_RemoveOne(t *testing.T) {
+	f := createFile()
+	defer removeFile()
+
+	idx := NewIndex(f)
+	idx.Add("engineer", 100, 10)
+	idx.Add("engineer", 100, 20)
+	idx.Add("engineer", 200, 30)
+	idx.Add("designer", 200, 40)
+
+	idx.RemoveOne("engineer", 100)
+	idx.RemoveOne("designer", 200)
+	idx.RemoveOne("nope", 200)
+
+	val, err := idx.Get("engineer")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []*IndexItem{NewIndexItem(100, 20), NewIndexItem(200, 30)}, val)
+
+	_, err = idx.Get("designer")
+	assert.NotNil(t, err)
 }
 
 func createFile() *os.File {
@@ -73,3 +113,73 @@ func removeFile() {
 		panic(err)
 	}
 }
+
+func TestIndex_Persist(t *testing.T) {
+	f := createFile()
+	defer removeFile()
+
+	idx := NewIndex(f)
+	assert.Nil(t, idx.AddAndPersist("engineer", 100, 10))
+	first, err := idx.ReadRaw()
+	assert.Nil(t, err)
+	assert.Nil(t, idx.AddAndPersist("designer", 100, 20))
+	assert.Nil(t, idx.AddAndPersist("engineer", 200, 30))
+	idx.RemoveOne("engineer", 100)
+	assert.Nil(t, idx.Persist())
+
+	// Every change is appended as a segment that only contains the changed items
+	raw, err := idx.ReadRaw()
+	assert.Nil(t, err)
+	assert.Equal(t, first, raw[:len(first)])
+	assert.Len(t, raw, 4*len(first))
+
+	loaded := NewIndex(idx.file)
+	assert.Nil(t, loaded.Load())
+	assert.Equal(t, idx.hMap, loaded.hMap)
+
+	// The segments are merged once they are longer than the merged file
+	for i := range int64(3000) {
+		assert.Nil(t, idx.AddAndPersist("engineer", 300, i))
+		idx.RemoveMany([]int64{i})
+		assert.Nil(t, idx.Persist())
+	}
+	raw, err = idx.ReadRaw()
+	assert.Nil(t, err)
+	assert.Less(t, len(raw), minMergeLen)
+
+	loaded = NewIndex(idx.file)
+	assert.Nil(t, loaded.Load())
+	assert.Equal(t, idx.hMap, loaded.hMap)
+	assert.Equal(t, idx.positions, loaded.positions)
+
+	// A segment that was not written completely is ignored
+	torn := bytes.Buffer{}
+	appendEntry(&torn, opAdd, "engineer", 400, 1)
+	_, err = idx.file.Write(segment(torn.Bytes())[:segmentHeaderLen+torn.Len()-1])
+	assert.Nil(t, err)
+	loaded = NewIndex(idx.file)
+	assert.Nil(t, loaded.Load())
+	assert.Equal(t, idx.hMap, loaded.hMap)
+	truncated, err := loaded.ReadRaw()
+	assert.Nil(t, err)
+	assert.Equal(t, raw, truncated)
+}
+
+func TestIndex_LoadLegacyFormat(t *testing.T) {
+	f := createFile()
+	defer removeFile()
+
+	_, err := f.Write([]byte{0xf0, 0x40, 0x0, 0x0, 0x0, 0xdc, 0x3b, 0x0, 0x0, 0x0, 0xdd, 0xd, 0x0, 0x0, 0x0, 0x2, 0x8, 0x0, 0x0, 0x0, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x65, 0x72, 0xde, 0x24, 0x0, 0x0, 0x0, 0xe6, 0x1f, 0x0, 0x0, 0x0, 0xf1, 0x1a, 0x0, 0x0, 0x0, 0x1, 0x8, 0x0, 0x0, 0x0, 0xa, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x8, 0x0, 0x0, 0x0, 0x64, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0})
+	assert.Nil(t, err)
+
+	idx := NewIndex(f)
+	assert.Nil(t, idx.Load())
+	val, err := idx.Get("engineer")
+	assert.Nil(t, err)
+	assert.Equal(t, []*IndexItem{NewIndexItem(100, 10)}, val)
+
+	// It's written as a segment
+	raw, err := idx.ReadRaw()
+	assert.Nil(t, err)
+	assert.Equal(t, byte(types.TypeFullTextSegment), raw[0])
+}
@@ -0,0 +1,104 @@
+package fulltext
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	platformencoding "github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// unmarshalLegacy adds the items of an index written as a single hash map before the file was split into segments:
+//
+//	240 len [hash map of values to lists of 241 len [id TLV] [page TLV]]
+func (idx *Index) unmarshalLegacy(data []byte) error {
+	byteUnmarshaler := platformencoding.NewValueUnmarshaler[byte]()
+	int32Unmarshaler := platformencoding.NewValueUnmarshaler[uint32]()
+
+	n := 0
+	// type
+	if err := byteUnmarshaler.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("fulltext.Index.unmarshalLegacy: type: %w", err)
+	}
+	n++
+	// len
+	if err := int32Unmarshaler.UnmarshalBinary(data[n:]); err != nil {
+		return fmt.Errorf("fulltext.Index.unmarshalLegacy: len: %w", err)
+	}
+	n += types.LenInt32
+
+	hmapUnmarshaler := platformencoding.NewHMapUnmarshaler(func() platformencoding.EmbeddedValueUnmarshaler {
+		return &IndexItem{}
+	})
+
+	if err := hmapUnmarshaler.UnmarshalBinary(data[n:]); err != nil {
+		return fmt.Errorf("fulltext.Index.unmarshalLegacy: hmap: %w", err)
+	}
+
+	for key, items := range hmapUnmarshaler.Value {
+		switch v := items.(type) {
+		case []platformencoding.EmbeddedValueUnmarshaler:
+			for _, item := range v {
+				val := item.GetValue()
+				switch itemVal := val.(type) {
+				case map[string]int64:
+					idx.add(key, itemVal["page"], itemVal["id"])
+				default:
+					return fmt.Errorf("fulltext.Index.unmarshalLegacy: hMap unmarshaler has invalid item type. want: map[string]int64, have: %T", itemVal)
+				}
+			}
+
+		default:
+			return fmt.Errorf("fulltext.Index.unmarshalLegacy: hMap unmarshaler has invalid type. want: []interface{...}, have: %T", v)
+		}
+	}
+	return nil
+}
+
+func (item *IndexItem) BinaryLen() uint32 {
+	return uint32(binary.Size(item)) + // two integers = 16
+		(2 * types.LenMeta) // 10 meta bytes for the two ints = 10
+}
+
+func (item *IndexItem) GetValue() interface{} {
+	return map[string]int64{
+		"id":   item.ID,
+		"page": item.PagePos,
+	}
+}
+
+func (item *IndexItem) UnmarshalBinary(data []byte) error {
+	byteUnmarshaler := platformencoding.NewValueUnmarshaler[byte]()
+	int32Unmarshaler := platformencoding.NewValueUnmarshaler[uint32]()
+	int64Unmarshaler := platformencoding.NewValueUnmarshaler[int64]()
+
+	n := 0
+	// type
+	if err := byteUnmarshaler.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: type: %w", err)
+	}
+	n++
+	// len
+	if err := int32Unmarshaler.UnmarshalBinary(data[n:]); err != nil {
+		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: len: %w", err)
+	}
+	n += types.LenInt32
+
+	idTLV := platformencoding.NewTLVUnmarshaler[int64](int64Unmarshaler)
+	if err := idTLV.UnmarshalBinary(data[n:]); err != nil {
+		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: ID: %w", err)
+	}
+	n += int(idTLV.BytesRead)
+	id := idTLV.Value
+
+	pageTLV := platformencoding.NewTLVUnmarshaler[int64](int64Unmarshaler)
+	if err := pageTLV.UnmarshalBinary(data[n:]); err != nil {
+		return fmt.Errorf("fulltext.IndexItem.UnmarshalBinary: ID: %w", err)
+	}
+	n += int(pageTLV.BytesRead)
+	page := pageTLV.Value
+
+	item.ID = id
+	item.PagePos = page
+	return nil
+}
@@ -0,0 +1,87 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// header is stored at the beginning of the table file before the column definitions:
+//
+//	80 len [primary key column TLV]... [page size int32 TLV]
+//
+// Tables created before the header existed start with the column definitions and their primary key is id
+// Tables created before pages had a fixed size have no page size. Their page size is 0
+type header struct {
+	primaryKey []string
+	pageSize   int
+}
+
+func newHeader(primaryKey []string, pageSize int) *header {
+	return &header{primaryKey: primaryKey, pageSize: pageSize}
+}
+
+func (h *header) MarshalBinary() ([]byte, error) {
+	content := bytes.Buffer{}
+	for _, col := range h.primaryKey {
+		b, err := encoding.NewTLVMarshaler(col).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("header.MarshalBinary: primary key: %w", err)
+		}
+		content.Write(b)
+	}
+	b, err := encoding.NewTLVMarshaler(int32(h.pageSize)).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("header.MarshalBinary: page size: %w", err)
+	}
+	content.Write(b)
+
+	buf := bytes.Buffer{}
+	// type
+	if err := binary.Write(&buf, binary.LittleEndian, types.TypeTableHeader); err != nil {
+		return nil, fmt.Errorf("header.MarshalBinary: type: %w", err)
+	}
+	// len
+	if err := binary.Write(&buf, binary.LittleEndian, uint32(content.Len())); err != nil {
+		return nil, fmt.Errorf("header.MarshalBinary: len: %w", err)
+	}
+	buf.Write(content.Bytes())
+	return buf.Bytes(), nil
+}
+
+func (h *header) UnmarshalBinary(data []byte) error {
+	if len(data) < int(types.LenMeta) || data[0] != types.TypeTableHeader {
+		return fmt.Errorf("header.UnmarshalBinary: expected type flag %d", types.TypeTableHeader)
+	}
+	h.primaryKey = make([]string, 0, 1)
+	n := int(types.LenMeta)
+	for n < len(data) {
+		if len(data) < n+int(types.LenMeta) {
+			return fmt.Errorf("header.UnmarshalBinary: unexpected end of data")
+		}
+		// The unmarshalers read the whole input so it needs to end where the value ends
+		end := n + int(types.LenMeta) + int(binary.LittleEndian.Uint32(data[n+types.LenByte:]))
+		if end > len(data) {
+			return fmt.Errorf("header.UnmarshalBinary: unexpected end of data")
+		}
+		if data[n] == types.TypeInt32 {
+			tlv := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[int32]())
+			if err := tlv.UnmarshalBinary(data[n:end]); err != nil {
+				return fmt.Errorf("header.UnmarshalBinary: page size: %w", err)
+			}
+			h.pageSize = int(tlv.Value)
+			n = end
+			continue
+		}
+		tlv := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[string]())
+		if err := tlv.UnmarshalBinary(data[n:end]); err != nil {
+			return fmt.Errorf("header.UnmarshalBinary: primary key: %w", err)
+		}
+		h.primaryKey = append(h.primaryKey, tlv.Value)
+		n = end
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
+)
+
+const (
+	metaChecksumOffset  = 1
+	metaRootOffset      = 5
+	metaPageCountOffset = 13
+)
+
+// tree is a B+tree whose nodes are stored in the pages of a file. The first page of the file is the meta page:
+//
+//	242 [checksum uint32][root int64][page count int64]
+//
+// Nodes are read and written through a buffer pool, so an operation only loads the nodes on its path and a modified
+// node stays in the pool as a dirty page until the pool writes it back. Deletes don't merge nodes, a node that
+// becomes empty stays in the tree until the index is cleared and built again
+//
+// Reads can run in parallel with each other but not with writes
+type tree struct {
+	file *os.File
+	// name identifies the file in the buffer pool
+	name string
+	pool *bufferpool.Pool
+	// root is the position of the root node or 0 if the tree is empty
+	root int64
+	// pageCount is the number of pages including the meta page
+	pageCount int64
+}
+
+// split is the result of splitting a node: the smallest key of the new node and its position
+type split struct {
+	key Key
+	raw []byte
+	pos int64
+}
+
+func newTree(f *os.File, pool *bufferpool.Pool) *tree {
+	t := &tree{
+		file: f,
+		name: f.Name(),
+		pool: pool,
+	}
+	pool.Attach(t.name, t.writeBack)
+	return t
+}
+
+// load reads the meta page. An empty file contains an empty tree
+func (t *tree) load() error {
+	stat, err := t.file.Stat()
+	if err != nil {
+		return fmt.Errorf("tree.load: %w", err)
+	}
+	t.root, t.pageCount = 0, 0
+	if stat.Size() == 0 {
+		return nil
+	}
+	data, err := t.fetch(0)
+	if err != nil {
+		return fmt.Errorf("tree.load: %w", err)
+	}
+	if data[0] != types.TypeBtreeMeta {
+		return fmt.Errorf("tree.load: expected type flag %d received %d", types.TypeBtreeMeta, data[0])
+	}
+	if binary.LittleEndian.Uint32(data[metaChecksumOffset:]) != crc32.ChecksumIEEE(data[metaRootOffset:]) {
+		return fmt.Errorf("tree.load: %w", NewChecksumMismatchError(0))
+	}
+	t.root = int64(binary.LittleEndian.Uint64(data[metaRootOffset:]))
+	t.pageCount = int64(binary.LittleEndian.Uint64(data[metaPageCountOffset:]))
+	return nil
+}
+
+// legacy reports true if the file contains an index written before indexes were B+trees
+func (t *tree) legacy() (bool, error) {
+	b := make([]byte, 1)
+	if _, err := t.file.ReadAt(b, 0); err != nil {
+		// The file is empty
+		return false, nil
+	}
+	return b[0] == types.TypeIndex, nil
+}
+
+// get returns the value of key. It reports false if the key is not in the tree
+func (t *tree) get(key Key) (int64, bool, error) {
+	if t.root == 0 {
+		return 0, false, nil
+	}
+	n, err := t.leaf(key)
+	if err != nil {
+		return 0, false, fmt.Errorf("tree.get: %w", err)
+	}
+	i := n.search(key)
+	if i < len(n.keys) && n.keys[i].Compare(key) == 0 {
+		return n.values[i], true, nil
+	}
+	return 0, false, nil
+}
+
+// marshalKey encodes key. It returns KeyTooLongError if the key doesn't fit into a node
+func marshalKey(key Key) ([]byte, error) {
+	raw, err := key.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshalKey: %w", err)
+	}
+	if len(raw) > MaxKeyLen {
+		return nil, fmt.Errorf("marshalKey: %w", NewKeyTooLongError(key, len(raw)))
+	}
+	return raw, nil
+}
+
+// put adds key or replaces its value
+func (t *tree) put(key Key, value int64) error {
+	raw, err := marshalKey(key)
+	if err != nil {
+		return fmt.Errorf("tree.put: %w", err)
+	}
+	root, pageCount := t.root, t.pageCount
+	if t.root == 0 {
+		leaf := &node{pos: t.allocate(), leaf: true}
+		if err = t.writeNode(leaf); err != nil {
+			return fmt.Errorf("tree.put: %w", err)
+		}
+		t.root = leaf.pos
+	}
+
+	s, err := t.insert(t.root, key, raw, value)
+	if err != nil {
+		return fmt.Errorf("tree.put: %w", err)
+	}
+	if s != nil {
+		// The root was split, so the tree grows by one level
+		newRoot := &node{
+			pos:    t.allocate(),
+			next:   t.root,
+			keys:   []Key{s.key},
+			raw:    [][]byte{s.raw},
+			values: []int64{s.pos},
+		}
+		if err = t.writeNode(newRoot); err != nil {
+			return fmt.Errorf("tree.put: %w", err)
+		}
+		t.root = newRoot.pos
+	}
+	if t.root != root || t.pageCount != pageCount {
+		if err = t.writeMeta(); err != nil {
+			return fmt.Errorf("tree.put: %w", err)
+		}
+	}
+	return nil
+}
+
+// insert adds key to the subtree of the node at pos. It returns the new node if the node had to be split
+func (t *tree) insert(pos int64, key Key, raw []byte, value int64) (*split, error) {
+	n, err := t.node(pos)
+	if err != nil {
+		return nil, fmt.Errorf("tree.insert: %w", err)
+	}
+	if n.leaf {
+		i := n.search(key)
+		if i < len(n.keys) && n.keys[i].Compare(key) == 0 {
+			n.values[i] = value
+		} else {
+			n.insertAt(i, key, raw, value)
+		}
+	} else {
+		i, child := n.child(key)
+		s, err := t.insert(child, key, raw, value)
+		if err != nil || s == nil {
+			return nil, err
+		}
+		n.insertAt(i, s.key, s.raw, s.pos)
+	}
+
+	if n.size() <= NodeSize {
+		if err = t.writeNode(n); err != nil {
+			return nil, fmt.Errorf("tree.insert: %w", err)
+		}
+		return nil, nil
+	}
+	right, sep, sepRaw := n.split(t.allocate())
+	if err = t.writeNode(n); err != nil {
+		return nil, fmt.Errorf("tree.insert: %w", err)
+	}
+	if err = t.writeNode(right); err != nil {
+		return nil, fmt.Errorf("tree.insert: %w", err)
+	}
+	return &split{key: sep, raw: sepRaw, pos: right.pos}, nil
+}
+
+// delete removes key from its leaf. It reports false if the key is not in the tree
+func (t *tree) delete(key Key) (bool, error) {
+	if t.root == 0 {
+		return false, nil
+	}
+	n, err := t.leaf(key)
+	if err != nil {
+		return false, fmt.Errorf("tree.delete: %w", err)
+	}
+	i := n.search(key)
+	if i == len(n.keys) || n.keys[i].Compare(key) != 0 {
+		return false, nil
+	}
+	n.removeAt(i)
+	if err = t.writeNode(n); err != nil {
+		return false, fmt.Errorf("tree.delete: %w", err)
+	}
+	return true, nil
+}
+
+// ascend calls fn for every key that is not smaller than from in ascending order until fn returns false
+// A nil from starts at the smallest key
+func (t *tree) ascend(from Key, fn func(key Key, value int64) bool) error {
+	if t.root == 0 {
+		return nil
+	}
+	// An empty key is smaller than every other one
+	n, err := t.leaf(from)
+	if err != nil {
+		return fmt.Errorf("tree.ascend: %w", err)
+	}
+	i := n.search(from)
+	for {
+		for ; i < len(n.keys); i++ {
+			if !fn(n.keys[i], n.values[i]) {
+				return nil
+			}
+		}
+		if n.next == 0 {
+			return nil
+		}
+		if n, err = t.node(n.next); err != nil {
+			return fmt.Errorf("tree.ascend: %w", err)
+		}
+		i = 0
+	}
+}
+
+// clear removes every node from the pool and the file
+func (t *tree) clear() error {
+	t.pool.Discard(t.name)
+	if err := t.file.Truncate(0); err != nil {
+		return fmt.Errorf("tree.clear: %w", err)
+	}
+	t.root, t.pageCount = 0, 0
+	return nil
+}
+
+// leaf returns the leaf that can contain key
+func (t *tree) leaf(key Key) (*node, error) {
+	n, err := t.node(t.root)
+	if err != nil {
+		return nil, fmt.Errorf("tree.leaf: %w", err)
+	}
+	for !n.leaf {
+		_, child := n.child(key)
+		if n, err = t.node(child); err != nil {
+			return nil, fmt.Errorf("tree.leaf: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// allocate returns the position of a new page at the end of the file
+func (t *tree) allocate() int64 {
+	// The meta page is the first one
+	t.pageCount = max(t.pageCount, 1)
+	pos := t.pageCount * NodeSize
+	t.pageCount++
+	return pos
+}
+
+func (t *tree) node(pos int64) (*node, error) {
+	data, err := t.fetch(pos)
+	if err != nil {
+		return nil, fmt.Errorf("tree.node: %w", err)
+	}
+	n, err := parseNode(data, pos)
+	if err != nil {
+		return nil, fmt.Errorf("tree.node: %w", err)
+	}
+	return n, nil
+}
+
+// fetch returns the page at pos from the buffer pool. The pool never modifies the content, so it's unpinned right away
+func (t *tree) fetch(pos int64) ([]byte, error) {
+	id := bufferpool.PageID{File: t.name, Pos: pos}
+	data, _, err := t.pool.Fetch(id, func() ([]byte, error) {
+		b := make([]byte, NodeSize)
+		n, err := t.file.ReadAt(b, pos)
+		if n != NodeSize {
+			if err != nil {
+				return nil, fmt.Errorf("tree.fetch: %w: %w", NewIncompleteReadError(NodeSize, n), err)
+			}
+			return nil, fmt.Errorf("tree.fetch: %w", NewIncompleteReadError(NodeSize, n))
+		}
+		return b, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tree.fetch: %w", err)
+	}
+	t.pool.Unpin(id)
+	return data, nil
+}
+
+func (t *tree) writeNode(n *node) error {
+	return t.write(n.pos, n.bytes())
+}
+
+func (t *tree) writeMeta() error {
+	data := make([]byte, NodeSize)
+	data[0] = types.TypeBtreeMeta
+	binary.LittleEndian.PutUint64(data[metaRootOffset:], uint64(t.root))
+	binary.LittleEndian.PutUint64(data[metaPageCountOffset:], uint64(t.pageCount))
+	binary.LittleEndian.PutUint32(data[metaChecksumOffset:], crc32.ChecksumIEEE(data[metaRootOffset:]))
+	return t.write(0, data)
+}
+
+// write replaces the page at pos in the buffer pool. The old content is not needed, so a page that is not in the pool
+// is not read
+func (t *tree) write(pos int64, data []byte) error {
+	id := bufferpool.PageID{File: t.name, Pos: pos}
+	if _, _, err := t.pool.Fetch(id, func() ([]byte, error) { return data, nil }); err != nil {
+		return fmt.Errorf("tree.write: %w", err)
+	}
+	defer t.pool.Unpin(id)
+	if err := t.pool.Update(id, data, true); err != nil {
+		return fmt.Errorf("tree.write: %w", err)
+	}
+	return nil
+}
+
+// writeBack writes a page of the buffer pool back into the file
+func (t *tree) writeBack(pos int64, data []byte) error {
+	n, err := t.file.WriteAt(data, pos)
+	if err != nil {
+		return fmt.Errorf("tree.writeBack: %w", err)
+	}
+	if n != len(data) {
+		return fmt.Errorf("tree.writeBack: %w", NewIncompleteWriteError(len(data), n))
+	}
+	return nil
+}
+
+// setPool moves the tree into another buffer pool after writing its dirty pages
+func (t *tree) setPool(pool *bufferpool.Pool) error {
+	if err := t.flush(); err != nil {
+		return fmt.Errorf("tree.setPool: %w", err)
+	}
+	t.pool.Detach(t.name)
+	t.pool = pool
+	t.pool.Attach(t.name, t.writeBack)
+	return nil
+}
+
+// flush writes the dirty pages of the tree into the file
+func (t *tree) flush() error {
+	if err := t.pool.Flush(t.name); err != nil {
+		return fmt.Errorf("tree.flush: %w", err)
+	}
+	return nil
+}
+
+// sync writes the dirty pages and commits the file to the disk
+func (t *tree) sync() error {
+	if err := t.flush(); err != nil {
+		return fmt.Errorf("tree.sync: %w", err)
+	}
+	if err := t.file.Sync(); err != nil {
+		return fmt.Errorf("tree.sync: %w", err)
+	}
+	return nil
+}
+
+func (t *tree) close() error {
+	if err := t.flush(); err != nil {
+		return fmt.Errorf("tree.close: %w", err)
+	}
+	t.pool.Detach(t.name)
+	if err := t.file.Close(); err != nil {
+		return fmt.Errorf("tree.close: %w", err)
+	}
+	return nil
+}
+
+// readRaw returns the content of the file after writing the dirty pages
+func (t *tree) readRaw() ([]byte, error) {
+	if err := t.flush(); err != nil {
+		return nil, fmt.Errorf("tree.readRaw: %w", err)
+	}
+	return readFile(t.file)
+}
@@ -29,13 +29,51 @@ func (e *IncompleteReadError) Error() string {
 }
 
 type ItemNotFoundError struct {
-	id int64
+	key Key
 }
 
-func NewItemNotFoundError(id int64) *ItemNotFoundError {
-	return &ItemNotFoundError{id: id}
+func NewItemNotFoundError(key Key) *ItemNotFoundError {
+	return &ItemNotFoundError{key: key}
 }
 
 func (e *ItemNotFoundError) Error() string {
-	return fmt.Sprintf("item with ID %d not found in index", e.id)
+	return fmt.Sprintf("item with key %v not found in index", []interface{}(e.key))
+}
+
+type InvalidKeyError struct {
+	key interface{}
+}
+
+func NewInvalidKeyError(key interface{}) *InvalidKeyError {
+	return &InvalidKeyError{key: key}
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid index key: %v (%T)", e.key, e.key)
+}
+
+type KeyTooLongError struct {
+	key Key
+	len int
+}
+
+func NewKeyTooLongError(key Key, n int) *KeyTooLongError {
+	return &KeyTooLongError{key: key, len: n}
+}
+
+func (e *KeyTooLongError) Error() string {
+	return fmt.Sprintf("index key %v is %d bytes long, the limit is %d bytes", []interface{}(e.key), e.len, MaxKeyLen)
+}
+
+// ChecksumMismatchError means a page was not written completely or it was modified outside of the database
+type ChecksumMismatchError struct {
+	pos int64
+}
+
+func NewChecksumMismatchError(pos int64) *ChecksumMismatchError {
+	return &ChecksumMismatchError{pos: pos}
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch: the index page at %d is corrupt", e.pos)
 }
@@ -0,0 +1,45 @@
+package index
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// writeFile replaces the content of f with b
+func writeFile(f *os.File, b []byte) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("index.writeFile: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("index.writeFile: %w", err)
+	}
+	n, err := f.Write(b)
+	if err != nil {
+		return fmt.Errorf("index.writeFile: %w", err)
+	}
+	if n != len(b) {
+		return fmt.Errorf("index.writeFile: %w", NewIncompleteWriteError(len(b), n))
+	}
+	return nil
+}
+
+// readFile returns the whole content of f
+func readFile(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("index.readFile: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("index.readFile: %w", err)
+	}
+	b := make([]byte, stat.Size())
+	n, err := f.Read(b)
+	if err != nil && !(err == io.EOF && len(b) == 0) {
+		return nil, fmt.Errorf("index.readFile: %w", err)
+	}
+	if n != len(b) {
+		return nil, fmt.Errorf("index.readFile: %w", NewIncompleteReadError(len(b), n))
+	}
+	return b, nil
+}
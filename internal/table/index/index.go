@@ -1,243 +1,193 @@
 package index
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
-	"io"
 	"os"
 
-	"github.com/google/btree"
-	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
-	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
 )
 
+// Index is the primary index of a table. It maps the primary key of every record to the page that contains it
+// The items are stored in a B+tree in the index file whose nodes are read through a buffer pool
 type Index struct {
-	btree *btree.BTreeG[Item]
-	file  *os.File
+	tree *tree
 }
 
-func NewIndex(f *os.File) *Index {
-	bt := btree.NewG[Item](2, func(a, b Item) bool {
-		return a.id < b.id
-	})
+func NewIndex(f *os.File, pool *bufferpool.Pool) *Index {
 	return &Index{
-		btree: bt,
-		file:  f,
+		tree: newTree(f, pool),
 	}
 }
 
+// SetBufferPool makes the index keep its nodes in pool. The dirty nodes of the pool used before are written first
+func (i *Index) SetBufferPool(pool *bufferpool.Pool) error {
+	if err := i.tree.setPool(pool); err != nil {
+		return fmt.Errorf("index.SetBufferPool: %w", err)
+	}
+	return nil
+}
+
+// Close writes the dirty nodes and closes the file
 func (i *Index) Close() error {
-	return i.file.Close()
+	if err := i.tree.close(); err != nil {
+		return fmt.Errorf("index.Close: %w", err)
+	}
+	return nil
 }
 
-func (i *Index) AddAndPersist(id, pagePos int64) error {
-	i.btree.ReplaceOrInsert(*NewItem(id, pagePos))
-	return i.persist()
+// Add adds an item or replaces the page of an existing key
+func (i *Index) Add(key Key, pagePos int64) error {
+	if err := i.tree.put(key, pagePos); err != nil {
+		return fmt.Errorf("index.Add: %w", err)
+	}
+	return nil
 }
 
-func (i *Index) RemoveManyAndPersist(ids []int64) error {
-	for _, id := range ids {
-		i.btree.Delete(Item{id: id})
+// Check returns KeyTooLongError if key cannot be added to the index
+func (i *Index) Check(key Key) error {
+	if _, err := marshalKey(key); err != nil {
+		return fmt.Errorf("index.Check: %w", err)
 	}
-	if err := i.persist(); err != nil {
-		return fmt.Errorf("index.RemoveManyAndPersist: %w", err)
+	return nil
+}
+
+// Remove removes the item of key. A key that is not in the index is ignored
+func (i *Index) Remove(key Key) error {
+	if _, err := i.tree.delete(key); err != nil {
+		return fmt.Errorf("index.Remove: %w", err)
 	}
 	return nil
 }
 
-func (i *Index) Add(id, pagePos int64) {
-	i.btree.ReplaceOrInsert(*NewItem(id, pagePos))
+func (i *Index) RemoveMany(keys []Key) error {
+	for _, key := range keys {
+		if err := i.Remove(key); err != nil {
+			return fmt.Errorf("index.RemoveMany: %w", err)
+		}
+	}
+	return nil
 }
 
-func (i *Index) Get(id int64) (Item, error) {
-	item, ok := i.btree.Get(Item{id: id})
-	if !ok {
-		return Item{}, NewItemNotFoundError(id)
+// Clear removes every item and empties the file
+func (i *Index) Clear() error {
+	if err := i.tree.clear(); err != nil {
+		return fmt.Errorf("index.Clear: %w", err)
 	}
-	return item, nil
+	return nil
 }
 
-func (i *Index) persist() error {
-	if err := i.file.Truncate(0); err != nil {
-		return fmt.Errorf("index.persist: %w", err)
+func (i *Index) Get(key Key) (Item, error) {
+	pagePos, ok, err := i.tree.get(key)
+	if err != nil {
+		return Item{}, fmt.Errorf("index.Get: %w", err)
 	}
-	if _, err := i.file.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("index.persist: %w", err)
+	if !ok {
+		return Item{}, NewItemNotFoundError(key)
 	}
+	return *NewItem(key, pagePos), nil
+}
 
-	b, err := i.MarshalBinary()
+// Range returns the items where the first column of the key is between from and to in ascending order
+// A nil bound means the range is unbounded on that side
+func (i *Index) Range(from, to *Bound) ([]Item, error) {
+	out := make([]Item, 0)
+	var start Key
+	if from != nil {
+		// A key with only the first column is smaller than every key that starts with it
+		start = NewKey(from.Key)
+	}
+	err := i.tree.ascend(start, func(key Key, pagePos int64) bool {
+		if from != nil && !from.Inclusive && compareKeys(key[0], from.Key) == 0 {
+			return true
+		}
+		if to != nil {
+			cmp := compareKeys(key[0], to.Key)
+			if cmp > 0 || (cmp == 0 && !to.Inclusive) {
+				return false
+			}
+		}
+		out = append(out, *NewItem(key, pagePos))
+		return true
+	})
 	if err != nil {
-		return fmt.Errorf("index.persist: %w", err)
+		return nil, fmt.Errorf("index.Range: %w", err)
 	}
-	n, err := i.file.Write(b)
-	if err != nil {
-		return fmt.Errorf("index.persist: %w", err)
+	return out, nil
+}
+
+func (i *Index) GetAll() ([]Item, error) {
+	return i.Range(nil, nil)
+}
+
+// Flush writes the dirty nodes into the file without syncing it
+func (i *Index) Flush() error {
+	if err := i.tree.flush(); err != nil {
+		return fmt.Errorf("index.Flush: %w", err)
 	}
-	if n != len(b) {
-		return fmt.Errorf("index.persist: %w", NewIncompleteWriteError(len(b), n))
+	return nil
+}
+
+// Sync writes the dirty nodes and commits the index file to the disk
+func (i *Index) Sync() error {
+	if err := i.tree.sync(); err != nil {
+		return fmt.Errorf("index.Sync: %w", err)
 	}
 	return nil
 }
 
+// Load reads the root of the B+tree. An index written in the format used before is converted into a B+tree
 func (i *Index) Load() error {
-	if _, err := i.file.Seek(0, io.SeekStart); err != nil {
+	legacy, err := i.tree.legacy()
+	if err != nil {
 		return fmt.Errorf("index.Load: %w", err)
 	}
-	stat, err := i.file.Stat()
+	if !legacy {
+		if err = i.tree.load(); err != nil {
+			return fmt.Errorf("index.Load: %w", err)
+		}
+		return nil
+	}
+
+	b, err := readFile(i.tree.file)
 	if err != nil {
 		return fmt.Errorf("index.Load: %w", err)
 	}
-	b := make([]byte, stat.Size())
-	n, err := i.file.Read(b)
+	items, err := unmarshalLegacyIndex(b)
 	if err != nil {
 		return fmt.Errorf("index.Load: %w", err)
 	}
-	if n != len(b) {
-		return fmt.Errorf("index.Load: %w", NewIncompleteReadError(len(b), n))
-	}
-	if err = i.UnmarshalBinary(b); err != nil {
+	if err = i.Clear(); err != nil {
 		return fmt.Errorf("index.Load: %w", err)
 	}
-	return nil
-}
-
-func (i *Index) MarshalBinary() ([]byte, error) {
-	buf := bytes.Buffer{}
-
-	// type
-	if err := binary.Write(&buf, binary.LittleEndian, types.TypeIndex); err != nil {
-		return nil, fmt.Errorf("index.MarshalBinary: type: %w", err)
-	}
-
-	// length
-	itemsLen := uint32(i.btree.Len() * binary.Size(Item{}))
-	itemsMetaLen := uint32(i.btree.Len()) * types.LenMeta
-	if err := binary.Write(&buf, binary.LittleEndian, itemsLen+itemsMetaLen); err != nil {
-		return nil, fmt.Errorf("index.MarshalBinary: len: %w", err)
-	}
-
-	for _, v := range i.GetAll() {
-		data, err := v.MarshalBinary()
-		if err != nil {
-			return nil, fmt.Errorf("index.MarshalBinary: %w", err)
+	for _, item := range items {
+		if err = i.Add(item.key, item.PagePos); err != nil {
+			return fmt.Errorf("index.Load: %w", err)
 		}
-		buf.Write(data)
-	}
-	return buf.Bytes(), nil
-}
-
-func (i *Index) UnmarshalBinary(data []byte) error {
-	byteUnmarshaler := encoding.NewValueUnmarshaler[byte]()
-	int32Unmarshaler := encoding.NewValueUnmarshaler[uint32]()
-	int64Unmarshaler := encoding.NewValueUnmarshaler[int64]()
-
-	n := 0
-	// type
-	if err := byteUnmarshaler.UnmarshalBinary(data); err != nil {
-		return fmt.Errorf("index.UnmarshalBinary: type: %w", err)
-	}
-	n++
-	// len
-	if err := int32Unmarshaler.UnmarshalBinary(data[n:]); err != nil {
-		return fmt.Errorf("index.UnmarshalBinary: len: %w", err)
 	}
-	n += types.LenInt32
-
-	for {
-		// type of index item
-		if err := byteUnmarshaler.UnmarshalBinary(data[n:]); err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return fmt.Errorf("index.UnmarshalBinary: ID type: %w", err)
-		}
-		n++
-		// len of index item
-		if err := int32Unmarshaler.UnmarshalBinary(data[n:]); err != nil {
-			return fmt.Errorf("index.UnmarshalBinary: ID len: %w", err)
-		}
-		n += types.LenInt32
-
-		idTLV := encoding.NewTLVUnmarshaler(int64Unmarshaler)
-		if err := idTLV.UnmarshalBinary(data[n:]); err != nil {
-			return fmt.Errorf("index.UnmarshalBinary: ID TLV: %w", err)
-		}
-		n += int(idTLV.BytesRead)
-		id := idTLV.Value
-
-		pagePosTLV := encoding.NewTLVUnmarshaler(int64Unmarshaler)
-		if err := pagePosTLV.UnmarshalBinary(data[n:]); err != nil {
-			return fmt.Errorf("index.UnmarshalBinary: page pos: %w", err)
-		}
-		n += int(pagePosTLV.BytesRead)
-		pagePos := pagePosTLV.Value
-		i.Add(id, pagePos)
+	if err = i.Flush(); err != nil {
+		return fmt.Errorf("index.Load: %w", err)
 	}
-}
-
-func (i *Index) GetAll() []Item {
-	out := make([]Item, 0)
-	i.btree.Ascend(func(a Item) bool {
-		out = append(out, a)
-		return true
-	})
-	return out
+	return nil
 }
 
 type Item struct {
-	id int64
+	key Key
 	// PagePos is the byte position where the page starts in the table returned by os.File.Seek()
 	PagePos int64
 }
 
-func NewItem(id, pagePos int64) *Item {
+func NewItem(key Key, pagePos int64) *Item {
 	return &Item{
-		id:      id,
+		key:     key,
 		PagePos: pagePos,
 	}
 }
 
-func (i *Item) MarshalBinary() ([]byte, error) {
-	buf := bytes.Buffer{}
-	// type
-	if err := binary.Write(&buf, binary.LittleEndian, types.TypeIndexItem); err != nil {
-		return nil, fmt.Errorf("item.MarshalBinary: type: %w", err)
-	}
-	// len
-	if err := binary.Write(&buf, binary.LittleEndian, uint32(binary.Size(i))); err != nil {
-		return nil, fmt.Errorf("item.MarshalBinary: len: %w", err)
-	}
-	idTLV := encoding.NewTLVMarshaler(i.id)
-	idBuf, err := idTLV.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("item.MarshalBinary: ID: %w", err)
-	}
-	buf.Write(idBuf)
-
-	pagePosTLV := encoding.NewTLVMarshaler(i.PagePos)
-	pagePosBuf, err := pagePosTLV.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("item.MarshalBinary: page pos: %w", err)
-	}
-	buf.Write(pagePosBuf)
-	return buf.Bytes(), nil
+func (i *Item) Key() Key {
+	return i.key
 }
 
 // ReadRaw returns the raw byte array stored in the idx. It's for debugging
 func (i *Index) ReadRaw() ([]byte, error) {
-	if _, err := i.file.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("index.ReadRaw: %w", err)
-	}
-
-	stat, err := i.file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("index.ReadRaw: %w", err)
-	}
-
-	buf := make([]byte, stat.Size())
-	if _, err = i.file.Read(buf); err != nil {
-		return nil, fmt.Errorf("index.ReadRaw: %w", err)
-	}
-	return buf, nil
+	return i.tree.readRaw()
 }
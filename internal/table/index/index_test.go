@@ -0,0 +1,123 @@
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func createFile(t *testing.T, name string) *os.File {
+	f, err := os.Create(filepath.Join(t.TempDir(), name))
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func TestIndex_CompositeKey(t *testing.T) {
+	idx := NewIndex(createFile(t, "users_idx.bin"), bufferpool.New(bufferpool.MinSize))
+	assert.Nil(t, idx.Add(NewKey("us", int64(2)), 100))
+	assert.Nil(t, idx.Add(NewKey("eu", int64(1)), 100))
+	assert.Nil(t, idx.Add(NewKey("us", int64(1)), 200))
+	assert.Nil(t, idx.Add(NewKey("ap", int64(7)), 300))
+
+	keys := func(items []Item, err error) []Key {
+		assert.Nil(t, err)
+		out := make([]Key, 0, len(items))
+		for _, v := range items {
+			out = append(out, v.Key())
+		}
+		return out
+	}
+
+	item, err := idx.Get(NewKey("us", int64(1)))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(200), item.PagePos)
+	_, err = idx.Get(NewKey("us", int64(3)))
+	var errNotFound *ItemNotFoundError
+	assert.ErrorAs(t, err, &errNotFound)
+
+	// Ranges are on the first column of the key
+	assert.Equal(t, []Key{NewKey("us", int64(1)), NewKey("us", int64(2))}, keys(idx.Range(NewBound("us", true), NewBound("us", true))))
+	assert.Equal(t, []Key{NewKey("eu", int64(1)), NewKey("us", int64(1)), NewKey("us", int64(2))}, keys(idx.Range(NewBound("ap", false), nil)))
+	assert.Equal(t, []Key{NewKey("ap", int64(7))}, keys(idx.Range(nil, NewBound("eu", false))))
+}
+
+func TestIndex_LoadLegacyFormat(t *testing.T) {
+	f := createFile(t, "users_idx.bin")
+
+	// Items used to store 16 as their length
+	_, err := f.Write([]byte{240, 42, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 77, 2, 0, 0, 0, 0, 0, 0, 241, 16, 0, 0, 0, 1, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1, 8, 0, 0, 0, 189, 2, 0, 0, 0, 0, 0, 0})
+	assert.Nil(t, err)
+
+	idx := NewIndex(f, bufferpool.New(bufferpool.MinSize))
+	assert.Nil(t, idx.Load())
+	items, err := idx.GetAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []Item{*NewItem(NewKey(int64(1)), 589), *NewItem(NewKey(int64(2)), 701)}, items)
+
+	// It's written as a B+tree
+	assert.Nil(t, idx.Add(NewKey(int64(3)), 589))
+	assert.Nil(t, idx.Flush())
+	loaded := NewIndex(f, bufferpool.New(bufferpool.MinSize))
+	assert.Nil(t, loaded.Load())
+	loadedItems, err := loaded.GetAll()
+	assert.Nil(t, err)
+	items, err = idx.GetAll()
+	assert.Nil(t, err)
+	assert.Equal(t, items, loadedItems)
+}
+
+func TestIndex_BTree(t *testing.T) {
+	f := createFile(t, "users_idx.bin")
+	// The pool only holds 16 nodes, so most of them are evicted and read again
+	pool := bufferpool.New(bufferpool.MinSize)
+	idx := NewIndex(f, pool)
+
+	const n = 5000
+	key := func(i int) Key {
+		return NewKey(fmt.Sprintf("user%05d", i), int64(i))
+	}
+	for _, i := range rand.New(rand.NewSource(1)).Perm(n) {
+		assert.Nil(t, idx.Add(key(i), int64(i)))
+	}
+	for i := 0; i < n; i += 2 {
+		assert.Nil(t, idx.Remove(key(i)))
+	}
+	assert.Greater(t, pool.Stats().Evictions, int64(0))
+
+	// Adding a key to a node that has space only writes the node
+	assert.Nil(t, idx.Flush())
+	assert.Nil(t, idx.Add(key(0), 0))
+	assert.Equal(t, 1, pool.Stats().Dirty)
+	assert.Nil(t, idx.Flush())
+
+	loaded := NewIndex(f, bufferpool.New(bufferpool.DefaultSize))
+	assert.Nil(t, loaded.Load())
+	items, err := loaded.GetAll()
+	assert.Nil(t, err)
+	assert.Len(t, items, n/2+1)
+	assert.Equal(t, key(0), items[0].Key())
+	for j, item := range items[1:] {
+		assert.Equal(t, key(2*j+1), item.Key())
+		assert.Equal(t, int64(2*j+1), item.PagePos)
+	}
+
+	item, err := loaded.Get(key(4001))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4001), item.PagePos)
+	_, err = loaded.Get(key(4000))
+	var errNotFound *ItemNotFoundError
+	assert.ErrorAs(t, err, &errNotFound)
+	items, err = loaded.Range(NewBound("user04000", false), NewBound("user04004", true))
+	assert.Nil(t, err)
+	assert.Equal(t, []Item{*NewItem(key(4001), 4001), *NewItem(key(4003), 4003)}, items)
+
+	var errTooLong *KeyTooLongError
+	assert.ErrorAs(t, loaded.Add(NewKey(strings.Repeat("a", MaxKeyLen)), 0), &errTooLong)
+}
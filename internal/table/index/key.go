@@ -0,0 +1,150 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// Key is the primary key of a record. It has one value for every column of the primary key in the order they were declared
+type Key []interface{}
+
+func NewKey(values ...interface{}) Key {
+	return values
+}
+
+// Compare compares the keys column by column. If every value of the shorter key equals the beginning of the longer key the shorter one is smaller,
+// so a key that only contains the first column of a composite key can be used to find every item that starts with it
+func (k Key) Compare(other Key) int {
+	for i := 0; i < len(k) && i < len(other); i++ {
+		if cmp := compareKeys(k[i], other[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return len(k) - len(other)
+}
+
+// MarshalBinary encodes every value of the key as a TLV
+// Floats that compare as equal are encoded the same way, so the String of equal keys is equal
+func (k Key) MarshalBinary() ([]byte, error) {
+	buf := bytes.Buffer{}
+	for _, v := range k {
+		b, err := encoding.NewTLVMarshaler(canonicalFloat(v)).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("Key.MarshalBinary: %w", err)
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+// String returns the encoded key. Two keys are equal if their strings are equal so it can be used as a map key
+func (k Key) String() string {
+	b, err := k.MarshalBinary()
+	if err != nil {
+		return fmt.Sprint([]interface{}(k))
+	}
+	return string(b)
+}
+
+// canonicalFloat replaces -0 with 0 and every NaN with the same NaN. Other values are returned as they are
+func canonicalFloat(v interface{}) interface{} {
+	switch f := v.(type) {
+	case float64:
+		if math.IsNaN(f) {
+			return math.NaN()
+		}
+		if f == 0 {
+			return float64(0)
+		}
+	case float32:
+		if math.IsNaN(float64(f)) {
+			return float32(math.NaN())
+		}
+		if f == 0 {
+			return float32(0)
+		}
+	}
+	return v
+}
+
+// unmarshalKeyValues decodes TLV encoded values until data is consumed
+func unmarshalKeyValues(data []byte) (Key, error) {
+	key := make(Key, 0, 1)
+	n := 0
+	for n < len(data) {
+		v, read, err := unmarshalKey(data[n:])
+		if err != nil {
+			return nil, fmt.Errorf("unmarshalKeyValues: %w", err)
+		}
+		key = append(key, v)
+		n += int(read)
+	}
+	return key, nil
+}
+
+// unmarshalKey decodes a TLV encoded scalar and returns the number of bytes read
+func unmarshalKey(data []byte) (interface{}, uint32, error) {
+	if len(data) == 0 {
+		return nil, 0, fmt.Errorf("unmarshalKey: empty input")
+	}
+	switch data[0] {
+	case types.TypeInt64:
+		return unmarshalTLV[int64](data)
+	case types.TypeInt32:
+		return unmarshalTLV[int32](data)
+	case types.TypeFloat64:
+		return unmarshalTLV[float64](data)
+	case types.TypeFloat32:
+		return unmarshalTLV[float32](data)
+	case types.TypeDate:
+		return unmarshalTLV[types.Date](data)
+	case types.TypeTimestamp:
+		return unmarshalTLV[time.Time](data)
+	case types.TypeInterval:
+		return unmarshalTLV[time.Duration](data)
+	case types.TypeDecimal:
+		return unmarshalTLV[types.Decimal](data)
+	case types.TypeBlob:
+		return unmarshalTLV[[]byte](data)
+	case types.TypeByte:
+		return unmarshalTLV[byte](data)
+	case types.TypeBool:
+		return unmarshalTLV[bool](data)
+	case types.TypeString:
+		return unmarshalTLV[string](data)
+	default:
+		return nil, 0, fmt.Errorf("unmarshalKey: unknown type: %d", data[0])
+	}
+}
+
+func unmarshalTLV[T any](data []byte) (interface{}, uint32, error) {
+	if len(data) < int(types.LenMeta) {
+		return nil, 0, fmt.Errorf("unmarshalTLV: %w", NewIncompleteReadError(int(types.LenMeta), len(data)))
+	}
+	// Strings are unmarshaled from the whole input so it needs to end where the value ends
+	end := types.LenMeta + binary.LittleEndian.Uint32(data[types.LenByte:types.LenMeta])
+	if int(end) > len(data) {
+		return nil, 0, fmt.Errorf("unmarshalTLV: %w", NewIncompleteReadError(int(end), len(data)))
+	}
+	tlv := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[T]())
+	if err := tlv.UnmarshalBinary(data[:end]); err != nil {
+		return nil, 0, fmt.Errorf("unmarshalTLV: %w", err)
+	}
+	return tlv.Value, tlv.BytesRead, nil
+}
+
+// compareKeys orders keys of the same type. Every key in an index has the type of the column
+// so types.Compare cannot fail for keys coming from the table
+func compareKeys(a, b interface{}) int {
+	cmp, err := types.Compare(a, b)
+	if err != nil {
+		return 0
+	}
+	return cmp
+}
@@ -0,0 +1,108 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// legacyItemLen is the length stored in items written before keys could be strings or composite. It was binary.Size(Item{}) instead of the real length
+const legacyItemLen = 16
+
+// unmarshalLegacyIndex decodes a primary index written as a whole before indexes were B+trees:
+//
+//	240 len [241 len [key TLV]... [page pos TLV]]...
+func unmarshalLegacyIndex(data []byte) ([]Item, error) {
+	if data[0] != types.TypeIndex {
+		return nil, fmt.Errorf("unmarshalLegacyIndex: expected type flag %d received %d", types.TypeIndex, data[0])
+	}
+	items := make([]Item, 0)
+	n := types.LenByte + types.LenInt32
+	int64Unmarshaler := encoding.NewValueUnmarshaler[int64]()
+	pagePosLen := int(types.LenMeta + types.LenInt64)
+
+	for n < len(data) {
+		if data[n] != types.TypeIndexItem {
+			return nil, fmt.Errorf("unmarshalLegacyIndex: expected type flag %d received %d", types.TypeIndexItem, data[n])
+		}
+		if len(data) < n+int(types.LenMeta) {
+			return nil, fmt.Errorf("unmarshalLegacyIndex: %w", NewIncompleteReadError(n+int(types.LenMeta), len(data)))
+		}
+		itemLen := int(binary.LittleEndian.Uint32(data[n+types.LenByte:]))
+		if itemLen == legacyItemLen {
+			// An int64 ID and the page pos
+			itemLen = 2 * pagePosLen
+		}
+		n += int(types.LenMeta)
+		if len(data) < n+itemLen || itemLen <= pagePosLen {
+			return nil, fmt.Errorf("unmarshalLegacyIndex: %w", NewIncompleteReadError(n+itemLen, len(data)))
+		}
+
+		key, err := unmarshalKeyValues(data[n : n+itemLen-pagePosLen])
+		if err != nil {
+			return nil, fmt.Errorf("unmarshalLegacyIndex: key: %w", err)
+		}
+		n += itemLen - pagePosLen
+
+		pagePosTLV := encoding.NewTLVUnmarshaler(int64Unmarshaler)
+		if err = pagePosTLV.UnmarshalBinary(data[n:]); err != nil {
+			return nil, fmt.Errorf("unmarshalLegacyIndex: page pos: %w", err)
+		}
+		n += int(pagePosTLV.BytesRead)
+		items = append(items, *NewItem(key, pagePosTLV.Value))
+	}
+	return items, nil
+}
+
+// unmarshalLegacySecondaryIndex decodes a secondary index written as a whole before indexes were B+trees. Every item
+// starts with the key:
+//
+//	240 len [241 len [key TLV] [primary key TLV]... [page pos TLV]]...
+func unmarshalLegacySecondaryIndex(data []byte) ([]SecondaryItem, error) {
+	if data[0] != types.TypeIndex {
+		return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: expected type flag %d received %d", types.TypeIndex, data[0])
+	}
+	items := make([]SecondaryItem, 0)
+	n := types.LenByte + types.LenInt32
+	int64Unmarshaler := encoding.NewValueUnmarshaler[int64]()
+
+	for n < len(data) {
+		if data[n] != types.TypeIndexItem {
+			return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: expected type flag %d received %d", types.TypeIndexItem, data[n])
+		}
+		if len(data) < n+int(types.LenMeta) {
+			return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: %w", NewIncompleteReadError(n+int(types.LenMeta), len(data)))
+		}
+		end := n + int(types.LenMeta) + int(binary.LittleEndian.Uint32(data[n+types.LenByte:]))
+		n += int(types.LenMeta)
+		if len(data) < end {
+			return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: %w", NewIncompleteReadError(end, len(data)))
+		}
+
+		key, read, err := unmarshalKey(data[n:])
+		if err != nil {
+			return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: key: %w", err)
+		}
+		n += int(read)
+
+		pagePosStart := end - int(types.LenMeta+types.LenInt64)
+		if pagePosStart <= n {
+			return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: item without primary key")
+		}
+		pk, err := unmarshalKeyValues(data[n:pagePosStart])
+		if err != nil {
+			return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: primary key: %w", err)
+		}
+		n = pagePosStart
+
+		pagePosTLV := encoding.NewTLVUnmarshaler(int64Unmarshaler)
+		if err = pagePosTLV.UnmarshalBinary(data[n:]); err != nil {
+			return nil, fmt.Errorf("unmarshalLegacySecondaryIndex: page pos: %w", err)
+		}
+		n += int(pagePosTLV.BytesRead)
+		items = append(items, *NewSecondaryItem(key, pk, pagePosTLV.Value))
+	}
+	return items, nil
+}
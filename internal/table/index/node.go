@@ -0,0 +1,180 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"sort"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+const (
+	// NodeSize is the size of the pages of an index file
+	NodeSize = 4096
+	// nodeHeaderLen is the length of the type, checksum, leaf flag, entry count and next position of a node
+	nodeHeaderLen = 16
+	// entryOverhead is the length of the key length and the value of an entry
+	entryOverhead = 10
+	// MaxKeyLen is the length of the longest encoded key. Four entries always fit into a node, so both halves of a
+	// split node fit into a page
+	MaxKeyLen = (NodeSize-nodeHeaderLen)/4 - entryOverhead
+
+	nodeChecksumOffset = 1
+	nodeLeafOffset     = 5
+	nodeCountOffset    = 6
+	nodeNextOffset     = 8
+)
+
+// node is a node of a B+tree stored in a page of NodeSize bytes:
+//
+//	243 [checksum uint32][leaf byte][entry count uint16][next int64] ([key len uint16][key TLV]... [value int64])...
+//
+// The entries of a leaf map keys to the page positions of records and next is the position of the leaf on its right
+// or 0. An internal node with n keys has n+1 children: next is the first one and the value of an entry is the child
+// that contains the keys greater than or equal to the key of the entry
+//
+// The checksum is the CRC-32 of everything after it
+type node struct {
+	pos  int64
+	leaf bool
+	next int64
+	keys []Key
+	// raw contains the encoded keys
+	raw    [][]byte
+	values []int64
+}
+
+// parseNode verifies the type and the checksum of the page read from pos and decodes its entries
+func parseNode(data []byte, pos int64) (*node, error) {
+	if len(data) != NodeSize || data[0] != types.TypeBtreeNode {
+		return nil, fmt.Errorf("parseNode: node expected at %d", pos)
+	}
+	if binary.LittleEndian.Uint32(data[nodeChecksumOffset:]) != crc32.ChecksumIEEE(data[nodeLeafOffset:]) {
+		return nil, fmt.Errorf("parseNode: %w", NewChecksumMismatchError(pos))
+	}
+	count := int(binary.LittleEndian.Uint16(data[nodeCountOffset:]))
+	n := &node{
+		pos:    pos,
+		leaf:   data[nodeLeafOffset] == 1,
+		next:   int64(binary.LittleEndian.Uint64(data[nodeNextOffset:])),
+		keys:   make([]Key, 0, count),
+		raw:    make([][]byte, 0, count),
+		values: make([]int64, 0, count),
+	}
+	off := nodeHeaderLen
+	for range count {
+		if off+entryOverhead > len(data) {
+			return nil, fmt.Errorf("parseNode: %w", NewIncompleteReadError(off+entryOverhead, len(data)))
+		}
+		keyLen := int(binary.LittleEndian.Uint16(data[off:]))
+		off += 2
+		if off+keyLen+types.LenInt64 > len(data) {
+			return nil, fmt.Errorf("parseNode: %w", NewIncompleteReadError(off+keyLen+types.LenInt64, len(data)))
+		}
+		raw := data[off : off+keyLen]
+		key, err := unmarshalKeyValues(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parseNode: %w", err)
+		}
+		off += keyLen
+		n.keys = append(n.keys, key)
+		n.raw = append(n.raw, raw)
+		n.values = append(n.values, int64(binary.LittleEndian.Uint64(data[off:])))
+		off += types.LenInt64
+	}
+	return n, nil
+}
+
+// bytes encodes the node into a new page
+func (n *node) bytes() []byte {
+	data := make([]byte, NodeSize)
+	data[0] = types.TypeBtreeNode
+	if n.leaf {
+		data[nodeLeafOffset] = 1
+	}
+	binary.LittleEndian.PutUint16(data[nodeCountOffset:], uint16(len(n.keys)))
+	binary.LittleEndian.PutUint64(data[nodeNextOffset:], uint64(n.next))
+	off := nodeHeaderLen
+	for i, raw := range n.raw {
+		binary.LittleEndian.PutUint16(data[off:], uint16(len(raw)))
+		off += 2
+		off += copy(data[off:], raw)
+		binary.LittleEndian.PutUint64(data[off:], uint64(n.values[i]))
+		off += types.LenInt64
+	}
+	binary.LittleEndian.PutUint32(data[nodeChecksumOffset:], crc32.ChecksumIEEE(data[nodeLeafOffset:]))
+	return data
+}
+
+// size returns the number of bytes the encoded node needs
+func (n *node) size() int {
+	size := nodeHeaderLen
+	for _, raw := range n.raw {
+		size += len(raw) + entryOverhead
+	}
+	return size
+}
+
+// search returns the index of the first key that is not smaller than key
+func (n *node) search(key Key) int {
+	return sort.Search(len(n.keys), func(i int) bool {
+		return n.keys[i].Compare(key) >= 0
+	})
+}
+
+// child returns the index and the position of the child of an internal node that can contain key
+func (n *node) child(key Key) (int, int64) {
+	i := sort.Search(len(n.keys), func(i int) bool {
+		return n.keys[i].Compare(key) > 0
+	})
+	if i == 0 {
+		return 0, n.next
+	}
+	return i, n.values[i-1]
+}
+
+func (n *node) insertAt(i int, key Key, raw []byte, value int64) {
+	n.keys = slices.Insert(n.keys, i, key)
+	n.raw = slices.Insert(n.raw, i, raw)
+	n.values = slices.Insert(n.values, i, value)
+}
+
+func (n *node) removeAt(i int) {
+	n.keys = slices.Delete(n.keys, i, i+1)
+	n.raw = slices.Delete(n.raw, i, i+1)
+	n.values = slices.Delete(n.values, i, i+1)
+}
+
+// split moves the entries of the second half of the node by size into a new node stored at pos. It returns the new
+// node and the smallest key that belongs to it
+func (n *node) split(pos int64) (*node, Key, []byte) {
+	total := n.size() - nodeHeaderLen
+	m, size := 0, 0
+	for m < len(n.raw)-1 && size < total/2 {
+		size += len(n.raw[m]) + entryOverhead
+		m++
+	}
+	m = max(m, 1)
+
+	right := &node{pos: pos, leaf: n.leaf}
+	key, raw := n.keys[m], n.raw[m]
+	if n.leaf {
+		right.next = n.next
+		n.next = pos
+		right.keys = slices.Clone(n.keys[m:])
+		right.raw = slices.Clone(n.raw[m:])
+		right.values = slices.Clone(n.values[m:])
+	} else {
+		// The key moves up into the parent and its child becomes the first child of the new node
+		right.next = n.values[m]
+		right.keys = slices.Clone(n.keys[m+1:])
+		right.raw = slices.Clone(n.raw[m+1:])
+		right.values = slices.Clone(n.values[m+1:])
+	}
+	n.keys = n.keys[:m]
+	n.raw = n.raw[:m]
+	n.values = n.values[:m]
+	return right, key, raw
+}
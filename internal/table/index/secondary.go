@@ -0,0 +1,216 @@
+package index
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
+)
+
+// Bound is one end of a range query
+type Bound struct {
+	Key       interface{}
+	Inclusive bool
+}
+
+func NewBound(key interface{}, inclusive bool) *Bound {
+	return &Bound{
+		Key:       key,
+		Inclusive: inclusive,
+	}
+}
+
+// SecondaryIndex is a B-tree index on a column that is not the first column of the primary key
+// Multiple records can have the same key so items are ordered by key first and primary key second. The B+tree stores
+// them with the key followed by the columns of the primary key
+// NULL values are not indexed
+type SecondaryIndex struct {
+	tree   *tree
+	column string
+}
+
+func NewSecondaryIndex(f *os.File, column string, pool *bufferpool.Pool) *SecondaryIndex {
+	return &SecondaryIndex{
+		tree:   newTree(f, pool),
+		column: column,
+	}
+}
+
+type SecondaryItem struct {
+	Key interface{}
+	// PK is the primary key of the record
+	PK Key
+	// PagePos is the byte position where the page starts in the table returned by os.File.Seek()
+	PagePos int64
+}
+
+func NewSecondaryItem(key interface{}, pk Key, pagePos int64) *SecondaryItem {
+	return &SecondaryItem{
+		Key:     key,
+		PK:      pk,
+		PagePos: pagePos,
+	}
+}
+
+func (i *SecondaryIndex) Column() string {
+	return i.column
+}
+
+// SetBufferPool makes the index keep its nodes in pool. The dirty nodes of the pool used before are written first
+func (i *SecondaryIndex) SetBufferPool(pool *bufferpool.Pool) error {
+	if err := i.tree.setPool(pool); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.SetBufferPool: %w", err)
+	}
+	return nil
+}
+
+// Close writes the dirty nodes and closes the file
+func (i *SecondaryIndex) Close() error {
+	if err := i.tree.close(); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Close: %w", err)
+	}
+	return nil
+}
+
+func (i *SecondaryIndex) Add(key interface{}, pk Key, pagePos int64) error {
+	if key == nil {
+		return nil
+	}
+	if err := i.tree.put(append(NewKey(key), pk...), pagePos); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Add: %w", err)
+	}
+	return nil
+}
+
+// Check returns KeyTooLongError if the item of key and pk cannot be added to the index
+func (i *SecondaryIndex) Check(key interface{}, pk Key) error {
+	if key == nil {
+		return nil
+	}
+	if _, err := marshalKey(append(NewKey(key), pk...)); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Check: %w", err)
+	}
+	return nil
+}
+
+// Remove removes the item of key and pk. An item that is not in the index is ignored
+func (i *SecondaryIndex) Remove(key interface{}, pk Key) error {
+	if key == nil {
+		return nil
+	}
+	if _, err := i.tree.delete(append(NewKey(key), pk...)); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Remove: %w", err)
+	}
+	return nil
+}
+
+// RemoveMany removes items identified by their key and primary key. PagePos is ignored
+func (i *SecondaryIndex) RemoveMany(items []SecondaryItem) error {
+	for _, item := range items {
+		if err := i.Remove(item.Key, item.PK); err != nil {
+			return fmt.Errorf("index.SecondaryIndex.RemoveMany: %w", err)
+		}
+	}
+	return nil
+}
+
+// Clear removes every item and empties the file
+func (i *SecondaryIndex) Clear() error {
+	if err := i.tree.clear(); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Clear: %w", err)
+	}
+	return nil
+}
+
+// Get returns every item with the given key ordered by primary key
+func (i *SecondaryIndex) Get(key interface{}) ([]SecondaryItem, error) {
+	return i.Range(NewBound(key, true), NewBound(key, true))
+}
+
+// Range returns the items with a key between from and to in ascending order
+// A nil bound means the range is unbounded on that side
+func (i *SecondaryIndex) Range(from, to *Bound) ([]SecondaryItem, error) {
+	out := make([]SecondaryItem, 0)
+	var start Key
+	if from != nil {
+		// An empty primary key is smaller than every other one
+		start = NewKey(from.Key)
+	}
+	err := i.tree.ascend(start, func(key Key, pagePos int64) bool {
+		if from != nil && !from.Inclusive && compareKeys(key[0], from.Key) == 0 {
+			return true
+		}
+		if to != nil {
+			cmp := compareKeys(key[0], to.Key)
+			if cmp > 0 || (cmp == 0 && !to.Inclusive) {
+				return false
+			}
+		}
+		out = append(out, *NewSecondaryItem(key[0], key[1:], pagePos))
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("index.SecondaryIndex.Range: %w", err)
+	}
+	return out, nil
+}
+
+func (i *SecondaryIndex) GetAll() ([]SecondaryItem, error) {
+	return i.Range(nil, nil)
+}
+
+// Flush writes the dirty nodes into the file without syncing it
+func (i *SecondaryIndex) Flush() error {
+	if err := i.tree.flush(); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Flush: %w", err)
+	}
+	return nil
+}
+
+// Sync writes the dirty nodes and commits the index file to the disk
+func (i *SecondaryIndex) Sync() error {
+	if err := i.tree.sync(); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Sync: %w", err)
+	}
+	return nil
+}
+
+// Load reads the root of the B+tree. An index written in the format used before is converted into a B+tree
+func (i *SecondaryIndex) Load() error {
+	legacy, err := i.tree.legacy()
+	if err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Load: %w", err)
+	}
+	if !legacy {
+		if err = i.tree.load(); err != nil {
+			return fmt.Errorf("index.SecondaryIndex.Load: %w", err)
+		}
+		return nil
+	}
+
+	b, err := readFile(i.tree.file)
+	if err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Load: %w", err)
+	}
+	items, err := unmarshalLegacySecondaryIndex(b)
+	if err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Load: %w", err)
+	}
+	if err = i.Clear(); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Load: %w", err)
+	}
+	for _, item := range items {
+		if err = i.Add(item.Key, item.PK, item.PagePos); err != nil {
+			return fmt.Errorf("index.SecondaryIndex.Load: %w", err)
+		}
+	}
+	if err = i.Flush(); err != nil {
+		return fmt.Errorf("index.SecondaryIndex.Load: %w", err)
+	}
+	return nil
+}
+
+// ReadRaw returns the raw byte array stored in the idx. It's for debugging
+func (i *SecondaryIndex) ReadRaw() ([]byte, error) {
+	return i.tree.readRaw()
+}
@@ -0,0 +1,54 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecondaryIndex_Range(t *testing.T) {
+	idx := NewSecondaryIndex(createFile(t, "users_age_idx.bin"), "age", bufferpool.New(bufferpool.MinSize))
+	assert.Nil(t, idx.Add(byte(30), NewKey(int64(1)), 100))
+	assert.Nil(t, idx.Add(byte(25), NewKey(int64(2)), 100))
+	assert.Nil(t, idx.Add(byte(30), NewKey(int64(3)), 200))
+	assert.Nil(t, idx.Add(byte(40), NewKey(int64(4)), 200))
+	assert.Nil(t, idx.Add(nil, NewKey(int64(5)), 200))
+
+	ids := func(items []SecondaryItem, err error) []int64 {
+		assert.Nil(t, err)
+		out := make([]int64, 0, len(items))
+		for _, v := range items {
+			out = append(out, v.PK[0].(int64))
+		}
+		return out
+	}
+
+	assert.Equal(t, []int64{1, 3}, ids(idx.Get(int64(30))))
+	assert.Equal(t, []int64{}, ids(idx.Get(byte(31))))
+	assert.Equal(t, []int64{2, 1, 3, 4}, ids(idx.GetAll()))
+	assert.Equal(t, []int64{4}, ids(idx.Range(NewBound(byte(30), false), nil)))
+	assert.Equal(t, []int64{2, 1, 3}, ids(idx.Range(nil, NewBound(byte(30), true))))
+	assert.Equal(t, []int64{2}, ids(idx.Range(NewBound(byte(20), true), NewBound(byte(30), false))))
+	assert.Equal(t, []int64{}, ids(idx.Range(NewBound(byte(40), true), NewBound(byte(20), true))))
+}
+
+func TestSecondaryIndex_Persist(t *testing.T) {
+	f := createFile(t, "users_username_idx.bin")
+
+	idx := NewSecondaryIndex(f, "username", bufferpool.New(bufferpool.MinSize))
+	assert.Nil(t, idx.Add("user2", NewKey(int64(2)), 300))
+	assert.Nil(t, idx.Add("user1", NewKey(int64(1)), 300))
+	assert.Nil(t, idx.Add("user10", NewKey("eu", int64(10)), 428))
+	assert.Nil(t, idx.RemoveMany([]SecondaryItem{{Key: "user2", PK: NewKey(int64(2))}}))
+	assert.Nil(t, idx.Flush())
+
+	loaded := NewSecondaryIndex(f, "username", bufferpool.New(bufferpool.MinSize))
+	assert.Nil(t, loaded.Load())
+	items, err := loaded.GetAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []SecondaryItem{
+		{Key: "user1", PK: NewKey(int64(1)), PagePos: 300},
+		{Key: "user10", PK: NewKey("eu", int64(10)), PagePos: 428},
+	}, items)
+}
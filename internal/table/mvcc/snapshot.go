@@ -0,0 +1,50 @@
+package mvcc
+
+import "math"
+
+// Latest sees every version that hasn't been deleted, including the ones written by the running transaction
+// It's used by the writer itself
+var Latest = NewSnapshot(math.MaxInt64, 0)
+
+// Snapshot decides which versions of a record a reader can see. Transaction IDs are the LSNs of their begin entries,
+// so every transaction that started before the snapshot was taken has a smaller ID than Next
+//
+// Records written outside of a transaction have no version. They are visible to every snapshot
+type Snapshot struct {
+	// Next is greater than the ID of every transaction that started before the snapshot was taken
+	Next int64
+	// Active is the ID of the transaction that was running when the snapshot was taken or 0
+	Active int64
+}
+
+func NewSnapshot(next, active int64) *Snapshot {
+	return &Snapshot{
+		Next:   next,
+		Active: active,
+	}
+}
+
+// Sees reports whether the changes of the transaction with the given ID were committed when the snapshot was taken
+// Transactions that were rolled back don't need to be considered because their changes are removed from the files
+func (s *Snapshot) Sees(txID int64) bool {
+	if txID == 0 {
+		return true
+	}
+	return txID < s.Next && txID != s.Active
+}
+
+// Visible reports whether a version created by xmin and deleted by xmax is visible. xmax is 0 if it wasn't deleted
+func (s *Snapshot) Visible(xmin, xmax int64) bool {
+	if !s.Sees(xmin) {
+		return false
+	}
+	return xmax == 0 || !s.Sees(xmax)
+}
+
+// Horizon returns the smallest transaction ID whose changes the snapshot might not see
+func (s *Snapshot) Horizon() int64 {
+	if s.Active != 0 {
+		return s.Active
+	}
+	return s.Next
+}
@@ -0,0 +1,36 @@
+package mvcc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot_Visible(t *testing.T) {
+	// Transaction 3 committed before the snapshot, 5 was running and 7 started afterwards
+	snap := NewSnapshot(6, 5)
+	tests := []struct {
+		name       string
+		xmin, xmax int64
+		visible    bool
+	}{
+		{name: "no version", xmin: 0, xmax: 0, visible: true},
+		{name: "created by committed", xmin: 3, xmax: 0, visible: true},
+		{name: "created by running", xmin: 5, xmax: 0, visible: false},
+		{name: "created later", xmin: 7, xmax: 0, visible: false},
+		{name: "deleted by committed", xmin: 3, xmax: 3, visible: false},
+		{name: "deleted by running", xmin: 3, xmax: 5, visible: true},
+		{name: "deleted later", xmin: 0, xmax: 7, visible: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.visible, snap.Visible(tt.xmin, tt.xmax))
+		})
+	}
+	assert.Equal(t, int64(5), snap.Horizon())
+	assert.Equal(t, int64(6), NewSnapshot(6, 0).Horizon())
+
+	// The writer sees its own changes
+	assert.True(t, Latest.Visible(5, 0))
+	assert.False(t, Latest.Visible(3, 5))
+}
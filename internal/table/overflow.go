@@ -0,0 +1,287 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	walencoding "github.com/omesh-barhate/ByteForge/internal/table/wal/encoding"
+)
+
+// overflowChunkHeaderLen is the length of the type, the length and the position of the next chunk of an overflow chunk
+//
+//	104 len [next page pos int64][next slot uint16] [data]
+//
+// The last chunk of a value has next page pos 0
+const overflowChunkHeaderLen = int(types.LenMeta) + types.LenInt64 + 2
+
+// overflowRecordLen returns the length of the records four of which fit into a page. A longer record has its largest
+// values moved into overflow chunks that are not longer than this
+func overflowRecordLen(pageSize int) int {
+	return (pageSize-pageHeaderLen)/4 - slotLen
+}
+
+// recordPlacer plans where the records of a table are written and returns the position of the page and the slot
+type recordPlacer interface {
+	place(record []byte) (int64, int64, error)
+}
+
+// spill moves the largest strings, blobs and JSON documents of record into overflow chunks until the record is not longer than
+// overflowRecordLen and returns the record that contains pointers to them. The chunks are placed like other records
+func spill(pl recordPlacer, record []byte, pageSize int) ([]byte, error) {
+	limit := overflowRecordLen(pageSize)
+	if len(record) <= limit {
+		return record, nil
+	}
+	head, fields, err := recordFields(record)
+	if err != nil {
+		return nil, fmt.Errorf("spill: %w", err)
+	}
+
+	candidates := make([]int, 0)
+	for i, f := range fields {
+		if (f[0] == types.TypeString || f[0] == types.TypeBlob || f[0] == types.TypeJSON) && len(f) > int(types.LenMeta)+types.LenOverflowPointer {
+			candidates = append(candidates, i)
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		return len(fields[b]) - len(fields[a])
+	})
+	length := len(record)
+	for _, i := range candidates {
+		if length <= limit {
+			break
+		}
+		ptr, err := placeOverflow(pl, fields[i][0], fields[i][types.LenMeta:], pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("spill: %w", err)
+		}
+		length -= len(fields[i]) - len(ptr)
+		fields[i] = ptr
+	}
+	return joinRecord(head, fields), nil
+}
+
+// overflowChunkLen returns the number of bytes of a value that are stored in one overflow chunk
+func overflowChunkLen(pageSize int) int {
+	return overflowRecordLen(pageSize) - overflowChunkHeaderLen
+}
+
+// placeOverflow places the chunks of value and returns the overflow pointer TLV that replaces it
+func placeOverflow(pl recordPlacer, typ byte, value []byte, pageSize int) ([]byte, error) {
+	chunkLen := overflowChunkLen(pageSize)
+	parts := make([][]byte, 0, (len(value)+chunkLen-1)/chunkLen)
+	for start := 0; start < len(value); start += chunkLen {
+		parts = append(parts, value[start:min(len(value), start+chunkLen)])
+	}
+	ptr, err := placeChunks(pl, typ, parts, len(value))
+	if err != nil {
+		return nil, fmt.Errorf("placeOverflow: %w", err)
+	}
+	return ptr, nil
+}
+
+// streamOverflow reads r until EOF and returns the TLV of the value. A value that fits into one chunk is returned as
+// a TLV of type typ. A longer one is placed in overflow chunks and its overflow pointer is returned
+// r is read one chunk at a time, so the value is never copied into a single buffer, but the chunks stay in memory
+// until the statement is logged
+func streamOverflow(pl recordPlacer, typ byte, r io.Reader, pageSize int) ([]byte, error) {
+	chunkLen := overflowChunkLen(pageSize)
+	parts := make([][]byte, 0)
+	length := 0
+	for {
+		buf := make([]byte, chunkLen)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			parts = append(parts, buf[:n])
+			length += n
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("streamOverflow: %w", err)
+		}
+		if int64(length) > math.MaxUint32-int64(chunkLen) {
+			return nil, fmt.Errorf("streamOverflow: the value is longer than %d bytes", uint32(math.MaxUint32))
+		}
+	}
+	if len(parts) <= 1 {
+		tlv := make([]byte, types.LenMeta, int(types.LenMeta)+length)
+		tlv[0] = typ
+		binary.LittleEndian.PutUint32(tlv[types.LenByte:], uint32(length))
+		if length > 0 {
+			tlv = append(tlv, parts[0]...)
+		}
+		return tlv, nil
+	}
+	ptr, err := placeChunks(pl, typ, parts, length)
+	if err != nil {
+		return nil, fmt.Errorf("streamOverflow: %w", err)
+	}
+	return ptr, nil
+}
+
+// placeChunks places a chunk for each part of a value of the given length and returns the overflow pointer TLV that
+// replaces the value. The last chunk is placed first, so every chunk knows where the next one is
+func placeChunks(pl recordPlacer, typ byte, parts [][]byte, length int) ([]byte, error) {
+	var nextPos int64
+	var nextSlot int64
+	for i := len(parts) - 1; i >= 0; i-- {
+		data := parts[i]
+		chunk := make([]byte, overflowChunkHeaderLen, overflowChunkHeaderLen+len(data))
+		chunk[0] = types.TypeOverflowChunk
+		binary.LittleEndian.PutUint32(chunk[types.LenByte:], uint32(overflowChunkHeaderLen-int(types.LenMeta)+len(data)))
+		binary.LittleEndian.PutUint64(chunk[types.LenMeta:], uint64(nextPos))
+		binary.LittleEndian.PutUint16(chunk[int(types.LenMeta)+types.LenInt64:], uint16(nextSlot))
+		chunk = append(chunk, data...)
+		var err error
+		if nextPos, nextSlot, err = pl.place(chunk); err != nil {
+			return nil, fmt.Errorf("placeChunks: %w", err)
+		}
+	}
+
+	ptr := make([]byte, int(types.LenMeta)+types.LenOverflowPointer)
+	ptr[0] = types.TypeOverflowPointer
+	binary.LittleEndian.PutUint32(ptr[types.LenByte:], types.LenOverflowPointer)
+	v := ptr[types.LenMeta:]
+	v[0] = typ
+	binary.LittleEndian.PutUint64(v[types.LenByte:], uint64(nextPos))
+	binary.LittleEndian.PutUint16(v[types.LenByte+types.LenInt64:], uint16(nextSlot))
+	binary.LittleEndian.PutUint32(v[types.LenByte+types.LenInt64+2:], uint32(length))
+	return ptr, nil
+}
+
+// inlineOverflow returns a copy of record where the overflow pointers are replaced by the values they point to
+func (t *Table) inlineOverflow(record []byte) ([]byte, error) {
+	head, fields, err := recordFields(record)
+	if err != nil {
+		return nil, fmt.Errorf("Table.inlineOverflow: %w", err)
+	}
+	for i, f := range fields {
+		if f[0] != types.TypeOverflowPointer {
+			continue
+		}
+		ptr, err := parser.UnmarshalOverflowPointer(f)
+		if err != nil {
+			return nil, fmt.Errorf("Table.inlineOverflow: %w", err)
+		}
+		value, err := t.readOverflow(ptr)
+		if err != nil {
+			return nil, fmt.Errorf("Table.inlineOverflow: %w", err)
+		}
+		tlv := make([]byte, types.LenMeta, int(types.LenMeta)+len(value))
+		tlv[0] = ptr.Type
+		binary.LittleEndian.PutUint32(tlv[types.LenByte:], uint32(len(value)))
+		fields[i] = append(tlv, value...)
+	}
+	return joinRecord(head, fields), nil
+}
+
+// readOverflow returns the value stored in the chunks ptr points to
+func (t *Table) readOverflow(ptr *parser.OverflowPointer) ([]byte, error) {
+	value := make([]byte, 0, ptr.Length)
+	err := t.walkOverflow(ptr, func(_ int64, _ int, chunk []byte) error {
+		value = append(value, chunk[overflowChunkHeaderLen:]...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Table.readOverflow: %w", err)
+	}
+	return value, nil
+}
+
+// overflowDeletes returns the changes that delete the overflow chunks of record
+func (t *Table) overflowDeletes(record []byte) ([]*walencoding.Change, error) {
+	_, fields, err := recordFields(record)
+	if err != nil {
+		return nil, fmt.Errorf("Table.overflowDeletes: %w", err)
+	}
+	changes := make([]*walencoding.Change, 0)
+	for _, f := range fields {
+		if f[0] != types.TypeOverflowPointer {
+			continue
+		}
+		ptr, err := parser.UnmarshalOverflowPointer(f)
+		if err != nil {
+			return nil, fmt.Errorf("Table.overflowDeletes: %w", err)
+		}
+		err = t.walkOverflow(ptr, func(pagePos int64, slot int, chunk []byte) error {
+			changes = append(changes, walencoding.NewDeleteChange(pagePos, int64(slot), slices.Clone(chunk)))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Table.overflowDeletes: %w", err)
+		}
+	}
+	return changes, nil
+}
+
+// walkOverflow calls fn with every chunk of the value ptr points to until fn returns an error. Consecutive chunks are
+// usually on the same page, so a page is only read again if the previous chunk was on another one
+func (t *Table) walkOverflow(ptr *parser.OverflowPointer, fn func(pagePos int64, slot int, chunk []byte) error) error {
+	var p *page
+	var curr int64
+	pagePos, slot := ptr.PagePos, ptr.Slot
+	for pagePos != 0 {
+		if p == nil || curr != pagePos {
+			var err error
+			if p, err = t.readPage(pagePos); err != nil {
+				return fmt.Errorf("Table.walkOverflow: %w", err)
+			}
+			curr = pagePos
+		}
+		chunk := p.record(slot)
+		if len(chunk) < overflowChunkHeaderLen || chunk[0] != types.TypeOverflowChunk {
+			return fmt.Errorf("Table.walkOverflow: %w", NewOverflowChunkNotFoundError(pagePos, slot))
+		}
+		if err := fn(pagePos, slot, chunk); err != nil {
+			return fmt.Errorf("Table.walkOverflow: %w", err)
+		}
+		pagePos = int64(binary.LittleEndian.Uint64(chunk[types.LenMeta:]))
+		slot = int(binary.LittleEndian.Uint16(chunk[int(types.LenMeta)+types.LenInt64:]))
+	}
+	return nil
+}
+
+// recordFields splits a record into its type, length and version and the TLVs of its columns
+func recordFields(record []byte) ([]byte, [][]byte, error) {
+	pos := int(types.LenMeta)
+	if len(record) > pos && record[pos] == types.TypeRecordVersion {
+		pos += int(types.LenMeta) + types.LenRecordVersion
+	}
+	if len(record) < pos || record[0] != types.TypeRecord {
+		return nil, nil, fmt.Errorf("recordFields: record expected")
+	}
+	head := record[:pos]
+	fields := make([][]byte, 0)
+	for pos < len(record) {
+		if pos+int(types.LenMeta) > len(record) {
+			return nil, nil, fmt.Errorf("recordFields: unexpected end of record at %d", pos)
+		}
+		end := pos + int(types.LenMeta) + int(binary.LittleEndian.Uint32(record[pos+types.LenByte:]))
+		if end > len(record) {
+			return nil, nil, fmt.Errorf("recordFields: unexpected end of record at %d", pos)
+		}
+		fields = append(fields, record[pos:end])
+		pos = end
+	}
+	return head, fields, nil
+}
+
+// joinRecord returns a new record with the type, length and version of head and the given column TLVs
+func joinRecord(head []byte, fields [][]byte) []byte {
+	buf := bytes.Buffer{}
+	buf.Write(head)
+	for _, f := range fields {
+		buf.Write(f)
+	}
+	b := buf.Bytes()
+	binary.LittleEndian.PutUint32(b[types.LenByte:], uint32(len(b)-int(types.LenMeta)))
+	return b
+}
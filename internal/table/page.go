@@ -0,0 +1,248 @@
+package table
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"slices"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+const (
+	DefaultPageSize = 4096
+	// pageHeaderLen is the length of the type, checksum, LSN, slot count and free end of a page
+	pageHeaderLen = 17
+	// slotLen is the length of the offset and the length of a record in the slot directory
+	slotLen = 4
+
+	pageChecksumOffset  = 1
+	pageLSNOffset       = 5
+	pageSlotCountOffset = 13
+	pageFreeEndOffset   = 15
+)
+
+// PageSizes contains the page sizes a table can be created with
+var PageSizes = []int{4096, 8192, 16384}
+
+// page is a fixed-size slotted page:
+//
+//	254 [checksum uint32][lsn int64][slot count uint16][free end uint16] [offset uint16][length uint16]... free space [record]...
+//
+// The slot directory grows from the header and the records grow from the end of the page towards it. A record keeps
+// its slot number for as long as it exists, so WAL changes and indexes don't depend on where its bytes are. An empty
+// slot has offset 0 and is reused by the next insert
+//
+// The checksum is the CRC-32 of everything after it. lsn is the LSN of the last WAL entry applied to the page
+type page struct {
+	data []byte
+}
+
+func newPage(size int) *page {
+	p := &page{data: make([]byte, size)}
+	p.data[0] = types.TypeSlottedPage
+	p.setFreeEnd(size)
+	return p
+}
+
+// parsePage verifies the type and the checksum of the page read from pos
+func parsePage(data []byte, pos int64) (*page, error) {
+	if len(data) < pageHeaderLen || data[0] != types.TypeSlottedPage {
+		return nil, fmt.Errorf("parsePage: page expected at %d", pos)
+	}
+	p := &page{data: data}
+	if binary.LittleEndian.Uint32(data[pageChecksumOffset:]) != p.checksum() {
+		return nil, fmt.Errorf("parsePage: %w", NewChecksumMismatchError(pos))
+	}
+	return p, nil
+}
+
+// maxRecordLen returns the length of the largest record that fits into an empty page of the given size
+func maxRecordLen(pageSize int) int {
+	return pageSize - pageHeaderLen - slotLen
+}
+
+func (p *page) checksum() uint32 {
+	return crc32.ChecksumIEEE(p.data[pageLSNOffset:])
+}
+
+// bytes returns the content of the page with an up-to-date checksum
+func (p *page) bytes() []byte {
+	binary.LittleEndian.PutUint32(p.data[pageChecksumOffset:], p.checksum())
+	return p.data
+}
+
+func (p *page) clone() *page {
+	return &page{data: slices.Clone(p.data)}
+}
+
+func (p *page) lsn() int64 {
+	return int64(binary.LittleEndian.Uint64(p.data[pageLSNOffset:]))
+}
+
+func (p *page) setLSN(lsn int64) {
+	binary.LittleEndian.PutUint64(p.data[pageLSNOffset:], uint64(lsn))
+}
+
+func (p *page) slotCount() int {
+	return int(binary.LittleEndian.Uint16(p.data[pageSlotCountOffset:]))
+}
+
+func (p *page) setSlotCount(n int) {
+	binary.LittleEndian.PutUint16(p.data[pageSlotCountOffset:], uint16(n))
+}
+
+func (p *page) freeEnd() int {
+	return int(binary.LittleEndian.Uint16(p.data[pageFreeEndOffset:]))
+}
+
+func (p *page) setFreeEnd(end int) {
+	binary.LittleEndian.PutUint16(p.data[pageFreeEndOffset:], uint16(end))
+}
+
+func (p *page) slot(i int) (offset, length int) {
+	pos := pageHeaderLen + i*slotLen
+	return int(binary.LittleEndian.Uint16(p.data[pos:])), int(binary.LittleEndian.Uint16(p.data[pos+2:]))
+}
+
+func (p *page) setSlot(i, offset, length int) {
+	pos := pageHeaderLen + i*slotLen
+	binary.LittleEndian.PutUint16(p.data[pos:], uint16(offset))
+	binary.LittleEndian.PutUint16(p.data[pos+2:], uint16(length))
+}
+
+// record returns the record stored in slot i or nil if the slot is empty
+func (p *page) record(i int) []byte {
+	if i < 0 || i >= p.slotCount() {
+		return nil
+	}
+	offset, length := p.slot(i)
+	if offset == 0 {
+		return nil
+	}
+	return p.data[offset : offset+length]
+}
+
+// slots returns the numbers of the slots that contain a record
+func (p *page) slots() []int {
+	slots := make([]int, 0, p.slotCount())
+	for i := range p.slotCount() {
+		if offset, _ := p.slot(i); offset != 0 {
+			slots = append(slots, i)
+		}
+	}
+	return slots
+}
+
+// free returns the number of bytes that are used by neither the slot directory nor a record
+func (p *page) free() int {
+	used := pageHeaderLen + p.slotCount()*slotLen
+	for i := range p.slotCount() {
+		_, length := p.slot(i)
+		used += length
+	}
+	return len(p.data) - used
+}
+
+// available returns how long a record can be so it surely fits into the page. It's stored in the free-space map
+func (p *page) available() int {
+	return max(0, p.free()-slotLen)
+}
+
+// emptySlot returns the first empty slot or the slot count if every slot is used
+func (p *page) emptySlot() int {
+	for i := range p.slotCount() {
+		if offset, _ := p.slot(i); offset == 0 {
+			return i
+		}
+	}
+	return p.slotCount()
+}
+
+// fits reports whether a record of length n can be inserted
+func (p *page) fits(n int) bool {
+	return p.fitsAt(p.emptySlot(), n)
+}
+
+// fitsAt reports whether a record of length n can be stored in slot i
+func (p *page) fitsAt(i, n int) bool {
+	if p.record(i) != nil {
+		return false
+	}
+	return n+max(0, i+1-p.slotCount())*slotLen <= p.free()
+}
+
+// insert stores record in the first empty slot and returns its number
+func (p *page) insert(record []byte) (int, error) {
+	slot := p.emptySlot()
+	if err := p.set(slot, record); err != nil {
+		return 0, fmt.Errorf("page.insert: %w", err)
+	}
+	return slot, nil
+}
+
+// set stores record in slot i. The slot has to be empty. If it's after the last slot, the slots in between are added
+// as empty slots
+// The records are moved to the end of the page if the free space between the slots and the records is too small
+func (p *page) set(i int, record []byte) error {
+	if p.record(i) != nil {
+		return fmt.Errorf("page.set: slot %d is not empty", i)
+	}
+	if !p.fitsAt(i, len(record)) {
+		return fmt.Errorf("page.set: %d bytes don't fit into slot %d. Only %d bytes are free", len(record), i, p.free())
+	}
+	count := max(p.slotCount(), i+1)
+	if pageHeaderLen+count*slotLen+len(record) > p.freeEnd() {
+		p.compact()
+	}
+	for j := p.slotCount(); j < count; j++ {
+		p.setSlot(j, 0, 0)
+	}
+	offset := p.freeEnd() - len(record)
+	copy(p.data[offset:], record)
+	p.setSlotCount(count)
+	p.setSlot(i, offset, len(record))
+	p.setFreeEnd(offset)
+	return nil
+}
+
+// replace overwrites the record in slot i with a record of the same length
+func (p *page) replace(i int, record []byte) error {
+	curr := p.record(i)
+	if curr == nil || len(curr) != len(record) {
+		return fmt.Errorf("page.replace: slot %d doesn't contain a record of %d bytes", i, len(record))
+	}
+	copy(curr, record)
+	return nil
+}
+
+// remove empties slot i and overwrites the record with zeros. Empty slots at the end of the directory are removed
+func (p *page) remove(i int) {
+	clear(p.record(i))
+	p.setSlot(i, 0, 0)
+	count := p.slotCount()
+	for count > 0 {
+		if offset, _ := p.slot(count - 1); offset != 0 {
+			break
+		}
+		count--
+	}
+	p.setSlotCount(count)
+}
+
+// compact moves every record to the end of the page so the free space is contiguous
+func (p *page) compact() {
+	slots := p.slots()
+	records := make([][]byte, len(slots))
+	for j, i := range slots {
+		records[j] = slices.Clone(p.record(i))
+	}
+	end := len(p.data)
+	for j, i := range slots {
+		end -= len(records[j])
+		copy(p.data[end:], records[j])
+		p.setSlot(i, end, len(records[j]))
+	}
+	clear(p.data[pageHeaderLen+p.slotCount()*slotLen : end])
+	p.setFreeEnd(end)
+}
@@ -0,0 +1,86 @@
+package table
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPage_InsertAndRemove(t *testing.T) {
+	p := newPage(DefaultPageSize)
+	assert.Equal(t, DefaultPageSize-pageHeaderLen-slotLen, p.available())
+
+	a := bytes.Repeat([]byte{1}, 100)
+	b := bytes.Repeat([]byte{2}, 200)
+	c := bytes.Repeat([]byte{3}, 300)
+	for i, r := range [][]byte{a, b, c} {
+		slot, err := p.insert(r)
+		assert.Nil(t, err)
+		assert.Equal(t, i, slot)
+	}
+	assert.Equal(t, []int{0, 1, 2}, p.slots())
+	assert.Equal(t, b, p.record(1))
+	assert.Equal(t, DefaultPageSize-pageHeaderLen-3*slotLen-600, p.free())
+
+	// The slot of a removed record is reused
+	p.remove(1)
+	assert.Nil(t, p.record(1))
+	assert.Equal(t, []int{0, 2}, p.slots())
+	slot, err := p.insert(c)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, slot)
+	assert.Equal(t, c, p.record(1))
+
+	// Empty slots at the end are removed
+	p.remove(2)
+	assert.Equal(t, 2, p.slotCount())
+
+	assert.NotNil(t, p.set(0, a), "slot 0 is not empty")
+
+	// The slots before a slot after the last one are added as empty slots
+	assert.Nil(t, p.set(4, a))
+	assert.Equal(t, 5, p.slotCount())
+	assert.Equal(t, []int{0, 1, 4}, p.slots())
+	assert.False(t, p.fitsAt(4, 10))
+	assert.False(t, p.fitsAt(2, DefaultPageSize))
+	assert.True(t, p.fitsAt(2, 10))
+}
+
+func TestPage_Compact(t *testing.T) {
+	p := newPage(DefaultPageSize)
+	half := bytes.Repeat([]byte{1}, 2000)
+	_, err := p.insert(half)
+	assert.Nil(t, err)
+	_, err = p.insert(bytes.Repeat([]byte{2}, 2000))
+	assert.Nil(t, err)
+	p.remove(0)
+
+	// The free space is fragmented, so the record only fits after the other one was moved to the end
+	large := bytes.Repeat([]byte{3}, 2050)
+	assert.True(t, p.fits(len(large)))
+	slot, err := p.insert(large)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, slot)
+	assert.Equal(t, large, p.record(0))
+	assert.Equal(t, bytes.Repeat([]byte{2}, 2000), p.record(1))
+	assert.False(t, p.fits(100))
+}
+
+func TestParsePage(t *testing.T) {
+	p := newPage(DefaultPageSize)
+	_, err := p.insert([]byte{100, 1, 0, 0, 0, 1})
+	assert.Nil(t, err)
+	p.setLSN(42)
+	data := p.bytes()
+
+	parsed, err := parsePage(data, 4096)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), parsed.lsn())
+	assert.Equal(t, []byte{100, 1, 0, 0, 0, 1}, parsed.record(0))
+
+	data[DefaultPageSize-1] = 2
+	_, err = parsePage(data, 4096)
+	var errChecksum *ChecksumMismatchError
+	assert.ErrorAs(t, err, &errChecksum)
+}
@@ -0,0 +1,511 @@
+package predicate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// Predicate is a condition evaluated against a record read from the table file
+// It uses SQL's three-valued logic: comparisons that involve NULL (nil) values are unknown, NOT of an unknown result is
+// still unknown, so neither age > 30 nor NOT (age > 30) matches records where age is NULL
+type Predicate interface {
+	// Evaluate reports whether record matches. Records for which the predicate is unknown don't match
+	Evaluate(record map[string]interface{}) (bool, error)
+	// Test returns the three-valued result of the predicate for record
+	Test(record map[string]interface{}) (Truth, error)
+	String() string
+}
+
+// Truth is the result of a predicate in three-valued logic
+type Truth int8
+
+const (
+	False Truth = iota
+	True
+	Unknown
+)
+
+// Not returns True for False, False for True and Unknown for Unknown
+func (t Truth) Not() Truth {
+	switch t {
+	case True:
+		return False
+	case False:
+		return True
+	default:
+		return Unknown
+	}
+}
+
+func truthOf(b bool) Truth {
+	if b {
+		return True
+	}
+	return False
+}
+
+// evaluate reports whether p is true for record
+func evaluate(p Predicate, record map[string]interface{}) (bool, error) {
+	t, err := p.Test(record)
+	if err != nil {
+		return false, err
+	}
+	return t == True, nil
+}
+
+type Op string
+
+const (
+	OpEq    Op = "="
+	OpNotEq Op = "!="
+	OpLt    Op = "<"
+	OpLte   Op = "<="
+	OpGt    Op = ">"
+	OpGte   Op = ">="
+)
+
+// Flip returns the operator that gives the same result when the operands are swapped, for example 1 < id is the same as id > 1
+func (op Op) Flip() Op {
+	switch op {
+	case OpLt:
+		return OpGt
+	case OpLte:
+		return OpGte
+	case OpGt:
+		return OpLt
+	case OpGte:
+		return OpLte
+	default:
+		return op
+	}
+}
+
+type (
+	// Comparison compares a column with a constant value: column op value
+	Comparison struct {
+		Column string
+		Op     Op
+		Value  interface{}
+	}
+
+	// In matches records where the column equals any of the values
+	In struct {
+		Column string
+		Values []interface{}
+	}
+
+	// Between matches records where From <= column <= To
+	Between struct {
+		Column string
+		From   interface{}
+		To     interface{}
+	}
+
+	IsNull struct {
+		Column string
+	}
+
+	// Like matches string columns against a pattern where % matches any sequence of characters and _ matches exactly one
+	Like struct {
+		Column  string
+		Pattern string
+	}
+
+	And struct {
+		Predicates []Predicate
+	}
+
+	Or struct {
+		Predicates []Predicate
+	}
+
+	Not struct {
+		Predicate Predicate
+	}
+
+	// JSONPath evaluates Predicate against a value inside the JSON document of Column instead of the whole column
+	// Predicate refers to the value by Name, which is the path as it was written, such as attrs->'address'->>'city'
+	JSONPath struct {
+		Column string
+		Name   string
+		// Path contains object keys as strings and array indexes as int64s
+		Path []interface{}
+		// AsText is set for ->>, which returns the value as a string instead of JSON
+		AsText    bool
+		Predicate Predicate
+	}
+)
+
+func Eq(column string, value interface{}) *Comparison {
+	return &Comparison{Column: column, Op: OpEq, Value: value}
+}
+
+func NewComparison(column string, op Op, value interface{}) *Comparison {
+	return &Comparison{Column: column, Op: op, Value: value}
+}
+
+func NewIn(column string, values []interface{}) *In {
+	return &In{Column: column, Values: values}
+}
+
+func NewBetween(column string, from, to interface{}) *Between {
+	return &Between{Column: column, From: from, To: to}
+}
+
+func NewIsNull(column string) *IsNull {
+	return &IsNull{Column: column}
+}
+
+func NewLike(column, pattern string) *Like {
+	return &Like{Column: column, Pattern: pattern}
+}
+
+func NewJSONPath(column, name string, path []interface{}, asText bool, p Predicate) *JSONPath {
+	return &JSONPath{Column: column, Name: name, Path: path, AsText: asText, Predicate: p}
+}
+
+func NewAnd(predicates ...Predicate) *And {
+	return &And{Predicates: predicates}
+}
+
+func NewOr(predicates ...Predicate) *Or {
+	return &Or{Predicates: predicates}
+}
+
+func NewNot(p Predicate) *Not {
+	return &Not{Predicate: p}
+}
+
+// FromMap converts the where statements used by table.Table, such as {"id": 1, "job": "designer"}, into equality comparisons joined by AND
+func FromMap(whereStmts map[string]interface{}) Predicate {
+	keys := make([]string, 0, len(whereStmts))
+	for k := range whereStmts {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	preds := make([]Predicate, 0, len(keys))
+	for _, k := range keys {
+		preds = append(preds, Eq(k, whereStmts[k]))
+	}
+	return NewAnd(preds...)
+}
+
+// Conjuncts returns the predicates that all need to be true for p to be true
+// Nested ANDs are flattened, any other predicate is returned as a single conjunct
+func Conjuncts(p Predicate) []Predicate {
+	and, ok := p.(*And)
+	if !ok {
+		return []Predicate{p}
+	}
+	out := make([]Predicate, 0, len(and.Predicates))
+	for _, v := range and.Predicates {
+		out = append(out, Conjuncts(v)...)
+	}
+	return out
+}
+
+// Columns returns every column referenced by p
+func Columns(p Predicate) []string {
+	cols := make([]string, 0)
+	var walk func(Predicate)
+	walk = func(p Predicate) {
+		switch v := p.(type) {
+		case *Comparison:
+			cols = append(cols, v.Column)
+		case *In:
+			cols = append(cols, v.Column)
+		case *Between:
+			cols = append(cols, v.Column)
+		case *IsNull:
+			cols = append(cols, v.Column)
+		case *Like:
+			cols = append(cols, v.Column)
+		case *And:
+			for _, c := range v.Predicates {
+				walk(c)
+			}
+		case *Or:
+			for _, c := range v.Predicates {
+				walk(c)
+			}
+		case *Not:
+			walk(v.Predicate)
+		case *JSONPath:
+			cols = append(cols, v.Column)
+		}
+	}
+	walk(p)
+	return cols
+}
+
+func (c *Comparison) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(c, record)
+}
+
+func (c *Comparison) Test(record map[string]interface{}) (Truth, error) {
+	val := record[c.Column]
+	if val == nil || c.Value == nil {
+		return Unknown, nil
+	}
+	cmp, err := types.Compare(val, c.Value)
+	if err != nil {
+		return False, fmt.Errorf("Comparison.Test: column %s: %w", c.Column, err)
+	}
+	switch c.Op {
+	case OpEq:
+		return truthOf(cmp == 0), nil
+	case OpNotEq:
+		return truthOf(cmp != 0), nil
+	case OpLt:
+		return truthOf(cmp < 0), nil
+	case OpLte:
+		return truthOf(cmp <= 0), nil
+	case OpGt:
+		return truthOf(cmp > 0), nil
+	case OpGte:
+		return truthOf(cmp >= 0), nil
+	default:
+		return False, fmt.Errorf("Comparison.Test: unknown operator: %s", c.Op)
+	}
+}
+
+func (c *Comparison) String() string {
+	return fmt.Sprintf("%s %s %s", c.Column, c.Op, formatValue(c.Value))
+}
+
+func (in *In) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(in, record)
+}
+
+// Test is unknown if the column is NULL or one of the values is NULL and none of the others are equal to the column
+func (in *In) Test(record map[string]interface{}) (Truth, error) {
+	res := False
+	for _, v := range in.Values {
+		t, err := Eq(in.Column, v).Test(record)
+		if err != nil {
+			return False, fmt.Errorf("In.Test: %w", err)
+		}
+		if t == True {
+			return True, nil
+		}
+		if t == Unknown {
+			res = Unknown
+		}
+	}
+	return res, nil
+}
+
+func (in *In) String() string {
+	values := make([]string, len(in.Values))
+	for i, v := range in.Values {
+		values[i] = formatValue(v)
+	}
+	return fmt.Sprintf("%s IN (%s)", in.Column, strings.Join(values, ", "))
+}
+
+func (b *Between) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(b, record)
+}
+
+func (b *Between) Test(record map[string]interface{}) (Truth, error) {
+	return NewAnd(
+		NewComparison(b.Column, OpGte, b.From),
+		NewComparison(b.Column, OpLte, b.To),
+	).Test(record)
+}
+
+func (b *Between) String() string {
+	return fmt.Sprintf("%s BETWEEN %s AND %s", b.Column, formatValue(b.From), formatValue(b.To))
+}
+
+func (n *IsNull) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(n, record)
+}
+
+func (n *IsNull) Test(record map[string]interface{}) (Truth, error) {
+	return truthOf(record[n.Column] == nil), nil
+}
+
+func (n *IsNull) String() string {
+	return fmt.Sprintf("%s IS NULL", n.Column)
+}
+
+func (l *Like) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(l, record)
+}
+
+func (l *Like) Test(record map[string]interface{}) (Truth, error) {
+	val := record[l.Column]
+	if val == nil {
+		return Unknown, nil
+	}
+	s, ok := val.(string)
+	if !ok {
+		return False, fmt.Errorf("Like.Test: column %s is not a string: %T", l.Column, val)
+	}
+	return truthOf(matchLike([]rune(s), []rune(l.Pattern))), nil
+}
+
+func (l *Like) String() string {
+	return fmt.Sprintf("%s LIKE %s", l.Column, formatValue(l.Pattern))
+}
+
+func (a *And) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(a, record)
+}
+
+// Test is false if any of the predicates is false, otherwise unknown if any of them is unknown
+func (a *And) Test(record map[string]interface{}) (Truth, error) {
+	res := True
+	for _, p := range a.Predicates {
+		t, err := p.Test(record)
+		if err != nil {
+			return False, err
+		}
+		if t == False {
+			return False, nil
+		}
+		if t == Unknown {
+			res = Unknown
+		}
+	}
+	return res, nil
+}
+
+func (a *And) String() string {
+	return join(a.Predicates, " AND ")
+}
+
+func (o *Or) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(o, record)
+}
+
+// Test is true if any of the predicates is true, otherwise unknown if any of them is unknown
+func (o *Or) Test(record map[string]interface{}) (Truth, error) {
+	res := False
+	for _, p := range o.Predicates {
+		t, err := p.Test(record)
+		if err != nil {
+			return False, err
+		}
+		if t == True {
+			return True, nil
+		}
+		if t == Unknown {
+			res = Unknown
+		}
+	}
+	return res, nil
+}
+
+func (o *Or) String() string {
+	return join(o.Predicates, " OR ")
+}
+
+func (n *Not) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(n, record)
+}
+
+func (n *Not) Test(record map[string]interface{}) (Truth, error) {
+	t, err := n.Predicate.Test(record)
+	if err != nil {
+		return False, err
+	}
+	return t.Not(), nil
+}
+
+func (n *Not) String() string {
+	return fmt.Sprintf("NOT (%s)", n.Predicate.String())
+}
+
+func (j *JSONPath) Evaluate(record map[string]interface{}) (bool, error) {
+	return evaluate(j, record)
+}
+
+func (j *JSONPath) Test(record map[string]interface{}) (Truth, error) {
+	var val interface{}
+	switch doc := record[j.Column].(type) {
+	case nil:
+	case types.JSON:
+		val = doc.Extract(j.Path, j.AsText)
+	default:
+		return False, fmt.Errorf("JSONPath.Test: column %s is not JSON: %T", j.Column, doc)
+	}
+	t, err := j.Predicate.Test(map[string]interface{}{j.Name: val})
+	if err != nil {
+		return False, fmt.Errorf("JSONPath.Test: %w", err)
+	}
+	return t, nil
+}
+
+func (j *JSONPath) String() string {
+	return j.Predicate.String()
+}
+
+// matchLike matches s against a LIKE pattern
+// It uses the usual two-pointer wildcard matching algorithm: on a mismatch it backtracks to the last % and lets it consume one more character
+func matchLike(s, pattern []rune) bool {
+	si, pi := 0, 0
+	starIdx, matchIdx := -1, 0
+	for si < len(s) {
+		if pi < len(pattern) && (pattern[pi] == '_' || pattern[pi] == s[si]) {
+			si++
+			pi++
+			continue
+		}
+		if pi < len(pattern) && pattern[pi] == '%' {
+			starIdx = pi
+			matchIdx = si
+			pi++
+			continue
+		}
+		if starIdx != -1 {
+			pi = starIdx + 1
+			matchIdx++
+			si = matchIdx
+			continue
+		}
+		return false
+	}
+	for pi < len(pattern) && pattern[pi] == '%' {
+		pi++
+	}
+	return pi == len(pattern)
+}
+
+func join(preds []Predicate, sep string) string {
+	parts := make([]string, len(preds))
+	for i, p := range preds {
+		parts[i] = "(" + p.String() + ")"
+		if len(preds) == 1 {
+			parts[i] = p.String()
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+	case []byte:
+		return fmt.Sprintf("X'%X'", val)
+	case types.JSON:
+		return formatValue(val.String())
+	case types.Date:
+		return fmt.Sprintf("DATE '%s'", val)
+	case time.Time:
+		return fmt.Sprintf("TIMESTAMP '%s'", val.Format("2006-01-02 15:04:05.999999Z07:00"))
+	case time.Duration:
+		return fmt.Sprintf("INTERVAL '%s'", val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,113 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	record := map[string]interface{}{
+		"id":       int64(10),
+		"username": "user10",
+		"age":      byte(31),
+		"job":      nil,
+	}
+
+	tests := []struct {
+		pred Predicate
+		want bool
+	}{
+		{Eq("id", int64(10)), true},
+		{NewComparison("id", OpNotEq, int64(10)), false},
+		{NewComparison("age", OpGt, byte(30)), true},
+		{NewComparison("age", OpLte, int64(30)), false},
+		{NewComparison("job", OpNotEq, "designer"), false},
+		{NewIn("id", []interface{}{int64(1), int64(10)}), true},
+		{NewIn("id", []interface{}{}), false},
+		{NewBetween("age", byte(31), byte(40)), true},
+		{NewIsNull("job"), true},
+		{NewNot(NewIsNull("age")), true},
+		{NewLike("username", "user%"), true},
+		{NewLike("username", "_ser1_"), true},
+		{NewLike("username", "%1"), false},
+		{NewLike("job", "%"), false},
+		{NewOr(Eq("id", int64(1)), Eq("username", "user10")), true},
+		{NewAnd(Eq("id", int64(10)), NewNot(Eq("age", byte(31)))), false},
+		{NewAnd(), true},
+	}
+	for _, tt := range tests {
+		got, err := tt.pred.Evaluate(record)
+		assert.Nil(t, err, tt.pred.String())
+		assert.Equal(t, tt.want, got, tt.pred.String())
+	}
+
+	_, err := NewComparison("username", OpLt, int64(1)).Evaluate(record)
+	assert.NotNil(t, err)
+}
+
+func TestThreeValuedLogic(t *testing.T) {
+	record := map[string]interface{}{
+		"id":  int64(10),
+		"age": nil,
+	}
+
+	tests := []struct {
+		pred Predicate
+		want Truth
+	}{
+		{NewComparison("age", OpGt, int64(30)), Unknown},
+		{NewNot(NewComparison("age", OpGt, int64(30))), Unknown},
+		{NewNot(NewIn("age", []interface{}{int64(1), int64(2)})), Unknown},
+		{NewNot(NewBetween("age", int64(20), int64(40))), Unknown},
+		{NewNot(NewLike("age", "%")), Unknown},
+		{NewNot(NewIn("id", []interface{}{int64(1), nil})), Unknown},
+		{NewNot(NewIn("id", []interface{}{int64(10), nil})), False},
+		{NewNot(NewIsNull("age")), False},
+		{NewAnd(Eq("id", int64(10)), Eq("age", int64(30))), Unknown},
+		{NewAnd(Eq("id", int64(1)), Eq("age", int64(30))), False},
+		{NewOr(Eq("id", int64(1)), Eq("age", int64(30))), Unknown},
+		{NewOr(Eq("id", int64(10)), Eq("age", int64(30))), True},
+		{NewNot(NewOr(Eq("id", int64(1)), Eq("age", int64(30)))), Unknown},
+	}
+	for _, tt := range tests {
+		got, err := tt.pred.Test(record)
+		assert.Nil(t, err, tt.pred.String())
+		assert.Equal(t, tt.want, got, tt.pred.String())
+
+		// Unknown results don't match
+		ok, err := tt.pred.Evaluate(record)
+		assert.Nil(t, err, tt.pred.String())
+		assert.Equal(t, tt.want == True, ok, tt.pred.String())
+	}
+}
+
+func TestMatchLike(t *testing.T) {
+	tests := []struct {
+		s       string
+		pattern string
+		want    bool
+	}{
+		{"", "", true},
+		{"", "%", true},
+		{"abc", "abc", true},
+		{"abc", "a%c", true},
+		{"abbbc", "a%b%c", true},
+		{"abc", "a_", false},
+		{"abc", "%%", true},
+		{"abc", "%d", false},
+		{"árvíztűrő", "árv_z%", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchLike([]rune(tt.s), []rune(tt.pattern)), "%s LIKE %s", tt.s, tt.pattern)
+	}
+}
+
+func TestConjunctsAndFromMap(t *testing.T) {
+	pred := FromMap(map[string]interface{}{"job": "designer", "id": int64(1)})
+	assert.Equal(t, "(id = 1) AND (job = 'designer')", pred.String())
+
+	nested := NewAnd(Eq("a", int64(1)), NewAnd(Eq("b", int64(2)), NewOr(Eq("c", int64(3)), Eq("d", int64(4)))))
+	assert.Len(t, Conjuncts(nested), 3)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, Columns(nested))
+}
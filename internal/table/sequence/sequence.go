@@ -0,0 +1,133 @@
+package sequence
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
+)
+
+const (
+	FileExtension = ".seq"
+	// CacheSize is the number of values reserved by a single write to the file
+	CacheSize = 32
+)
+
+// Sequence generates increasing int64 values. It's safe to use from multiple goroutines
+//
+// The file contains the high-water mark as an int64 TLV. It's the largest value that may have been handed out,
+// so it's written and synced before a value above it is returned. Values are reserved in batches of CacheSize and
+// Close writes back the last value that was actually used. After a crash the sequence continues above the high-water mark,
+// so some values can be skipped but none of them are returned twice
+type Sequence struct {
+	Name          string
+	file          *os.File
+	mu            sync.Mutex
+	last          int64
+	highWaterMark int64
+}
+
+func NewSequence(f *os.File, name string) *Sequence {
+	return &Sequence{
+		Name: name,
+		file: f,
+	}
+}
+
+// Load reads the high-water mark from the file. The next value is the one after it
+func (s *Sequence) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("Sequence.Load: %w", err)
+	}
+	b := make([]byte, stat.Size())
+	if _, err = s.file.ReadAt(b, 0); err != nil {
+		return fmt.Errorf("Sequence.Load: %w", err)
+	}
+	tlv := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[int64]())
+	if err = tlv.UnmarshalBinary(b); err != nil {
+		return fmt.Errorf("Sequence.Load: %w", err)
+	}
+	s.last = tlv.Value
+	s.highWaterMark = tlv.Value
+	return nil
+}
+
+// Reset makes start the next value of the sequence
+func (s *Sequence) Reset(start int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.persist(start - 1); err != nil {
+		return fmt.Errorf("Sequence.Reset: %w", err)
+	}
+	s.last = start - 1
+	return nil
+}
+
+// Next returns the next value of the sequence
+func (s *Sequence) Next() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last >= s.highWaterMark {
+		if err := s.persist(s.last + CacheSize); err != nil {
+			return 0, fmt.Errorf("Sequence.Next: %w", err)
+		}
+	}
+	s.last++
+	return s.last, nil
+}
+
+// Advance makes sure that Next returns a value larger than v. It's used when a record is inserted with an explicit value
+func (s *Sequence) Advance(v int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v <= s.last {
+		return nil
+	}
+	if v > s.highWaterMark {
+		if err := s.persist(v); err != nil {
+			return fmt.Errorf("Sequence.Advance: %w", err)
+		}
+	}
+	s.last = v
+	return nil
+}
+
+// Last returns the last value handed out or the value before the first one if Next hasn't been called yet
+func (s *Sequence) Last() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Close gives back the reserved values that were not used and closes the file
+func (s *Sequence) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.highWaterMark != s.last {
+		if err := s.persist(s.last); err != nil {
+			return fmt.Errorf("Sequence.Close: %w", err)
+		}
+	}
+	return s.file.Close()
+}
+
+// persist writes the high-water mark and waits until it reaches the disk
+// The TLV is smaller than a disk sector so it's overwritten in place
+func (s *Sequence) persist(highWaterMark int64) error {
+	b, err := encoding.NewTLVMarshaler(highWaterMark).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("Sequence.persist: %w", err)
+	}
+	if _, err = s.file.WriteAt(b, 0); err != nil {
+		return fmt.Errorf("Sequence.persist: %w", err)
+	}
+	if err = s.file.Sync(); err != nil {
+		return fmt.Errorf("Sequence.persist: %w", err)
+	}
+	s.highWaterMark = highWaterMark
+	return nil
+}
@@ -0,0 +1,89 @@
+package sequence
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openSequence(t *testing.T, path string) *Sequence {
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := NewSequence(f, "users_id_seq")
+	if err = seq.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return seq
+}
+
+func TestSequence_NextAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users_id_seq"+FileExtension)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := NewSequence(f, "users_id_seq")
+	assert.Nil(t, seq.Reset(1))
+
+	for want := int64(1); want <= 3; want++ {
+		v, err := seq.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, want, v)
+	}
+	assert.Nil(t, seq.Advance(10))
+	assert.Nil(t, seq.Advance(5))
+	v, err := seq.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), v)
+
+	// Close gives back the unused reserved values
+	assert.Nil(t, seq.Close())
+	seq = openSequence(t, path)
+	v, err = seq.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), v)
+
+	// Without Close the reserved values are skipped but never returned twice
+	seq = openSequence(t, path)
+	v, err = seq.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12+CacheSize), v)
+}
+
+func TestSequence_Concurrent(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "s"+FileExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := NewSequence(f, "s")
+	assert.Nil(t, seq.Reset(1))
+	defer seq.Close()
+
+	const n = 100
+	values := make(chan int64, n)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := seq.Next()
+			assert.Nil(t, err)
+			values <- v
+		}()
+	}
+	wg.Wait()
+	close(values)
+
+	seen := make(map[int64]bool, n)
+	for v := range values {
+		assert.False(t, seen[v], "duplicate value %d", v)
+		seen[v] = true
+	}
+	assert.Len(t, seen, n)
+	assert.Equal(t, int64(n), seq.Last())
+}
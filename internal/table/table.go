@@ -3,30 +3,40 @@ package table
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"math"
+	"math/big"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/omesh-barhate/ByteForge/internal/platform"
 	"github.com/omesh-barhate/ByteForge/internal/platform/parser"
 	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
 	platformio "github.com/omesh-barhate/ByteForge/internal/platform/parser/io"
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table/bufferpool"
 	"github.com/omesh-barhate/ByteForge/internal/table/column"
 	columnio "github.com/omesh-barhate/ByteForge/internal/table/column/io"
+	"github.com/omesh-barhate/ByteForge/internal/table/fsm"
 	"github.com/omesh-barhate/ByteForge/internal/table/fulltext"
 	"github.com/omesh-barhate/ByteForge/internal/table/index"
+	"github.com/omesh-barhate/ByteForge/internal/table/mvcc"
+	"github.com/omesh-barhate/ByteForge/internal/table/predicate"
+	"github.com/omesh-barhate/ByteForge/internal/table/sequence"
 	"github.com/omesh-barhate/ByteForge/internal/table/wal"
 	walencoding "github.com/omesh-barhate/ByteForge/internal/table/wal/encoding"
 )
 
 const (
 	FileExtension           = ".bin"
-	PageSize                = 128
 	AccessTypeFullTextIdx   = "fulltext"
 	AccessTypeBtreeIdx      = "btree"
 	AccessTypeFullTableScan = "full_table_scan"
@@ -34,50 +44,83 @@ const (
 
 type Columns map[string]*column.Column
 
+// Table stores records in fixed-size slotted pages and keeps a B-tree index on the primary key. The pages start at the
+// first multiple of the page size after the column definitions. It's safe to use from multiple goroutines:
+// Select holds a read lock and every method that modifies the table or its indexes holds the write lock
+//
+// Records written by a transaction are versioned. Deleting or updating them inside a transaction only stores the ID of
+// the transaction in the old version, so SelectSnapshot can still read it until ReclaimVersions removes it
 type Table struct {
+	// mu is only taken by exported methods. Unexported ones expect the caller to hold it
+	mu sync.RWMutex
+	// rewriteMu is held by SelectSnapshot while it reads the pages one by one, so Vacuum doesn't replace the file
+	// between two of them
+	rewriteMu   sync.RWMutex
 	Name        string
 	file        *os.File
 	columnNames []string
 	columns     Columns
+	// primaryKey contains the columns of the primary key in the order they were declared
+	primaryKey []string
 
+	// reader and columnDefReader read the header and column definitions from the offset of the file when the table is
+	// opened. Pages are read with ReadAt
 	reader          *platformio.Reader
-	recordParser    *parser.RecordParser
 	columnDefReader *columnio.ColumnDefinitionReader
-
-	index       *index.Index
-	wal         *wal.WAL
-	lru         *platform.LRU[string, index.Page]
+	// pageSize is 0 if the table was created before pages had a fixed size until Upgrade converts it
+	pageSize int
+	// dataStart is the position of the first page
+	dataStart int64
+	// fsm stores the free space of every page
+	fsm *fsm.FreeSpaceMap
+
+	index *index.Index
+	// secondaryIdxs contains the B-tree indexes created with CreateIndex keyed by column name
+	secondaryIdxs map[string]*index.SecondaryIndex
+	wal           *wal.WAL
+	// pool holds the pages of the table. It's shared by the tables of a database
+	pool        *bufferpool.Pool
 	fullTextIdx *fulltext.Index
+	// sequence generates the values of the auto-increment column. It's owned by the database
+	sequence *sequence.Sequence
+	// expiredPages contains the pages with versions that were deleted by a transaction but not reclaimed yet
+	// The indexes only point to the latest versions, so index lookups with a snapshot read these pages too
+	expiredPages map[int64]bool
 }
 
 func NewTable(
 	f *os.File,
 	idxFile *os.File,
 	fullTextIdxFile *os.File,
+	fsmFile *os.File,
 	reader *platformio.Reader,
 	columnDefReader *columnio.ColumnDefinitionReader,
 	wal *wal.WAL,
 ) (*Table, error) {
-	if f == nil || reader == nil || columnDefReader == nil || idxFile == nil {
+	if f == nil || reader == nil || columnDefReader == nil || idxFile == nil || fsmFile == nil {
 		return nil, fmt.Errorf("NewTable: nil argument")
 	}
 	tableName, err := getTableName(f)
 	if err != nil {
 		return nil, fmt.Errorf("NewTable: %w", err)
 	}
+	pool := bufferpool.New(bufferpool.DefaultSize)
 	t := &Table{
 		file:            f,
 		Name:            tableName,
 		columns:         make(Columns),
 		reader:          reader,
 		columnDefReader: columnDefReader,
-		index:           index.NewIndex(idxFile),
+		index:           index.NewIndex(idxFile, pool),
+		secondaryIdxs:   make(map[string]*index.SecondaryIndex),
 		fullTextIdx:     fulltext.NewIndex(fullTextIdxFile),
-		lru: platform.NewLRU[string, index.Page](10, func(a, b string) bool {
-			return a == b
-		}),
-		wal: wal,
+		pool:            pool,
+		wal:             wal,
+		pageSize:        DefaultPageSize,
+		fsm:             fsm.New(fsmFile, DefaultPageSize),
+		expiredPages:    make(map[int64]bool),
 	}
+	t.pool.Attach(t.Name, t.writeBack)
 	return t, nil
 }
 
@@ -85,13 +128,15 @@ func NewTableWithColumns(
 	f *os.File,
 	idxFile *os.File,
 	fullTextIdxFile *os.File,
+	fsmFile *os.File,
 	reader *platformio.Reader,
 	columnDefReader *columnio.ColumnDefinitionReader,
 	wal *wal.WAL,
 	columns Columns,
 	columnNames []string,
+	primaryKey []string,
 ) (*Table, error) {
-	t, err := NewTable(f, idxFile, fullTextIdxFile, reader, columnDefReader, wal)
+	t, err := NewTable(f, idxFile, fullTextIdxFile, fsmFile, reader, columnDefReader, wal)
 	if err != nil {
 		return nil, fmt.Errorf("NewTableWithColumns: %w", err)
 	}
@@ -99,6 +144,18 @@ func NewTableWithColumns(
 	if err != nil {
 		return nil, fmt.Errorf("NewTableWithColumns: %w", err)
 	}
+	if err = t.setPrimaryKey(primaryKey); err != nil {
+		return nil, fmt.Errorf("NewTableWithColumns: %w", err)
+	}
+	n := 0
+	for _, col := range columns {
+		if col.Opts.AutoIncrement {
+			n++
+		}
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("NewTableWithColumns: %w", NewMultipleAutoIncrementColumnsError(t.Name))
+	}
 	return t, nil
 }
 
@@ -114,24 +171,160 @@ func (t *Table) SetColumns(columns Columns, columnNames []string) error {
 	return nil
 }
 
+// setPrimaryKey validates the primary key columns and marks them with column.Opts.PrimaryKey
+func (t *Table) setPrimaryKey(cols []string) error {
+	if len(cols) == 0 {
+		return NewInvalidPrimaryKeyError(t.Name, "the table has no primary key")
+	}
+	for i, name := range cols {
+		col, ok := t.columns[name]
+		if !ok {
+			return column.NewUnknownColumnError(t.Name, name)
+		}
+		if slices.Contains(cols[:i], name) {
+			return NewInvalidPrimaryKeyError(t.Name, fmt.Sprintf("column %s is listed more than once", name))
+		}
+		switch col.DataType() {
+		case types.TypeInt64, types.TypeFloat64, types.TypeFloat32, types.TypeString, types.TypeDate, types.TypeTimestamp:
+		default:
+			return NewInvalidPrimaryKeyError(t.Name, fmt.Sprintf("column %s has to be INT64, FLOAT64, FLOAT32, STRING, DATE or TIMESTAMP", name))
+		}
+		if col.Opts.AllowNull {
+			return column.NewNullablePrimaryKeyError(name)
+		}
+	}
+	for name, col := range t.columns {
+		col.Opts.PrimaryKey = slices.Contains(cols, name)
+	}
+	t.primaryKey = cols
+	return nil
+}
+
+// PrimaryKey returns the columns of the primary key
+func (t *Table) PrimaryKey() []string {
+	return t.primaryKey
+}
+
+// primaryKeyOf returns the primary key of record
+func (t *Table) primaryKeyOf(record map[string]interface{}) (index.Key, error) {
+	key := make(index.Key, 0, len(t.primaryKey))
+	for _, col := range t.primaryKey {
+		v := record[col]
+		if v == nil {
+			return nil, column.NewCannotBeNullError(col)
+		}
+		key = append(key, v)
+	}
+	return key, nil
+}
+
 func (t *Table) ColumnNames() []string {
 	return t.columnNames
 }
 
+func (t *Table) Columns() Columns {
+	return t.columns
+}
+
+// IndexInfo describes one index of a table
+type IndexInfo struct {
+	// Type is either AccessTypeBtreeIdx or AccessTypeFullTextIdx
+	Type string
+	// Column is the indexed column. The columns of a composite primary key are separated by commas
+	Column string
+	// Primary is true for the index on the primary key
+	Primary bool
+}
+
+// Indexes returns every index of the table. The primary index always comes first
+func (t *Table) Indexes() []IndexInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	indexes := []IndexInfo{{Type: AccessTypeBtreeIdx, Column: strings.Join(t.primaryKey, ", "), Primary: true}}
+	for _, name := range t.btreeColumns()[1:] {
+		indexes = append(indexes, IndexInfo{Type: AccessTypeBtreeIdx, Column: name})
+	}
+	for _, name := range t.columnNames {
+		if t.columns[name].Opts.FullTextIdx {
+			indexes = append(indexes, IndexInfo{Type: AccessTypeFullTextIdx, Column: name})
+		}
+	}
+	return indexes
+}
+
 func (t *Table) FullTextIdx() *fulltext.Index {
 	return t.fullTextIdx
 }
 
-func (t *Table) SetRecordParser(recParser *parser.RecordParser) error {
-	if recParser == nil {
-		return fmt.Errorf("Table.SetRecordReader: record reader cannot be nil")
+// SetSequence sets the sequence that generates the values of the auto-increment column
+func (t *Table) SetSequence(seq *sequence.Sequence) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.AutoIncrementColumn() == "" {
+		return fmt.Errorf("Table.SetSequence: %w", NewNoAutoIncrementColumnError(t.Name))
+	}
+	t.sequence = seq
+	return nil
+}
+
+// SetPageSize sets the size of the pages of a new table. It has to be called before WriteColumnDefinitions
+func (t *Table) SetPageSize(size int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !slices.Contains(PageSizes, size) {
+		return fmt.Errorf("Table.SetPageSize: %w", NewInvalidPageSizeError(size))
+	}
+	t.pageSize = size
+	t.fsm.SetPageSize(size)
+	return nil
+}
+
+// PageSize returns the size of the pages of the table
+func (t *Table) PageSize() int {
+	return t.pageSize
+}
+
+// AutoIncrementColumn returns the name of the auto-increment column or an empty string if the table doesn't have one
+func (t *Table) AutoIncrementColumn() string {
+	for _, name := range t.columnNames {
+		if t.columns[name].Opts.AutoIncrement {
+			return name
+		}
+	}
+	return ""
+}
+
+// SetBufferPool makes the table and its B-tree indexes keep their pages in pool. The dirty pages of the pool used
+// before are written first
+func (t *Table) SetBufferPool(pool *bufferpool.Pool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.pool.Flush(t.Name); err != nil {
+		return fmt.Errorf("Table.SetBufferPool: %w", err)
+	}
+	t.pool.Detach(t.Name)
+	t.pool = pool
+	t.pool.Attach(t.Name, t.writeBack)
+	if err := t.index.SetBufferPool(pool); err != nil {
+		return fmt.Errorf("Table.SetBufferPool: %w", err)
+	}
+	for _, idx := range t.secondaryIdxs {
+		if err := idx.SetBufferPool(pool); err != nil {
+			return fmt.Errorf("Table.SetBufferPool: %w", err)
+		}
 	}
-	t.recordParser = recParser
 	return nil
 }
 
-// Close closes the table and the index files
+// Close writes the dirty pages of the table, removes them from the buffer pool and closes the table and the index
+// files. The WAL belongs to the database so it's not closed
 func (t *Table) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.pool.Flush(t.Name); err != nil {
+		return fmt.Errorf("Table.Close: %w", err)
+	}
+	t.pool.Detach(t.Name)
 	if err := t.file.Close(); err != nil {
 		return fmt.Errorf("Table.Close: %w", err)
 	}
@@ -141,28 +334,157 @@ func (t *Table) Close() error {
 	if err := t.fullTextIdx.Close(); err != nil {
 		return fmt.Errorf("Table.Close: %w", err)
 	}
+	if err := t.fsm.Close(); err != nil {
+		return fmt.Errorf("Table.Close: %w", err)
+	}
+	for _, idx := range t.secondaryIdxs {
+		if err := idx.Close(); err != nil {
+			return fmt.Errorf("Table.Close: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateIndex creates a B-tree index on col from the records already in the table and persists it into f
+func (t *Table) CreateIndex(col string, f *os.File) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.ensureIndexable(col); err != nil {
+		return fmt.Errorf("Table.CreateIndex: %w", err)
+	}
+
+	idx := index.NewSecondaryIndex(f, col, t.pool)
+	pages, err := t.pagePositions()
+	if err != nil {
+		return fmt.Errorf("Table.CreateIndex: %w", err)
+	}
+	for _, pagePos := range pages {
+		p, err := t.readPage(pagePos)
+		if err != nil {
+			return fmt.Errorf("Table.CreateIndex: %w", err)
+		}
+		records, err := t.pageRecords(p)
+		if err != nil {
+			return fmt.Errorf("Table.CreateIndex: %w", err)
+		}
+		for _, r := range records {
+			// Deleted versions are not indexed
+			if r.raw.Xmax != 0 {
+				continue
+			}
+			key, err := t.primaryKeyOf(r.raw.Record)
+			if err != nil {
+				return fmt.Errorf("Table.CreateIndex: %w", err)
+			}
+			if err = idx.Add(r.raw.Record[col], key, pagePos); err != nil {
+				return fmt.Errorf("Table.CreateIndex: %w", err)
+			}
+		}
+	}
+	// Creating the index is not logged in the WAL, so it's written right away
+	if err := idx.Flush(); err != nil {
+		return fmt.Errorf("Table.CreateIndex: %w", err)
+	}
+	t.secondaryIdxs[col] = idx
+	if err := t.sync(); err != nil {
+		return fmt.Errorf("Table.CreateIndex: %w", err)
+	}
+	return nil
+}
+
+// LoadIndex loads the B-tree index on col that was created by CreateIndex
+func (t *Table) LoadIndex(col string, f *os.File) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.ensureIndexable(col); err != nil {
+		return fmt.Errorf("Table.LoadIndex: %w", err)
+	}
+	idx := index.NewSecondaryIndex(f, col, t.pool)
+	if err := idx.Load(); err != nil {
+		return fmt.Errorf("Table.LoadIndex: %w", err)
+	}
+	t.secondaryIdxs[col] = idx
+	return nil
+}
+
+func (t *Table) ensureIndexable(col string) error {
+	c, ok := t.columns[col]
+	if !ok {
+		return column.NewUnknownColumnError(t.Name, col)
+	}
+	if c.DataType() == types.TypeBlob {
+		return column.NewBlobIndexError(col)
+	}
+	if c.DataType() == types.TypeJSON {
+		return column.NewJSONIndexError(col)
+	}
+	// The primary index can already be used to search by the first column of the primary key
+	if col == t.primaryKey[0] {
+		return NewIndexAlreadyExistsError(t.Name, col)
+	}
+	if _, ok := t.secondaryIdxs[col]; ok {
+		return NewIndexAlreadyExistsError(t.Name, col)
+	}
 	return nil
 }
 
+// WriteColumnDefinitions writes the table header followed by the column definitions
 func (t *Table) WriteColumnDefinitions() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.writeColumnDefinitions(t.file); err != nil {
+		return fmt.Errorf("Table.WriteColumnDefinitions: %w", err)
+	}
+	if err := t.sync(); err != nil {
+		return fmt.Errorf("Table.WriteColumnDefinitions: %w", err)
+	}
+	return nil
+}
+
+// writeColumnDefinitions writes the header and the column definitions into f and pads them to the first page
+func (t *Table) writeColumnDefinitions(f *os.File) error {
+	b, err := newHeader(t.primaryKey, t.pageSize).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("Table.writeColumnDefinitions: %w", err)
+	}
+	if _, err = f.Write(b); err != nil {
+		return fmt.Errorf("Table.writeColumnDefinitions: %w", err)
+	}
+	n := int64(len(b))
+
 	for _, v := range t.columnNames {
 		b, err := t.columns[v].MarshalBinary()
 		if err != nil {
-			return fmt.Errorf("Table.WriteColumnDefinitions: %w", err)
+			return fmt.Errorf("Table.writeColumnDefinitions: %w", err)
 		}
 
-		colWriter := columnio.NewColumnDefinitionWriter(t.file)
+		colWriter := columnio.NewColumnDefinitionWriter(f)
 		if _, err = colWriter.Write(b); err != nil {
-			return fmt.Errorf("Table.WriteColumnDefinitions: %w", err)
+			return fmt.Errorf("Table.writeColumnDefinitions: %w", err)
 		}
+		n += int64(len(b))
+	}
+	t.dataStart = alignToPage(n, t.pageSize)
+	// The padding makes the column definition reader stop at the first page
+	if _, err = f.Write(make([]byte, t.dataStart-n)); err != nil {
+		return fmt.Errorf("Table.writeColumnDefinitions: %w", err)
 	}
 	return nil
 }
 
+// alignToPage returns the first multiple of pageSize that is not smaller than n
+func alignToPage(n int64, pageSize int) int64 {
+	size := int64(pageSize)
+	return (n + size - 1) / size * size
+}
+
+// ReadColumnDefinitions reads the table header and the column definitions
 func (t *Table) ReadColumnDefinitions() error {
-	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+	h, headerLen, err := t.readHeader()
+	if err != nil {
 		return fmt.Errorf("Table.ReadColumnDefinitions: %w", err)
 	}
+	end := int64(headerLen)
 
 	for {
 		buf := make([]byte, 4096)
@@ -177,31 +499,171 @@ func (t *Table) ReadColumnDefinitions() error {
 		if err = col.UnmarshalBinary(buf[:n]); err != nil {
 			return fmt.Errorf("Table.ReadColumnDefinitions: %w", err)
 		}
+		end += int64(n)
 		colName := col.NameToStr()
 		t.columns[colName] = &col
 		t.columnNames = append(t.columnNames, colName)
 	}
+	if err = t.setPrimaryKey(h.primaryKey); err != nil {
+		return fmt.Errorf("Table.ReadColumnDefinitions: %w", err)
+	}
+	t.pageSize = h.pageSize
+	// The pages of legacy tables start right after the column definitions
+	t.dataStart = end
+	if t.pageSize != 0 {
+		t.dataStart = alignToPage(end, t.pageSize)
+		t.fsm.SetPageSize(t.pageSize)
+	}
 	return nil
 }
 
-func (t *Table) Insert(record map[string]interface{}, useWAL bool) (int, error) {
-	if _, err := t.file.Seek(0, io.SeekEnd); err != nil {
-		return 0, fmt.Errorf("Table.Insert: %w", err)
+// readHeader reads the header at the beginning of the file and leaves the file pointer at the first column definition
+// It returns the length of the header, which is 0 for tables created before the header existed
+func (t *Table) readHeader() (*header, int, error) {
+	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("Table.readHeader: %w", err)
+	}
+	dataType, err := t.reader.ReadByte()
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.readHeader: %w", err)
+	}
+	if _, err = t.file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("Table.readHeader: %w", err)
+	}
+	// Tables created before the header existed always have an id column as their primary key
+	if dataType != types.TypeTableHeader {
+		return newHeader([]string{"id"}, 0), 0, nil
+	}
+
+	b, err := t.reader.ReadTLV()
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.readHeader: %w", err)
+	}
+	h := &header{}
+	if err = h.UnmarshalBinary(b); err != nil {
+		return nil, 0, fmt.Errorf("Table.readHeader: %w", err)
+	}
+	return h, len(b), nil
+}
+
+// Insert inserts record and returns its primary key
+// An auto-increment column that is missing from record or NULL gets the next value of the sequence of the table
+// A BLOB column takes a []byte or an io.Reader. A reader is read until EOF while its value is split into overflow
+// chunks, so large values don't have to be in record as a whole
+func (t *Table) Insert(record map[string]interface{}, useWAL bool) (index.Key, error) {
+	key, syncLSN, err := t.insert(0, record, useWAL)
+	if err != nil {
+		return key, fmt.Errorf("Table.Insert: %w", err)
+	}
+	if err = t.syncWAL(syncLSN); err != nil {
+		return key, fmt.Errorf("Table.Insert: %w", err)
+	}
+	return key, nil
+}
+
+// InsertTx is like Insert, but the record is written by the transaction with txID. Its version stores txID, so
+// snapshots don't see it until the transaction is committed. The change is always logged
+func (t *Table) InsertTx(txID int64, record map[string]interface{}) (index.Key, error) {
+	key, syncLSN, err := t.insert(txID, record, true)
+	if err != nil {
+		return key, fmt.Errorf("Table.InsertTx: %w", err)
+	}
+	if err = t.syncWAL(syncLSN); err != nil {
+		return key, fmt.Errorf("Table.InsertTx: %w", err)
+	}
+	return key, nil
+}
+
+// insert inserts record for the transaction with txID while holding t.mu. txID is 0 outside of a transaction
+// It returns the LSN the WAL has to be synced up to afterwards
+func (t *Table) insert(txID int64, record map[string]interface{}, useWAL bool) (index.Key, int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	record, err := t.fillAutoIncrement(record)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	if record, err = t.convertValues(record); err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	if err = t.validateColumns(record); err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	key, err := t.primaryKeyOf(record)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	// Constraints are checked before anything is written to the WAL or the table file
+	if err = t.checkUnique([]map[string]interface{}{record}, nil, t.uniqueConstraints()); err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	if err = t.checkIndexKeys([]map[string]interface{}{record}); err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	pl, err := t.newPlacement(nil, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	if record, err = t.streamBlobs(pl, record); err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	buf, err := t.marshalRecord(record, txID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+	changes, placed, err := pl.placeRecords([][]byte{buf}, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: %w", err)
+	}
+
+	// Changes that are not logged don't set the LSN of the page
+	var lsn, syncLSN int64
+	if useWAL {
+		if lsn, syncLSN, err = t.logChangesNoWait(txID, walencoding.OpInsert, changes); err != nil {
+			return nil, 0, fmt.Errorf("Table.insert: %w", err)
+		}
+	}
+
+	if err = t.applyChanges(changes, lsn); err != nil {
+		return nil, 0, fmt.Errorf("Table.insert: unable to insert into page: %w. record: %v", err, record)
+	}
+	if err = t.addToIndexes(record, key, index.NewPage(placed[0].PagePos)); err != nil {
+		return key, 0, fmt.Errorf("Table.insert: %w. record: %v", err, record)
+	}
+
+	if err = t.advanceSequence(record); err != nil {
+		return key, 0, fmt.Errorf("Table.insert: %w", err)
 	}
-	if err := t.validateColumns(record); err != nil {
-		return 0, fmt.Errorf("Table.Insert: %w", err)
+	if err = t.sync(); err != nil {
+		return key, 0, fmt.Errorf("Table.insert: %w", err)
 	}
 
+	return key, syncLSN, nil
+}
+
+// marshalRecord encodes record as:
+//
+//	100 len [102 16 [xmin int64][xmax int64]] [column TLV]...
+//
+// The version is only written inside a transaction. xmin is txID and xmax is 0
+func (t *Table) marshalRecord(record map[string]interface{}, txID int64) ([]byte, error) {
 	var sizeOfRecord uint32 = 0
+	if txID != 0 {
+		sizeOfRecord += types.LenMeta + types.LenRecordVersion
+	}
 	for _, col := range t.columnNames {
 		val, ok := record[col]
 		if !ok {
-			return 0, fmt.Errorf("Table.Insert: column missing from insert params: %s", col)
+			return nil, fmt.Errorf("Table.marshalRecord: column missing from insert params: %s", col)
+		}
+		if enc, ok := val.(encodedValue); ok {
+			sizeOfRecord += uint32(len(enc))
+			continue
 		}
 		tlvMarshaler := encoding.NewTLVMarshaler(val)
 		length, err := tlvMarshaler.TLVLength()
 		if err != nil {
-			return 0, fmt.Errorf("Table.Insert: %w", err)
+			return nil, fmt.Errorf("Table.marshalRecord: %w", err)
 		}
 		sizeOfRecord += length
 	}
@@ -210,61 +672,224 @@ func (t *Table) Insert(record map[string]interface{}, useWAL bool) (int, error)
 
 	// type
 	if err := binary.Write(&buf, binary.LittleEndian, types.TypeRecord); err != nil {
-		return 0, fmt.Errorf("Table.Insert: type: %w", err)
+		return nil, fmt.Errorf("Table.marshalRecord: type: %w", err)
 	}
 
 	// length of whole record
 	if err := binary.Write(&buf, binary.LittleEndian, sizeOfRecord); err != nil {
-		return 0, fmt.Errorf("Table.Insert: len: %w", err)
+		return nil, fmt.Errorf("Table.marshalRecord: len: %w", err)
+	}
+
+	if txID != 0 {
+		for _, v := range []interface{}{types.TypeRecordVersion, uint32(types.LenRecordVersion), txID, int64(0)} {
+			if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+				return nil, fmt.Errorf("Table.marshalRecord: version: %w", err)
+			}
+		}
 	}
 
 	for _, col := range t.columnNames {
 		v := record[col]
+		if enc, ok := v.(encodedValue); ok {
+			buf.Write(enc)
+			continue
+		}
 		tlvMarshaler := encoding.NewTLVMarshaler(v)
 		b, err := tlvMarshaler.MarshalBinary()
 		if err != nil {
-			return 0, fmt.Errorf("Table.Insert: %w", err)
+			return nil, fmt.Errorf("Table.marshalRecord: %w", err)
 		}
 		buf.Write(b)
 	}
+	return buf.Bytes(), nil
+}
 
-	var walEntry *wal.Entry
-	if useWAL {
-		var err error
-		walEntry, err = t.wal.Append(walencoding.OpInsert, t.Name, buf.Bytes())
+// addToIndexes adds a record stored in page to every index of the table
+func (t *Table) addToIndexes(record map[string]interface{}, key index.Key, page *index.Page) error {
+	if err := t.index.Add(key, page.StartPos); err != nil {
+		return fmt.Errorf("Table.addToIndexes: unable to add to index: %w", err)
+	}
+	for col, idx := range t.secondaryIdxs {
+		if err := idx.Add(record[col], key, page.StartPos); err != nil {
+			return fmt.Errorf("Table.addToIndexes: unable to add to index on %s: %w", col, err)
+		}
+	}
+	// ColumnNotFoundError means the table has no full-text index, so it's not an error
+	if err := t.addToFullTextIdx(record, key, page); err != nil && !errors.Is(err, &fulltext.ColumnNotFoundError{}) {
+		return fmt.Errorf("Table.addToIndexes: unable to add to full-text index: %w", err)
+	}
+	return nil
+}
+
+// checkIndexKeys returns index.KeyTooLongError if a record has a key that cannot be stored in one of the indexes
+// Long keys are rejected instead of being shortened, so the records are checked before they are logged. Otherwise a
+// record could be written without its index entries
+func (t *Table) checkIndexKeys(records []map[string]interface{}) error {
+	for _, r := range records {
+		key, err := t.primaryKeyOf(r)
 		if err != nil {
-			return 0, fmt.Errorf("Table.Insert: %w", err)
+			return fmt.Errorf("Table.checkIndexKeys: %w", err)
+		}
+		if err = t.index.Check(key); err != nil {
+			return fmt.Errorf("Table.checkIndexKeys: %w", err)
+		}
+		for col, idx := range t.secondaryIdxs {
+			if err = idx.Check(r[col], key); err != nil {
+				return fmt.Errorf("Table.checkIndexKeys: column %s: %w", col, err)
+			}
 		}
 	}
+	return nil
+}
 
-	page, err := t.insertIntoPage(buf)
+// fillAutoIncrement returns a copy of record where the auto-increment column has a value
+// It returns AutoIncrementOutOfRangeError if the next value of the sequence doesn't fit into the column
+func (t *Table) fillAutoIncrement(record map[string]interface{}) (map[string]interface{}, error) {
+	col := t.AutoIncrementColumn()
+	if col == "" || record[col] != nil {
+		return record, nil
+	}
+	if t.sequence == nil {
+		return nil, fmt.Errorf("Table.fillAutoIncrement: %w", NewSequenceNotSetError(t.Name, col))
+	}
+	v, err := t.sequence.Next()
 	if err != nil {
-		return 0, fmt.Errorf("table.Insert: unable to insert into page: %w. record: %v", err, record)
+		return nil, fmt.Errorf("Table.fillAutoIncrement: %w", err)
 	}
-	if err = t.index.AddAndPersist(record["id"].(int64), page.StartPos); err != nil {
-		return 1, fmt.Errorf("table.Insert: unable to add to index: %w. record: %v", err, record)
+	filled := maps.Clone(record)
+	switch t.columns[col].DataType() {
+	case types.TypeInt32:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return nil, fmt.Errorf("Table.fillAutoIncrement: %w", NewAutoIncrementOutOfRangeError(t.Name, col, v))
+		}
+		filled[col] = int32(v)
+	case types.TypeByte:
+		if v < 0 || v > math.MaxUint8 {
+			return nil, fmt.Errorf("Table.fillAutoIncrement: %w", NewAutoIncrementOutOfRangeError(t.Name, col, v))
+		}
+		filled[col] = byte(v)
+	default:
+		filled[col] = v
 	}
-	if err = t.addToFullTextIdx(record, page); err != nil {
-		// This happens when the table has no full-text index, so it's not an error
-		if errors.Is(err, &fulltext.ColumnNotFoundError{}) {
-			return 1, nil
+	return filled, nil
+}
+
+// convertValues returns a copy of record where the values are converted to the types they are stored with:
+// the time.Time values of DATE columns are replaced by their date, the values of TIMESTAMP columns are rounded to
+// microseconds, the values of DECIMAL columns are converted to a types.Decimal with the scale of the column, the
+// strings of BLOB columns are converted to []byte and the values of JSON columns are converted to a types.JSON
+// It returns InvalidDecimalError if a value doesn't fit into its DECIMAL column without rounding and InvalidJSONError
+// if a value of a JSON column is not a valid document
+func (t *Table) convertValues(record map[string]interface{}) (map[string]interface{}, error) {
+	var converted map[string]interface{}
+	for col, v := range record {
+		c, exists := t.columns[col]
+		if !exists || v == nil {
+			continue
+		}
+		var newValue interface{}
+		switch c.DataType() {
+		case types.TypeDate:
+			if tm, ok := v.(time.Time); ok {
+				newValue = types.DateOf(tm)
+			}
+		case types.TypeTimestamp:
+			if tm, ok := v.(time.Time); ok {
+				newValue = types.Timestamp(tm)
+			}
+		case types.TypeBlob:
+			if s, ok := v.(string); ok {
+				newValue = []byte(s)
+			}
+		case types.TypeDecimal:
+			d, err := asDecimal(v)
+			if err == nil {
+				d, err = d.Fit(c.Opts.Precision, c.Opts.Scale)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Table.convertValues: %w", NewInvalidDecimalError(col, v, c.Opts.Precision, c.Opts.Scale, err))
+			}
+			newValue = d
+		case types.TypeJSON:
+			j, err := asJSON(v)
+			if err != nil {
+				return nil, fmt.Errorf("Table.convertValues: %w", NewInvalidJSONError(col, err))
+			}
+			newValue = j
+		}
+		if newValue == nil {
+			continue
+		}
+		if converted == nil {
+			converted = maps.Clone(record)
 		}
-		return 1, fmt.Errorf("table.Insert: unable to add to full-text index: %w. record: %v", err, record)
+		converted[col] = newValue
 	}
-	if err = t.invalidateCache(page); err != nil {
-		return 1, fmt.Errorf("table.Insert: %w", err)
+	if converted == nil {
+		return record, nil
 	}
+	return converted, nil
+}
 
-	if useWAL {
-		if err = t.wal.Commit(walEntry); err != nil {
-			return 0, fmt.Errorf("Table.Insert: %w", err)
-		}
+// asJSON converts a types.JSON, JSON text as a string, []byte or json.RawMessage, or a Go value such as a
+// map[string]interface{} to a types.JSON
+func asJSON(v interface{}) (types.JSON, error) {
+	switch val := v.(type) {
+	case types.JSON:
+		return val, nil
+	case string:
+		return types.ParseJSON(val)
+	case []byte:
+		return types.ParseJSON(string(val))
+	case json.RawMessage:
+		return types.ParseJSON(string(val))
 	}
+	return types.NewJSON(v)
+}
+
+// asDecimal converts a types.Decimal, a number, a *big.Int, a *big.Rat or a string such as "12.30" to a types.Decimal
+// A float is converted to the shortest decimal that reads back as the same float, so 0.1 becomes 0.1
+func asDecimal(v interface{}) (types.Decimal, error) {
+	switch val := v.(type) {
+	case types.Decimal:
+		return val, nil
+	case string:
+		return types.ParseDecimal(val)
+	case *big.Int:
+		return types.NewDecimal(val, 0), nil
+	case *big.Rat:
+		return types.DecimalFromRat(val)
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return types.Decimal{}, fmt.Errorf("asDecimal: %v is not a number", val)
+		}
+		return types.ParseDecimal(strconv.FormatFloat(val, 'f', -1, 64))
+	case float32:
+		return asDecimal(float64(val))
+	}
+	if i, ok := asInt64(v); ok {
+		return types.DecimalFromInt64(i), nil
+	}
+	return types.Decimal{}, fmt.Errorf("asDecimal: cannot convert %T to DECIMAL", v)
+}
 
-	return 1, nil
+// advanceSequence makes sure that the sequence doesn't generate a value that was inserted explicitly
+func (t *Table) advanceSequence(record map[string]interface{}) error {
+	col := t.AutoIncrementColumn()
+	if col == "" || t.sequence == nil {
+		return nil
+	}
+	v, ok := asInt64(record[col])
+	if !ok {
+		return nil
+	}
+	if err := t.sequence.Advance(v); err != nil {
+		return fmt.Errorf("Table.advanceSequence: %w", err)
+	}
+	return nil
 }
 
-func (t *Table) addToFullTextIdx(record map[string]interface{}, page *index.Page) error {
+func (t *Table) addToFullTextIdx(record map[string]interface{}, key index.Key, page *index.Page) error {
 	col, err := t.getFullTextIdxCol(record)
 	// It's not necessarily an error, so we return it as it is
 	if errors.Is(err, &fulltext.ColumnNotFoundError{}) {
@@ -279,9 +904,16 @@ func (t *Table) addToFullTextIdx(record map[string]interface{}, page *index.Page
 
 	switch v := record[col.NameToStr()].(type) {
 	case string:
-		if err = t.fullTextIdx.AddAndPersist(v, page.StartPos, record["id"].(int64)); err != nil {
+		// The full-text index stores int64 IDs only for information
+		var id int64
+		if len(key) == 1 {
+			id, _ = key[0].(int64)
+		}
+		if err = t.fullTextIdx.AddAndPersist(v, page.StartPos, id); err != nil {
 			return fmt.Errorf("table.addToFullTextIdx: %w", err)
 		}
+	case nil:
+		// NULL values are not indexed
 	default:
 		return fmt.Errorf("table.addToFullTextIdx: unable to add to full-text index: value is not string: %v", record[col.NameToStr()])
 	}
@@ -289,271 +921,567 @@ func (t *Table) addToFullTextIdx(record map[string]interface{}, page *index.Page
 	return nil
 }
 
-// insertIntoPage finds the first page that can fit buf and writes it into the page
-func (t *Table) insertIntoPage(buf bytes.Buffer) (*index.Page, error) {
-	page, err := t.seekToNextPage(uint32(buf.Len()))
+// placeRecords returns the insert changes of the records and of the overflow chunks of their large values in the
+// order they have to be written, and the insert change of each record
+// The free-space map suggests a page for each record. If it doesn't have enough space, a new page is added at the end
+// of the file. Pending changes are applied before the inserts, so they are applied to the copies of the pages the
+// records are planned on
+//
+// replaced contains the delete change of the old version of each record or nil. A record is written into the slot of
+// its old version if it fits into the page. Other records cannot take the slot before that
+func (t *Table) placeRecords(records [][]byte, pending, replaced []*walencoding.Change) ([]*walencoding.Change, []*walencoding.Change, error) {
+	pl, err := t.newPlacement(pending, replaced)
 	if err != nil {
-		return nil, fmt.Errorf("Table.insertIntoPage: %w", err)
+		return nil, nil, fmt.Errorf("Table.placeRecords: %w", err)
 	}
-
-	lenBuf := bytes.Buffer{}
-	if err = binary.Write(&lenBuf, binary.LittleEndian, uint32(buf.Len())); err != nil {
-		return nil, fmt.Errorf("Table.insertIntoPage: len: %w", err)
+	changes, placed, err := pl.placeRecords(records, replaced)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Table.placeRecords: %w", err)
 	}
+	return changes, placed, nil
+}
 
-	n, err := t.file.Write(buf.Bytes())
+// newPlacement returns a placement that plans inserts after the pending changes. The slots of the replaced records
+// stay occupied until placeRecords writes the new versions into them
+func (t *Table) newPlacement(pending, replaced []*walencoding.Change) (*placement, error) {
+	pageCount, err := t.pageCount()
 	if err != nil {
-		return nil, fmt.Errorf("Table.insertIntoPage: %w", err)
-	}
-	if n != buf.Len() {
-		return nil, columnio.NewIncompleteWriteError(buf.Len(), n)
+		return nil, fmt.Errorf("Table.newPlacement: %w", err)
 	}
-
-	// seek back to the beginning of page
-	if _, err = t.file.Seek(page.StartPos, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("Table.insertIntoPage: %w", err)
+	pl := &placement{
+		t:         t,
+		pending:   pending,
+		held:      make(map[*walencoding.Change]bool),
+		pages:     make(map[int64]*page),
+		pageCount: pageCount,
 	}
-	if _, err = t.updatePageSize(page.StartPos, int32(buf.Len())); err != nil {
-		return nil, fmt.Errorf("Table.insertIntoPage: %w", err)
+	for _, c := range replaced {
+		if c != nil {
+			pl.held[c] = true
+		}
 	}
-	return page, nil
+	return pl, nil
 }
 
-// seekToNextPage seeks the file to the first page that can hold lenToFit number of bytes
-func (t *Table) seekToNextPage(lenToFit uint32) (*index.Page, error) {
-	if _, err := t.file.Seek(0, io.SeekStart); err != nil && err != io.EOF {
-		return nil, fmt.Errorf("Table.seekToNextPage: %w", err)
-	}
-	for {
-		err := t.seekUntil(types.TypePage)
-		if err != nil {
-			if err == io.EOF {
-				return t.insertEmptyPage()
+// placeRecords plans the inserts of records and returns every change of the placement and the changes that insert
+// the records. See Table.placeRecords
+func (pl *placement) placeRecords(records [][]byte, replaced []*walencoding.Change) ([]*walencoding.Change, []*walencoding.Change, error) {
+	t := pl.t
+	var err error
+	placed := make([]*walencoding.Change, 0, len(records))
+	for i, r := range records {
+		if r, err = spill(pl, r, t.pageSize); err != nil {
+			return nil, nil, fmt.Errorf("placement.placeRecords: %w", err)
+		}
+		if len(r) > maxRecordLen(t.pageSize) {
+			return nil, nil, fmt.Errorf("placement.placeRecords: %w", NewRecordTooLargeError(t.Name, len(r), maxRecordLen(t.pageSize)))
+		}
+		inPlace := false
+		if i < len(replaced) && replaced[i] != nil {
+			if inPlace, err = pl.placeAt(r, replaced[i]); err != nil {
+				return nil, nil, fmt.Errorf("placement.placeRecords: %w", err)
 			}
-			return nil, fmt.Errorf("Table.seekToNextPage: %w", err)
-		}
-		// Skipping the type definition byte
-		_, err = t.reader.ReadByte()
-		if err != nil {
-			return nil, err
-		}
-		currPageLen, err := t.reader.ReadUint32()
-		if err != nil {
-			return nil, fmt.Errorf("Table.seekToNextPage: %w", err)
 		}
-		if currPageLen+lenToFit <= PageSize {
-			meta := int64(types.LenByte + types.LenInt32)
-			pagePos, err := t.file.Seek(-1*(types.LenByte+types.LenInt32), io.SeekCurrent)
-			if err != nil {
-				return nil, fmt.Errorf("Table.seekToNextPage: %w", err)
-			}
-			if _, err = t.file.Seek(int64(currPageLen)+meta, io.SeekCurrent); err != nil {
-				return nil, fmt.Errorf("Table.seekToNextPage: %w", err)
+		if !inPlace {
+			if _, _, err = pl.place(r); err != nil {
+				return nil, nil, fmt.Errorf("placement.placeRecords: %w", err)
 			}
-			return index.NewPage(pagePos), nil
 		}
+		placed = append(placed, pl.changes[len(pl.changes)-1])
 	}
+	return pl.changes, placed, nil
 }
 
-func (t *Table) insertEmptyPage() (*index.Page, error) {
-	buf := bytes.Buffer{}
+// placement plans the inserts of a statement on copies of the pages they are written to
+type placement struct {
+	t       *Table
+	pending []*walencoding.Change
+	// held contains the pending deletes that are only applied by placeAt, so their slots stay occupied until then
+	held map[*walencoding.Change]bool
+	// pages contains the copies of the pages that records were planned on
+	pages     map[int64]*page
+	pageCount int64
+	changes   []*walencoding.Change
+}
 
-	// type
-	if err := binary.Write(&buf, binary.LittleEndian, types.TypePage); err != nil {
-		return nil, fmt.Errorf("Table.insertEmptyPage: type: %w", err)
+// place plans the insert of record and adds its change to the changes of the statement
+func (pl *placement) place(record []byte) (int64, int64, error) {
+	var p *page
+	pageNo, ok := pl.t.fsm.Find(len(record))
+	if ok && pageNo < pl.pageCount {
+		var err error
+		if p, err = pl.page(pl.t.pagePos(pageNo)); err != nil {
+			return 0, 0, fmt.Errorf("placement.place: %w", err)
+		}
+		if !p.fits(len(record)) {
+			// The hint was wrong, so it's corrected for the next insert
+			if err = pl.t.fsm.Set(pageNo, p.available()); err != nil {
+				return 0, 0, fmt.Errorf("placement.place: %w", err)
+			}
+			p = nil
+		}
 	}
-
-	// length
-	if err := binary.Write(&buf, binary.LittleEndian, uint32(0)); err != nil {
-		return nil, fmt.Errorf("Table.insertEmptyPage: len: %w", err)
+	if p == nil {
+		pageNo = pl.pageCount
+		pl.pageCount++
+		p = newPage(pl.t.pageSize)
+		pl.pages[pl.t.pagePos(pageNo)] = p
 	}
 
-	n, err := t.file.Write(buf.Bytes())
+	slot, err := p.insert(record)
 	if err != nil {
-		return nil, fmt.Errorf("Table.insertEmptyPage: %w", err)
+		return 0, 0, fmt.Errorf("placement.place: %w", err)
 	}
-	if n != buf.Len() {
-		return nil, fmt.Errorf("Table.insertEmptyPage: %w", columnio.NewIncompleteWriteError(buf.Len(), n))
+	if err = pl.t.fsm.Set(pageNo, p.available()); err != nil {
+		return 0, 0, fmt.Errorf("placement.place: %w", err)
 	}
+	pl.changes = append(pl.changes, walencoding.NewInsertChange(pl.t.pagePos(pageNo), int64(slot), record))
+	return pl.t.pagePos(pageNo), int64(slot), nil
+}
 
-	currPos, err := t.file.Seek(0, io.SeekCurrent)
-	// startPos should point at the very first byte, that is types.TypePage and 5 bytes before the current pos
-	startPos := currPos - int64(types.LenMeta)
-	if startPos <= 0 {
-		return nil, fmt.Errorf("Table.insertEmptyPage: start position is %d", startPos)
+// placeAt applies the held delete c and plans the insert of record into the slot it emptied
+// It reports false if the record doesn't fit into the page anymore. The slot is free for other records then
+func (pl *placement) placeAt(record []byte, c *walencoding.Change) (bool, error) {
+	p, err := pl.page(c.PagePos)
+	if err != nil {
+		return false, fmt.Errorf("placement.placeAt: %w", err)
+	}
+	delete(pl.held, c)
+	if err = pl.t.applyChange(p, c); err != nil {
+		return false, fmt.Errorf("placement.placeAt: %w", err)
+	}
+	fits := p.fitsAt(int(c.Slot), len(record))
+	if fits {
+		if err = p.set(int(c.Slot), record); err != nil {
+			return false, fmt.Errorf("placement.placeAt: %w", err)
+		}
+		pl.changes = append(pl.changes, walencoding.NewInsertChange(c.PagePos, c.Slot, record))
+	}
+	if err = pl.t.fsm.Set(pl.t.pageNo(c.PagePos), p.available()); err != nil {
+		return false, fmt.Errorf("placement.placeAt: %w", err)
 	}
-	return index.NewPage(startPos), nil
+	return fits, nil
 }
 
-// seekUntil finds the first occurrence of targetType and seeks the file to it's position
-func (t *Table) seekUntil(targetType byte) error {
-	for {
-		dataType, err := t.reader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				return err
-			}
-			return fmt.Errorf("Table.seekUntil: %w", err)
+// page returns a copy of the page at pagePos with the pending changes applied except the held ones
+func (pl *placement) page(pagePos int64) (*page, error) {
+	if p, ok := pl.pages[pagePos]; ok {
+		return p, nil
+	}
+	cached, err := pl.t.readPage(pagePos)
+	if err != nil {
+		return nil, fmt.Errorf("placement.page: %w", err)
+	}
+	p := cached.clone()
+	for _, c := range pl.pending {
+		if c.PagePos != pagePos || pl.held[c] {
+			continue
 		}
-		if dataType == targetType {
-			if _, err = t.file.Seek(-1, io.SeekCurrent); err != nil {
-				return fmt.Errorf("Table.seekUntil: %w", err)
-			}
-			return nil
+		if err = pl.t.applyChange(p, c); err != nil {
+			return nil, fmt.Errorf("placement.page: %w", err)
 		}
+	}
+	pl.pages[pagePos] = p
+	return p, nil
+}
 
-		if targetType == types.TypeRecord && dataType == types.TypePage {
-			// Ignore page's len
-			if _, err := t.reader.ReadUint32(); err != nil {
-				return fmt.Errorf("Table.seekUntil: %w", err)
-			}
-			// The first type flag inside a page should be a record
-			dataType, err = t.skipDeletedRecords()
-			if err != nil {
-				return fmt.Errorf("Table.seekUntil: %w", err)
-			}
-			if dataType != targetType {
-				return fmt.Errorf("Table.seekUntil: first byte inside a page should be %d but %d found", types.TypeRecord, dataType)
-			}
+// logChanges appends changes that are not part of a transaction to the WAL and returns the LSN of the entry. The
+// changes can only be applied after they were logged
+func (t *Table) logChanges(op string, changes []*walencoding.Change) (int64, error) {
+	lsn, err := t.logTxChanges(0, op, changes)
+	if err != nil {
+		return 0, fmt.Errorf("Table.logChanges: %w", err)
+	}
+	return lsn, nil
+}
 
-			if _, err = t.file.Seek(-1, io.SeekCurrent); err != nil {
-				return fmt.Errorf("Table.seekUntil: %w", err)
-			}
-			return nil
-		}
+// logChangesNoWait is like logTxChanges, but it doesn't wait for the sync of DurabilityGroup. syncLSN is the LSN the
+// caller has to sync the WAL up to after it released t.mu, so other writers can append to the WAL in the meantime
+// It's 0 if the entry is already durable
+func (t *Table) logChangesNoWait(txID int64, op string, changes []*walencoding.Change) (lsn int64, syncLSN int64, err error) {
+	data, err := walencoding.MarshalChanges(changes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Table.logChangesNoWait: %w", err)
+	}
+	lsn, durable, err := t.wal.AppendNoWait(txID, op, t.Name, data)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Table.logChangesNoWait: %w", err)
+	}
+	if durable {
+		return lsn, 0, nil
+	}
+	return lsn, lsn, nil
+}
 
-		length, err := t.reader.ReadUint32()
-		if err != nil {
-			return fmt.Errorf("Table.seekUntil: %w", err)
+// syncWAL syncs the WAL up to syncLSN returned by logChangesNoWait
+func (t *Table) syncWAL(syncLSN int64) error {
+	if err := t.wal.SyncUpTo(syncLSN); err != nil {
+		return fmt.Errorf("Table.syncWAL: %w", err)
+	}
+	return nil
+}
+
+// logTxChanges is like logChanges but the entry belongs to the transaction with txID
+func (t *Table) logTxChanges(txID int64, op string, changes []*walencoding.Change) (int64, error) {
+	data, err := walencoding.MarshalChanges(changes)
+	if err != nil {
+		return 0, fmt.Errorf("Table.logTxChanges: %w", err)
+	}
+	lsn, err := t.wal.Append(txID, op, t.Name, data)
+	if err != nil {
+		return 0, fmt.Errorf("Table.logTxChanges: %w", err)
+	}
+	return lsn, nil
+}
+
+// applyChanges modifies the pages of the changes of the WAL entry with the given LSN and stores the LSN in them
+// A page whose LSN is not smaller already contains the changes, so the WAL can be replayed after a crash. Changes that
+// were not logged have LSN 0 and they are always applied
+//
+// The pages are modified in the buffer pool and written back later. A page after the end of the file is written right
+// away without the changes, so the size of the file is always the number of pages
+func (t *Table) applyChanges(changes []*walencoding.Change, lsn int64) error {
+	pagePositions := make([]int64, 0)
+	byPage := make(map[int64][]*walencoding.Change)
+	for _, c := range changes {
+		if _, ok := byPage[c.PagePos]; !ok {
+			pagePositions = append(pagePositions, c.PagePos)
+		}
+		byPage[c.PagePos] = append(byPage[c.PagePos], c)
+	}
+	stat, err := t.file.Stat()
+	if err != nil {
+		return fmt.Errorf("Table.applyChanges: %w", err)
+	}
+	for _, pagePos := range pagePositions {
+		if err = t.applyPageChanges(pagePos, pagePos >= stat.Size(), byPage[pagePos], lsn); err != nil {
+			return fmt.Errorf("Table.applyChanges: %w", err)
 		}
+	}
+	return nil
+}
 
-		if _, err = t.file.Seek(int64(length), io.SeekCurrent); err != nil {
-			return fmt.Errorf("Table.seekUntil: %w", err)
+// applyPageChanges applies changes to a copy of the page at pagePos and replaces the page in the buffer pool with it
+// The page is pinned until then. An appended page doesn't exist in the file yet
+func (t *Table) applyPageChanges(pagePos int64, appended bool, changes []*walencoding.Change, lsn int64) error {
+	id := t.pageID(pagePos)
+	data, _, err := t.pool.Fetch(id, func() ([]byte, error) {
+		if appended {
+			return newPage(t.pageSize).bytes(), nil
+		}
+		return t.loadPage(pagePos)
+	})
+	if err != nil {
+		return fmt.Errorf("Table.applyPageChanges: %w", err)
+	}
+	defer t.pool.Unpin(id)
+	p := &page{data: slices.Clone(data)}
+	if lsn != 0 && p.lsn() >= lsn {
+		return nil
+	}
+	for _, c := range changes {
+		if err = t.applyChange(p, c); err != nil {
+			return fmt.Errorf("Table.applyPageChanges: %w", err)
+		}
+	}
+	if lsn != 0 {
+		p.setLSN(lsn)
+	}
+	// The changes are written back by the buffer pool once the WAL entry is durable
+	if appended {
+		if err = t.writeAt(data, pagePos); err != nil {
+			return fmt.Errorf("Table.applyPageChanges: %w", err)
 		}
 	}
+	if err = t.pool.Update(id, p.bytes(), true); err != nil {
+		return fmt.Errorf("Table.applyPageChanges: %w", err)
+	}
+	if err = t.fsm.Set(t.pageNo(pagePos), p.available()); err != nil {
+		return fmt.Errorf("Table.applyPageChanges: %w", err)
+	}
+	return nil
 }
 
-func (t *Table) skipDeletedRecords() (dataType byte, err error) {
-	for {
-		dType, err := t.reader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				return 0, err
-			}
-			return 0, fmt.Errorf("Table.skipDeletedRecords: %w", err)
+// applyChange modifies a page in memory
+// An insert fails if the slot contains another record. A delete or an expire fails if the slot contains something else
+// than the record before the change. An expire is skipped if the record was deleted since then
+func (t *Table) applyChange(p *page, c *walencoding.Change) error {
+	slot := int(c.Slot)
+	curr := p.record(slot)
+	switch c.Op {
+	case walencoding.OpInsert:
+		if curr != nil {
+			return fmt.Errorf("Table.applyChange: %w", NewChangeConflictError(c.Op, c.PagePos, c.Slot))
+		}
+		if err := p.set(slot, c.Record); err != nil {
+			return fmt.Errorf("Table.applyChange: %w", err)
+		}
+	case walencoding.OpDelete:
+		if !bytes.Equal(curr, c.Record) {
+			return fmt.Errorf("Table.applyChange: %w", NewChangeConflictError(c.Op, c.PagePos, c.Slot))
+		}
+		p.remove(slot)
+	case walencoding.OpExpire:
+		if curr == nil {
+			return nil
 		}
-		if dType == types.TypeDeletedRecord {
-			l, err := t.reader.ReadUint32()
-			if err != nil {
-				return 0, fmt.Errorf("RecordParser.Parse: %w", err)
-			}
-			if _, err = t.file.Seek(int64(l), io.SeekCurrent); err != nil {
-				return 0, fmt.Errorf("RecordParser.Parse: %w", err)
-			}
+		before := slices.Clone(c.Record)
+		clear(before[xmaxOffset : xmaxOffset+types.LenInt64])
+		if !bytes.Equal(curr, before) {
+			return fmt.Errorf("Table.applyChange: %w", NewChangeConflictError(c.Op, c.PagePos, c.Slot))
+		}
+		if err := p.replace(slot, c.Record); err != nil {
+			return fmt.Errorf("Table.applyChange: %w", err)
 		}
-		if dType == types.TypeRecord {
-			return dType, nil
+		t.expiredPages[c.PagePos] = true
+	default:
+		return fmt.Errorf("Table.applyChange: unsupported operation: %s", c.Op)
+	}
+	return nil
+}
+
+// xmaxOffset is the position of the ID of the deleting transaction inside a versioned record
+const xmaxOffset = types.LenMeta + types.LenMeta + types.LenInt64
+
+// expiredRecord returns a copy of a versioned record where xmax is txID
+func expiredRecord(record []byte, txID int64) []byte {
+	expired := slices.Clone(record)
+	binary.LittleEndian.PutUint64(expired[xmaxOffset:], uint64(txID))
+	return expired
+}
+
+// Sync writes the dirty pages and commits the table file and every index file to the disk
+func (t *Table) Sync() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if err := t.syncFiles(); err != nil {
+		return fmt.Errorf("Table.Sync: %w", err)
+	}
+	return nil
+}
+
+// sync commits the files to the disk if the durability of the WAL is DurabilityAlways
+// Otherwise they are synced by checkpoints. Until then recovery replays the WAL
+func (t *Table) sync() error {
+	if t.wal.Durability() != wal.DurabilityAlways {
+		return nil
+	}
+	if err := t.syncFiles(); err != nil {
+		return fmt.Errorf("Table.sync: %w", err)
+	}
+	return nil
+}
+
+// Flush writes the dirty pages of the table and its B-tree indexes from the buffer pool into their files without
+// syncing them
+func (t *Table) Flush() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if err := t.pool.Flush(t.Name); err != nil {
+		return fmt.Errorf("Table.Flush: %w", err)
+	}
+	if err := t.index.Flush(); err != nil {
+		return fmt.Errorf("Table.Flush: %w", err)
+	}
+	for _, idx := range t.secondaryIdxs {
+		if err := idx.Flush(); err != nil {
+			return fmt.Errorf("Table.Flush: %w", err)
 		}
 	}
+	return nil
 }
 
+// syncFiles writes the dirty pages and commits the table file and every index file to the disk
+func (t *Table) syncFiles() error {
+	if err := t.pool.Flush(t.Name); err != nil {
+		return fmt.Errorf("Table.syncFiles: %w", err)
+	}
+	if err := t.file.Sync(); err != nil {
+		return fmt.Errorf("Table.syncFiles: %w", err)
+	}
+	if err := t.index.Sync(); err != nil {
+		return fmt.Errorf("Table.syncFiles: %w", err)
+	}
+	if err := t.fullTextIdx.Sync(); err != nil {
+		return fmt.Errorf("Table.syncFiles: %w", err)
+	}
+	if err := t.fsm.Sync(); err != nil {
+		return fmt.Errorf("Table.syncFiles: %w", err)
+	}
+	for _, idx := range t.secondaryIdxs {
+		if err := idx.Sync(); err != nil {
+			return fmt.Errorf("Table.syncFiles: %w", err)
+		}
+	}
+	return nil
+}
+
+func (t *Table) writeAt(b []byte, pos int64) error {
+	n, err := t.file.WriteAt(b, pos)
+	if err != nil {
+		return fmt.Errorf("Table.writeAt: %w", err)
+	}
+	if n != len(b) {
+		return fmt.Errorf("Table.writeAt: %w", columnio.NewIncompleteWriteError(len(b), n))
+	}
+	return nil
+}
+
+// writeBack writes a page of the buffer pool back into the table file
+func (t *Table) writeBack(pos int64, data []byte) error {
+	if err := t.writeAt(data, pos); err != nil {
+		return fmt.Errorf("Table.writeBack: %w", err)
+	}
+	return nil
+}
+
+// Select returns the records that match every key-value pair in whereStmts
 func (t *Table) Select(whereStmts map[string]interface{}) (*SelectResult, error) {
-	if err := t.ensureFilePointer(); err != nil {
-		return nil, fmt.Errorf("Table.Select: %w", err)
+	return t.SelectWhere(predicate.FromMap(whereStmts))
+}
+
+// SelectWhere returns the records that satisfy pred including the changes of the running transaction
+// Other reads can run at the same time, but writers wait until every record was read
+func (t *Table) SelectWhere(pred predicate.Predicate) (*SelectResult, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	result, err := t.selectWhere(pred, mvcc.Latest, noLock{})
+	if err != nil {
+		return nil, fmt.Errorf("Table.SelectWhere: %w", err)
+	}
+	return result, nil
+}
+
+// SelectSnapshot returns the versions visible in snap that satisfy pred
+// The read lock is only held while a page is read, so writers can modify the table between two pages. The caller has
+// to make sure that the versions snap can see are not reclaimed in the meantime
+func (t *Table) SelectSnapshot(pred predicate.Predicate, snap *mvcc.Snapshot) (*SelectResult, error) {
+	t.rewriteMu.RLock()
+	defer t.rewriteMu.RUnlock()
+	result, err := t.selectWhere(pred, snap, t.mu.RLocker())
+	if err != nil {
+		return nil, fmt.Errorf("Table.SelectSnapshot: %w", err)
 	}
+	return result, nil
+}
+
+// noLock is passed to selectWhere by callers that already hold the lock of the table
+type noLock struct{}
 
-	defer func() {
-		t.recordParser = parser.NewRecordParser(t.file, t.ColumnNames())
-	}()
+func (noLock) Lock()   {}
+func (noLock) Unlock() {}
 
+// selectWhere finds the pages that can contain records satisfying pred and reads them one by one
+// l is held while the pages are looked up and while each of them is read
+func (t *Table) selectWhere(pred predicate.Predicate, snap *mvcc.Snapshot, l sync.Locker) (*SelectResult, error) {
 	result := newSelectResult()
-	accessType := t.detectAccessType(whereStmts)
+	l.Lock()
+	pagePositions, err := t.findPages(pred, snap, result)
+	l.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("Table.selectWhere: %w", err)
+	}
 
-	if accessType == AccessTypeBtreeIdx {
-		result.Type = `index (btree)`
-		val, err := t.index.Get(whereStmts["id"].(int64))
+	for _, pagePos := range pagePositions {
+		l.Lock()
+		err = t.selectFromPage(pagePos, pred, snap, result)
+		l.Unlock()
 		if err != nil {
-			return nil, fmt.Errorf("table.Select: %w", err)
-		}
-		cacheHit, err := t.readPage(val.PagePos)
-		if err != nil {
-			return nil, fmt.Errorf("table.Select: %w", err)
+			return nil, fmt.Errorf("Table.selectWhere: %w", err)
 		}
+	}
+	return result, nil
+}
 
-		result.Extra = "Not using page cache"
-		if cacheHit {
-			result.Extra = "Using page cache"
-		}
+// findPages returns the positions of the pages that have to be read to find the records satisfying pred and sets the
+// type of result. A page is only returned once even if an index points to it multiple times
+func (t *Table) findPages(pred predicate.Predicate, snap *mvcc.Snapshot, result *SelectResult) ([]int64, error) {
+	pageCount, err := t.pageCount()
+	if err != nil {
+		return nil, fmt.Errorf("Table.findPages: %w", err)
+	}
+	if pageCount == 0 {
+		return nil, nil
+	}
 
-		if err = t.readRecords(whereStmts, result); err != nil {
-			return nil, fmt.Errorf("table.select: %w", err)
+	var pagePositions []int64
+	path := t.detectAccessType(pred)
+	switch path.accessType {
+	case AccessTypeBtreeIdx:
+		result.Type = "index (btree)"
+		if path.keys == nil {
+			result.Type = "range (btree)"
 		}
-		return result, nil
-	}
-	if accessType == AccessTypeFullTextIdx {
-		col, err := t.getFullTextIdxCol(whereStmts)
+		positions, err := t.btreeLookup(path)
 		if err != nil {
-			return nil, fmt.Errorf("table.Select: %w", err)
+			return nil, fmt.Errorf("Table.findPages: %w", err)
 		}
+		pagePositions = positions
+	case AccessTypeFullTextIdx:
 		result.Type = "index (fulltext)"
-		result.Extra = "Not using page cache"
-
-		switch v := whereStmts[col.NameToStr()].(type) {
-		case string:
-			items, err := t.fullTextIdx.Get(v)
-			if err != nil {
-				return nil, fmt.Errorf("table.Select: %w", err)
-			}
-
-			processedPages := make([]int64, 0)
-			for _, item := range items {
-				if slices.Contains(processedPages, item.PagePos) {
-					continue
-				}
-				cacheHit, err := t.readPage(item.PagePos)
-				if err != nil {
-					return nil, fmt.Errorf("table.Select: %w", err)
-				}
-				if cacheHit {
-					result.Extra = "Using page cache"
-				}
-				if err = t.readRecords(whereStmts, result); err != nil {
-					return nil, fmt.Errorf("table.Select: %w", err)
-				}
-				processedPages = append(processedPages, item.PagePos)
-			}
-			return result, nil
-		default:
-			return nil, fmt.Errorf("table.Select: unable to use full-text index: value is not string: %v", whereStmts[col.NameToStr()])
+		items, err := t.fullTextIdx.Get(path.fullTextValue)
+		if err != nil && !errors.Is(err, fulltext.ErrItemNotFound) {
+			return nil, fmt.Errorf("Table.findPages: %w", err)
 		}
-	}
-	if accessType == AccessTypeFullTableScan {
+		for _, item := range items {
+			pagePositions = append(pagePositions, item.PagePos)
+		}
+	case AccessTypeFullTableScan:
 		result.Type = "ALL"
-		if err := t.readRecords(whereStmts, result); err != nil {
-			return nil, fmt.Errorf("table.Select: %w", err)
+		positions, err := t.pagePositions()
+		if err != nil {
+			return nil, fmt.Errorf("Table.findPages: %w", err)
 		}
-		return result, nil
+		return positions, nil
+	default:
+		return nil, fmt.Errorf("Table.findPages: invalid access type")
 	}
 
-	return nil, fmt.Errorf("table.Select: invalid access type")
+	// The latest version is never on one of the expired pages
+	if snap != mvcc.Latest {
+		pagePositions = append(pagePositions, slices.Sorted(maps.Keys(t.expiredPages))...)
+	}
+	seen := make(map[int64]struct{}, len(pagePositions))
+	unique := make([]int64, 0, len(pagePositions))
+	for _, pagePos := range pagePositions {
+		if _, ok := seen[pagePos]; ok {
+			continue
+		}
+		seen[pagePos] = struct{}{}
+		unique = append(unique, pagePos)
+	}
+	return unique, nil
 }
 
-func (t *Table) readRecords(whereStmts map[string]interface{}, result *SelectResult) error {
-	for {
-		err := t.recordParser.Parse()
-		if err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return fmt.Errorf("table.select: %w", err)
+// selectFromPage adds the records of a page that are visible in snap and satisfy pred to result
+func (t *Table) selectFromPage(pagePos int64, pred predicate.Predicate, snap *mvcc.Snapshot, result *SelectResult) error {
+	p, hit, err := t.fetchPage(pagePos)
+	if err != nil {
+		return fmt.Errorf("Table.selectFromPage: %w", err)
+	}
+	if hit {
+		result.Extra = "Using page cache"
+	}
+	records, err := t.pageRecords(p)
+	if err != nil {
+		return fmt.Errorf("Table.selectFromPage: %w", err)
+	}
+
+	for _, r := range records {
+		rawRecord := r.raw
+		if !snap.Visible(rawRecord.Xmin, rawRecord.Xmax) {
+			continue
 		}
-		rawRecord := t.recordParser.Value
 		result.RowsInspected++
 
 		if err := t.ensureColumnLength(rawRecord.Record); err != nil {
-			return fmt.Errorf("table.select: %w", column.NewMismatchingColumnsError(len(t.columns), len(rawRecord.Record)))
+			return fmt.Errorf("Table.selectFromPage: %w", column.NewMismatchingColumnsError(len(t.columns), len(rawRecord.Record)))
+		}
+		ok, err := pred.Evaluate(rawRecord.Record)
+		if err != nil {
+			return fmt.Errorf("Table.selectFromPage: %w", err)
 		}
-		if !t.evaluateWhereStmt(whereStmts, rawRecord.Record) {
+		if !ok {
 			continue
 		}
 
@@ -563,28 +1491,358 @@ func (t *Table) readRecords(whereStmts map[string]interface{}, result *SelectRes
 		}
 		result.Rows = append(result.Rows, res)
 	}
+	return nil
 }
 
-// detectAccessType returns what kind of index can be used to satisfy the given where statement
-// If no index can be used based on the columns an empty string is returned
-// Valid index types can be:
-//   - btree
-//   - fulltext
-func (t *Table) detectAccessType(whereStmts map[string]interface{}) string {
-	fields := make([]string, 0)
-	for k, _ := range whereStmts {
-		fields = append(fields, k)
+// pageRecord is a record read from a page
+type pageRecord struct {
+	slot int
+	raw  *parser.RawRecord
+}
+
+// pageRecords parses the records of p in the order of their slots. Overflow chunks are skipped and the values stored
+// in them are read into the records that point to them
+func (t *Table) pageRecords(p *page) ([]pageRecord, error) {
+	slots := p.slots()
+	records := make([]pageRecord, 0, len(slots))
+	for _, slot := range slots {
+		if p.record(slot)[0] == types.TypeOverflowChunk {
+			continue
+		}
+		recordParser := parser.NewRecordParser(bytes.NewReader(p.record(slot)), t.columnNames)
+		recordParser.SetOverflowReader(t.readOverflow)
+		if err := recordParser.Parse(); err != nil {
+			return nil, fmt.Errorf("Table.pageRecords: slot %d: %w", slot, err)
+		}
+		records = append(records, pageRecord{slot: slot, raw: recordParser.Value})
 	}
+	return records, nil
+}
+
+// accessPath describes how the records matching a predicate can be found
+type accessPath struct {
+	accessType string
+	// column is the column of the btree index. It's either the first column of the primary key or a column with a secondary index
+	column string
+	// keys contains the values of column = x and column IN (...) conditions. If it's nil the btree index is used for a range
+	keys []interface{}
+	// from and to are the bounds of the range. nil means unbounded
+	from          *index.Bound
+	to            *index.Bound
+	fullTextValue string
+}
 
-	if slices.Contains(fields, "id") {
-		return AccessTypeBtreeIdx
+// detectAccessType returns what kind of index can be used to satisfy the given predicate
+// Only the conditions that must be true for every matching record (the top-level AND) are considered. They are checked in this order:
+//   - btree: column = x or column IN (...) where column is the first column of the primary key or has a secondary index
+//   - btree: a range such as column > x or column BETWEEN x AND y on the same columns
+//   - fulltext: equality on a column with a full-text index
+//   - full_table_scan: otherwise
+func (t *Table) detectAccessType(pred predicate.Predicate) *accessPath {
+	conjuncts := predicate.Conjuncts(pred)
+	btreeColumns := t.btreeColumns()
+
+	for _, col := range btreeColumns {
+		if path := t.keyLookup(col, conjuncts); path != nil {
+			return path
+		}
+	}
+	for _, col := range btreeColumns {
+		if path := t.keyRange(col, conjuncts); path != nil {
+			return path
+		}
 	}
 
-	_, err := t.getFullTextIdxCol(whereStmts)
-	if err != nil {
-		return AccessTypeFullTableScan
+	for _, c := range conjuncts {
+		p, ok := c.(*predicate.Comparison)
+		if !ok || p.Op != predicate.OpEq {
+			continue
+		}
+		col, ok := t.columns[p.Column]
+		if !ok || !col.Opts.FullTextIdx {
+			continue
+		}
+		if v, ok := p.Value.(string); ok {
+			return &accessPath{accessType: AccessTypeFullTextIdx, fullTextValue: v}
+		}
+	}
+	return &accessPath{accessType: AccessTypeFullTableScan}
+}
+
+// btreeColumns returns the columns that have a B-tree index. The first column of the primary key always comes first
+func (t *Table) btreeColumns() []string {
+	cols := []string{t.primaryKey[0]}
+	for _, name := range t.columnNames {
+		if _, ok := t.secondaryIdxs[name]; ok {
+			cols = append(cols, name)
+		}
+	}
+	return cols
+}
+
+// keyLookup returns an access path for col = x or col IN (...) or nil if there's no such condition
+func (t *Table) keyLookup(col string, conjuncts []predicate.Predicate) *accessPath {
+	for _, c := range conjuncts {
+		switch p := c.(type) {
+		case *predicate.Comparison:
+			if p.Column != col || p.Op != predicate.OpEq {
+				continue
+			}
+			if key, ok := t.indexKey(col, p.Value); ok {
+				return &accessPath{accessType: AccessTypeBtreeIdx, column: col, keys: []interface{}{key}}
+			}
+		case *predicate.In:
+			if p.Column != col {
+				continue
+			}
+			keys := make([]interface{}, 0, len(p.Values))
+			for _, v := range p.Values {
+				// NULL never matches
+				if v == nil {
+					continue
+				}
+				key, ok := t.indexKey(col, v)
+				if !ok {
+					keys = nil
+					break
+				}
+				keys = append(keys, key)
+			}
+			if keys != nil {
+				return &accessPath{accessType: AccessTypeBtreeIdx, column: col, keys: keys}
+			}
+		}
+	}
+	return nil
+}
+
+// keyRange narrows the conditions on col into a single range
+// It returns nil if none of the conjuncts constrains col
+func (t *Table) keyRange(col string, conjuncts []predicate.Predicate) *accessPath {
+	path := &accessPath{accessType: AccessTypeBtreeIdx, column: col}
+	found := false
+
+	// narrow replaces the current bound if the new one is more restrictive
+	narrow := func(curr *index.Bound, key interface{}, inclusive bool, lower bool) *index.Bound {
+		if curr == nil {
+			return index.NewBound(key, inclusive)
+		}
+		cmp, err := types.Compare(key, curr.Key)
+		if err != nil {
+			return curr
+		}
+		if (lower && cmp > 0) || (!lower && cmp < 0) || (cmp == 0 && !inclusive) {
+			return index.NewBound(key, inclusive)
+		}
+		return curr
+	}
+
+	for _, c := range conjuncts {
+		switch p := c.(type) {
+		case *predicate.Comparison:
+			if p.Column != col {
+				continue
+			}
+			var inclusive, lower bool
+			switch p.Op {
+			case predicate.OpGt:
+				inclusive, lower = false, true
+			case predicate.OpGte:
+				inclusive, lower = true, true
+			case predicate.OpLt:
+				inclusive, lower = false, false
+			case predicate.OpLte:
+				inclusive, lower = true, false
+			default:
+				continue
+			}
+			bound, inclusive := t.roundBound(col, p.Value, inclusive, lower)
+			key, ok := t.indexKey(col, bound)
+			if !ok {
+				continue
+			}
+			if lower {
+				path.from = narrow(path.from, key, inclusive, true)
+			} else {
+				path.to = narrow(path.to, key, inclusive, false)
+			}
+			found = true
+		case *predicate.Between:
+			if p.Column != col {
+				continue
+			}
+			fromBound, fromInclusive := t.roundBound(col, p.From, true, true)
+			toBound, toInclusive := t.roundBound(col, p.To, true, false)
+			from, okFrom := t.indexKey(col, fromBound)
+			to, okTo := t.indexKey(col, toBound)
+			if !okFrom || !okTo {
+				continue
+			}
+			path.from = narrow(path.from, from, fromInclusive, true)
+			path.to = narrow(path.to, to, toInclusive, false)
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return path
+}
+
+// indexKey converts v into a key that can be used to search the B-tree index of col
+// It returns false if v cannot be compared with the values of the column
+func (t *Table) indexKey(col string, v interface{}) (interface{}, bool) {
+	c, ok := t.columns[col]
+	if !ok || v == nil {
+		return nil, false
+	}
+	switch c.DataType() {
+	case types.TypeInt64, types.TypeInt32, types.TypeByte:
+		if _, ok := asInt64(v); ok {
+			return v, true
+		}
+		// A float can only equal an integer if it's integral. Other floats cannot use the index
+		if f, ok := v.(float64); ok && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), true
+		}
+	case types.TypeFloat64, types.TypeFloat32:
+		// Floats of both widths and integers are compared exactly by types.Compare
+		if _, ok := asFloat64(v); ok {
+			return v, true
+		}
+	case types.TypeDate:
+		if _, ok := v.(types.Date); ok {
+			return v, true
+		}
+	case types.TypeTimestamp:
+		if _, ok := v.(time.Time); ok {
+			return v, true
+		}
+	case types.TypeInterval:
+		if _, ok := v.(time.Duration); ok {
+			return v, true
+		}
+	case types.TypeDecimal:
+		// Decimals and integers are compared exactly by types.Compare
+		if _, ok := v.(types.Decimal); ok {
+			return v, true
+		}
+		if _, ok := asInt64(v); ok {
+			return v, true
+		}
+	case types.TypeString:
+		if _, ok := v.(string); ok {
+			return v, true
+		}
+	case types.TypeBool:
+		if _, ok := v.(bool); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// roundBound rounds a float bound of an integer column towards the inside of the range, so id > 10.5 becomes
+// id >= 11 and id <= 10.5 becomes id <= 10. lower is set for the lower bound. Other values are returned as they are
+func (t *Table) roundBound(col string, v interface{}, inclusive, lower bool) (interface{}, bool) {
+	f, ok := v.(float64)
+	if !ok {
+		return v, inclusive
+	}
+	switch t.columns[col].DataType() {
+	case types.TypeInt64, types.TypeInt32, types.TypeByte:
+	default:
+		return v, inclusive
+	}
+	rounded := math.Floor(f)
+	if lower {
+		rounded = math.Ceil(f)
+	}
+	if rounded != f {
+		return rounded, true
+	}
+	return rounded, inclusive
+}
+
+// btreeLookup returns the positions of the pages that contain the records found by path
+func (t *Table) btreeLookup(path *accessPath) ([]int64, error) {
+	pagePositions := make([]int64, 0)
+	if path.column == t.primaryKey[0] {
+		items := make([]index.Item, 0)
+		if path.keys == nil {
+			found, err := t.index.Range(path.from, path.to)
+			if err != nil {
+				return nil, fmt.Errorf("Table.btreeLookup: %w", err)
+			}
+			items = found
+		}
+		// A key only contains the first column so it can match many records if the primary key is composite
+		for _, key := range path.keys {
+			bound := index.NewBound(key, true)
+			found, err := t.index.Range(bound, bound)
+			if err != nil {
+				return nil, fmt.Errorf("Table.btreeLookup: %w", err)
+			}
+			items = append(items, found...)
+		}
+		for _, item := range items {
+			pagePositions = append(pagePositions, item.PagePos)
+		}
+		return pagePositions, nil
+	}
+
+	idx, ok := t.secondaryIdxs[path.column]
+	if !ok {
+		return nil, fmt.Errorf("Table.btreeLookup: %w", NewIndexDoesNotExistError(t.Name, path.column))
+	}
+	items := make([]index.SecondaryItem, 0)
+	if path.keys == nil {
+		found, err := idx.Range(path.from, path.to)
+		if err != nil {
+			return nil, fmt.Errorf("Table.btreeLookup: %w", err)
+		}
+		items = found
+	}
+	for _, key := range path.keys {
+		found, err := idx.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("Table.btreeLookup: %w", err)
+		}
+		items = append(items, found...)
+	}
+	for _, item := range items {
+		pagePositions = append(pagePositions, item.PagePos)
+	}
+	return pagePositions, nil
+}
+
+// asFloat64 converts an integer or a float to float64
+func asFloat64(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	}
+	if i, ok := asInt64(v); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+func asInt64(v interface{}) (int64, bool) {
+	switch val := v.(type) {
+	case int64:
+		return val, true
+	case int32:
+		return int64(val), true
+	case byte:
+		return int64(val), true
+	case int:
+		return int64(val), true
+	default:
+		return 0, false
 	}
-	return AccessTypeFullTextIdx
 }
 
 func (t *Table) getFullTextIdxCol(columns map[string]interface{}) (*column.Column, error) {
@@ -596,18 +1854,72 @@ func (t *Table) getFullTextIdxCol(columns map[string]interface{}) (*column.Colum
 	return nil, fulltext.NewColumnNotFoundError()
 }
 
-func (t *Table) Update(whereStmts map[string]interface{}, values map[string]interface{}) (int, error) {
-	if err := t.ensureFilePointer(); err != nil {
-		return 0, fmt.Errorf("Table.Update: %w", err)
+// Update sets values in every record that matches every key-value pair in whereStmts
+func (t *Table) Update(whereStmts map[string]interface{}, values map[string]interface{}) (*UpdateResult, error) {
+	return t.UpdateWhere(predicate.FromMap(whereStmts), values)
+}
+
+// UpdateWhere sets values in every record that satisfies pred
+func (t *Table) UpdateWhere(pred predicate.Predicate, values map[string]interface{}) (*UpdateResult, error) {
+	res, syncLSN, err := t.updateWhere(0, pred, values)
+	if err != nil {
+		return nil, fmt.Errorf("Table.UpdateWhere: %w", err)
+	}
+	if err = t.syncWAL(syncLSN); err != nil {
+		return nil, fmt.Errorf("Table.UpdateWhere: %w", err)
+	}
+	return res, nil
+}
+
+// UpdateWhereTx is like UpdateWhere, but the records are updated by the transaction with txID. The old versions of
+// records written by other transactions are kept for the snapshots that still see them
+func (t *Table) UpdateWhereTx(txID int64, pred predicate.Predicate, values map[string]interface{}) (*UpdateResult, error) {
+	res, syncLSN, err := t.updateWhere(txID, pred, values)
+	if err != nil {
+		return nil, fmt.Errorf("Table.UpdateWhereTx: %w", err)
+	}
+	if err = t.syncWAL(syncLSN); err != nil {
+		return nil, fmt.Errorf("Table.UpdateWhereTx: %w", err)
+	}
+	return res, nil
+}
+
+// updateWhere updates the records for the transaction with txID while holding t.mu. It returns the LSN the WAL has
+// to be synced up to afterwards
+func (t *Table) updateWhere(txID int64, pred predicate.Predicate, values map[string]interface{}) (*UpdateResult, int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pageCount, err := t.pageCount()
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+	if pageCount == 0 {
+		return &UpdateResult{}, 0, nil
+	}
+	if values, err = t.convertValues(values); err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+	if values, err = readBlobs(values); err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
 	}
 	if err := t.validateColumns(values); err != nil {
-		return 0, fmt.Errorf("Table.Update: %w", err)
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+	// A duplicate key has to be detected before anything is written
+	if err := t.checkUniqueUpdate(pred, values); err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
 	}
 
-	result, err := t.delete(whereStmts)
+	result, err := t.findDeletable(txID, pred)
 	if err != nil {
-		return 0, fmt.Errorf("Table.Update: %w", err)
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+	if len(result.deletedRecords) == 0 {
+		return &UpdateResult{}, 0, nil
 	}
+
+	updatedRecords := make([]map[string]interface{}, 0, len(result.deletedRecords))
+	bufs := make([][]byte, 0, len(result.deletedRecords))
 	for _, rawRecord := range result.deletedRecords {
 		updatedRecord := make(map[string]interface{})
 		for k, v := range rawRecord.Record {
@@ -617,407 +1929,531 @@ func (t *Table) Update(whereStmts map[string]interface{}, values map[string]inte
 				updatedRecord[k] = v
 			}
 		}
-		if _, err = t.Insert(updatedRecord, false); err != nil {
-			return 0, fmt.Errorf("Table.Update: %w", err)
+		buf, err := t.marshalRecord(updatedRecord, txID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
 		}
+		updatedRecords = append(updatedRecords, updatedRecord)
+		bufs = append(bufs, buf)
 	}
-	return len(result.deletedRecords), nil
-}
-
-func (t *Table) Delete(whereStmts map[string]interface{}) (int, error) {
-	if err := t.ensureFilePointer(); err != nil {
-		return 0, fmt.Errorf("Table.Delete: %w", err)
+	if err = t.checkIndexKeys(updatedRecords); err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+	// A new version is written into the slot of the old one if it still fits into the page, otherwise it's moved to
+	// another page. An expired version has to stay where it is, so its new version is always placed somewhere else
+	replaced := make([]*walencoding.Change, len(result.recordChanges))
+	for i, c := range result.recordChanges {
+		if c.Op == walencoding.OpDelete {
+			replaced[i] = c
+		}
+	}
+	// The old versions are deleted and the new ones are inserted by the same WAL entry
+	inserts, placed, err := t.placeRecords(bufs, result.changes, replaced)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
 	}
-	result, err := t.delete(whereStmts)
+	changes := append(slices.Clone(result.changes), inserts...)
+	lsn, syncLSN, err := t.logChangesNoWait(txID, walencoding.OpUpdate, changes)
 	if err != nil {
-		return 0, fmt.Errorf("Table.Delete: %w", err)
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
 	}
-	for _, p := range result.effectedPages {
-		if err = t.invalidateCache(p); err != nil {
-			return len(result.deletedRecords), err
+	if err = t.applyChanges(changes, lsn); err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+
+	if err = t.updateIndexes(result, updatedRecords, placed); err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+	for _, updatedRecord := range updatedRecords {
+		if err = t.advanceSequence(updatedRecord); err != nil {
+			return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
 		}
 	}
-	return len(result.deletedRecords), nil
+	if err = t.sync(); err != nil {
+		return nil, 0, fmt.Errorf("Table.updateWhere: %w", err)
+	}
+	return &UpdateResult{Updated: len(result.deletedRecords), Pages: changedPages(changes)}, syncLSN, nil
 }
 
-func (t *Table) LoadIdx() error {
-	return t.index.Load()
+// UpdateResult describes the records an update modified
+type UpdateResult struct {
+	// Updated is the number of updated records
+	Updated int
+	// Pages contains the positions of the pages the update modified in ascending order: the pages of the old versions,
+	// the pages the new versions were placed on and the pages of their overflow chunks. A version that was rewritten
+	// in its slot only modified its own page
+	Pages []int64
 }
 
-func (t *Table) LoadFullTextIdx() error {
-	return t.fullTextIdx.Load()
+// changedPages returns the positions of the pages changes modify in ascending order
+func changedPages(changes []*walencoding.Change) []int64 {
+	pages := make([]int64, 0, len(changes))
+	for _, c := range changes {
+		pages = append(pages, c.PagePos)
+	}
+	slices.Sort(pages)
+	return slices.Compact(pages)
 }
 
-// ensureFilePointer seeks the file pointer the to first record
-func (t *Table) ensureFilePointer() error {
-	if _, err := t.file.Seek(0, 0); err != nil {
-		return fmt.Errorf("Table.ensureFilePointer: %w", err)
-	}
-	if err := t.seekUntil(types.TypeRecord); err != nil {
-		if err == io.EOF {
-			return err
-		}
-		return fmt.Errorf("Table.ensureFilePointer: %w", err)
-	}
-	return nil
+// Delete deletes every record that matches every key-value pair in whereStmts
+func (t *Table) Delete(whereStmts map[string]interface{}) (int, error) {
+	return t.DeleteWhere(predicate.FromMap(whereStmts))
 }
 
-// ensureColumnLength checks if the given map has the same amount of columns as the table definition
-// It is used to check raw records read from the table file
-func (t *Table) ensureColumnLength(record map[string]interface{}) error {
-	if len(record) != len(t.columns) {
-		return fmt.Errorf("Table.ensureColumnLength: %w", column.NewMismatchingColumnsError(len(t.columns), len(record)))
+// DeleteWhere deletes every record that satisfies pred
+func (t *Table) DeleteWhere(pred predicate.Predicate) (int, error) {
+	n, syncLSN, err := t.deleteWhere(0, pred)
+	if err != nil {
+		return 0, fmt.Errorf("Table.DeleteWhere: %w", err)
 	}
-	return nil
+	if err = t.syncWAL(syncLSN); err != nil {
+		return 0, fmt.Errorf("Table.DeleteWhere: %w", err)
+	}
+	return n, nil
 }
 
-func (t *Table) evaluateWhereStmt(whereStmt map[string]interface{}, record map[string]interface{}) bool {
-	for k, v := range whereStmt {
-		if record[k] != v {
-			return false
-		}
+// DeleteWhereTx is like DeleteWhere, but the records are deleted by the transaction with txID. The versions written by
+// other transactions are only expired, so the snapshots that still see them can read them
+func (t *Table) DeleteWhereTx(txID int64, pred predicate.Predicate) (int, error) {
+	n, syncLSN, err := t.deleteWhere(txID, pred)
+	if err != nil {
+		return 0, fmt.Errorf("Table.DeleteWhereTx: %w", err)
+	}
+	if err = t.syncWAL(syncLSN); err != nil {
+		return 0, fmt.Errorf("Table.DeleteWhereTx: %w", err)
 	}
-	return true
+	return n, nil
 }
 
-// pagePositions returns all the page positions in the table file as a slice
-func (t *Table) pagePositions() (pos []int64, e error) {
-	positions := make([]int64, 0)
-	origPos, err := t.file.Seek(0, io.SeekCurrent)
+// deleteWhere deletes the records for the transaction with txID while holding t.mu. It returns the LSN the WAL has to
+// be synced up to afterwards
+func (t *Table) deleteWhere(txID int64, pred predicate.Predicate) (int, int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result, err := t.findDeletable(txID, pred)
 	if err != nil {
-		return nil, fmt.Errorf("Table.pagePositions: %w", err)
+		return 0, 0, fmt.Errorf("Table.deleteWhere: %w", err)
 	}
-	defer func() {
-		if _, err = t.file.Seek(origPos, io.SeekStart); err != nil {
-			e = err
-		}
-	}()
-
-	if _, err = t.file.Seek(0, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("Table.pagePositions: %w", err)
+	if len(result.deletedRecords) == 0 {
+		return 0, 0, nil
 	}
 
-	if err := t.seekUntil(types.TypePage); err != nil {
-		if err == io.EOF {
-			return []int64{}, nil
-		}
-		return nil, fmt.Errorf("Table.pagePositions: %w", err)
+	lsn, syncLSN, err := t.logChangesNoWait(txID, walencoding.OpDelete, result.changes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Table.deleteWhere: %w", err)
 	}
+	if err = t.applyChanges(result.changes, lsn); err != nil {
+		return 0, 0, fmt.Errorf("Table.deleteWhere: %w", err)
+	}
+	if err = t.removeFromIndexes(result); err != nil {
+		return 0, 0, fmt.Errorf("Table.deleteWhere: %w", err)
+	}
+	if err = t.sync(); err != nil {
+		return 0, 0, fmt.Errorf("Table.deleteWhere: %w", err)
+	}
+	return len(result.deletedRecords), syncLSN, nil
+}
 
-	for {
-		dataType, err := t.reader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				return positions, nil
-			}
-			return nil, fmt.Errorf("Table.pagePositions: %w", err)
-		}
-		if dataType != types.TypePage {
-			return positions, nil
-		}
-		p, err := t.file.Seek(0, io.SeekCurrent)
-		if err != nil {
-			return nil, fmt.Errorf("Table.pagePositions: %w", err)
-		}
-		// the type byte has already been read, so we need to subtract 1 from the current position
-		positions = append(positions, p-1)
+func (t *Table) LoadIdx() error {
+	return t.index.Load()
+}
 
-		length, err := t.reader.ReadUint32()
+func (t *Table) LoadFullTextIdx() error {
+	return t.fullTextIdx.Load()
+}
+
+// LoadFreeSpaceMap loads the free-space map of the table. It's built from the pages if its file is empty
+func (t *Table) LoadFreeSpaceMap() error {
+	if err := t.fsm.Load(); err != nil {
+		return fmt.Errorf("Table.LoadFreeSpaceMap: %w", err)
+	}
+	pageCount, err := t.pageCount()
+	if err != nil {
+		return fmt.Errorf("Table.LoadFreeSpaceMap: %w", err)
+	}
+	if t.fsm.Len() > 0 || pageCount == 0 {
+		return nil
+	}
+	free := make([]int, 0, pageCount)
+	for pageNo := range pageCount {
+		p, err := t.readPage(t.pagePos(pageNo))
 		if err != nil {
-			return nil, fmt.Errorf("Table.pagePositions: %w", err)
-		}
-		if _, err = t.file.Seek(int64(length), io.SeekCurrent); err != nil {
-			return nil, fmt.Errorf("Table.pagePositions: %w", err)
+			return fmt.Errorf("Table.LoadFreeSpaceMap: %w", err)
 		}
+		free = append(free, p.available())
+	}
+	if err = t.fsm.Reset(free); err != nil {
+		return fmt.Errorf("Table.LoadFreeSpaceMap: %w", err)
 	}
+	return nil
 }
 
-// findContainingPage returns the position of the page that contains the given position
-//
-//	 Example
-//		pagePositions = [430,558,686]
-//	 pos = 602
-//	 result = 558
-//
-// The result is 558 because position 602 can be found in the page located between 558..686
-func findContainingPage(pagePositions []int64, pos int64) (int64, error) {
-	if len(pagePositions) == 0 {
-		return -1, fmt.Errorf("fincContainingPage: %w", NewPageNotFoundError(pagePositions, pos))
+// Legacy reports whether the table was created before pages had a fixed size
+func (t *Table) Legacy() bool {
+	return t.pageSize == 0
+}
+
+// Upgrade converts a table created before pages had a fixed size. Its records are copied into slotted pages of a new
+// file that replaces the old one, then the indexes and the free-space map are built again
+// The WAL must not contain changes of the table that were not checkpointed because they refer to the old pages
+func (t *Table) Upgrade() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.Legacy() {
+		return nil
 	}
-	if pagePositions[0] > pos {
-		return -1, fmt.Errorf("fincContainingPage: %w", NewPageNotFoundError(pagePositions, pos))
+	records, err := t.readLegacyRecords()
+	if err != nil {
+		return fmt.Errorf("Table.Upgrade: %w", err)
 	}
-	var left int64
-	right := int64(len(pagePositions) - 1)
-	for left < right {
-		mid := left + (right-left+1)/2
-		if pagePositions[mid] < pos {
-			left = mid
-		} else {
-			right = mid - 1
-		}
+
+	t.pageSize = DefaultPageSize
+	t.fsm.SetPageSize(t.pageSize)
+	if _, err = t.rewrite(records); err != nil {
+		return fmt.Errorf("Table.Upgrade: %w", err)
 	}
-	return pagePositions[left], nil
+	log.Printf("Table.Upgrade: %s: converted %d records\n", t.Name, len(records))
+	return nil
 }
 
-// updatePageSize increases or decreases the size of a page by offset
-// if the new size is 0 the page is removed
-func (t *Table) updatePageSize(page int64, offset int32) (deleted bool, e error) {
-	origPos, err := t.file.Seek(0, io.SeekCurrent)
+// readLegacyRecords returns the records stored in the pages of a legacy table. Deleted records are skipped
+//
+//	255 len [100 len record]... 255 len ...
+func (t *Table) readLegacyRecords() ([][]byte, error) {
+	stat, err := t.file.Stat()
 	if err != nil {
-		return false, fmt.Errorf("Table.updatePageSize: %w", err)
-	}
-	defer func() {
-		if _, err := t.file.Seek(origPos, io.SeekStart); err != nil {
-			e = err
+		return nil, fmt.Errorf("Table.readLegacyRecords: %w", err)
+	}
+	b := make([]byte, max(0, stat.Size()-t.dataStart))
+	if _, err = t.file.ReadAt(b, t.dataStart); err != nil {
+		return nil, fmt.Errorf("Table.readLegacyRecords: %w", err)
+	}
+	records := make([][]byte, 0)
+	for n := 0; n < len(b); {
+		if len(b) < n+int(types.LenMeta) {
+			return nil, fmt.Errorf("Table.readLegacyRecords: unexpected end of file at %d", t.dataStart+int64(n))
+		}
+		end := n + int(types.LenMeta) + int(binary.LittleEndian.Uint32(b[n+types.LenByte:]))
+		switch b[n] {
+		case types.TypePage:
+			// The records are inside the page
+			n += int(types.LenMeta)
+			continue
+		case types.TypeRecord:
+			if end > len(b) {
+				return nil, fmt.Errorf("Table.readLegacyRecords: unexpected end of file at %d", t.dataStart+int64(n))
+			}
+			records = append(records, b[n:end])
+		case types.TypeDeletedRecord:
+		default:
+			return nil, fmt.Errorf("Table.readLegacyRecords: unexpected type %d at %d", b[n], t.dataStart+int64(n))
 		}
-	}()
+		n = end
+	}
+	return records, nil
+}
 
-	if _, err = t.file.Seek(page, io.SeekStart); err != nil {
-		return false, fmt.Errorf("Table.updatePageSize: %w", err)
+// ensureColumnLength checks if the given map has the same amount of columns as the table definition
+// It is used to check raw records read from the table file
+func (t *Table) ensureColumnLength(record map[string]interface{}) error {
+	if len(record) != len(t.columns) {
+		return fmt.Errorf("Table.ensureColumnLength: %w", column.NewMismatchingColumnsError(len(t.columns), len(record)))
 	}
+	return nil
+}
 
-	dataType, err := t.reader.ReadByte()
+// pageCount returns the number of pages in the table file
+func (t *Table) pageCount() (int64, error) {
+	stat, err := t.file.Stat()
 	if err != nil {
-		if err == io.EOF {
-			return false, err
-		}
-		return false, fmt.Errorf("Table.updatePageSize: %w", err)
+		return 0, fmt.Errorf("Table.pageCount: %w", err)
 	}
-	if dataType != types.TypePage {
-		return false, fmt.Errorf("Table.updatePageSize: file pointer is at wrong position: expected: %d, actual: %d", types.TypePage, dataType)
-	}
-	length, err := t.reader.ReadUint32()
+	return max(0, stat.Size()-t.dataStart) / int64(t.pageSize), nil
+}
+
+// pagePositions returns the positions of every page in the table file
+func (t *Table) pagePositions() ([]int64, error) {
+	pageCount, err := t.pageCount()
 	if err != nil {
-		return false, fmt.Errorf("Table.updatePageSize: %w", err)
+		return nil, fmt.Errorf("Table.pagePositions: %w", err)
 	}
-	if _, err = t.file.Seek(-1*types.LenInt32, io.SeekCurrent); err != nil {
-		return false, fmt.Errorf("Table.updatePageSize: %w", err)
+	positions := make([]int64, 0, pageCount)
+	for pageNo := range pageCount {
+		positions = append(positions, t.pagePos(pageNo))
 	}
+	return positions, nil
+}
 
-	var newLength uint32
-	if offset >= 0 {
-		newLength = length + uint32(offset)
-	} else {
-		newLength = length - uint32(-offset)
-	}
+func (t *Table) pagePos(pageNo int64) int64 {
+	return t.dataStart + pageNo*int64(t.pageSize)
+}
+
+func (t *Table) pageNo(pagePos int64) int64 {
+	return (pagePos - t.dataStart) / int64(t.pageSize)
+}
 
-	marshaler := encoding.NewValueMarshaler[uint32](newLength)
-	b, err := marshaler.MarshalBinary()
+// readPage returns the page starting at pagePos from the buffer pool. It's read from the disk if it's not in the pool
+// The page must not be modified, it has to be cloned first
+func (t *Table) readPage(pagePos int64) (*page, error) {
+	p, _, err := t.fetchPage(pagePos)
 	if err != nil {
-		return false, fmt.Errorf("Table.updatePageSize: %w", err)
+		return nil, fmt.Errorf("Table.readPage: %w", err)
 	}
-	n, err := t.file.Write(b)
-	if n != len(b) {
-		return false, fmt.Errorf("Table.updatePageSize: %w", columnio.NewIncompleteWriteError(len(b), n))
+	return p, nil
+}
+
+// fetchPage is like readPage but it also reports whether the page was in the buffer pool
+func (t *Table) fetchPage(pagePos int64) (*page, bool, error) {
+	id := t.pageID(pagePos)
+	data, hit, err := t.pool.Fetch(id, func() ([]byte, error) {
+		return t.loadPage(pagePos)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("Table.fetchPage: %w", err)
 	}
+	// The content of a page in the pool is replaced instead of modified, so it can be read after it's unpinned
+	t.pool.Unpin(id)
+	return &page{data: data}, hit, nil
+}
 
-	if newLength == 0 {
-		if err = t.removeEmptyPage(page); err != nil {
-			return false, fmt.Errorf("Table.updatePageSize: %w", err)
-		}
-		return true, nil
+// loadPage reads the page starting at pagePos from the disk and verifies its checksum
+func (t *Table) loadPage(pagePos int64) ([]byte, error) {
+	data := make([]byte, t.pageSize)
+	if _, err := t.file.ReadAt(data, pagePos); err != nil {
+		return nil, fmt.Errorf("Table.loadPage: %w", err)
 	}
-	return false, nil
+	if _, err := parsePage(data, pagePos); err != nil {
+		return nil, fmt.Errorf("Table.loadPage: %w", err)
+	}
+	return data, nil
+}
+
+func (t *Table) pageID(pagePos int64) bufferpool.PageID {
+	return bufferpool.PageID{File: t.Name, Pos: pagePos}
 }
 
-func (t *Table) removeEmptyPage(page int64) (e error) {
-	origPos, err := t.file.Seek(0, io.SeekCurrent)
+// findDeletable returns the records that satisfy the given predicate and the changes that delete them
+// Inside the transaction with txID versions created by an earlier transaction are expired instead of deleted
+// Nothing is modified, the changes have to be logged and applied by the caller
+func (t *Table) findDeletable(txID int64, pred predicate.Predicate) (*deleteResult, error) {
+	result := newDeleteResult()
+	pages, err := t.pagePositions()
 	if err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+		return nil, fmt.Errorf("Table.findDeletable: %w", err)
 	}
-	defer func() {
-		if _, err := t.file.Seek(origPos, io.SeekStart); err != nil {
-			e = err
+	for _, pagePos := range pages {
+		p, err := t.readPage(pagePos)
+		if err != nil {
+			return nil, fmt.Errorf("Table.findDeletable: %w", err)
+		}
+		records, err := t.pageRecords(p)
+		if err != nil {
+			return nil, fmt.Errorf("Table.findDeletable: %w", err)
 		}
-	}()
+		for _, r := range records {
+			rawRecord := r.raw
+			if !mvcc.Latest.Visible(rawRecord.Xmin, rawRecord.Xmax) {
+				continue
+			}
+			if err := t.ensureColumnLength(rawRecord.Record); err != nil {
+				return nil, fmt.Errorf("Table.findDeletable: %w", err)
+			}
 
-	if _, err = t.file.Seek(page, io.SeekStart); err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
-	}
-	dataType, err := t.reader.ReadByte()
-	if err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
-	}
-	if dataType != types.TypePage {
-		return fmt.Errorf("Table.removeEmptyPage: file pointer points to invalid byte: %d", dataType)
-	}
-	length, err := t.reader.ReadUint32()
-	if err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+			ok, err := pred.Evaluate(rawRecord.Record)
+			if err != nil {
+				return nil, fmt.Errorf("Table.findDeletable: %w", err)
+			}
+			if !ok {
+				continue
+			}
+
+			log.Printf("Eligable for deletion: %v\n", rawRecord)
+			key, err := t.primaryKeyOf(rawRecord.Record)
+			if err != nil {
+				return nil, fmt.Errorf("Table.findDeletable: %w", err)
+			}
+			// The record before the deletion lets the WAL detect a change that doesn't belong to the page
+			before := slices.Clone(p.record(r.slot))
+			change := walencoding.NewDeleteChange(pagePos, int64(r.slot), before)
+			// Other snapshots cannot see the versions of the running transaction, so those are deleted right away
+			if txID != 0 && rawRecord.Versioned && rawRecord.Xmin != txID {
+				change = walencoding.NewExpireChange(pagePos, int64(r.slot), expiredRecord(before, txID))
+			}
+			result.addRecord(rawRecord, key, index.NewPage(pagePos), change)
+			// An expired version keeps its overflow chunks until it's reclaimed
+			if change.Op == walencoding.OpDelete {
+				chunks, err := t.overflowDeletes(before)
+				if err != nil {
+					return nil, fmt.Errorf("Table.findDeletable: %w", err)
+				}
+				result.changes = append(result.changes, chunks...)
+			}
+		}
 	}
-	if length != 0 {
-		return fmt.Errorf("Table.removeEmptyPage: %w", NewPageNotEmptyError(page, length))
+	return result, nil
+}
+
+// removeFromIndexes removes the deleted records from every index of the table
+func (t *Table) removeFromIndexes(result *deleteResult) error {
+	if err := t.index.RemoveMany(result.keys); err != nil {
+		return fmt.Errorf("Table.removeFromIndexes: %w", err)
 	}
-	stat, err := t.file.Stat()
-	if err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+	if err := t.removeFromFullTextIdx(result); err != nil {
+		return fmt.Errorf("Table.removeFromIndexes: %w", err)
 	}
-	beforeReader := io.NewSectionReader(t.file, 0, page)
-	afterReader := io.NewSectionReader(t.file, page+types.LenByte+types.LenInt32, stat.Size())
-	beforeBuf := make([]byte, page)
-	if _, err = beforeReader.Read(beforeBuf); err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+	for col, idx := range t.secondaryIdxs {
+		items := make([]index.SecondaryItem, 0, len(result.deletedRecords))
+		for i, rawRecord := range result.deletedRecords {
+			items = append(items, *index.NewSecondaryItem(rawRecord.Record[col], result.keys[i], result.effectedPages[i].StartPos))
+		}
+		if err := idx.RemoveMany(items); err != nil {
+			return fmt.Errorf("Table.removeFromIndexes: %w", err)
+		}
 	}
-	afterBuf := make([]byte, stat.Size()-(page+types.LenByte+types.LenInt32))
-	if _, err = afterReader.Read(afterBuf); err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+	return nil
+}
+
+// removeFromFullTextIdx removes the values of the deleted records from the full-text index
+func (t *Table) removeFromFullTextIdx(result *deleteResult) error {
+	if len(result.deletedRecords) == 0 {
+		return nil
 	}
-	if _, err = t.file.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+	col, err := t.getFullTextIdxCol(result.deletedRecords[0].Record)
+	if errors.Is(err, &fulltext.ColumnNotFoundError{}) {
+		return nil
 	}
-	bw, err := t.file.Write(beforeBuf)
 	if err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+		return fmt.Errorf("Table.removeFromFullTextIdx: %w", err)
 	}
-	aw, err := t.file.Write(afterBuf)
-	if err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+	for i, rawRecord := range result.deletedRecords {
+		if v, ok := rawRecord.Record[col.NameToStr()].(string); ok {
+			t.fullTextIdx.RemoveOne(v, result.effectedPages[i].StartPos)
+		}
 	}
-	if err = t.file.Truncate(int64(bw + aw)); err != nil {
-		return fmt.Errorf("Table.removeEmptyPage: %w", err)
+	if err = t.fullTextIdx.Persist(); err != nil {
+		return fmt.Errorf("Table.removeFromFullTextIdx: %w", err)
 	}
 	return nil
 }
 
-// readPage reads a page starting at pagePos from the LRU page cache or the disk if it cannot be found in cache
-// It returns true on cache hit, false otherwise
-func (t *Table) readPage(pagePos int64) (bool, error) {
-	if _, err := t.file.Seek(pagePos, io.SeekStart); err != nil {
-		return false, fmt.Errorf("table.readPageFromCache: %w", err)
+// updateIndexes replaces the entries of the records of result with the entries of their new versions stored in the
+// pages of placed. Only the entries whose value, primary key or page changed are modified
+func (t *Table) updateIndexes(result *deleteResult, records []map[string]interface{}, placed []*walencoding.Change) error {
+	keys := make([]index.Key, 0, len(records))
+	moved := make([]bool, 0, len(records))
+	for i, r := range records {
+		key, err := t.primaryKeyOf(r)
+		if err != nil {
+			return fmt.Errorf("Table.updateIndexes: %w", err)
+		}
+		keys = append(keys, key)
+		moved = append(moved, key.Compare(result.keys[i]) != 0 || placed[i].PagePos != result.effectedPages[i].StartPos)
 	}
 
-	key := t.pageKey(pagePos)
-	item, err := t.lru.Get(key)
-	if err != nil && !errors.Is(err, &platform.ItemNotFoundError{}) {
-		return false, fmt.Errorf("table.readPageFromCache: %w", err)
+	// Every old entry is removed before the new ones are added, so a record can take a key another one had before
+	for i := range records {
+		if moved[i] {
+			if err := t.index.Remove(result.keys[i]); err != nil {
+				return fmt.Errorf("Table.updateIndexes: %w", err)
+			}
+		}
 	}
-
-	// If the given page is not found in the LRU cache we read it from disk and put it in LRU
-	if err != nil && errors.Is(err, &platform.ItemNotFoundError{}) {
-		pr := platformio.NewPageReader(t.reader)
-		pageContent := make([]byte, PageSize+types.LenByte+types.LenInt32)
-		n, err := pr.Read(pageContent)
-		if err != nil {
-			return false, fmt.Errorf("table.readPageFromCache: %w", err)
-		}
-		pageContent = pageContent[:n]
-		reader := bytes.NewReader(pageContent)
-		t.recordParser = parser.NewRecordParser(reader, t.ColumnNames())
-		if err = t.lru.Put(key, *index.NewPageWithContent(pagePos, pageContent)); err != nil {
-			return false, fmt.Errorf("table.readPageFromCache: %w", err)
-		}
-		return false, nil
-	} else {
-		t.recordParser = parser.NewRecordParser(
-			bytes.NewReader(item.Content),
-			t.ColumnNames(),
-		)
-		return true, nil
-	}
-}
-
-// delete deletes records that satisfy the given where statement
-// It also maintains:
-//   - page sizes
-//   - index
-func (t *Table) delete(whereStmts map[string]interface{}) (*deleteResult, error) {
-	recordsToDelete := make([]*DeletableRecord, 0)
-	result := newDeleteResult()
-	for {
-		err := t.recordParser.Parse()
-		if err == io.EOF {
-			break
+	for i := range records {
+		if moved[i] {
+			if err := t.index.Add(keys[i], placed[i].PagePos); err != nil {
+				return fmt.Errorf("Table.updateIndexes: %w", err)
+			}
 		}
-		if err != nil {
-			return nil, err
+	}
+
+	for col, idx := range t.secondaryIdxs {
+		changed := make([]bool, 0, len(records))
+		for i, r := range records {
+			old := result.deletedRecords[i].Record[col]
+			changed = append(changed, moved[i] || !sameValue(old, r[col]))
+			if !changed[i] {
+				continue
+			}
+			if err := idx.Remove(old, result.keys[i]); err != nil {
+				return fmt.Errorf("Table.updateIndexes: %w", err)
+			}
 		}
-		rawRecord := t.recordParser.Value
-		if err := t.ensureColumnLength(rawRecord.Record); err != nil {
-			return nil, fmt.Errorf("Table.delete: %w", err)
+		for i, r := range records {
+			if !changed[i] {
+				continue
+			}
+			if err := idx.Add(r[col], keys[i], placed[i].PagePos); err != nil {
+				return fmt.Errorf("Table.updateIndexes: %w", err)
+			}
 		}
+	}
 
-		if !t.evaluateWhereStmt(whereStmts, rawRecord.Record) {
+	col, err := t.getFullTextIdxCol(records[0])
+	if errors.Is(err, &fulltext.ColumnNotFoundError{}) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Table.updateIndexes: %w", err)
+	}
+	changed := false
+	for i, r := range records {
+		// NULL values are not indexed, the same as in addToFullTextIdx
+		old, oldIndexed := result.deletedRecords[i].Record[col.NameToStr()].(string)
+		v, indexed := r[col.NameToStr()].(string)
+		if old == v && oldIndexed == indexed && placed[i].PagePos == result.effectedPages[i].StartPos {
 			continue
 		}
-
-		log.Printf("Eligable for deletion: %v\n", rawRecord)
-		result.addRecord(rawRecord)
-		pos, err := t.file.Seek(-1*int64(rawRecord.FullSize), io.SeekCurrent)
-		if err != nil {
-			return nil, fmt.Errorf("Table.delete: %w", err)
-		}
-		id, err := rawRecord.Id()
-		if err != nil {
-			return nil, fmt.Errorf("Table.delete: %w", err)
-		}
-		pages, err := t.pagePositions()
-		if err != nil {
-			return nil, fmt.Errorf("Table.delete: %w", err)
-		}
-		page, err := findContainingPage(pages, pos)
-		if err != nil {
-			return nil, fmt.Errorf("Table.delete: %w", err)
+		if oldIndexed {
+			t.fullTextIdx.RemoveOne(old, result.effectedPages[i].StartPos)
 		}
-		result.addPage(index.NewPage(page))
-		recordsToDelete = append(recordsToDelete, NewDeletableRecord(id, pos, rawRecord.FullSize))
-		if _, err = t.file.Seek(int64(rawRecord.FullSize), io.SeekCurrent); err != nil {
-			return nil, fmt.Errorf("Table.delete: %w", err)
+		if indexed {
+			// The full-text index stores int64 IDs only for information
+			var id int64
+			if len(keys[i]) == 1 {
+				id, _ = keys[i][0].(int64)
+			}
+			t.fullTextIdx.Add(v, placed[i].PagePos, id)
 		}
+		changed = true
 	}
-
-	if _, err := t.markRecordsDeleted(recordsToDelete); err != nil {
-		return nil, fmt.Errorf("Table.delete: %w", err)
-	}
-
-	ids := make([]int64, len(recordsToDelete))
-	for _, v := range recordsToDelete {
-		ids = append(ids, v.id)
-	}
-	if err := t.index.RemoveManyAndPersist(ids); err != nil {
-		return nil, fmt.Errorf("Table.delete: %w", err)
-	}
-	if err := t.fullTextIdx.RemoveManyAndPersist(ids); err != nil {
-		return nil, fmt.Errorf("Table.delete: %w", err)
+	if changed {
+		if err = t.fullTextIdx.Persist(); err != nil {
+			return fmt.Errorf("Table.updateIndexes: %w", err)
+		}
 	}
-	return result, nil
+	return nil
 }
 
-func (t *Table) markRecordsDeleted(deletableRecords []*DeletableRecord) (n int, e error) {
-	for _, rec := range deletableRecords {
-		if _, err := t.file.Seek(rec.pos, io.SeekStart); err != nil {
-			return 0, fmt.Errorf("Table.markRecordsDeleted: %w", err)
-		}
-		if err := binary.Write(t.file, binary.LittleEndian, types.TypeDeletedRecord); err != nil {
-			return 0, fmt.Errorf("Table.markRecordsDeleted: %w", err)
-		}
-		length, err := t.reader.ReadUint32()
-		if err != nil {
-			return 0, fmt.Errorf("Table.markRecordsDeleted: %w", err)
-		}
-		zeroBytes := make([]byte, length)
-		if err = binary.Write(t.file, binary.LittleEndian, zeroBytes); err != nil {
-			return 0, fmt.Errorf("Table.markRecordsDeleted: %w", err)
-		}
+// sameValue reports whether two column values are equal. NULL is only equal to NULL
+func sameValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
 	}
-	return len(deletableRecords), nil
+	cmp, err := types.Compare(a, b)
+	return err == nil && cmp == 0
 }
 
 type deleteResult struct {
 	deletedRecords []*parser.RawRecord
 	effectedPages  []*index.Page
+	keys           []index.Key
+	// changes contains an OpDelete or OpExpire change for every deleted record and an OpDelete change for the overflow
+	// chunks of the records that are deleted
+	changes []*walencoding.Change
+	// recordChanges contains the change of every deleted record
+	recordChanges []*walencoding.Change
 }
 
 func newDeleteResult() *deleteResult {
 	return &deleteResult{}
 }
 
-func (dr *deleteResult) addRecord(r *parser.RawRecord) {
+func (dr *deleteResult) addRecord(r *parser.RawRecord, key index.Key, p *index.Page, change *walencoding.Change) {
 	dr.deletedRecords = append(dr.deletedRecords, r)
-}
-
-func (dr *deleteResult) addPage(p *index.Page) {
+	dr.keys = append(dr.keys, key)
 	dr.effectedPages = append(dr.effectedPages, p)
+	dr.changes = append(dr.changes, change)
+	dr.recordChanges = append(dr.recordChanges, change)
 }
 
 type SelectResult struct {
@@ -1046,18 +2482,130 @@ func (t *Table) validateColumns(columns map[string]interface{}) error {
 	return nil
 }
 
-func (t *Table) pageKey(pagePos int64) string {
-	return fmt.Sprintf("%s-%d", t.Name, pagePos)
+// uniqueConstraints returns the column sets that cannot have the same values in two records. The primary key comes first
+func (t *Table) uniqueConstraints() [][]string {
+	constraints := [][]string{t.primaryKey}
+	for _, col := range t.columnNames {
+		if t.columns[col].IsUnique() && !slices.Equal(t.primaryKey, []string{col}) {
+			constraints = append(constraints, []string{col})
+		}
+	}
+	return constraints
+}
+
+// checkUnique returns DuplicateKeyError if records would violate one of the constraints,
+// either because two of them have the same values or because a record already in the table has them
+// Records in the table whose primary key is in replaced are not considered duplicates because they are about to be deleted
+func (t *Table) checkUnique(records []map[string]interface{}, replaced map[string]bool, constraints [][]string) error {
+	for _, cols := range constraints {
+		seen := make(map[string]bool, len(records))
+		for _, record := range records {
+			key, ok := uniqueKeyOf(record, cols)
+			// NULL is never equal to anything so it cannot be a duplicate
+			if !ok {
+				continue
+			}
+			if seen[key.String()] {
+				return fmt.Errorf("Table.checkUnique: %w", NewDuplicateKeyError(t.Name, strings.Join(cols, ", "), keyValue(key)))
+			}
+			seen[key.String()] = true
+
+			exists, err := t.keyExists(cols, key, replaced)
+			if err != nil {
+				return fmt.Errorf("Table.checkUnique: %w", err)
+			}
+			if exists {
+				return fmt.Errorf("Table.checkUnique: %w", NewDuplicateKeyError(t.Name, strings.Join(cols, ", "), keyValue(key)))
+			}
+		}
+	}
+	return nil
+}
+
+// uniqueKeyOf returns the values of cols in record. It returns false if any of them is NULL
+func uniqueKeyOf(record map[string]interface{}, cols []string) (index.Key, bool) {
+	key := make(index.Key, 0, len(cols))
+	for _, col := range cols {
+		v := record[col]
+		if v == nil {
+			return nil, false
+		}
+		key = append(key, v)
+	}
+	return key, true
+}
+
+// keyValue returns the value of a single column key as it is so errors don't print it as a list
+func keyValue(key index.Key) interface{} {
+	if len(key) == 1 {
+		return key[0]
+	}
+	return []interface{}(key)
+}
+
+func (t *Table) keyExists(cols []string, key index.Key, replaced map[string]bool) (bool, error) {
+	if slices.Equal(cols, t.primaryKey) {
+		if _, err := t.index.Get(key); err != nil {
+			var errNotFound *index.ItemNotFoundError
+			if errors.As(err, &errNotFound) {
+				return false, nil
+			}
+			return false, fmt.Errorf("Table.keyExists: %w", err)
+		}
+		return !replaced[key.String()], nil
+	}
+
+	// Every other constraint is a single UNIQUE column
+	res, err := t.selectWhere(predicate.Eq(cols[0], key[0]), mvcc.Latest, noLock{})
+	if err != nil {
+		return false, fmt.Errorf("Table.keyExists: %w", err)
+	}
+	for _, row := range res.Rows {
+		pk, err := t.primaryKeyOf(row)
+		if err != nil {
+			return false, fmt.Errorf("Table.keyExists: %w", err)
+		}
+		if !replaced[pk.String()] {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
-func (t *Table) invalidateCache(page *index.Page) error {
-	if err := t.lru.Remove(t.pageKey(page.StartPos)); err != nil {
-		if errors.Is(err, &platform.ItemNotFoundError{}) {
-			log.Printf("invalidating page from cache that doesn't exist: %d", page.StartPos)
+// checkUniqueUpdate returns DuplicateKeyError if setting values in the records that satisfy pred would violate a unique constraint
+func (t *Table) checkUniqueUpdate(pred predicate.Predicate, values map[string]interface{}) error {
+	constraints := make([][]string, 0)
+	for _, cols := range t.uniqueConstraints() {
+		if slices.ContainsFunc(cols, func(col string) bool {
+			_, ok := values[col]
+			return ok
+		}) {
+			constraints = append(constraints, cols)
 		}
-		if !errors.Is(err, &platform.ItemNotFoundError{}) {
-			return fmt.Errorf("table.invalidateCache: %w, page key: %s", err, t.pageKey(page.StartPos))
+	}
+	if len(constraints) == 0 {
+		return nil
+	}
+
+	res, err := t.selectWhere(pred, mvcc.Latest, noLock{})
+	if err != nil {
+		return fmt.Errorf("Table.checkUniqueUpdate: %w", err)
+	}
+	// The updated records are deleted before they are inserted again
+	replaced := make(map[string]bool, len(res.Rows))
+	updated := make([]map[string]interface{}, 0, len(res.Rows))
+	for _, row := range res.Rows {
+		pk, err := t.primaryKeyOf(row)
+		if err != nil {
+			return fmt.Errorf("Table.checkUniqueUpdate: %w", err)
 		}
+		replaced[pk.String()] = true
+		record := maps.Clone(row)
+		maps.Copy(record, values)
+		updated = append(updated, record)
+	}
+	if err = t.checkUnique(updated, replaced, constraints); err != nil {
+		return fmt.Errorf("Table.checkUniqueUpdate: %w", err)
 	}
 	return nil
 }
@@ -1075,59 +2623,264 @@ func getTableName(f *os.File) (string, error) {
 	return filenameParts[len(filenameParts)-1], nil
 }
 
-func (t *Table) RestoreWAL() error {
-	if _, err := t.file.Seek(0, io.SeekEnd); err != nil {
-		return fmt.Errorf("Table.RestoreWAL: %w", err)
+// Redo applies the changes of WAL entries that belong to the table again
+// The table file may or may not contain the changes, so the indexes are built again from it afterwards
+func (t *Table) Redo(entries []*walencoding.WALUnmarshaler) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, entry := range entries {
+		changes, err := walencoding.UnmarshalChanges(entry.Data)
+		if err != nil {
+			return fmt.Errorf("Table.Redo: LSN %d: %w", entry.LSN, err)
+		}
+		if err = t.applyChanges(changes, entry.LSN); err != nil {
+			return fmt.Errorf("Table.Redo: LSN %d: %w", entry.LSN, err)
+		}
 	}
+	if err := t.rebuildIndexes(); err != nil {
+		return fmt.Errorf("Table.Redo: %w", err)
+	}
+	log.Printf("Table.Redo: %s: replayed %d entries\n", t.Name, len(entries))
+	return nil
+}
 
-	restorableData, err := t.wal.GetRestorableData()
-	if err != nil {
-		return fmt.Errorf("Table.RestoreWAL: %w", err)
+// Undo reverts the changes of the WAL entries the transaction with txID logged for the table. entries are in the
+// order of their LSNs and they are reverted from the last change to the first one
+// The reverting changes are logged as an OpUndo entry of the transaction, so recovery replays them instead of undoing
+// the transaction again. The indexes are built again from the table file afterwards
+func (t *Table) Undo(txID int64, entries []*walencoding.WALUnmarshaler) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	undo := make([]*walencoding.Change, 0)
+	for _, entry := range slices.Backward(entries) {
+		changes, err := walencoding.UnmarshalChanges(entry.Data)
+		if err != nil {
+			return fmt.Errorf("Table.Undo: LSN %d: %w", entry.LSN, err)
+		}
+		for _, c := range slices.Backward(changes) {
+			undo = append(undo, inverseChanges(c)...)
+		}
 	}
-	// Nothing to restore
-	if restorableData == nil {
-		fmt.Printf("RestoreWAL skipped\n")
+	if len(undo) == 0 {
 		return nil
 	}
 
-	n, err := t.file.Write(restorableData.Data)
+	lsn, err := t.logTxChanges(txID, walencoding.OpUndo, undo)
 	if err != nil {
-		return fmt.Errorf("Table.RestoreWAL: %w", err)
+		return fmt.Errorf("Table.Undo: %w", err)
+	}
+	if err = t.applyChanges(undo, lsn); err != nil {
+		return fmt.Errorf("Table.Undo: %w", err)
+	}
+	if err = t.rebuildIndexes(); err != nil {
+		return fmt.Errorf("Table.Undo: %w", err)
+	}
+	if err = t.sync(); err != nil {
+		return fmt.Errorf("Table.Undo: %w", err)
+	}
+	return nil
+}
+
+// inverseChanges returns the changes that revert c
+// An expired record is replaced by the record before the change, which is the same record without the ID of the
+// deleting transaction
+func inverseChanges(c *walencoding.Change) []*walencoding.Change {
+	switch c.Op {
+	case walencoding.OpInsert:
+		return []*walencoding.Change{walencoding.NewDeleteChange(c.PagePos, c.Slot, c.Record)}
+	case walencoding.OpDelete:
+		return []*walencoding.Change{walencoding.NewInsertChange(c.PagePos, c.Slot, c.Record)}
+	default:
+		return []*walencoding.Change{
+			walencoding.NewDeleteChange(c.PagePos, c.Slot, c.Record),
+			walencoding.NewInsertChange(c.PagePos, c.Slot, expiredRecord(c.Record, 0)),
+		}
+	}
+}
+
+// rebuildIndexes clears every index and adds the records of every page to them again. The free-space map is built
+// again too
+func (t *Table) rebuildIndexes() error {
+	if err := t.index.Clear(); err != nil {
+		return fmt.Errorf("Table.rebuildIndexes: %w", err)
 	}
-	if n != len(restorableData.Data) {
-		return fmt.Errorf("Table.RestoreWAL: %w", columnio.NewIncompleteWriteError(len(restorableData.Data), n))
+	for _, idx := range t.secondaryIdxs {
+		if err := idx.Clear(); err != nil {
+			return fmt.Errorf("Table.rebuildIndexes: %w", err)
+		}
 	}
+	t.fullTextIdx.Clear()
+	clear(t.expiredPages)
 
-	fmt.Printf("RestoreWAL wrote %d bytes\n", n)
+	fullTextCol := ""
+	for _, col := range t.columnNames {
+		if t.columns[col].Opts.FullTextIdx {
+			fullTextCol = col
+		}
+	}
 
-	if err = t.wal.Commit(restorableData.LastEntry); err != nil {
-		return fmt.Errorf("Table.RestoreWAL: %w", err)
+	pages, err := t.pagePositions()
+	if err != nil {
+		return fmt.Errorf("Table.rebuildIndexes: %w", err)
+	}
+	free := make([]int, 0, len(pages))
+	for _, pagePos := range pages {
+		p, err := t.readPage(pagePos)
+		if err != nil {
+			return fmt.Errorf("Table.rebuildIndexes: %w", err)
+		}
+		free = append(free, p.available())
+		records, err := t.pageRecords(p)
+		if err != nil {
+			return fmt.Errorf("Table.rebuildIndexes: %w", err)
+		}
+		for _, r := range records {
+			// The indexes only contain the latest versions
+			if r.raw.Xmax != 0 {
+				t.expiredPages[pagePos] = true
+				continue
+			}
+			record := r.raw.Record
+			key, err := t.primaryKeyOf(record)
+			if err != nil {
+				return fmt.Errorf("Table.rebuildIndexes: %w", err)
+			}
+			if err = t.index.Add(key, pagePos); err != nil {
+				return fmt.Errorf("Table.rebuildIndexes: %w", err)
+			}
+			for col, idx := range t.secondaryIdxs {
+				if err = idx.Add(record[col], key, pagePos); err != nil {
+					return fmt.Errorf("Table.rebuildIndexes: %w", err)
+				}
+			}
+			if v, ok := record[fullTextCol].(string); fullTextCol != "" && ok {
+				var id int64
+				if len(key) == 1 {
+					id, _ = key[0].(int64)
+				}
+				t.fullTextIdx.Add(v, pagePos, id)
+			}
+		}
 	}
 
+	// Rebuilding is not logged in the WAL, so the indexes are written right away
+	if err = t.index.Flush(); err != nil {
+		return fmt.Errorf("Table.rebuildIndexes: %w", err)
+	}
+	for _, idx := range t.secondaryIdxs {
+		if err = idx.Flush(); err != nil {
+			return fmt.Errorf("Table.rebuildIndexes: %w", err)
+		}
+	}
+	if err = t.fullTextIdx.Persist(); err != nil {
+		return fmt.Errorf("Table.rebuildIndexes: %w", err)
+	}
+	if err = t.fsm.Reset(free); err != nil {
+		return fmt.Errorf("Table.rebuildIndexes: %w", err)
+	}
 	return nil
 }
 
+// ReclaimVersions deletes the versions that were deleted by a transaction whose ID is smaller than horizon and returns
+// how many were deleted. The caller has to make sure that no snapshot can see them anymore
+func (t *Table) ReclaimVersions(horizon int64) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pages, err := t.pagePositions()
+	if err != nil {
+		return 0, fmt.Errorf("Table.ReclaimVersions: %w", err)
+	}
+	changes := make([]*walencoding.Change, 0)
+	reclaimed := 0
+	expiredPages := make(map[int64]bool)
+	for _, pagePos := range pages {
+		p, err := t.readPage(pagePos)
+		if err != nil {
+			return 0, fmt.Errorf("Table.ReclaimVersions: %w", err)
+		}
+		records, err := t.pageRecords(p)
+		if err != nil {
+			return 0, fmt.Errorf("Table.ReclaimVersions: %w", err)
+		}
+		for _, r := range records {
+			if r.raw.Xmax == 0 {
+				continue
+			}
+			if r.raw.Xmax >= horizon {
+				expiredPages[pagePos] = true
+				continue
+			}
+			chunks, err := t.overflowDeletes(p.record(r.slot))
+			if err != nil {
+				return 0, fmt.Errorf("Table.ReclaimVersions: %w", err)
+			}
+			changes = append(changes, walencoding.NewDeleteChange(pagePos, int64(r.slot), p.record(r.slot)))
+			changes = append(changes, chunks...)
+			reclaimed++
+		}
+	}
+	if len(changes) == 0 {
+		t.expiredPages = expiredPages
+		return 0, nil
+	}
+
+	lsn, err := t.logChanges(walencoding.OpDelete, changes)
+	if err != nil {
+		return 0, fmt.Errorf("Table.ReclaimVersions: %w", err)
+	}
+	if err = t.applyChanges(changes, lsn); err != nil {
+		return 0, fmt.Errorf("Table.ReclaimVersions: %w", err)
+	}
+	t.expiredPages = expiredPages
+	if err = t.sync(); err != nil {
+		return 0, fmt.Errorf("Table.ReclaimVersions: %w", err)
+	}
+	return reclaimed, nil
+}
+
 // ---- Debug ----
 
 // ReadRaw returns the raw byte array stored in the table. It's for debugging
 func (t *Table) ReadRaw() ([]byte, error) {
-	if _, err := t.file.Seek(0, 0); err != nil {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if err := t.pool.Flush(t.Name); err != nil {
 		return nil, fmt.Errorf("Table.ReadRaw: %w", err)
 	}
-
 	stat, err := t.file.Stat()
 	if err != nil {
 		return nil, fmt.Errorf("Table.ReadRaw: %w", err)
 	}
 
 	buf := make([]byte, stat.Size())
-	if _, err = t.file.Read(buf); err != nil {
+	if _, err = t.file.ReadAt(buf, 0); err != nil {
 		return nil, fmt.Errorf("Table.ReadRaw: %w", err)
 	}
 
 	return buf, nil
 }
 
+// ReadRawRecords returns the records of every page in the order of their slots. It's for debugging
+func (t *Table) ReadRawRecords() ([][]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	pages, err := t.pagePositions()
+	if err != nil {
+		return nil, fmt.Errorf("Table.ReadRawRecords: %w", err)
+	}
+	records := make([][]byte, 0)
+	for _, pagePos := range pages {
+		p, err := t.readPage(pagePos)
+		if err != nil {
+			return nil, fmt.Errorf("Table.ReadRawRecords: %w", err)
+		}
+		for _, slot := range p.slots() {
+			records = append(records, p.record(slot))
+		}
+	}
+	return records, nil
+}
+
 // ReadRawIdx returns the raw byte array stored in the file. It's for debugging
 func (t *Table) ReadRawIdx() ([]byte, error) {
 	return t.index.ReadRaw()
@@ -1138,25 +2891,12 @@ func (t *Table) ReadRawFullTextIdx() ([]byte, error) {
 	return t.fullTextIdx.ReadRaw()
 }
 
-func (t *Table) GetIndex() []index.Item {
+func (t *Table) GetIndex() ([]index.Item, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return t.index.GetAll()
 }
 
-type DeletableRecord struct {
-	id   int64
-	page int64
-	pos  int64
-	len  uint32
-}
-
-func NewDeletableRecord(id, pos int64, len uint32) *DeletableRecord {
-	return &DeletableRecord{
-		id:  id,
-		pos: pos,
-		len: len,
-	}
-}
-
 func GetTableName(f *os.File) (string, error) {
 	// path/to/db/table.bin
 	parts := strings.Split(f.Name(), ".")
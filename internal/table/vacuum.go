@@ -0,0 +1,189 @@
+package table
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	platformio "github.com/omesh-barhate/ByteForge/internal/platform/parser/io"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	columnio "github.com/omesh-barhate/ByteForge/internal/table/column/io"
+	walencoding "github.com/omesh-barhate/ByteForge/internal/table/wal/encoding"
+)
+
+// Vacuum rewrites the table file with the records packed into as few pages as possible, so the space of deleted
+// records is given back to the file system. The indexes and the free-space map are built again for the new pages
+// Versions deleted by a transaction are kept, ReclaimVersions has to remove them first
+//
+// The caller has to take a checkpoint before, so no WAL entry after it refers to the pages of the old file
+func (t *Table) Vacuum() error {
+	t.rewriteMu.Lock()
+	defer t.rewriteMu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pages, err := t.pagePositions()
+	if err != nil {
+		return fmt.Errorf("Table.Vacuum: %w", err)
+	}
+	records := make([][]byte, 0)
+	for _, pagePos := range pages {
+		p, err := t.readPage(pagePos)
+		if err != nil {
+			return fmt.Errorf("Table.Vacuum: %w", err)
+		}
+		for _, slot := range p.slots() {
+			// The chunks are written again with the records they belong to
+			if p.record(slot)[0] == types.TypeOverflowChunk {
+				continue
+			}
+			r, err := t.inlineOverflow(p.record(slot))
+			if err != nil {
+				return fmt.Errorf("Table.Vacuum: %w", err)
+			}
+			records = append(records, r)
+		}
+	}
+
+	// Replaying the entry rebuilds the indexes, so they match the file that was in place when the process stopped
+	if _, err = t.logChanges(walencoding.OpVacuum, nil); err != nil {
+		return fmt.Errorf("Table.Vacuum: %w", err)
+	}
+	n, err := t.rewrite(records)
+	if err != nil {
+		return fmt.Errorf("Table.Vacuum: %w", err)
+	}
+	log.Printf("Table.Vacuum: %s: %d pages before, %d pages after\n", t.Name, len(pages), n)
+	return nil
+}
+
+// rewrite replaces the table file with one that contains the column definitions and records packed into consecutive
+// pages and returns the number of pages. The new file is written next to the old one and renamed over it, so a crash
+// leaves one of them in place. The indexes and the free-space map are built again from the new file
+func (t *Table) rewrite(records [][]byte) (int64, error) {
+	// Nothing is written into the old file after it was replaced
+	if err := t.pool.Flush(t.Name); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	path := t.file.Name()
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err = t.writeColumnDefinitions(tmp); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	pk := &packer{
+		file:     tmp,
+		pageSize: t.pageSize,
+		pos:      t.dataStart,
+	}
+	for _, r := range records {
+		if r, err = spill(pk, r, t.pageSize); err != nil {
+			tmp.Close()
+			return 0, fmt.Errorf("Table.rewrite: %w", err)
+		}
+		if len(r) > maxRecordLen(t.pageSize) {
+			tmp.Close()
+			return 0, fmt.Errorf("Table.rewrite: %w", NewRecordTooLargeError(t.Name, len(r), maxRecordLen(t.pageSize)))
+		}
+		if _, _, err = pk.place(r); err != nil {
+			tmp.Close()
+			return 0, fmt.Errorf("Table.rewrite: %w", err)
+		}
+	}
+	if err = pk.flush(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	if err = syncDir(filepath.Dir(path)); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+
+	if err = t.file.Close(); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	t.file = f
+	if t.reader, err = platformio.NewReader(f); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	t.columnDefReader = columnio.NewColumnDefinitionReader(f, t.reader)
+	t.pool.Discard(t.Name)
+	if err = t.rebuildIndexes(); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	if err = t.syncFiles(); err != nil {
+		return 0, fmt.Errorf("Table.rewrite: %w", err)
+	}
+	return pk.pageCount, nil
+}
+
+// packer writes records into consecutive pages of a new table file. A page is written when the next record doesn't
+// fit into it anymore
+type packer struct {
+	file     *os.File
+	pageSize int
+	// pos is the position of p in the file
+	pos       int64
+	p         *page
+	pageCount int64
+}
+
+func (pk *packer) place(record []byte) (int64, int64, error) {
+	if pk.p != nil && !pk.p.fits(len(record)) {
+		if err := pk.flush(); err != nil {
+			return 0, 0, fmt.Errorf("packer.place: %w", err)
+		}
+		pk.pos += int64(pk.pageSize)
+	}
+	if pk.p == nil {
+		pk.p = newPage(pk.pageSize)
+		pk.pageCount++
+	}
+	slot, err := pk.p.insert(record)
+	if err != nil {
+		return 0, 0, fmt.Errorf("packer.place: %w", err)
+	}
+	return pk.pos, int64(slot), nil
+}
+
+// flush writes the current page
+func (pk *packer) flush() error {
+	if pk.p == nil {
+		return nil
+	}
+	if _, err := pk.file.WriteAt(pk.p.bytes(), pk.pos); err != nil {
+		return fmt.Errorf("packer.flush: %w", err)
+	}
+	pk.p = nil
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("syncDir: %w", err)
+	}
+	defer d.Close()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("syncDir: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package wal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Durability controls when the engine calls fsync on the files it writes
+type Durability int
+
+const (
+	// DurabilityCommit syncs the WAL when a transaction commits or a change outside of a transaction is logged
+	DurabilityCommit Durability = iota
+	// DurabilityGroup syncs at the same points as DurabilityCommit, but one fsync covers every writer that is waiting
+	// for its entry to become durable
+	DurabilityGroup
+	// DurabilityAlways syncs the WAL after every entry and the table and index files after every change
+	DurabilityAlways
+	// DurabilityOff never syncs the WAL. The last changes can be lost on power loss
+	DurabilityOff
+)
+
+var durabilityNames = map[Durability]string{
+	DurabilityCommit: "commit",
+	DurabilityGroup:  "group",
+	DurabilityAlways: "always",
+	DurabilityOff:    "off",
+}
+
+func (d Durability) String() string {
+	if name, ok := durabilityNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("Durability(%d)", int(d))
+}
+
+// ParseDurability returns the durability called name
+func ParseDurability(name string) (Durability, error) {
+	for d, n := range durabilityNames {
+		if strings.EqualFold(n, name) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("wal.ParseDurability: %w", NewUnknownDurabilityError(name))
+}
@@ -0,0 +1,96 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// Change is a single modification of a table file. The data of a WAL entry is a list of changes
+//
+// OpInsert stores Record in the slot of the page that starts at PagePos
+// OpDelete empties the slot. Record is the record before it was deleted
+// OpExpire overwrites the record in the slot with Record, which only differs from it in the ID of the deleting transaction
+//
+// Pages store the LSN of the last entry applied to them, so a change is skipped when its entry is replayed again
+type Change struct {
+	Op      string
+	PagePos int64
+	Slot    int64
+	// Record is the whole record including its type and length
+	Record []byte
+}
+
+func NewInsertChange(pagePos, slot int64, record []byte) *Change {
+	return &Change{Op: OpInsert, PagePos: pagePos, Slot: slot, Record: record}
+}
+
+func NewDeleteChange(pagePos, slot int64, record []byte) *Change {
+	return &Change{Op: OpDelete, PagePos: pagePos, Slot: slot, Record: record}
+}
+
+func NewExpireChange(pagePos, slot int64, record []byte) *Change {
+	return &Change{Op: OpExpire, PagePos: pagePos, Slot: slot, Record: record}
+}
+
+// MarshalChanges encodes changes as:
+//
+//	[op string TLV][page pos int64 TLV][slot int64 TLV][100 len record]...
+func MarshalChanges(changes []*Change) ([]byte, error) {
+	buf := bytes.Buffer{}
+	for _, c := range changes {
+		for _, v := range []interface{}{c.Op, c.PagePos, c.Slot} {
+			b, err := encoding.NewTLVMarshaler(v).MarshalBinary()
+			if err != nil {
+				return nil, fmt.Errorf("MarshalChanges: %w", err)
+			}
+			buf.Write(b)
+		}
+		buf.Write(c.Record)
+	}
+	return buf.Bytes(), nil
+}
+
+func UnmarshalChanges(data []byte) ([]*Change, error) {
+	changes := make([]*Change, 0)
+	var n uint32
+	for n < uint32(len(data)) {
+		opTLV := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[string]())
+		if err := opTLV.UnmarshalBinary(data[n:]); err != nil {
+			return nil, fmt.Errorf("UnmarshalChanges: op: %w", err)
+		}
+		n += opTLV.BytesRead
+		if opTLV.Value != OpInsert && opTLV.Value != OpDelete && opTLV.Value != OpExpire {
+			return nil, fmt.Errorf("UnmarshalChanges: unsupported operation: %s", opTLV.Value)
+		}
+
+		positions := make([]int64, 0, 2)
+		for range 2 {
+			posTLV := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[int64]())
+			if err := posTLV.UnmarshalBinary(data[n:]); err != nil {
+				return nil, fmt.Errorf("UnmarshalChanges: position: %w", err)
+			}
+			n += posTLV.BytesRead
+			positions = append(positions, posTLV.Value)
+		}
+
+		if uint32(len(data)) < n+types.LenMeta || data[n] != types.TypeRecord {
+			return nil, fmt.Errorf("UnmarshalChanges: %w", NewIncompleteEntryError(int(n+types.LenMeta), len(data)))
+		}
+		end := n + types.LenMeta + binary.LittleEndian.Uint32(data[n+types.LenByte:])
+		if uint32(len(data)) < end {
+			return nil, fmt.Errorf("UnmarshalChanges: %w", NewIncompleteEntryError(int(end), len(data)))
+		}
+		changes = append(changes, &Change{
+			Op:      opTLV.Value,
+			PagePos: positions[0],
+			Slot:    positions[1],
+			Record:  data[n:end],
+		})
+		n = end
+	}
+	return changes, nil
+}
@@ -0,0 +1,62 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+)
+
+// CheckpointMarshaler encodes the LSN of the last checkpoint as:
+//
+//	21 len [lsn int64 TLV]
+type CheckpointMarshaler struct {
+	LSN int64
+}
+
+func NewCheckpointMarshaler(lsn int64) *CheckpointMarshaler {
+	return &CheckpointMarshaler{LSN: lsn}
+}
+
+func (m *CheckpointMarshaler) MarshalBinary() ([]byte, error) {
+	lsnBuf, err := encoding.NewTLVMarshaler(m.LSN).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("CheckpointMarshaler.MarshalBinary: %w", err)
+	}
+	buf := bytes.Buffer{}
+	// type
+	if err = binary.Write(&buf, binary.LittleEndian, types.TypeWALCheckpoint); err != nil {
+		return nil, fmt.Errorf("CheckpointMarshaler.MarshalBinary: type: %w", err)
+	}
+	// length
+	if err = binary.Write(&buf, binary.LittleEndian, uint32(len(lsnBuf))); err != nil {
+		return nil, fmt.Errorf("CheckpointMarshaler.MarshalBinary: len: %w", err)
+	}
+	buf.Write(lsnBuf)
+	return buf.Bytes(), nil
+}
+
+type CheckpointUnmarshaler struct {
+	LSN int64
+}
+
+func NewCheckpointUnmarshaler() *CheckpointUnmarshaler {
+	return &CheckpointUnmarshaler{}
+}
+
+func (u *CheckpointUnmarshaler) UnmarshalBinary(data []byte) error {
+	if len(data) < int(types.LenMeta) {
+		return fmt.Errorf("CheckpointUnmarshaler.UnmarshalBinary: %w", NewIncompleteEntryError(int(types.LenMeta), len(data)))
+	}
+	if data[0] != types.TypeWALCheckpoint {
+		return fmt.Errorf("CheckpointUnmarshaler.UnmarshalBinary: %w", NewCorruptEntryError(fmt.Sprintf("invalid type: %d", data[0])))
+	}
+	lsnTLV := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[int64]())
+	if err := lsnTLV.UnmarshalBinary(data[types.LenMeta:]); err != nil {
+		return fmt.Errorf("CheckpointUnmarshaler.UnmarshalBinary: %w", err)
+	}
+	u.LSN = lsnTLV.Value
+	return nil
+}
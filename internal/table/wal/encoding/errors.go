@@ -0,0 +1,28 @@
+package encoding
+
+import "fmt"
+
+type IncompleteEntryError struct {
+	expectedBytes int
+	actualBytes   int
+}
+
+func NewIncompleteEntryError(expectedBytes, actualBytes int) *IncompleteEntryError {
+	return &IncompleteEntryError{expectedBytes: expectedBytes, actualBytes: actualBytes}
+}
+
+func (e *IncompleteEntryError) Error() string {
+	return fmt.Sprintf("incomplete WAL entry: expected %d bytes, but only %d are available", e.expectedBytes, e.actualBytes)
+}
+
+type CorruptEntryError struct {
+	reason string
+}
+
+func NewCorruptEntryError(reason string) *CorruptEntryError {
+	return &CorruptEntryError{reason: reason}
+}
+
+func (e *CorruptEntryError) Error() string {
+	return fmt.Sprintf("corrupt WAL entry: %s", e.reason)
+}
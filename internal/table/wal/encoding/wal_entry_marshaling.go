@@ -2,7 +2,9 @@ package encoding
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 
 	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
 	"github.com/omesh-barhate/ByteForge/internal/platform/types"
@@ -10,18 +12,40 @@ import (
 
 const (
 	OpInsert = "insert"
+	OpDelete = "delete"
+	OpUpdate = "update"
+	// OpExpire is only used by changes. It sets the ID of the transaction that deleted a version of a record
+	OpExpire = "expire"
+	// OpBegin, OpCommit and OpRollback mark the boundaries of a transaction. They have no table and no data
+	OpBegin    = "begin"
+	OpCommit   = "commit"
+	OpRollback = "rollback"
+	// OpUndo reverts the changes of a transaction that is rolled back. Its changes are the inverse of the changes the
+	// transaction logged for the table in reverse order
+	OpUndo = "undo"
+	// OpVacuum is logged before a table file is rewritten. It has no data. Replaying it builds the indexes of the table
+	// again from the file that is in place
+	OpVacuum = "vacuum"
 )
 
+// WALMarshaler encodes an entry as:
+//
+//	20 len [lsn int64 TLV][tx id int64 TLV][op string TLV][table string TLV][data][crc32 of everything after len]
+//
+// The transaction ID is the LSN of the begin entry of the transaction the entry belongs to or 0 outside of a
+// transaction
 type WALMarshaler struct {
-	ID    string
+	LSN   int64
+	TxID  int64
 	Table string
 	Op    string
 	Data  []byte
 }
 
-func NewWALMarshaler(id, op, table string, data []byte) *WALMarshaler {
+func NewWALMarshaler(lsn, txID int64, op, table string, data []byte) *WALMarshaler {
 	return &WALMarshaler{
-		ID:    id,
+		LSN:   lsn,
+		TxID:  txID,
 		Table: table,
 		Op:    op,
 		Data:  data,
@@ -29,69 +53,93 @@ func NewWALMarshaler(id, op, table string, data []byte) *WALMarshaler {
 }
 
 func (m *WALMarshaler) MarshalBinary() ([]byte, error) {
-	buf := bytes.Buffer{}
-
-	typeMarshaler := encoding.NewValueMarshaler(types.TypeWALEntry)
-	typeBuf, err := typeMarshaler.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("WALMarshaler.Append: %w", err)
-	}
-	buf.Write(typeBuf)
-
-	length, err := m.len()
-	if err != nil {
-		return nil, fmt.Errorf("WALMarshaler.Append: %w", err)
-	}
-
-	lenMarshaler := encoding.NewValueMarshaler(length)
-	lenBuf, err := lenMarshaler.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("WALMarshaler.Append: %w", err)
+	value := bytes.Buffer{}
+	for _, v := range []interface{}{m.LSN, m.TxID, m.Op, m.Table} {
+		b, err := encoding.NewTLVMarshaler(v).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("WALMarshaler.MarshalBinary: %w", err)
+		}
+		value.Write(b)
 	}
-	buf.Write(lenBuf)
+	value.Write(m.Data)
 
-	idMarshaler := encoding.NewTLVMarshaler(m.ID)
-	idBuf, err := idMarshaler.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("WAL.Append: %w", err)
+	buf := bytes.Buffer{}
+	// type
+	if err := binary.Write(&buf, binary.LittleEndian, types.TypeWALEntry); err != nil {
+		return nil, fmt.Errorf("WALMarshaler.MarshalBinary: type: %w", err)
 	}
-	buf.Write(idBuf)
-
-	opMarshaler := encoding.NewTLVMarshaler(m.Op)
-	opBuf, err := opMarshaler.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("WAL.Append: %w", err)
+	// length
+	if err := binary.Write(&buf, binary.LittleEndian, uint32(value.Len()+types.LenInt32)); err != nil {
+		return nil, fmt.Errorf("WALMarshaler.MarshalBinary: len: %w", err)
 	}
-	buf.Write(opBuf)
-
-	tableMarshaler := encoding.NewTLVMarshaler(m.Table)
-	tableBuf, err := tableMarshaler.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("WAL.Append: %w", err)
+	checksum := crc32.ChecksumIEEE(value.Bytes())
+	buf.Write(value.Bytes())
+	if err := binary.Write(&buf, binary.LittleEndian, checksum); err != nil {
+		return nil, fmt.Errorf("WALMarshaler.MarshalBinary: checksum: %w", err)
 	}
-	buf.Write(tableBuf)
-	buf.Write(m.Data)
-
 	return buf.Bytes(), nil
 }
 
-func (m *WALMarshaler) len() (uint32, error) {
-	idMarshaler := encoding.NewTLVMarshaler(m.ID)
-	opMarshaler := encoding.NewTLVMarshaler(OpInsert)
-	tableMarshaler := encoding.NewTLVMarshaler(m.Table)
+// WALUnmarshaler decodes an entry created by WALMarshaler
+// Entries written before the transaction ID was added don't have it, their TxID is 0
+type WALUnmarshaler struct {
+	LSN   int64
+	TxID  int64
+	Table string
+	Op    string
+	Data  []byte
+	// BytesRead is the length of the whole entry including its type, length and checksum
+	BytesRead uint32
+}
+
+func NewWALUnmarshaler() *WALUnmarshaler {
+	return &WALUnmarshaler{}
+}
 
-	idLen, err := idMarshaler.TLVLength()
-	if err != nil {
-		return 0, fmt.Errorf("WALMarshaler: %w", err)
+func (u *WALUnmarshaler) UnmarshalBinary(data []byte) error {
+	if len(data) < int(types.LenMeta) {
+		return fmt.Errorf("WALUnmarshaler.UnmarshalBinary: %w", NewIncompleteEntryError(int(types.LenMeta), len(data)))
+	}
+	if data[0] != types.TypeWALEntry {
+		return fmt.Errorf("WALUnmarshaler.UnmarshalBinary: %w", NewCorruptEntryError(fmt.Sprintf("invalid type: %d", data[0])))
 	}
-	opLen, err := opMarshaler.TLVLength()
-	if err != nil {
-		return 0, fmt.Errorf("WALMarshaler: %w", err)
+	length := binary.LittleEndian.Uint32(data[types.LenByte:])
+	end := types.LenMeta + length
+	if length < types.LenInt32 || uint32(len(data)) < end {
+		return fmt.Errorf("WALUnmarshaler.UnmarshalBinary: %w", NewIncompleteEntryError(int(end), len(data)))
 	}
-	tableLen, err := tableMarshaler.TLVLength()
-	if err != nil {
-		return 0, fmt.Errorf("WALMarshaler: %w", err)
+	value := data[types.LenMeta : end-types.LenInt32]
+	if crc32.ChecksumIEEE(value) != binary.LittleEndian.Uint32(data[end-types.LenInt32:]) {
+		return fmt.Errorf("WALUnmarshaler.UnmarshalBinary: %w", NewCorruptEntryError("checksum mismatch"))
 	}
 
-	return idLen + opLen + tableLen + uint32(len(m.Data)), nil
+	lsnTLV := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[int64]())
+	if err := lsnTLV.UnmarshalBinary(value); err != nil {
+		return fmt.Errorf("WALUnmarshaler.UnmarshalBinary: lsn: %w", err)
+	}
+	n := lsnTLV.BytesRead
+	var txID int64
+	if n < uint32(len(value)) && value[n] == types.TypeInt64 {
+		txIDTLV := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[int64]())
+		if err := txIDTLV.UnmarshalBinary(value[n:]); err != nil {
+			return fmt.Errorf("WALUnmarshaler.UnmarshalBinary: tx id: %w", err)
+		}
+		txID = txIDTLV.Value
+		n += txIDTLV.BytesRead
+	}
+	strs := make([]string, 0, 2)
+	for _, field := range []string{"op", "table"} {
+		tlv := encoding.NewTLVUnmarshaler(encoding.NewValueUnmarshaler[string]())
+		if err := tlv.UnmarshalBinary(value[n:]); err != nil {
+			return fmt.Errorf("WALUnmarshaler.UnmarshalBinary: %s: %w", field, err)
+		}
+		strs = append(strs, tlv.Value)
+		n += tlv.BytesRead
+	}
+	u.LSN = lsnTLV.Value
+	u.TxID = txID
+	u.Op, u.Table = strs[0], strs[1]
+	u.Data = value[n:]
+	u.BytesRead = end
+	return nil
 }
@@ -0,0 +1,15 @@
+package wal
+
+import "fmt"
+
+type UnknownDurabilityError struct {
+	name string
+}
+
+func NewUnknownDurabilityError(name string) *UnknownDurabilityError {
+	return &UnknownDurabilityError{name: name}
+}
+
+func (e *UnknownDurabilityError) Error() string {
+	return fmt.Sprintf("unknown durability: %s. Expected off, commit, group or always", e.name)
+}
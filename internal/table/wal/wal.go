@@ -1,356 +1,522 @@
 package wal
 
 import (
-	"bytes"
-	"crypto/rand"
-	"encoding/binary"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/omesh-barhate/ByteForge/internal/platform/parser"
-	"github.com/omesh-barhate/ByteForge/internal/platform/parser/encoding"
-	platformio "github.com/omesh-barhate/ByteForge/internal/platform/parser/io"
-	"github.com/omesh-barhate/ByteForge/internal/platform/types"
 	walencoding "github.com/omesh-barhate/ByteForge/internal/table/wal/encoding"
 )
 
 const (
-	FilenameTmpl           = "%s_wal.bin"
-	LastCommitFilenameTmpl = "%s_wal_last_commit.bin"
+	// DirName is the directory inside the database that contains the segments and the checkpoint
+	DirName = "wal"
+	// SegmentFilenameTmpl is the name of a segment. It contains the LSN of its first entry
+	SegmentFilenameTmpl = "%020d.bin"
+	CheckpointFilename  = "checkpoint.bin"
+	// SegmentSize is the size after which a new segment is started
+	SegmentSize = 1 << 20
+	// CheckpointSize is the number of bytes appended since the last checkpoint after which a new one is needed
+	CheckpointSize = 4 << 20
 )
 
-type (
-	WAL struct {
-		file           *os.File
-		lastCommitFile *os.File
-	}
-	Entry struct {
-		ID  string
-		Len uint32
-	}
-	RestorableData struct {
-		LastEntry *Entry
-		Data      []byte
-	}
-)
+// WAL is the write-ahead log of a database. Every entry gets a log sequence number (LSN) that is one larger than the
+// previous one. The log is split into segments and a new segment is started when the current one reaches SegmentSize
+//
+// A checkpoint stores the LSN of the last entry whose changes are durable in the table files. The segments that
+// only contain older entries are removed, recovery only needs the entries after the checkpoint
+//
+// When entries are synced depends on the durability. The default is DurabilityCommit
+type WAL struct {
+	mu         sync.Mutex
+	dir        string
+	durability Durability
+	// segment is the segment entries are appended to. It's nil until the first entry after opening or a checkpoint
+	segment     *os.File
+	segmentSize int64
+	// lsn is the LSN of the last appended entry
+	lsn           int64
+	checkpointLSN int64
+	// sinceCheckpoint is the number of bytes appended since the last checkpoint
+	sinceCheckpoint int64
+	// syncedLSN is the LSN of the last entry that is known to be on the disk
+	syncedLSN int64
+	// syncMu is held while the current segment is synced by DurabilityGroup. Writers that wait for it find their
+	// entry already synced most of the time
+	syncMu sync.Mutex
+	// groupDelay is how long DurabilityGroup waits before it syncs, so more writers can append their entries
+	groupDelay time.Duration
+	stats      Stats
+}
 
-func NewWAL(dbPath, tableName string) (*WAL, error) {
-	path := filepath.Join(dbPath, fmt.Sprintf(FilenameTmpl, tableName))
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR, 0777)
+// Stats counts the entries appended to the WAL and the fsyncs of its segments since it was opened
+type Stats struct {
+	Entries int64
+	Syncs   int64
+}
+
+// Open opens the WAL of the database stored in dbPath and creates its directory if it doesn't exist
+// An incomplete entry at the end of the last segment is removed
+func Open(dbPath string) (*WAL, error) {
+	dir := filepath.Join(dbPath, DirName)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, fmt.Errorf("wal.Open: %w", err)
+	}
+	w := &WAL{dir: dir}
+	checkpointLSN, err := w.readCheckpoint()
 	if err != nil {
-		f, err = os.Create(path)
-		if err != nil {
-			return nil, fmt.Errorf("NewWal: %w", err)
-		}
+		return nil, fmt.Errorf("wal.Open: %w", err)
 	}
+	w.checkpointLSN = checkpointLSN
+	w.lsn = checkpointLSN
 
-	path = filepath.Join(dbPath, fmt.Sprintf(LastCommitFilenameTmpl, tableName))
-	lastIDFile, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR, 0777)
+	entries, err := w.readEntries()
 	if err != nil {
-		lastIDFile, err = os.Create(path)
-		if err != nil {
-			return nil, fmt.Errorf("NewWal: %w", err)
+		return nil, fmt.Errorf("wal.Open: %w", err)
+	}
+	for _, e := range entries {
+		w.lsn = max(w.lsn, e.LSN)
+		if e.LSN > checkpointLSN {
+			w.sinceCheckpoint += int64(e.BytesRead)
 		}
 	}
+	w.syncedLSN = w.lsn
+	return w, nil
+}
 
-	return &WAL{
-		file:           f,
-		lastCommitFile: lastIDFile,
-	}, nil
+// SetDurability changes when entries are synced from the next appended entry
+func (w *WAL) SetDurability(d Durability) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.durability = d
 }
 
-func (w *WAL) Append(op, table string, data []byte) (*Entry, error) {
-	id, err := generateID()
-	if err != nil {
-		return nil, fmt.Errorf("WAL.Append: %w", err)
-	}
+// SetGroupCommitDelay sets how long DurabilityGroup waits before it syncs the entries that are waiting for it. The
+// entries other writers append in the meantime are synced by the same fsync. The default is 0
+func (w *WAL) SetGroupCommitDelay(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.groupDelay = d
+}
 
-	if _, err := w.file.Seek(0, io.SeekEnd); err != nil {
-		return nil, fmt.Errorf("WAL.Append: %w", err)
-	}
+func (w *WAL) Durability() Durability {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.durability
+}
 
-	marshaler := walencoding.NewWALMarshaler(id, op, table, data)
-	buf, err := marshaler.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("WAL.Append: %w", err)
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.segment == nil {
+		return nil
 	}
-
-	if err = w.write(buf); err != nil {
-		return nil, fmt.Errorf("WAL.Append: %w", err)
+	if err := w.segment.Close(); err != nil {
+		return fmt.Errorf("WAL.Close: %w", err)
 	}
-
-	return newEntry(id, buf), nil
+	w.segment = nil
+	return nil
 }
 
-func (w *WAL) Commit(entry *Entry) error {
-	marshaler := walencoding.NewLastCommitMarshaler(entry.ID, entry.Len)
-	data, err := marshaler.MarshalBinary()
+// Append writes a new entry of the transaction with txID and returns its LSN. txID is 0 outside of a transaction
+// Depending on the durability the entry is synced before Append returns
+func (w *WAL) Append(txID int64, op, table string, data []byte) (int64, error) {
+	lsn, durable, err := w.append(txID, op, table, data)
 	if err != nil {
-		return fmt.Errorf("WAL.Commit: %w", err)
+		return 0, fmt.Errorf("WAL.Append: %w", err)
 	}
-
-	if err := os.WriteFile(w.lastCommitFile.Name(), data, 0644); err != nil {
-		return fmt.Errorf("WAL.Commit: %w", err)
+	if !durable {
+		if err = w.SyncUpTo(lsn); err != nil {
+			return 0, fmt.Errorf("WAL.Append: %w", err)
+		}
 	}
-	return nil
+	return lsn, nil
 }
 
-func (w *WAL) GetRestorableData() (*RestorableData, error) {
-	if _, err := w.lastCommitFile.Seek(0, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("WAL.GetRestorableData: seek: %w", err)
+// AppendNoWait is like Append, but it doesn't wait for the sync of DurabilityGroup. It reports false if the entry
+// still has to be synced by SyncUpTo before it's durable
+// Callers release their locks before they call SyncUpTo, so the entries other writers append in the meantime are
+// synced by the same fsync
+func (w *WAL) AppendNoWait(txID int64, op, table string, data []byte) (int64, bool, error) {
+	lsn, durable, err := w.append(txID, op, table, data)
+	if err != nil {
+		return 0, false, fmt.Errorf("WAL.AppendNoWait: %w", err)
 	}
+	return lsn, durable, nil
+}
 
-	data := make([]byte, 1024)
-	n, err := w.lastCommitFile.Read(data)
+// append writes the entry and syncs it unless the durability is DurabilityGroup
+// durable is false if the entry still has to be synced by SyncUpTo
+func (w *WAL) append(txID int64, op, table string, data []byte) (lsn int64, durable bool, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lsn = w.lsn + 1
+	buf, err := walencoding.NewWALMarshaler(lsn, txID, op, table, data).MarshalBinary()
 	if err != nil {
-		return nil, fmt.Errorf("WAL.GetRestorableData: read: %w", err)
+		return 0, false, fmt.Errorf("WAL.append: %w", err)
 	}
-
-	data = data[:n]
-	unmarshaler := walencoding.NewLastCommitUnmarshaler()
-	if err = unmarshaler.UnmarshalBinary(data); err != nil {
-		return nil, fmt.Errorf("WAL.GetRestorableData: unmarshal: %w", err)
+	if w.segment == nil || w.segmentSize+int64(len(buf)) > SegmentSize {
+		if err = w.startSegment(lsn); err != nil {
+			return 0, false, fmt.Errorf("WAL.append: %w", err)
+		}
 	}
-	lastCommittedID := unmarshaler.ID
 
-	lastEntry, err := w.readLastEntry(unmarshaler.Len)
+	n, err := w.segment.Write(buf)
 	if err != nil {
-		return nil, fmt.Errorf("WAL.GetRestorableData: %w", err)
+		return 0, false, fmt.Errorf("WAL.append: %w", err)
 	}
-
-	if lastEntry.ID == lastCommittedID {
-		return nil, nil
+	if n != len(buf) {
+		return 0, false, fmt.Errorf("WAL.append: incomplete write. %d bytes written instead of %d", n, len(buf))
 	}
+	w.lsn = lsn
+	w.segmentSize += int64(n)
+	w.sinceCheckpoint += int64(n)
+	w.stats.Entries++
 
-	buf, err := w.getRestorableData(lastCommittedID)
-	if err != nil {
-		return nil, fmt.Errorf("WAL.GetRestorableData: %w", err)
+	if !w.needsSync(txID, op) {
+		return lsn, true, nil
 	}
+	if w.durability == DurabilityGroup {
+		return lsn, false, nil
+	}
+	if err = w.segment.Sync(); err != nil {
+		return 0, false, fmt.Errorf("WAL.append: %w", err)
+	}
+	w.stats.Syncs++
+	w.syncedLSN = lsn
+	return lsn, true, nil
+}
 
-	return newRestorableData(lastEntry, buf), nil
+// needsSync reports whether an entry with op of the transaction with txID has to be synced before Append returns
+// A change inside a transaction only becomes durable with the commit entry. Recovery undoes it without one
+func (w *WAL) needsSync(txID int64, op string) bool {
+	// The indexes don't match a rewritten table file until the entry is replayed, so it's synced in every mode
+	if op == walencoding.OpVacuum {
+		return true
+	}
+	switch w.durability {
+	case DurabilityOff:
+		return false
+	case DurabilityAlways:
+		return true
+	}
+	switch op {
+	case walencoding.OpCommit:
+		return true
+	case walencoding.OpBegin, walencoding.OpRollback:
+		return false
+	}
+	return txID == 0
 }
 
-func (w *WAL) getRestorableData(commitID string) ([]byte, error) {
-	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
+// SyncUpTo syncs the current segment unless the entry with lsn has already been synced or the durability is
+// DurabilityOff
+// Only one writer syncs at a time. Everything appended before the fsync started is synced by it, so the writers
+// waiting for syncMu usually return without another fsync
+func (w *WAL) SyncUpTo(lsn int64) error {
+	if w.synced(lsn) {
+		return nil
 	}
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
 
-	r, err := platformio.NewReader(w.file)
-	if err != nil {
-		return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
+	w.mu.Lock()
+	if w.syncedLSN >= lsn || w.durability == DurabilityOff {
+		w.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(0)
+	if w.durability == DurabilityGroup {
+		delay = w.groupDelay
+	}
+	w.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
 	}
 
-	commitIDFound := false
-	buf := bytes.Buffer{}
-	for {
-		t, err := r.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				return buf.Bytes(), nil
-			}
-			return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
-		}
-		if t != types.TypeWALEntry {
-			return nil, fmt.Errorf("WAL.getRestorableData: invalid type")
-		}
+	w.mu.Lock()
+	// A checkpoint or a new segment may have synced the entry in the meantime
+	if w.syncedLSN >= lsn {
+		w.mu.Unlock()
+		return nil
+	}
+	f, last := w.segment, w.lsn
+	w.mu.Unlock()
 
-		length, err := r.ReadUint32()
-		if err != nil {
-			return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
-		}
+	err := f.Sync()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// The segment is synced before it's closed by startSegment
+	if errors.Is(err, os.ErrClosed) && w.syncedLSN >= lsn {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("WAL.SyncUpTo: %w", err)
+	}
+	w.stats.Syncs++
+	w.syncedLSN = max(w.syncedLSN, last)
+	return nil
+}
 
-		tlvParser := parser.NewTLVParser(r)
-		val, err := tlvParser.Parse()
-		id := val.(string)
+func (w *WAL) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
 
-		if err != nil {
-			return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
-		}
+// synced reports whether the entry with lsn doesn't need to be synced, so SyncUpTo doesn't wait for a running sync
+func (w *WAL) synced(lsn int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.syncedLSN >= lsn || w.durability == DurabilityOff
+}
 
-		if id == commitID {
-			commitIDFound = true
-			if err = w.skipEntry(id, length); err != nil {
-				return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
-			}
-			continue
-		}
+// LSN returns the LSN of the last appended entry
+func (w *WAL) LSN() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lsn
+}
 
-		// We are before the commit ID so entry can be skipped entirely
-		if !commitIDFound {
-			if err = w.skipEntry(id, length); err != nil {
-				return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
-			}
-			continue
-		}
+// NeedsCheckpoint reports whether more than CheckpointSize bytes were appended since the last checkpoint
+func (w *WAL) NeedsCheckpoint() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sinceCheckpoint >= CheckpointSize
+}
 
-		// We are after the commit so this entry needs to be restored
+// Entries returns the entries appended after the last checkpoint in the order of their LSNs
+func (w *WAL) Entries() ([]*walencoding.WALUnmarshaler, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entries, err := w.readEntries()
+	if err != nil {
+		return nil, fmt.Errorf("WAL.Entries: %w", err)
+	}
+	i := slices.IndexFunc(entries, func(e *walencoding.WALUnmarshaler) bool {
+		return e.LSN > w.checkpointLSN
+	})
+	if i == -1 {
+		return nil, nil
+	}
+	return entries[i:], nil
+}
 
-		// op
-		val, err = tlvParser.Parse()
-		op := val.(string)
-		if op != walencoding.OpInsert {
-			return nil, fmt.Errorf("WAL.getRestorableData: unspoorted operation: %s", op)
-		}
+// Checkpoint records that the changes of every entry up to lsn are durable and removes the segments that only
+// contain such entries. The caller has to sync the table files before
+func (w *WAL) Checkpoint(lsn int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-		// table
-		val, err = tlvParser.Parse()
+	b, err := walencoding.NewCheckpointMarshaler(lsn).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("WAL.Checkpoint: %w", err)
+	}
+	// The checkpoint is written into a new file and renamed, so it's either the old or the new one after a crash
+	tmp := filepath.Join(w.dir, CheckpointFilename+".tmp")
+	if err = writeFile(tmp, b); err != nil {
+		return fmt.Errorf("WAL.Checkpoint: %w", err)
+	}
+	if err = os.Rename(tmp, filepath.Join(w.dir, CheckpointFilename)); err != nil {
+		return fmt.Errorf("WAL.Checkpoint: %w", err)
+	}
+	if err = syncDir(w.dir); err != nil {
+		return fmt.Errorf("WAL.Checkpoint: %w", err)
+	}
+	w.checkpointLSN = lsn
 
-		// data
-		t, err = r.ReadByte()
-		if err != nil {
-			return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
-		}
-		if t != types.TypeRecord {
-			return nil, fmt.Errorf("WAL.getRestorableData: invalid type: %d, %d was expected", t, types.TypeRecord)
+	// Every entry is covered, so the next one starts a new segment. The entries don't need to be synced anymore
+	if lsn >= w.lsn {
+		w.syncedLSN = w.lsn
+		if w.segment != nil {
+			if err = w.segment.Close(); err != nil {
+				return fmt.Errorf("WAL.Checkpoint: %w", err)
+			}
+			w.segment = nil
 		}
+		w.sinceCheckpoint = 0
+	}
 
-		length, err = r.ReadUint32()
-		if err != nil {
-			return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
+	segments, err := w.segments()
+	if err != nil {
+		return fmt.Errorf("WAL.Checkpoint: %w", err)
+	}
+	for i, first := range segments {
+		// A segment ends where the next one starts. The last one is only covered if every entry is
+		last := w.lsn
+		if i < len(segments)-1 {
+			last = segments[i+1] - 1
 		}
-
-		buf.WriteByte(t)
-		if err = binary.Write(&buf, binary.LittleEndian, length); err != nil {
-			return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
+		if last > lsn || (w.segment != nil && i == len(segments)-1) {
+			continue
 		}
-
-		record := make([]byte, length)
-		if _, err = r.Read(record); err != nil {
-			return nil, fmt.Errorf("WAL.getRestorableData: %w", err)
+		if err = os.Remove(filepath.Join(w.dir, fmt.Sprintf(SegmentFilenameTmpl, first))); err != nil {
+			return fmt.Errorf("WAL.Checkpoint: %w", err)
 		}
-		buf.Write(record)
 	}
+	return nil
 }
 
-func (w *WAL) skipEntry(id string, length uint32) error {
-	// Seek back to the beginning of the ID
-	if _, err := w.file.Seek(-1*(int64(len(id)+types.LenByte+types.LenInt32)), io.SeekCurrent); err != nil {
-		return fmt.Errorf("WAL.skipEntry: %w", err)
-	}
-	// Skip current entry
-	if _, err := w.file.Seek(int64(length), io.SeekCurrent); err != nil {
-		return fmt.Errorf("WAL.skipEntry: %w", err)
-	}
-	return nil
+// CheckpointLSN returns the LSN stored by the last checkpoint
+func (w *WAL) CheckpointLSN() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.checkpointLSN
 }
 
-func (w *WAL) readLastEntry(length uint32) (*Entry, error) {
-	if _, err := w.file.Seek(-1*int64(length), io.SeekEnd); err != nil {
-		return nil, fmt.Errorf("WAL.readLastEntry: %w", err)
+// startSegment syncs and closes the current segment unless the durability is DurabilityOff and creates a new one
+func (w *WAL) startSegment(firstLSN int64) error {
+	if w.segment != nil {
+		if w.durability != DurabilityOff {
+			if err := w.segment.Sync(); err != nil {
+				return fmt.Errorf("WAL.startSegment: %w", err)
+			}
+			w.stats.Syncs++
+			w.syncedLSN = w.lsn
+		}
+		if err := w.segment.Close(); err != nil {
+			return fmt.Errorf("WAL.startSegment: %w", err)
+		}
 	}
-
-	buf := make([]byte, length)
-	n, err := w.file.Read(buf)
+	path := filepath.Join(w.dir, fmt.Sprintf(SegmentFilenameTmpl, firstLSN))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
-		return nil, fmt.Errorf("WAL.readLastEntry: %w", err)
+		return fmt.Errorf("WAL.startSegment: %w", err)
 	}
-	if n != int(length) {
-		return nil, fmt.Errorf("WAL.readLastEntry: incomplete read")
+	if err = syncDir(w.dir); err != nil {
+		return fmt.Errorf("WAL.startSegment: %w", err)
 	}
+	w.segment = f
+	w.segmentSize = 0
+	return nil
+}
 
-	byteUnmarshaler := encoding.NewValueUnmarshaler[byte]()
-	intUnmarshaler := encoding.NewValueUnmarshaler[uint32]()
-	bytesRead := 0
-
-	// type
-	if err = byteUnmarshaler.UnmarshalBinary(buf); err != nil {
-		return nil, fmt.Errorf("WAL.readLastEntry: type: %w", err)
+// segments returns the first LSN of every segment in ascending order
+func (w *WAL) segments() ([]int64, error) {
+	dirEntries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("WAL.segments: %w", err)
 	}
-	bytesRead += types.LenByte
-
-	// len
-	if err = intUnmarshaler.UnmarshalBinary(buf[bytesRead:]); err != nil {
-		return nil, fmt.Errorf("WAL.readLastEntry: len: %w", err)
+	segments := make([]int64, 0)
+	for _, e := range dirEntries {
+		name, ok := strings.CutSuffix(e.Name(), filepath.Ext(SegmentFilenameTmpl))
+		if !ok {
+			continue
+		}
+		lsn, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, lsn)
 	}
-	bytesRead += types.LenInt32
-
-	strUnmarshaler := encoding.NewValueUnmarshaler[string]()
-	tlvUnmarshaler := encoding.NewTLVUnmarshaler(strUnmarshaler)
+	slices.Sort(segments)
+	return segments, nil
+}
 
-	// ID
-	if err = tlvUnmarshaler.UnmarshalBinary(buf[bytesRead:]); err != nil {
-		return nil, fmt.Errorf("WAL.readLastEntry: val: %w", err)
+// readEntries reads the entries of every segment
+// An incomplete entry at the end of the last segment was being appended when the process stopped. Its changes were
+// never applied, so it's cut off the file. Otherwise the next entries would be appended after it
+func (w *WAL) readEntries() ([]*walencoding.WALUnmarshaler, error) {
+	segments, err := w.segments()
+	if err != nil {
+		return nil, fmt.Errorf("WAL.readEntries: %w", err)
 	}
-	bytesRead += len(tlvUnmarshaler.Value)
-	id := tlvUnmarshaler.Value
-
-	return &Entry{
-		ID:  id,
-		Len: length,
-	}, nil
+	entries := make([]*walencoding.WALUnmarshaler, 0)
+	for i, first := range segments {
+		path := filepath.Join(w.dir, fmt.Sprintf(SegmentFilenameTmpl, first))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("WAL.readEntries: %w", err)
+		}
+		var n uint32
+		for n < uint32(len(data)) {
+			entry := walencoding.NewWALUnmarshaler()
+			if err = entry.UnmarshalBinary(data[n:]); err != nil {
+				if i != len(segments)-1 {
+					return nil, fmt.Errorf("WAL.readEntries: %s: %w", path, err)
+				}
+				log.Printf("WAL.readEntries: truncating incomplete entry at %d: %v\n", n, err)
+				if err = os.Truncate(path, int64(n)); err != nil {
+					return nil, fmt.Errorf("WAL.readEntries: %w", err)
+				}
+				break
+			}
+			entries = append(entries, entry)
+			n += entry.BytesRead
+		}
+	}
+	return entries, nil
 }
 
-func (w *WAL) write(buf []byte) error {
-	n, err := w.file.Write(buf)
+func (w *WAL) readCheckpoint() (int64, error) {
+	data, err := os.ReadFile(filepath.Join(w.dir, CheckpointFilename))
 	if err != nil {
-		return fmt.Errorf("WAL.write: %w", err)
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("WAL.readCheckpoint: %w", err)
 	}
-	if n != len(buf) {
-		return fmt.Errorf("WAL.write: incomplete write. %d bytes written instead of %d", n, len(buf))
+	unmarshaler := walencoding.NewCheckpointUnmarshaler()
+	if err = unmarshaler.UnmarshalBinary(data); err != nil {
+		return 0, fmt.Errorf("WAL.readCheckpoint: %w", err)
 	}
-	return nil
+	return unmarshaler.LSN, nil
 }
 
-// ReadRaw returns the raw byte array stored in the WAL file. It's for debugging
+// ReadRaw returns the raw byte array stored in the current segment. It's for debugging
 func (w *WAL) ReadRaw() ([]byte, error) {
-	_, err := w.file.Seek(0, 0)
-	if err != nil {
-		return nil, fmt.Errorf("WAL.ReadRaw: %w", err)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.segment == nil {
+		return []byte{}, nil
 	}
-
-	stat, err := w.file.Stat()
-	if err != nil {
+	if _, err := w.segment.Seek(0, io.SeekStart); err != nil {
 		return nil, fmt.Errorf("WAL.ReadRaw: %w", err)
 	}
-
-	buf := make([]byte, stat.Size())
-	if _, err = w.file.Read(buf); err != nil {
+	buf, err := io.ReadAll(w.segment)
+	if err != nil {
 		return nil, fmt.Errorf("WAL.ReadRaw: %w", err)
 	}
 	return buf, nil
 }
 
-// ReadLastCommitRaw returns the raw byte array stored in the last commit file. It's for debugging
-func (w *WAL) ReadLastCommitRaw() ([]byte, error) {
-	_, err := w.lastCommitFile.Seek(0, 0)
+// writeFile creates the file at path with content and syncs it
+func writeFile(path string, content []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
 	if err != nil {
-		return nil, fmt.Errorf("WAL.ReadRaw: %w", err)
+		return fmt.Errorf("wal.writeFile: %w", err)
 	}
-
-	stat, err := w.lastCommitFile.Stat()
+	defer f.Close()
+	n, err := f.Write(content)
 	if err != nil {
-		return nil, fmt.Errorf("WAL.ReadRaw: %w", err)
+		return fmt.Errorf("wal.writeFile: %w", err)
 	}
-
-	buf := make([]byte, stat.Size())
-	if _, err = w.lastCommitFile.Read(buf); err != nil {
-		return nil, fmt.Errorf("WAL.ReadRaw: %w", err)
+	if n != len(content) {
+		return fmt.Errorf("wal.writeFile: incomplete write. %d bytes written instead of %d", n, len(content))
 	}
-	return buf, nil
-}
-
-func newEntry(id string, data []byte) *Entry {
-	return &Entry{
-		ID:  id,
-		Len: uint32(len(data)),
-	}
-}
-
-func newRestorableData(entry *Entry, data []byte) *RestorableData {
-	return &RestorableData{
-		LastEntry: entry,
-		Data:      data,
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("wal.writeFile: %w", err)
 	}
+	return nil
 }
 
-func generateID() (string, error) {
-	b := make([]byte, 16)
-	_, err := rand.Read(b)
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
 	if err != nil {
-		return "", fmt.Errorf("wal.generateID: %w", err)
+		return fmt.Errorf("wal.syncDir: %w", err)
 	}
-	return hex.EncodeToString(b), nil
+	defer d.Close()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("wal.syncDir: %w", err)
+	}
+	return nil
 }
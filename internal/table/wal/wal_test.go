@@ -0,0 +1,203 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	walencoding "github.com/omesh-barhate/ByteForge/internal/table/wal/encoding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWAL_AppendAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir)
+	assert.Nil(t, err)
+	for i := range 3 {
+		lsn, err := w.Append(int64(i), walencoding.OpInsert, "users", []byte{byte(i)})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(i+1), lsn)
+	}
+	assert.Nil(t, w.Close())
+
+	w, err = Open(dir)
+	assert.Nil(t, err)
+	defer w.Close()
+	assert.Equal(t, int64(3), w.LSN())
+	entries, err := w.Entries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "users", entries[2].Table)
+	assert.Equal(t, []byte{2}, entries[2].Data)
+	assert.Equal(t, int64(2), entries[2].TxID)
+
+	// LSNs continue after the last entry
+	lsn, err := w.Append(0, walencoding.OpDelete, "users", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), lsn)
+}
+
+func TestWAL_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir)
+	assert.Nil(t, err)
+	for range 2 {
+		_, err = w.Append(0, walencoding.OpInsert, "users", []byte{1})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Checkpoint(w.LSN()))
+	entries, err := w.Entries()
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+	assert.NoFileExists(t, filepath.Join(dir, DirName, fmt.Sprintf(SegmentFilenameTmpl, 1)))
+
+	lsn, err := w.Append(0, walencoding.OpInsert, "users", []byte{2})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), lsn)
+	assert.FileExists(t, filepath.Join(dir, DirName, fmt.Sprintf(SegmentFilenameTmpl, 3)))
+	assert.Nil(t, w.Close())
+
+	// The LSN of the checkpoint is kept even though its segment was removed
+	w, err = Open(dir)
+	assert.Nil(t, err)
+	defer w.Close()
+	assert.Equal(t, int64(2), w.CheckpointLSN())
+	entries, err = w.Entries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, int64(3), entries[0].LSN)
+}
+
+func TestOpen_TruncatesIncompleteEntry(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir)
+	assert.Nil(t, err)
+	_, err = w.Append(0, walencoding.OpInsert, "users", []byte{1})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	// The process stops while it's appending a new entry
+	entry, err := walencoding.NewWALMarshaler(2, 0, walencoding.OpInsert, "users", []byte{2}).MarshalBinary()
+	assert.Nil(t, err)
+	path := filepath.Join(dir, DirName, fmt.Sprintf(SegmentFilenameTmpl, 1))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0666)
+	assert.Nil(t, err)
+	_, err = f.Write(entry[:len(entry)-2])
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	w, err = Open(dir)
+	assert.Nil(t, err)
+	defer w.Close()
+	entries, err := w.Entries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	lsn, err := w.Append(0, walencoding.OpInsert, "users", []byte{3})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), lsn)
+	entries, err = w.Entries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestWAL_Durability(t *testing.T) {
+	ops := []string{walencoding.OpInsert, walencoding.OpBegin, walencoding.OpInsert, walencoding.OpCommit}
+	// The entries after the begin entry belong to its transaction
+	txIDs := []int64{0, 0, 2, 2}
+	tests := []struct {
+		durability Durability
+		// synced contains the synced LSN after every op
+		synced []int64
+		syncs  int64
+	}{
+		{durability: DurabilityOff, synced: []int64{0, 0, 0, 0}, syncs: 0},
+		{durability: DurabilityCommit, synced: []int64{1, 1, 1, 4}, syncs: 2},
+		{durability: DurabilityGroup, synced: []int64{1, 1, 1, 4}, syncs: 2},
+		{durability: DurabilityAlways, synced: []int64{1, 2, 3, 4}, syncs: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.durability.String(), func(t *testing.T) {
+			w, err := Open(t.TempDir())
+			assert.Nil(t, err)
+			defer w.Close()
+			w.SetDurability(tt.durability)
+			for i, op := range ops {
+				_, err = w.Append(txIDs[i], op, "users", nil)
+				assert.Nil(t, err)
+				assert.Equal(t, tt.synced[i], w.syncedLSN, op)
+			}
+			assert.Equal(t, Stats{Entries: 4, Syncs: tt.syncs}, w.Stats())
+		})
+	}
+}
+
+func TestWAL_GroupCommit(t *testing.T) {
+	w, err := Open(t.TempDir())
+	assert.Nil(t, err)
+	defer w.Close()
+	w.SetDurability(DurabilityGroup)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lsn, err := w.Append(0, walencoding.OpInsert, "users", []byte{1})
+			assert.Nil(t, err)
+			// Every writer returns after its own entry was synced, possibly by another writer
+			w.mu.Lock()
+			assert.GreaterOrEqual(t, w.syncedLSN, lsn)
+			w.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(20), w.LSN())
+
+	// AppendNoWait leaves the sync to the caller
+	lsn, durable, err := w.AppendNoWait(0, walencoding.OpInsert, "users", []byte{1})
+	assert.Nil(t, err)
+	assert.False(t, durable)
+	assert.Less(t, w.syncedLSN, lsn)
+	assert.Nil(t, w.SyncUpTo(lsn))
+	assert.Equal(t, lsn, w.syncedLSN)
+}
+
+func TestWAL_GroupCommitDelay(t *testing.T) {
+	w, err := Open(t.TempDir())
+	assert.Nil(t, err)
+	defer w.Close()
+	w.SetDurability(DurabilityGroup)
+	w.SetGroupCommitDelay(10 * time.Millisecond)
+
+	// The writers append while the first one waits, so they share its fsync
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := w.Append(0, walencoding.OpInsert, "users", []byte{1})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Less(t, w.Stats().Syncs, int64(20))
+	assert.Equal(t, w.LSN(), w.syncedLSN)
+}
+
+func TestParseDurability(t *testing.T) {
+	for _, d := range []Durability{DurabilityOff, DurabilityCommit, DurabilityGroup, DurabilityAlways} {
+		parsed, err := ParseDurability(d.String())
+		assert.Nil(t, err)
+		assert.Equal(t, d, parsed)
+	}
+	d, err := ParseDurability("ALWAYS")
+	assert.Nil(t, err)
+	assert.Equal(t, DurabilityAlways, d)
+
+	_, err = ParseDurability("sometimes")
+	var unknownErr *UnknownDurabilityError
+	assert.ErrorAs(t, err, &unknownErr)
+}
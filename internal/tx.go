@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/index"
+	"github.com/omesh-barhate/ByteForge/internal/table/predicate"
+	walencoding "github.com/omesh-barhate/ByteForge/internal/table/wal/encoding"
+)
+
+// Tx is a transaction that can modify several tables. Only one transaction runs at a time, Begin waits until the
+// running one is committed or rolled back
+//
+// The changes are logged in the WAL between a begin and a commit or rollback entry and applied to the pages in the
+// buffer pool right away. The commit entry is the commit point. Rollback reverts the changes with the records the WAL
+// entries contain and logs the reverting changes too. Recovery replays every entry and then reverts the transactions
+// that were neither committed nor rolled back. Values generated by sequences are not given back by a rollback
+//
+// The LSN of the begin entry is the ID of the transaction. It's stored in the versions of the records it writes and
+// deletes, so reads outside of the transaction don't see its changes until it's committed
+type Tx struct {
+	db *Database
+	// tables contains the tables modified by the transaction keyed by name
+	tables map[string]*table.Table
+	// logged is true after the begin entry was appended to the WAL
+	logged bool
+	// id is the LSN of the begin entry
+	id   int64
+	done bool
+}
+
+// Begin starts a transaction. It blocks while another transaction is running
+func (db *Database) Begin() (*Tx, error) {
+	db.txMu.Lock()
+	tx := &Tx{
+		db:     db,
+		tables: make(map[string]*table.Table),
+	}
+	db.tx = tx
+	return tx, nil
+}
+
+// Insert inserts record into a table and returns its primary key
+func (tx *Tx) Insert(tableName string, record map[string]interface{}) (index.Key, error) {
+	t, err := tx.modify(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("Tx.Insert: %w", err)
+	}
+	key, err := t.InsertTx(tx.id, record)
+	if err != nil {
+		return nil, fmt.Errorf("Tx.Insert: %w", err)
+	}
+	return key, nil
+}
+
+// UpdateWhere sets values in every record of a table that satisfies pred
+func (tx *Tx) UpdateWhere(tableName string, pred predicate.Predicate, values map[string]interface{}) (*table.UpdateResult, error) {
+	t, err := tx.modify(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("Tx.UpdateWhere: %w", err)
+	}
+	res, err := t.UpdateWhereTx(tx.id, pred, values)
+	if err != nil {
+		return nil, fmt.Errorf("Tx.UpdateWhere: %w", err)
+	}
+	return res, nil
+}
+
+// DeleteWhere deletes every record of a table that satisfies pred
+func (tx *Tx) DeleteWhere(tableName string, pred predicate.Predicate) (int, error) {
+	t, err := tx.modify(tableName)
+	if err != nil {
+		return 0, fmt.Errorf("Tx.DeleteWhere: %w", err)
+	}
+	n, err := t.DeleteWhereTx(tx.id, pred)
+	if err != nil {
+		return 0, fmt.Errorf("Tx.DeleteWhere: %w", err)
+	}
+	return n, nil
+}
+
+// SelectWhere returns the records of a table that satisfy pred including the changes made by the transaction
+func (tx *Tx) SelectWhere(tableName string, pred predicate.Predicate) (*table.SelectResult, error) {
+	t, err := tx.table(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("Tx.SelectWhere: %w", err)
+	}
+	result, err := t.SelectWhere(pred)
+	if err != nil {
+		return nil, fmt.Errorf("Tx.SelectWhere: %w", err)
+	}
+	return result, nil
+}
+
+// Commit makes the changes of the transaction durable
+// If the commit entry cannot be appended to the WAL the transaction is rolled back
+//
+// With wal.DurabilityGroup the next transaction can start while the commit entry is synced, so one fsync covers the
+// commit entries of several transactions. Commit returns after its own entry is durable
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return fmt.Errorf("Tx.Commit: %w", NewTxDoneError())
+	}
+	syncLSN, err := tx.log(walencoding.OpCommit)
+	if err != nil {
+		if rbErr := tx.rollback(); rbErr != nil {
+			err = fmt.Errorf("%w, rollback: %w", err, rbErr)
+		}
+		tx.finish()
+		return fmt.Errorf("Tx.Commit: %w", err)
+	}
+	tx.finish()
+	if err = tx.db.wal.SyncUpTo(syncLSN); err != nil {
+		return fmt.Errorf("Tx.Commit: %w", err)
+	}
+	// The checkpoint is taken after the transaction is finished, so the versions it replaced can be reclaimed
+	if tx.db.wal.NeedsCheckpoint() {
+		if err = tx.db.Checkpoint(); err != nil {
+			return fmt.Errorf("Tx.Commit: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rollback undoes every change of the transaction
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return fmt.Errorf("Tx.Rollback: %w", NewTxDoneError())
+	}
+	err := tx.rollback()
+	tx.finish()
+	if err != nil {
+		return fmt.Errorf("Tx.Rollback: %w", err)
+	}
+	return nil
+}
+
+// rollback reverts the changes the transaction logged in the WAL and appends the rollback entry
+func (tx *Tx) rollback() error {
+	if !tx.logged {
+		return nil
+	}
+	entries, err := tx.db.wal.Entries()
+	if err != nil {
+		return fmt.Errorf("Tx.rollback: %w", err)
+	}
+	byTable := txEntries(entries)[tx.id]
+	for name, t := range tx.tables {
+		if err = t.Undo(tx.id, byTable[name]); err != nil {
+			return fmt.Errorf("Tx.rollback: %w", err)
+		}
+	}
+	if _, err = tx.log(walencoding.OpRollback); err != nil {
+		return fmt.Errorf("Tx.rollback: %w", err)
+	}
+	return nil
+}
+
+// txEntries returns the entries that transactions logged for tables keyed by the ID of the transaction and the name of
+// the table
+func txEntries(entries []*walencoding.WALUnmarshaler) map[int64]map[string][]*walencoding.WALUnmarshaler {
+	byTx := make(map[int64]map[string][]*walencoding.WALUnmarshaler)
+	for _, e := range entries {
+		if e.TxID == 0 || e.Table == "" {
+			continue
+		}
+		if _, ok := byTx[e.TxID]; !ok {
+			byTx[e.TxID] = make(map[string][]*walencoding.WALUnmarshaler)
+		}
+		byTx[e.TxID][e.Table] = append(byTx[e.TxID][e.Table], e)
+	}
+	return byTx
+}
+
+// log appends a commit or rollback entry to the WAL if the transaction has logged its begin entry
+// It returns the LSN the WAL still has to be synced up to before the entry is durable or 0 if it already is
+func (tx *Tx) log(op string) (int64, error) {
+	if !tx.logged {
+		return 0, nil
+	}
+	lsn, durable, err := tx.db.wal.AppendNoWait(tx.id, op, "", nil)
+	if err != nil {
+		return 0, fmt.Errorf("Tx.log: %w", err)
+	}
+	if durable {
+		return 0, nil
+	}
+	return lsn, nil
+}
+
+func (tx *Tx) finish() {
+	// The changes become visible to new snapshots
+	tx.db.snapMu.Lock()
+	tx.db.activeTxID = 0
+	tx.db.snapMu.Unlock()
+	tx.done = true
+	tx.tables = nil
+	tx.db.tx = nil
+	tx.db.txMu.Unlock()
+}
+
+func (tx *Tx) table(name string) (*table.Table, error) {
+	if tx.done {
+		return nil, fmt.Errorf("Tx.table: %w", NewTxDoneError())
+	}
+	t, ok := tx.db.Tables[name]
+	if !ok {
+		return nil, fmt.Errorf("Tx.table: %w", NewTableDoesNotExistError(name))
+	}
+	return t, nil
+}
+
+// modify returns the table called name and records that the transaction modifies it
+func (tx *Tx) modify(name string) (*table.Table, error) {
+	t, err := tx.table(name)
+	if err != nil {
+		return nil, fmt.Errorf("Tx.modify: %w", err)
+	}
+	if _, ok := tx.tables[name]; ok {
+		return t, nil
+	}
+	if !tx.logged {
+		if err = tx.begin(); err != nil {
+			return nil, fmt.Errorf("Tx.modify: %w", err)
+		}
+	}
+	tx.tables[name] = t
+	return t, nil
+}
+
+// begin appends the begin entry to the WAL and makes the transaction the active one of the database
+// Snapshots taken afterwards know that its changes are not committed yet
+func (tx *Tx) begin() error {
+	tx.db.snapMu.Lock()
+	defer tx.db.snapMu.Unlock()
+	lsn, err := tx.db.wal.Append(0, walencoding.OpBegin, "", nil)
+	if err != nil {
+		return fmt.Errorf("Tx.begin: %w", err)
+	}
+	tx.id = lsn
+	tx.logged = true
+	tx.db.activeTxID = lsn
+	return nil
+}
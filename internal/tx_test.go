@@ -0,0 +1,422 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omesh-barhate/ByteForge/internal/platform/types"
+	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/column"
+	"github.com/omesh-barhate/ByteForge/internal/table/predicate"
+	"github.com/omesh-barhate/ByteForge/internal/table/wal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTx_CommitAndRollback(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	_, err = tx.Insert("orders", map[string]interface{}{"id": int64(10), "user_id": int64(1)})
+	assert.Nil(t, err)
+	// The transaction sees its own changes
+	assertRowCount(t, tx, "users", 1)
+	assert.Nil(t, tx.Rollback())
+
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	assertRowCount(t, tx, "users", 0)
+	assertRowCount(t, tx, "orders", 0)
+	insertUser(t, tx, 1, "user1")
+	insertUser(t, tx, 2, "user2")
+	_, err = tx.Insert("orders", map[string]interface{}{"id": int64(10), "user_id": int64(2)})
+	assert.Nil(t, err)
+	updated, err := tx.UpdateWhere("users", predicate.NewComparison("id", predicate.OpEq, int64(2)), map[string]interface{}{"username": "updated"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, updated.Updated)
+	assert.Nil(t, tx.Commit())
+
+	var errDone *TxDoneError
+	assert.ErrorAs(t, tx.Commit(), &errDone)
+	_, err = tx.Insert("users", map[string]interface{}{"id": int64(3)})
+	assert.ErrorAs(t, err, &errDone)
+
+	assert.Nil(t, db.Close())
+	db, err = NewDatabase("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	res, err := db.Tables["users"].Select(map[string]interface{}{"id": int64(2)})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, "updated", res.Rows[0]["username"])
+	res, err = db.Tables["orders"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+}
+
+func TestTx_RecoverAfterCrash(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	crashedPath := filepath.Join(BaseDir, "test_crashed")
+	defer os.RemoveAll(crashedPath)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	assert.Nil(t, tx.Commit())
+
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 2, "user2")
+	_, err = tx.DeleteWhere("users", predicate.NewComparison("id", predicate.OpEq, int64(1)))
+	assert.Nil(t, err)
+	_, err = tx.Insert("orders", map[string]interface{}{"id": int64(10), "user_id": int64(2)})
+	assert.Nil(t, err)
+	// Copying the files while the transaction is running is the same as a crash before Commit
+	assert.Nil(t, os.CopyFS(crashedPath, os.DirFS(db.Path)))
+	assert.Nil(t, tx.Rollback())
+	assert.Nil(t, db.Close())
+
+	crashed, err := NewDatabase("test_crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crashed.Close()
+	res, err := crashed.Tables["users"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, int64(1), res.Rows[0]["id"])
+	res, err = crashed.Tables["orders"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Rows)
+}
+
+func TestTx_RolledBackChangesAreNotReplayed(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	crashedPath := filepath.Join(BaseDir, "test_crashed")
+	defer os.RemoveAll(crashedPath)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	assert.Nil(t, tx.Commit())
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 2, "user2")
+	assert.Nil(t, tx.Rollback())
+
+	// Both transactions are in the WAL because there was no checkpoint since
+	assert.Nil(t, os.CopyFS(crashedPath, os.DirFS(db.Path)))
+	assert.Nil(t, db.Close())
+
+	crashed, err := NewDatabase("test_crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crashed.Close()
+	res, err := crashed.Tables["users"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, int64(1), res.Rows[0]["id"])
+}
+
+// A change outside of a transaction is replayed even if it was logged while a transaction that was never committed
+// was running
+func TestTx_RecoverInterleavedChanges(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	crashedPath := filepath.Join(BaseDir, "test_crashed")
+	defer os.RemoveAll(crashedPath)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	_, err = tx.Insert("orders", map[string]interface{}{"id": int64(10), "user_id": int64(1)})
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+	// The pages are written, so the next changes are only in the buffer pool and in the WAL
+	assert.Nil(t, db.Checkpoint())
+
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 2, "user2")
+	_, err = db.Tables["orders"].Insert(map[string]interface{}{"id": int64(11), "user_id": int64(1)}, true)
+	assert.Nil(t, err)
+	insertUser(t, tx, 3, "user3")
+	assert.Nil(t, os.CopyFS(crashedPath, os.DirFS(db.Path)))
+	assert.Nil(t, tx.Rollback())
+	assert.Nil(t, db.Close())
+
+	crashed, err := NewDatabase("test_crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crashed.Close()
+	res, err := crashed.Tables["users"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	res, err = crashed.Tables["orders"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 2)
+}
+
+// The pages a transaction modified can be written into the table files before it's committed. Rollback and recovery
+// revert them with the records in the WAL
+func TestTx_UndoWrittenPages(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	crashedPath := filepath.Join(BaseDir, "test_crashed")
+	defer os.RemoveAll(crashedPath)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	insertUser(t, tx, 2, "user2")
+	assert.Nil(t, tx.Commit())
+
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	_, err = tx.UpdateWhere("users", predicate.Eq("id", int64(1)), map[string]interface{}{"username": "updated"})
+	assert.Nil(t, err)
+	_, err = tx.DeleteWhere("users", predicate.Eq("id", int64(2)))
+	assert.Nil(t, err)
+	insertUser(t, tx, 3, "user3")
+	_, err = tx.Insert("orders", map[string]interface{}{"id": int64(10), "user_id": int64(3)})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Tables["users"].Flush())
+	assert.Nil(t, db.Tables["orders"].Flush())
+	assert.Nil(t, os.CopyFS(crashedPath, os.DirFS(db.Path)))
+	assert.Nil(t, tx.Rollback())
+
+	assertUsers := func(db *Database) {
+		res, err := db.Tables["users"].Select(map[string]interface{}{})
+		assert.Nil(t, err)
+		usernames := make(map[int64]interface{})
+		for _, row := range res.Rows {
+			usernames[row["id"].(int64)] = row["username"]
+		}
+		assert.Equal(t, map[int64]interface{}{1: "user1", 2: "user2"}, usernames)
+		res, err = db.Tables["users"].Select(map[string]interface{}{"id": int64(1)})
+		assert.Nil(t, err)
+		assert.Len(t, res.Rows, 1)
+		res, err = db.Tables["orders"].Select(map[string]interface{}{})
+		assert.Nil(t, err)
+		assert.Empty(t, res.Rows)
+	}
+	assertUsers(db)
+	assert.Nil(t, db.Close())
+
+	crashed, err := NewDatabase("test_crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crashed.Close()
+	assertUsers(crashed)
+}
+
+// A write outside of the transaction is not part of it, even if it modifies the same table
+func TestTx_SameTableWriteOutsideOfTransaction(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	_, err = db.Tables["users"].Insert(map[string]interface{}{
+		"id":        int64(2),
+		"username":  "user2",
+		"age":       byte(30),
+		"job":       "designer",
+		"is_active": true,
+	}, true)
+	assert.Nil(t, err)
+
+	// Snapshots see the write right away
+	res, err := db.SelectWhere("users", predicate.NewAnd())
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, int64(2), res.Rows[0]["id"])
+
+	assert.Nil(t, tx.Rollback())
+	res, err = db.Tables["users"].Select(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, int64(2), res.Rows[0]["id"])
+}
+
+func TestTx_BeginWaitsForRunningTransaction(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+
+	started := make(chan *Tx)
+	go func() {
+		tx, _ := db.Begin()
+		started <- tx
+	}()
+	select {
+	case <-started:
+		t.Fatal("Begin should wait until the running transaction is committed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Nil(t, tx.Commit())
+	select {
+	case tx = <-started:
+		assertRowCount(t, tx, "users", 1)
+		assert.Nil(t, tx.Rollback())
+	case <-time.After(time.Second):
+		t.Fatal("Begin should return after the running transaction is committed")
+	}
+}
+
+func createTxTestDB(t *testing.T) *Database {
+	db, err := CreateDatabase("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTable(db)
+
+	id, err := column.New("id", types.TypeInt64, column.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := column.New("user_id", types.TypeInt64, column.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.CreateTable(db.Path, "orders", []string{"id", "user_id"}, table.Columns{"id": id, "user_id": userID}, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func insertUser(t *testing.T, tx *Tx, id int64, username string) {
+	_, err := tx.Insert("users", map[string]interface{}{
+		"id":        id,
+		"username":  username,
+		"age":       byte(30),
+		"job":       "designer",
+		"is_active": true,
+	})
+	assert.Nil(t, err)
+}
+
+func assertRowCount(t *testing.T, tx *Tx, tableName string, expected int) {
+	res, err := tx.SelectWhere(tableName, predicate.NewAnd())
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, expected)
+}
+
+// Reads outside of a transaction see the records as they were when they started, even through an index
+func TestTx_SnapshotIsolation(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	insertUser(t, tx, 2, "user2")
+	assert.Nil(t, tx.Commit())
+
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	_, err = tx.UpdateWhere("users", predicate.Eq("id", int64(1)), map[string]interface{}{"username": "updated"})
+	assert.Nil(t, err)
+	_, err = tx.DeleteWhere("users", predicate.Eq("id", int64(2)))
+	assert.Nil(t, err)
+	insertUser(t, tx, 3, "user3")
+	assertRowCount(t, tx, "users", 2)
+
+	assertUsernames(t, db, predicate.NewAnd(), "user1", "user2")
+	assertUsernames(t, db, predicate.Eq("id", int64(1)), "user1")
+	assertUsernames(t, db, predicate.Eq("id", int64(3)))
+	// Versions visible to a snapshot are kept until it's released
+	snap := db.snapshot()
+	assert.Nil(t, tx.Commit())
+	n, err := db.Vacuum()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	res, err := db.Tables["users"].SelectSnapshot(predicate.Eq("id", int64(2)), snap)
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 1)
+
+	assertUsernames(t, db, predicate.NewAnd(), "updated", "user3")
+	db.releaseSnapshot(snap)
+	n, err = db.Vacuum()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assertUsernames(t, db, predicate.Eq("id", int64(1)), "updated")
+
+	// The expired versions and their deletion are replayed after a crash
+	crashedPath := filepath.Join(BaseDir, "test_crashed")
+	defer os.RemoveAll(crashedPath)
+	assert.Nil(t, os.CopyFS(crashedPath, os.DirFS(db.Path)))
+	assert.Nil(t, db.Close())
+	crashed, err := NewDatabase("test_crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crashed.Close()
+	res, err = crashed.SelectWhere("users", predicate.NewAnd())
+	assert.Nil(t, err)
+	assert.Len(t, res.Rows, 2)
+	res, err = crashed.SelectWhere("users", predicate.Eq("id", int64(2)))
+	assert.Nil(t, err)
+	assert.Empty(t, res.Rows)
+}
+
+func TestTx_CommitCheckpointReclaimsReplacedVersions(t *testing.T) {
+	db := createTxTestDB(t)
+	defer removeDB()
+	id, err := column.New("id", types.TypeInt64, column.Opts{})
+	assert.Nil(t, err)
+	data, err := column.New("data", types.TypeBlob, column.Opts{})
+	assert.Nil(t, err)
+	_, err = db.CreateTable(db.Path, "files", []string{"id", "data"}, table.Columns{"id": id, "data": data}, []string{"id"})
+	assert.Nil(t, err)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	insertUser(t, tx, 1, "user1")
+	assert.Nil(t, tx.Commit())
+
+	// The blob makes the WAL large enough for the commit to take a checkpoint
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	_, err = tx.UpdateWhere("users", predicate.Eq("id", int64(1)), map[string]interface{}{"username": "updated"})
+	assert.Nil(t, err)
+	_, err = tx.Insert("files", map[string]interface{}{"id": int64(1), "data": make([]byte, wal.CheckpointSize)})
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	// The transaction was finished before the checkpoint, so the version it replaced is already reclaimed
+	assert.Equal(t, db.wal.LSN(), db.wal.CheckpointLSN())
+	records, err := db.Tables["users"].ReadRawRecords()
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assertUsernames(t, db, predicate.NewAnd(), "updated")
+}
+
+func assertUsernames(t *testing.T, db *Database, pred predicate.Predicate, expected ...string) {
+	res, err := db.SelectWhere("users", pred)
+	assert.Nil(t, err)
+	usernames := make([]string, 0, len(res.Rows))
+	for _, row := range res.Rows {
+		usernames = append(usernames, row["username"].(string))
+	}
+	assert.ElementsMatch(t, expected, usernames)
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/omesh-barhate/ByteForge/internal/table"
+	"github.com/omesh-barhate/ByteForge/internal/table/mvcc"
+)
+
+// snapshot returns a snapshot of the committed transactions and registers it, so Vacuum keeps the versions it can see
+// It has to be released with releaseSnapshot after it was read
+func (db *Database) snapshot() *mvcc.Snapshot {
+	db.snapMu.Lock()
+	defer db.snapMu.Unlock()
+	snap := mvcc.NewSnapshot(db.wal.LSN()+1, db.activeTxID)
+	db.snapshots[snap.Horizon()]++
+	return snap
+}
+
+func (db *Database) releaseSnapshot(snap *mvcc.Snapshot) {
+	db.snapMu.Lock()
+	defer db.snapMu.Unlock()
+	db.snapshots[snap.Horizon()]--
+	if db.snapshots[snap.Horizon()] <= 0 {
+		delete(db.snapshots, snap.Horizon())
+	}
+}
+
+// horizon returns the smallest transaction ID whose changes one of the snapshots or the running transaction might not see
+func (db *Database) horizon() int64 {
+	db.snapMu.Lock()
+	defer db.snapMu.Unlock()
+	h := db.wal.LSN() + 1
+	if db.activeTxID != 0 {
+		h = min(h, db.activeTxID)
+	}
+	for snapHorizon := range db.snapshots {
+		h = min(h, snapHorizon)
+	}
+	return h
+}
+
+// Vacuum deletes the versions of records that were deleted by a transaction and cannot be seen by any snapshot anymore
+// and rewrites the files of the given tables, or of every table if there are none, so the space of deleted records is
+// given back. It returns how many versions were deleted and waits until the running transaction is finished
+func (db *Database) Vacuum(tableNames ...string) (int, error) {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	if len(tableNames) == 0 {
+		tableNames = db.TableNames()
+	}
+	tables := make([]*table.Table, 0, len(tableNames))
+	for _, name := range tableNames {
+		t, ok := db.Table(name)
+		if !ok {
+			return 0, fmt.Errorf("Database.Vacuum: %w", NewTableDoesNotExistError(name))
+		}
+		tables = append(tables, t)
+	}
+
+	n, err := db.reclaimVersions()
+	if err != nil {
+		return n, fmt.Errorf("Database.Vacuum: %w", err)
+	}
+	// The entries before the checkpoint refer to the pages of the old files, so they must not be replayed anymore
+	if err = db.checkpoint(); err != nil {
+		return n, fmt.Errorf("Database.Vacuum: %w", err)
+	}
+	for _, t := range tables {
+		if err = t.Vacuum(); err != nil {
+			return n, fmt.Errorf("Database.Vacuum: %w", err)
+		}
+	}
+	if err = db.checkpoint(); err != nil {
+		return n, fmt.Errorf("Database.Vacuum: %w", err)
+	}
+	return n, nil
+}
+
+// reclaimVersions deletes the versions of records that no snapshot can see anymore and returns how many were deleted
+func (db *Database) reclaimVersions() (int, error) {
+	horizon := db.horizon()
+	n := 0
+	for _, t := range db.Tables {
+		reclaimed, err := t.ReclaimVersions(horizon)
+		n += reclaimed
+		if err != nil {
+			return n, fmt.Errorf("Database.reclaimVersions: %w", err)
+		}
+	}
+	return n, nil
+}